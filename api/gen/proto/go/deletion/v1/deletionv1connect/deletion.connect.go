@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: deletion/v1/deletion.proto
+
+package deletionv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	v1 "github.com/grafana/pyroscope/api/gen/proto/go/deletion/v1"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// DeletionServiceName is the fully-qualified name of the DeletionService service.
+	DeletionServiceName = "deletion.v1.DeletionService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// DeletionServiceCreateDeleteRequestProcedure is the fully-qualified name of the DeletionService's
+	// CreateDeleteRequest RPC.
+	DeletionServiceCreateDeleteRequestProcedure = "/deletion.v1.DeletionService/CreateDeleteRequest"
+	// DeletionServiceListDeleteRequestsProcedure is the fully-qualified name of the DeletionService's
+	// ListDeleteRequests RPC.
+	DeletionServiceListDeleteRequestsProcedure = "/deletion.v1.DeletionService/ListDeleteRequests"
+	// DeletionServiceCancelDeleteRequestProcedure is the fully-qualified name of the DeletionService's
+	// CancelDeleteRequest RPC.
+	DeletionServiceCancelDeleteRequestProcedure = "/deletion.v1.DeletionService/CancelDeleteRequest"
+)
+
+// These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
+var (
+	deletionServiceServiceDescriptor                   = v1.File_deletion_v1_deletion_proto.Services().ByName("DeletionService")
+	deletionServiceCreateDeleteRequestMethodDescriptor = deletionServiceServiceDescriptor.Methods().ByName("CreateDeleteRequest")
+	deletionServiceListDeleteRequestsMethodDescriptor  = deletionServiceServiceDescriptor.Methods().ByName("ListDeleteRequests")
+	deletionServiceCancelDeleteRequestMethodDescriptor = deletionServiceServiceDescriptor.Methods().ByName("CancelDeleteRequest")
+)
+
+// DeletionServiceClient is a client for the deletion.v1.DeletionService service.
+type DeletionServiceClient interface {
+	// Create a request to delete the profiles of the series matching the label selector within the time range. The
+	// profiles are no longer returned by queries once the request is created, and are removed from the storage by the
+	// compactor eventually.
+	CreateDeleteRequest(context.Context, *connect.Request[v1.CreateDeleteRequestRequest]) (*connect.Response[v1.CreateDeleteRequestResponse], error)
+	// Retrieves the list of delete requests of the tenant.
+	ListDeleteRequests(context.Context, *connect.Request[v1.ListDeleteRequestsRequest]) (*connect.Response[v1.ListDeleteRequestsResponse], error)
+	// Cancel a delete request. Only the requests that have not been processed by the compactor yet can be cancelled.
+	CancelDeleteRequest(context.Context, *connect.Request[v1.CancelDeleteRequestRequest]) (*connect.Response[v1.CancelDeleteRequestResponse], error)
+}
+
+// NewDeletionServiceClient constructs a client for the deletion.v1.DeletionService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewDeletionServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) DeletionServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	return &deletionServiceClient{
+		createDeleteRequest: connect.NewClient[v1.CreateDeleteRequestRequest, v1.CreateDeleteRequestResponse](
+			httpClient,
+			baseURL+DeletionServiceCreateDeleteRequestProcedure,
+			connect.WithSchema(deletionServiceCreateDeleteRequestMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		listDeleteRequests: connect.NewClient[v1.ListDeleteRequestsRequest, v1.ListDeleteRequestsResponse](
+			httpClient,
+			baseURL+DeletionServiceListDeleteRequestsProcedure,
+			connect.WithSchema(deletionServiceListDeleteRequestsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		cancelDeleteRequest: connect.NewClient[v1.CancelDeleteRequestRequest, v1.CancelDeleteRequestResponse](
+			httpClient,
+			baseURL+DeletionServiceCancelDeleteRequestProcedure,
+			connect.WithSchema(deletionServiceCancelDeleteRequestMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// deletionServiceClient implements DeletionServiceClient.
+type deletionServiceClient struct {
+	createDeleteRequest *connect.Client[v1.CreateDeleteRequestRequest, v1.CreateDeleteRequestResponse]
+	listDeleteRequests  *connect.Client[v1.ListDeleteRequestsRequest, v1.ListDeleteRequestsResponse]
+	cancelDeleteRequest *connect.Client[v1.CancelDeleteRequestRequest, v1.CancelDeleteRequestResponse]
+}
+
+// CreateDeleteRequest calls deletion.v1.DeletionService.CreateDeleteRequest.
+func (c *deletionServiceClient) CreateDeleteRequest(ctx context.Context, req *connect.Request[v1.CreateDeleteRequestRequest]) (*connect.Response[v1.CreateDeleteRequestResponse], error) {
+	return c.createDeleteRequest.CallUnary(ctx, req)
+}
+
+// ListDeleteRequests calls deletion.v1.DeletionService.ListDeleteRequests.
+func (c *deletionServiceClient) ListDeleteRequests(ctx context.Context, req *connect.Request[v1.ListDeleteRequestsRequest]) (*connect.Response[v1.ListDeleteRequestsResponse], error) {
+	return c.listDeleteRequests.CallUnary(ctx, req)
+}
+
+// CancelDeleteRequest calls deletion.v1.DeletionService.CancelDeleteRequest.
+func (c *deletionServiceClient) CancelDeleteRequest(ctx context.Context, req *connect.Request[v1.CancelDeleteRequestRequest]) (*connect.Response[v1.CancelDeleteRequestResponse], error) {
+	return c.cancelDeleteRequest.CallUnary(ctx, req)
+}
+
+// DeletionServiceHandler is an implementation of the deletion.v1.DeletionService service.
+type DeletionServiceHandler interface {
+	// Create a request to delete the profiles of the series matching the label selector within the time range. The
+	// profiles are no longer returned by queries once the request is created, and are removed from the storage by the
+	// compactor eventually.
+	CreateDeleteRequest(context.Context, *connect.Request[v1.CreateDeleteRequestRequest]) (*connect.Response[v1.CreateDeleteRequestResponse], error)
+	// Retrieves the list of delete requests of the tenant.
+	ListDeleteRequests(context.Context, *connect.Request[v1.ListDeleteRequestsRequest]) (*connect.Response[v1.ListDeleteRequestsResponse], error)
+	// Cancel a delete request. Only the requests that have not been processed by the compactor yet can be cancelled.
+	CancelDeleteRequest(context.Context, *connect.Request[v1.CancelDeleteRequestRequest]) (*connect.Response[v1.CancelDeleteRequestResponse], error)
+}
+
+// NewDeletionServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewDeletionServiceHandler(svc DeletionServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	deletionServiceCreateDeleteRequestHandler := connect.NewUnaryHandler(
+		DeletionServiceCreateDeleteRequestProcedure,
+		svc.CreateDeleteRequest,
+		connect.WithSchema(deletionServiceCreateDeleteRequestMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	deletionServiceListDeleteRequestsHandler := connect.NewUnaryHandler(
+		DeletionServiceListDeleteRequestsProcedure,
+		svc.ListDeleteRequests,
+		connect.WithSchema(deletionServiceListDeleteRequestsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	deletionServiceCancelDeleteRequestHandler := connect.NewUnaryHandler(
+		DeletionServiceCancelDeleteRequestProcedure,
+		svc.CancelDeleteRequest,
+		connect.WithSchema(deletionServiceCancelDeleteRequestMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/deletion.v1.DeletionService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case DeletionServiceCreateDeleteRequestProcedure:
+			deletionServiceCreateDeleteRequestHandler.ServeHTTP(w, r)
+		case DeletionServiceListDeleteRequestsProcedure:
+			deletionServiceListDeleteRequestsHandler.ServeHTTP(w, r)
+		case DeletionServiceCancelDeleteRequestProcedure:
+			deletionServiceCancelDeleteRequestHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedDeletionServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedDeletionServiceHandler struct{}
+
+func (UnimplementedDeletionServiceHandler) CreateDeleteRequest(context.Context, *connect.Request[v1.CreateDeleteRequestRequest]) (*connect.Response[v1.CreateDeleteRequestResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("deletion.v1.DeletionService.CreateDeleteRequest is not implemented"))
+}
+
+func (UnimplementedDeletionServiceHandler) ListDeleteRequests(context.Context, *connect.Request[v1.ListDeleteRequestsRequest]) (*connect.Response[v1.ListDeleteRequestsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("deletion.v1.DeletionService.ListDeleteRequests is not implemented"))
+}
+
+func (UnimplementedDeletionServiceHandler) CancelDeleteRequest(context.Context, *connect.Request[v1.CancelDeleteRequestRequest]) (*connect.Response[v1.CancelDeleteRequestResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("deletion.v1.DeletionService.CancelDeleteRequest is not implemented"))
+}
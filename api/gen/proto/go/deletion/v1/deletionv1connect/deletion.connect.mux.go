@@ -0,0 +1,37 @@
+// Code generated by protoc-gen-connect-go-mux. DO NOT EDIT.
+//
+// Source: deletion/v1/deletion.proto
+
+package deletionv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	mux "github.com/gorilla/mux"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion0_1_0
+
+// RegisterDeletionServiceHandler register an HTTP handler to a mux.Router from the service
+// implementation.
+func RegisterDeletionServiceHandler(mux *mux.Router, svc DeletionServiceHandler, opts ...connect.HandlerOption) {
+	mux.Handle("/deletion.v1.DeletionService/CreateDeleteRequest", connect.NewUnaryHandler(
+		"/deletion.v1.DeletionService/CreateDeleteRequest",
+		svc.CreateDeleteRequest,
+		opts...,
+	))
+	mux.Handle("/deletion.v1.DeletionService/ListDeleteRequests", connect.NewUnaryHandler(
+		"/deletion.v1.DeletionService/ListDeleteRequests",
+		svc.ListDeleteRequests,
+		opts...,
+	))
+	mux.Handle("/deletion.v1.DeletionService/CancelDeleteRequest", connect.NewUnaryHandler(
+		"/deletion.v1.DeletionService/CancelDeleteRequest",
+		svc.CancelDeleteRequest,
+		opts...,
+	))
+}
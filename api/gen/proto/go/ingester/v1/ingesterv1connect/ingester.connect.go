@@ -68,6 +68,15 @@ const (
 	// IngesterServiceGetProfileStatsProcedure is the fully-qualified name of the IngesterService's
 	// GetProfileStats RPC.
 	IngesterServiceGetProfileStatsProcedure = "/ingester.v1.IngesterService/GetProfileStats"
+	// IngesterServiceGetCardinalityProcedure is the fully-qualified name of the IngesterService's
+	// GetCardinality RPC.
+	IngesterServiceGetCardinalityProcedure = "/ingester.v1.IngesterService/GetCardinality"
+	// IngesterServiceSelectExemplarsProcedure is the fully-qualified name of the IngesterService's
+	// SelectExemplars RPC.
+	IngesterServiceSelectExemplarsProcedure = "/ingester.v1.IngesterService/SelectExemplars"
+	// IngesterServiceSelectProfileByIDProcedure is the fully-qualified name of the IngesterService's
+	// SelectProfileByID RPC.
+	IngesterServiceSelectProfileByIDProcedure = "/ingester.v1.IngesterService/SelectProfileByID"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
@@ -85,6 +94,9 @@ var (
 	ingesterServiceMergeSpanProfileMethodDescriptor         = ingesterServiceServiceDescriptor.Methods().ByName("MergeSpanProfile")
 	ingesterServiceBlockMetadataMethodDescriptor            = ingesterServiceServiceDescriptor.Methods().ByName("BlockMetadata")
 	ingesterServiceGetProfileStatsMethodDescriptor          = ingesterServiceServiceDescriptor.Methods().ByName("GetProfileStats")
+	ingesterServiceGetCardinalityMethodDescriptor           = ingesterServiceServiceDescriptor.Methods().ByName("GetCardinality")
+	ingesterServiceSelectExemplarsMethodDescriptor          = ingesterServiceServiceDescriptor.Methods().ByName("SelectExemplars")
+	ingesterServiceSelectProfileByIDMethodDescriptor        = ingesterServiceServiceDescriptor.Methods().ByName("SelectProfileByID")
 )
 
 // IngesterServiceClient is a client for the ingester.v1.IngesterService service.
@@ -104,6 +116,13 @@ type IngesterServiceClient interface {
 	BlockMetadata(context.Context, *connect.Request[v1.BlockMetadataRequest]) (*connect.Response[v1.BlockMetadataResponse], error)
 	// GetProfileStats returns profile stats for the current tenant.
 	GetProfileStats(context.Context, *connect.Request[v12.GetProfileStatsRequest]) (*connect.Response[v12.GetProfileStatsResponse], error)
+	// GetCardinality returns the label values and series of the current
+	// tenant read from the TSDB index, for the querier to merge.
+	GetCardinality(context.Context, *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error)
+	// SelectExemplars returns the individual profiles with the highest total values.
+	SelectExemplars(context.Context, *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error)
+	// SelectProfileByID returns a single profile in the pprof format.
+	SelectProfileByID(context.Context, *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v1.SelectProfileByIDResponse], error)
 }
 
 // NewIngesterServiceClient constructs a client for the ingester.v1.IngesterService service. By
@@ -188,6 +207,24 @@ func NewIngesterServiceClient(httpClient connect.HTTPClient, baseURL string, opt
 			connect.WithSchema(ingesterServiceGetProfileStatsMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		getCardinality: connect.NewClient[v1.GetCardinalityRequest, v1.GetCardinalityResponse](
+			httpClient,
+			baseURL+IngesterServiceGetCardinalityProcedure,
+			connect.WithSchema(ingesterServiceGetCardinalityMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		selectExemplars: connect.NewClient[v1.SelectExemplarsRequest, v1.SelectExemplarsResponse](
+			httpClient,
+			baseURL+IngesterServiceSelectExemplarsProcedure,
+			connect.WithSchema(ingesterServiceSelectExemplarsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		selectProfileByID: connect.NewClient[v1.SelectProfileByIDRequest, v1.SelectProfileByIDResponse](
+			httpClient,
+			baseURL+IngesterServiceSelectProfileByIDProcedure,
+			connect.WithSchema(ingesterServiceSelectProfileByIDMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
@@ -205,6 +242,9 @@ type ingesterServiceClient struct {
 	mergeSpanProfile         *connect.Client[v1.MergeSpanProfileRequest, v1.MergeSpanProfileResponse]
 	blockMetadata            *connect.Client[v1.BlockMetadataRequest, v1.BlockMetadataResponse]
 	getProfileStats          *connect.Client[v12.GetProfileStatsRequest, v12.GetProfileStatsResponse]
+	getCardinality           *connect.Client[v1.GetCardinalityRequest, v1.GetCardinalityResponse]
+	selectExemplars          *connect.Client[v1.SelectExemplarsRequest, v1.SelectExemplarsResponse]
+	selectProfileByID        *connect.Client[v1.SelectProfileByIDRequest, v1.SelectProfileByIDResponse]
 }
 
 // Push calls ingester.v1.IngesterService.Push.
@@ -267,6 +307,21 @@ func (c *ingesterServiceClient) GetProfileStats(ctx context.Context, req *connec
 	return c.getProfileStats.CallUnary(ctx, req)
 }
 
+// GetCardinality calls ingester.v1.IngesterService.GetCardinality.
+func (c *ingesterServiceClient) GetCardinality(ctx context.Context, req *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error) {
+	return c.getCardinality.CallUnary(ctx, req)
+}
+
+// SelectExemplars calls ingester.v1.IngesterService.SelectExemplars.
+func (c *ingesterServiceClient) SelectExemplars(ctx context.Context, req *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error) {
+	return c.selectExemplars.CallUnary(ctx, req)
+}
+
+// SelectProfileByID calls ingester.v1.IngesterService.SelectProfileByID.
+func (c *ingesterServiceClient) SelectProfileByID(ctx context.Context, req *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v1.SelectProfileByIDResponse], error) {
+	return c.selectProfileByID.CallUnary(ctx, req)
+}
+
 // IngesterServiceHandler is an implementation of the ingester.v1.IngesterService service.
 type IngesterServiceHandler interface {
 	Push(context.Context, *connect.Request[v11.PushRequest]) (*connect.Response[v11.PushResponse], error)
@@ -284,6 +339,13 @@ type IngesterServiceHandler interface {
 	BlockMetadata(context.Context, *connect.Request[v1.BlockMetadataRequest]) (*connect.Response[v1.BlockMetadataResponse], error)
 	// GetProfileStats returns profile stats for the current tenant.
 	GetProfileStats(context.Context, *connect.Request[v12.GetProfileStatsRequest]) (*connect.Response[v12.GetProfileStatsResponse], error)
+	// GetCardinality returns the label values and series of the current
+	// tenant read from the TSDB index, for the querier to merge.
+	GetCardinality(context.Context, *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error)
+	// SelectExemplars returns the individual profiles with the highest total values.
+	SelectExemplars(context.Context, *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error)
+	// SelectProfileByID returns a single profile in the pprof format.
+	SelectProfileByID(context.Context, *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v1.SelectProfileByIDResponse], error)
 }
 
 // NewIngesterServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -364,6 +426,24 @@ func NewIngesterServiceHandler(svc IngesterServiceHandler, opts ...connect.Handl
 		connect.WithSchema(ingesterServiceGetProfileStatsMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	ingesterServiceGetCardinalityHandler := connect.NewUnaryHandler(
+		IngesterServiceGetCardinalityProcedure,
+		svc.GetCardinality,
+		connect.WithSchema(ingesterServiceGetCardinalityMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	ingesterServiceSelectExemplarsHandler := connect.NewUnaryHandler(
+		IngesterServiceSelectExemplarsProcedure,
+		svc.SelectExemplars,
+		connect.WithSchema(ingesterServiceSelectExemplarsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	ingesterServiceSelectProfileByIDHandler := connect.NewUnaryHandler(
+		IngesterServiceSelectProfileByIDProcedure,
+		svc.SelectProfileByID,
+		connect.WithSchema(ingesterServiceSelectProfileByIDMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/ingester.v1.IngesterService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case IngesterServicePushProcedure:
@@ -390,6 +470,12 @@ func NewIngesterServiceHandler(svc IngesterServiceHandler, opts ...connect.Handl
 			ingesterServiceBlockMetadataHandler.ServeHTTP(w, r)
 		case IngesterServiceGetProfileStatsProcedure:
 			ingesterServiceGetProfileStatsHandler.ServeHTTP(w, r)
+		case IngesterServiceGetCardinalityProcedure:
+			ingesterServiceGetCardinalityHandler.ServeHTTP(w, r)
+		case IngesterServiceSelectExemplarsProcedure:
+			ingesterServiceSelectExemplarsHandler.ServeHTTP(w, r)
+		case IngesterServiceSelectProfileByIDProcedure:
+			ingesterServiceSelectProfileByIDHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -446,3 +532,15 @@ func (UnimplementedIngesterServiceHandler) BlockMetadata(context.Context, *conne
 func (UnimplementedIngesterServiceHandler) GetProfileStats(context.Context, *connect.Request[v12.GetProfileStatsRequest]) (*connect.Response[v12.GetProfileStatsResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ingester.v1.IngesterService.GetProfileStats is not implemented"))
 }
+
+func (UnimplementedIngesterServiceHandler) GetCardinality(context.Context, *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ingester.v1.IngesterService.GetCardinality is not implemented"))
+}
+
+func (UnimplementedIngesterServiceHandler) SelectExemplars(context.Context, *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ingester.v1.IngesterService.SelectExemplars is not implemented"))
+}
+
+func (UnimplementedIngesterServiceHandler) SelectProfileByID(context.Context, *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v1.SelectProfileByIDResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ingester.v1.IngesterService.SelectProfileByID is not implemented"))
+}
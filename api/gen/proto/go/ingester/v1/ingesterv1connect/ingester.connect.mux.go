@@ -79,4 +79,19 @@ func RegisterIngesterServiceHandler(mux *mux.Router, svc IngesterServiceHandler,
 		svc.GetProfileStats,
 		opts...,
 	))
+	mux.Handle("/ingester.v1.IngesterService/GetCardinality", connect.NewUnaryHandler(
+		"/ingester.v1.IngesterService/GetCardinality",
+		svc.GetCardinality,
+		opts...,
+	))
+	mux.Handle("/ingester.v1.IngesterService/SelectExemplars", connect.NewUnaryHandler(
+		"/ingester.v1.IngesterService/SelectExemplars",
+		svc.SelectExemplars,
+		opts...,
+	))
+	mux.Handle("/ingester.v1.IngesterService/SelectProfileByID", connect.NewUnaryHandler(
+		"/ingester.v1.IngesterService/SelectProfileByID",
+		svc.SelectProfileByID,
+		opts...,
+	))
 }
@@ -52,17 +52,32 @@ const (
 	// QuerierServiceSelectMergeSpanProfileProcedure is the fully-qualified name of the QuerierService's
 	// SelectMergeSpanProfile RPC.
 	QuerierServiceSelectMergeSpanProfileProcedure = "/querier.v1.QuerierService/SelectMergeSpanProfile"
+	// QuerierServiceSelectFunctionsProcedure is the fully-qualified name of the QuerierService's
+	// SelectFunctions RPC.
+	QuerierServiceSelectFunctionsProcedure = "/querier.v1.QuerierService/SelectFunctions"
 	// QuerierServiceSelectMergeProfileProcedure is the fully-qualified name of the QuerierService's
 	// SelectMergeProfile RPC.
 	QuerierServiceSelectMergeProfileProcedure = "/querier.v1.QuerierService/SelectMergeProfile"
 	// QuerierServiceSelectSeriesProcedure is the fully-qualified name of the QuerierService's
 	// SelectSeries RPC.
 	QuerierServiceSelectSeriesProcedure = "/querier.v1.QuerierService/SelectSeries"
+	// QuerierServiceSelectExemplarsProcedure is the fully-qualified name of the QuerierService's
+	// SelectExemplars RPC.
+	QuerierServiceSelectExemplarsProcedure = "/querier.v1.QuerierService/SelectExemplars"
+	// QuerierServiceSelectProfileByIDProcedure is the fully-qualified name of the QuerierService's
+	// SelectProfileByID RPC.
+	QuerierServiceSelectProfileByIDProcedure = "/querier.v1.QuerierService/SelectProfileByID"
 	// QuerierServiceDiffProcedure is the fully-qualified name of the QuerierService's Diff RPC.
 	QuerierServiceDiffProcedure = "/querier.v1.QuerierService/Diff"
+	// QuerierServiceDiffByLabelProcedure is the fully-qualified name of the QuerierService's
+	// DiffByLabel RPC.
+	QuerierServiceDiffByLabelProcedure = "/querier.v1.QuerierService/DiffByLabel"
 	// QuerierServiceGetProfileStatsProcedure is the fully-qualified name of the QuerierService's
 	// GetProfileStats RPC.
 	QuerierServiceGetProfileStatsProcedure = "/querier.v1.QuerierService/GetProfileStats"
+	// QuerierServiceGetCardinalityProcedure is the fully-qualified name of the QuerierService's
+	// GetCardinality RPC.
+	QuerierServiceGetCardinalityProcedure = "/querier.v1.QuerierService/GetCardinality"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
@@ -74,10 +89,15 @@ var (
 	querierServiceSeriesMethodDescriptor                 = querierServiceServiceDescriptor.Methods().ByName("Series")
 	querierServiceSelectMergeStacktracesMethodDescriptor = querierServiceServiceDescriptor.Methods().ByName("SelectMergeStacktraces")
 	querierServiceSelectMergeSpanProfileMethodDescriptor = querierServiceServiceDescriptor.Methods().ByName("SelectMergeSpanProfile")
+	querierServiceSelectFunctionsMethodDescriptor        = querierServiceServiceDescriptor.Methods().ByName("SelectFunctions")
 	querierServiceSelectMergeProfileMethodDescriptor     = querierServiceServiceDescriptor.Methods().ByName("SelectMergeProfile")
 	querierServiceSelectSeriesMethodDescriptor           = querierServiceServiceDescriptor.Methods().ByName("SelectSeries")
+	querierServiceSelectExemplarsMethodDescriptor        = querierServiceServiceDescriptor.Methods().ByName("SelectExemplars")
+	querierServiceSelectProfileByIDMethodDescriptor      = querierServiceServiceDescriptor.Methods().ByName("SelectProfileByID")
 	querierServiceDiffMethodDescriptor                   = querierServiceServiceDescriptor.Methods().ByName("Diff")
+	querierServiceDiffByLabelMethodDescriptor            = querierServiceServiceDescriptor.Methods().ByName("DiffByLabel")
 	querierServiceGetProfileStatsMethodDescriptor        = querierServiceServiceDescriptor.Methods().ByName("GetProfileStats")
+	querierServiceGetCardinalityMethodDescriptor         = querierServiceServiceDescriptor.Methods().ByName("GetCardinality")
 )
 
 // QuerierServiceClient is a client for the querier.v1.QuerierService service.
@@ -94,14 +114,26 @@ type QuerierServiceClient interface {
 	SelectMergeStacktraces(context.Context, *connect.Request[v1.SelectMergeStacktracesRequest]) (*connect.Response[v1.SelectMergeStacktracesResponse], error)
 	// SelectMergeSpanProfile returns matching profiles aggregated in a flamegraph format. It will combine samples from within the same callstack, with each element being grouped by its function name.
 	SelectMergeSpanProfile(context.Context, *connect.Request[v1.SelectMergeSpanProfileRequest]) (*connect.Response[v1.SelectMergeSpanProfileResponse], error)
+	// SelectFunctions returns matching profiles aggregated in a table of functions with their self and total values, sorted and truncated server-side.
+	SelectFunctions(context.Context, *connect.Request[v1.SelectFunctionsRequest]) (*connect.Response[v1.SelectFunctionsResponse], error)
 	// SelectMergeProfile returns matching profiles aggregated in pprof format. It will contain all information stored (so including filenames and line number, if ingested).
 	SelectMergeProfile(context.Context, *connect.Request[v1.SelectMergeProfileRequest]) (*connect.Response[v12.Profile], error)
 	// SelectSeries returns a time series for the total sum of the requested profiles.
 	SelectSeries(context.Context, *connect.Request[v1.SelectSeriesRequest]) (*connect.Response[v1.SelectSeriesResponse], error)
+	// SelectExemplars returns the individual profiles with the highest total values, along with their IDs, timestamps and labels.
+	SelectExemplars(context.Context, *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error)
+	// SelectProfileByID returns a single profile, identified by its ID, in pprof format.
+	SelectProfileByID(context.Context, *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v12.Profile], error)
 	// Diff returns a diff of two profiles
 	Diff(context.Context, *connect.Request[v1.DiffRequest]) (*connect.Response[v1.DiffResponse], error)
+	// DiffByLabel returns a diff of the profiles matching the selector for two
+	// values of a label, along with per-function deltas ranked by regression.
+	DiffByLabel(context.Context, *connect.Request[v1.DiffByLabelRequest]) (*connect.Response[v1.DiffByLabelResponse], error)
 	// GetProfileStats returns profile stats for the current tenant.
 	GetProfileStats(context.Context, *connect.Request[v11.GetProfileStatsRequest]) (*connect.Response[v11.GetProfileStatsResponse], error)
+	// GetCardinality returns the label names with the most distinct values and
+	// the services and profile types with the most series for the current tenant.
+	GetCardinality(context.Context, *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error)
 }
 
 // NewQuerierServiceClient constructs a client for the querier.v1.QuerierService service. By
@@ -150,6 +182,12 @@ func NewQuerierServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(querierServiceSelectMergeSpanProfileMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		selectFunctions: connect.NewClient[v1.SelectFunctionsRequest, v1.SelectFunctionsResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectFunctionsProcedure,
+			connect.WithSchema(querierServiceSelectFunctionsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 		selectMergeProfile: connect.NewClient[v1.SelectMergeProfileRequest, v12.Profile](
 			httpClient,
 			baseURL+QuerierServiceSelectMergeProfileProcedure,
@@ -162,18 +200,42 @@ func NewQuerierServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(querierServiceSelectSeriesMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		selectExemplars: connect.NewClient[v1.SelectExemplarsRequest, v1.SelectExemplarsResponse](
+			httpClient,
+			baseURL+QuerierServiceSelectExemplarsProcedure,
+			connect.WithSchema(querierServiceSelectExemplarsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		selectProfileByID: connect.NewClient[v1.SelectProfileByIDRequest, v12.Profile](
+			httpClient,
+			baseURL+QuerierServiceSelectProfileByIDProcedure,
+			connect.WithSchema(querierServiceSelectProfileByIDMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 		diff: connect.NewClient[v1.DiffRequest, v1.DiffResponse](
 			httpClient,
 			baseURL+QuerierServiceDiffProcedure,
 			connect.WithSchema(querierServiceDiffMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		diffByLabel: connect.NewClient[v1.DiffByLabelRequest, v1.DiffByLabelResponse](
+			httpClient,
+			baseURL+QuerierServiceDiffByLabelProcedure,
+			connect.WithSchema(querierServiceDiffByLabelMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 		getProfileStats: connect.NewClient[v11.GetProfileStatsRequest, v11.GetProfileStatsResponse](
 			httpClient,
 			baseURL+QuerierServiceGetProfileStatsProcedure,
 			connect.WithSchema(querierServiceGetProfileStatsMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		getCardinality: connect.NewClient[v1.GetCardinalityRequest, v1.GetCardinalityResponse](
+			httpClient,
+			baseURL+QuerierServiceGetCardinalityProcedure,
+			connect.WithSchema(querierServiceGetCardinalityMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
@@ -185,10 +247,15 @@ type querierServiceClient struct {
 	series                 *connect.Client[v1.SeriesRequest, v1.SeriesResponse]
 	selectMergeStacktraces *connect.Client[v1.SelectMergeStacktracesRequest, v1.SelectMergeStacktracesResponse]
 	selectMergeSpanProfile *connect.Client[v1.SelectMergeSpanProfileRequest, v1.SelectMergeSpanProfileResponse]
+	selectFunctions        *connect.Client[v1.SelectFunctionsRequest, v1.SelectFunctionsResponse]
 	selectMergeProfile     *connect.Client[v1.SelectMergeProfileRequest, v12.Profile]
 	selectSeries           *connect.Client[v1.SelectSeriesRequest, v1.SelectSeriesResponse]
+	selectExemplars        *connect.Client[v1.SelectExemplarsRequest, v1.SelectExemplarsResponse]
+	selectProfileByID      *connect.Client[v1.SelectProfileByIDRequest, v12.Profile]
 	diff                   *connect.Client[v1.DiffRequest, v1.DiffResponse]
+	diffByLabel            *connect.Client[v1.DiffByLabelRequest, v1.DiffByLabelResponse]
 	getProfileStats        *connect.Client[v11.GetProfileStatsRequest, v11.GetProfileStatsResponse]
+	getCardinality         *connect.Client[v1.GetCardinalityRequest, v1.GetCardinalityResponse]
 }
 
 // ProfileTypes calls querier.v1.QuerierService.ProfileTypes.
@@ -221,6 +288,11 @@ func (c *querierServiceClient) SelectMergeSpanProfile(ctx context.Context, req *
 	return c.selectMergeSpanProfile.CallUnary(ctx, req)
 }
 
+// SelectFunctions calls querier.v1.QuerierService.SelectFunctions.
+func (c *querierServiceClient) SelectFunctions(ctx context.Context, req *connect.Request[v1.SelectFunctionsRequest]) (*connect.Response[v1.SelectFunctionsResponse], error) {
+	return c.selectFunctions.CallUnary(ctx, req)
+}
+
 // SelectMergeProfile calls querier.v1.QuerierService.SelectMergeProfile.
 func (c *querierServiceClient) SelectMergeProfile(ctx context.Context, req *connect.Request[v1.SelectMergeProfileRequest]) (*connect.Response[v12.Profile], error) {
 	return c.selectMergeProfile.CallUnary(ctx, req)
@@ -231,16 +303,36 @@ func (c *querierServiceClient) SelectSeries(ctx context.Context, req *connect.Re
 	return c.selectSeries.CallUnary(ctx, req)
 }
 
+// SelectExemplars calls querier.v1.QuerierService.SelectExemplars.
+func (c *querierServiceClient) SelectExemplars(ctx context.Context, req *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error) {
+	return c.selectExemplars.CallUnary(ctx, req)
+}
+
+// SelectProfileByID calls querier.v1.QuerierService.SelectProfileByID.
+func (c *querierServiceClient) SelectProfileByID(ctx context.Context, req *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v12.Profile], error) {
+	return c.selectProfileByID.CallUnary(ctx, req)
+}
+
 // Diff calls querier.v1.QuerierService.Diff.
 func (c *querierServiceClient) Diff(ctx context.Context, req *connect.Request[v1.DiffRequest]) (*connect.Response[v1.DiffResponse], error) {
 	return c.diff.CallUnary(ctx, req)
 }
 
+// DiffByLabel calls querier.v1.QuerierService.DiffByLabel.
+func (c *querierServiceClient) DiffByLabel(ctx context.Context, req *connect.Request[v1.DiffByLabelRequest]) (*connect.Response[v1.DiffByLabelResponse], error) {
+	return c.diffByLabel.CallUnary(ctx, req)
+}
+
 // GetProfileStats calls querier.v1.QuerierService.GetProfileStats.
 func (c *querierServiceClient) GetProfileStats(ctx context.Context, req *connect.Request[v11.GetProfileStatsRequest]) (*connect.Response[v11.GetProfileStatsResponse], error) {
 	return c.getProfileStats.CallUnary(ctx, req)
 }
 
+// GetCardinality calls querier.v1.QuerierService.GetCardinality.
+func (c *querierServiceClient) GetCardinality(ctx context.Context, req *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error) {
+	return c.getCardinality.CallUnary(ctx, req)
+}
+
 // QuerierServiceHandler is an implementation of the querier.v1.QuerierService service.
 type QuerierServiceHandler interface {
 	// ProfileType returns a list of the existing profile types.
@@ -255,14 +347,26 @@ type QuerierServiceHandler interface {
 	SelectMergeStacktraces(context.Context, *connect.Request[v1.SelectMergeStacktracesRequest]) (*connect.Response[v1.SelectMergeStacktracesResponse], error)
 	// SelectMergeSpanProfile returns matching profiles aggregated in a flamegraph format. It will combine samples from within the same callstack, with each element being grouped by its function name.
 	SelectMergeSpanProfile(context.Context, *connect.Request[v1.SelectMergeSpanProfileRequest]) (*connect.Response[v1.SelectMergeSpanProfileResponse], error)
+	// SelectFunctions returns matching profiles aggregated in a table of functions with their self and total values, sorted and truncated server-side.
+	SelectFunctions(context.Context, *connect.Request[v1.SelectFunctionsRequest]) (*connect.Response[v1.SelectFunctionsResponse], error)
 	// SelectMergeProfile returns matching profiles aggregated in pprof format. It will contain all information stored (so including filenames and line number, if ingested).
 	SelectMergeProfile(context.Context, *connect.Request[v1.SelectMergeProfileRequest]) (*connect.Response[v12.Profile], error)
 	// SelectSeries returns a time series for the total sum of the requested profiles.
 	SelectSeries(context.Context, *connect.Request[v1.SelectSeriesRequest]) (*connect.Response[v1.SelectSeriesResponse], error)
+	// SelectExemplars returns the individual profiles with the highest total values, along with their IDs, timestamps and labels.
+	SelectExemplars(context.Context, *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error)
+	// SelectProfileByID returns a single profile, identified by its ID, in pprof format.
+	SelectProfileByID(context.Context, *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v12.Profile], error)
 	// Diff returns a diff of two profiles
 	Diff(context.Context, *connect.Request[v1.DiffRequest]) (*connect.Response[v1.DiffResponse], error)
+	// DiffByLabel returns a diff of the profiles matching the selector for two
+	// values of a label, along with per-function deltas ranked by regression.
+	DiffByLabel(context.Context, *connect.Request[v1.DiffByLabelRequest]) (*connect.Response[v1.DiffByLabelResponse], error)
 	// GetProfileStats returns profile stats for the current tenant.
 	GetProfileStats(context.Context, *connect.Request[v11.GetProfileStatsRequest]) (*connect.Response[v11.GetProfileStatsResponse], error)
+	// GetCardinality returns the label names with the most distinct values and
+	// the services and profile types with the most series for the current tenant.
+	GetCardinality(context.Context, *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error)
 }
 
 // NewQuerierServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -307,6 +411,12 @@ func NewQuerierServiceHandler(svc QuerierServiceHandler, opts ...connect.Handler
 		connect.WithSchema(querierServiceSelectMergeSpanProfileMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	querierServiceSelectFunctionsHandler := connect.NewUnaryHandler(
+		QuerierServiceSelectFunctionsProcedure,
+		svc.SelectFunctions,
+		connect.WithSchema(querierServiceSelectFunctionsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	querierServiceSelectMergeProfileHandler := connect.NewUnaryHandler(
 		QuerierServiceSelectMergeProfileProcedure,
 		svc.SelectMergeProfile,
@@ -319,18 +429,42 @@ func NewQuerierServiceHandler(svc QuerierServiceHandler, opts ...connect.Handler
 		connect.WithSchema(querierServiceSelectSeriesMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	querierServiceSelectExemplarsHandler := connect.NewUnaryHandler(
+		QuerierServiceSelectExemplarsProcedure,
+		svc.SelectExemplars,
+		connect.WithSchema(querierServiceSelectExemplarsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	querierServiceSelectProfileByIDHandler := connect.NewUnaryHandler(
+		QuerierServiceSelectProfileByIDProcedure,
+		svc.SelectProfileByID,
+		connect.WithSchema(querierServiceSelectProfileByIDMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	querierServiceDiffHandler := connect.NewUnaryHandler(
 		QuerierServiceDiffProcedure,
 		svc.Diff,
 		connect.WithSchema(querierServiceDiffMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	querierServiceDiffByLabelHandler := connect.NewUnaryHandler(
+		QuerierServiceDiffByLabelProcedure,
+		svc.DiffByLabel,
+		connect.WithSchema(querierServiceDiffByLabelMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	querierServiceGetProfileStatsHandler := connect.NewUnaryHandler(
 		QuerierServiceGetProfileStatsProcedure,
 		svc.GetProfileStats,
 		connect.WithSchema(querierServiceGetProfileStatsMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	querierServiceGetCardinalityHandler := connect.NewUnaryHandler(
+		QuerierServiceGetCardinalityProcedure,
+		svc.GetCardinality,
+		connect.WithSchema(querierServiceGetCardinalityMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/querier.v1.QuerierService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case QuerierServiceProfileTypesProcedure:
@@ -345,14 +479,24 @@ func NewQuerierServiceHandler(svc QuerierServiceHandler, opts ...connect.Handler
 			querierServiceSelectMergeStacktracesHandler.ServeHTTP(w, r)
 		case QuerierServiceSelectMergeSpanProfileProcedure:
 			querierServiceSelectMergeSpanProfileHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectFunctionsProcedure:
+			querierServiceSelectFunctionsHandler.ServeHTTP(w, r)
 		case QuerierServiceSelectMergeProfileProcedure:
 			querierServiceSelectMergeProfileHandler.ServeHTTP(w, r)
 		case QuerierServiceSelectSeriesProcedure:
 			querierServiceSelectSeriesHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectExemplarsProcedure:
+			querierServiceSelectExemplarsHandler.ServeHTTP(w, r)
+		case QuerierServiceSelectProfileByIDProcedure:
+			querierServiceSelectProfileByIDHandler.ServeHTTP(w, r)
 		case QuerierServiceDiffProcedure:
 			querierServiceDiffHandler.ServeHTTP(w, r)
+		case QuerierServiceDiffByLabelProcedure:
+			querierServiceDiffByLabelHandler.ServeHTTP(w, r)
 		case QuerierServiceGetProfileStatsProcedure:
 			querierServiceGetProfileStatsHandler.ServeHTTP(w, r)
+		case QuerierServiceGetCardinalityProcedure:
+			querierServiceGetCardinalityHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -386,6 +530,10 @@ func (UnimplementedQuerierServiceHandler) SelectMergeSpanProfile(context.Context
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectMergeSpanProfile is not implemented"))
 }
 
+func (UnimplementedQuerierServiceHandler) SelectFunctions(context.Context, *connect.Request[v1.SelectFunctionsRequest]) (*connect.Response[v1.SelectFunctionsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectFunctions is not implemented"))
+}
+
 func (UnimplementedQuerierServiceHandler) SelectMergeProfile(context.Context, *connect.Request[v1.SelectMergeProfileRequest]) (*connect.Response[v12.Profile], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectMergeProfile is not implemented"))
 }
@@ -394,10 +542,26 @@ func (UnimplementedQuerierServiceHandler) SelectSeries(context.Context, *connect
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectSeries is not implemented"))
 }
 
+func (UnimplementedQuerierServiceHandler) SelectExemplars(context.Context, *connect.Request[v1.SelectExemplarsRequest]) (*connect.Response[v1.SelectExemplarsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectExemplars is not implemented"))
+}
+
+func (UnimplementedQuerierServiceHandler) SelectProfileByID(context.Context, *connect.Request[v1.SelectProfileByIDRequest]) (*connect.Response[v12.Profile], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.SelectProfileByID is not implemented"))
+}
+
 func (UnimplementedQuerierServiceHandler) Diff(context.Context, *connect.Request[v1.DiffRequest]) (*connect.Response[v1.DiffResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.Diff is not implemented"))
 }
 
+func (UnimplementedQuerierServiceHandler) DiffByLabel(context.Context, *connect.Request[v1.DiffByLabelRequest]) (*connect.Response[v1.DiffByLabelResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.DiffByLabel is not implemented"))
+}
+
 func (UnimplementedQuerierServiceHandler) GetProfileStats(context.Context, *connect.Request[v11.GetProfileStatsRequest]) (*connect.Response[v11.GetProfileStatsResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.GetProfileStats is not implemented"))
 }
+
+func (UnimplementedQuerierServiceHandler) GetCardinality(context.Context, *connect.Request[v1.GetCardinalityRequest]) (*connect.Response[v1.GetCardinalityResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("querier.v1.QuerierService.GetCardinality is not implemented"))
+}
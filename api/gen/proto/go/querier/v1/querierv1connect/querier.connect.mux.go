@@ -49,6 +49,11 @@ func RegisterQuerierServiceHandler(mux *mux.Router, svc QuerierServiceHandler, o
 		svc.SelectMergeSpanProfile,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/SelectFunctions", connect.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectFunctions",
+		svc.SelectFunctions,
+		opts...,
+	))
 	mux.Handle("/querier.v1.QuerierService/SelectMergeProfile", connect.NewUnaryHandler(
 		"/querier.v1.QuerierService/SelectMergeProfile",
 		svc.SelectMergeProfile,
@@ -59,14 +64,34 @@ func RegisterQuerierServiceHandler(mux *mux.Router, svc QuerierServiceHandler, o
 		svc.SelectSeries,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/SelectExemplars", connect.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectExemplars",
+		svc.SelectExemplars,
+		opts...,
+	))
+	mux.Handle("/querier.v1.QuerierService/SelectProfileByID", connect.NewUnaryHandler(
+		"/querier.v1.QuerierService/SelectProfileByID",
+		svc.SelectProfileByID,
+		opts...,
+	))
 	mux.Handle("/querier.v1.QuerierService/Diff", connect.NewUnaryHandler(
 		"/querier.v1.QuerierService/Diff",
 		svc.Diff,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/DiffByLabel", connect.NewUnaryHandler(
+		"/querier.v1.QuerierService/DiffByLabel",
+		svc.DiffByLabel,
+		opts...,
+	))
 	mux.Handle("/querier.v1.QuerierService/GetProfileStats", connect.NewUnaryHandler(
 		"/querier.v1.QuerierService/GetProfileStats",
 		svc.GetProfileStats,
 		opts...,
 	))
+	mux.Handle("/querier.v1.QuerierService/GetCardinality", connect.NewUnaryHandler(
+		"/querier.v1.QuerierService/GetCardinality",
+		svc.GetCardinality,
+		opts...,
+	))
 }
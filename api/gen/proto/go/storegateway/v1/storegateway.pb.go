@@ -35,7 +35,7 @@ var file_storegateway_v1_storegateway_proto_rawDesc = []byte{
 	0x73, 0x74, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x12, 0x70, 0x75, 0x73, 0x68,
 	0x2f, 0x76, 0x31, 0x2f, 0x70, 0x75, 0x73, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x14,
 	0x74, 0x79, 0x70, 0x65, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x32, 0xe7, 0x06, 0x0a, 0x13, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x47, 0x61,
+	0x72, 0x6f, 0x74, 0x6f, 0x32, 0x8a, 0x09, 0x0a, 0x13, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x47, 0x61,
 	0x74, 0x65, 0x77, 0x61, 0x79, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x7d, 0x0a, 0x18,
 	0x4d, 0x65, 0x72, 0x67, 0x65, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x53, 0x74, 0x61,
 	0x63, 0x6b, 0x74, 0x72, 0x61, 0x63, 0x65, 0x73, 0x12, 0x2c, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73,
@@ -89,21 +89,39 @@ var file_storegateway_v1_storegateway_proto_rawDesc = []byte{
 	0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
 	0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73,
 	0x74, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x4d, 0x65, 0x74, 0x61,
-	0x64, 0x61, 0x74, 0x61, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0xd3,
-	0x01, 0x0a, 0x13, 0x63, 0x6f, 0x6d, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
-	0x77, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x42, 0x11, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74,
-	0x65, 0x77, 0x61, 0x79, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x4c, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x72, 0x61, 0x66, 0x61, 0x6e, 0x61, 0x2f,
-	0x70, 0x79, 0x72, 0x6f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x67, 0x65,
-	0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x6f, 0x2f, 0x73, 0x74, 0x6f, 0x72, 0x65,
-	0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x2f, 0x76, 0x31, 0x3b, 0x73, 0x74, 0x6f, 0x72, 0x65,
-	0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x53, 0x58, 0x58, 0xaa,
-	0x02, 0x0f, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x2e, 0x56,
-	0x31, 0xca, 0x02, 0x0f, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79,
-	0x5c, 0x56, 0x31, 0xe2, 0x02, 0x1b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77,
-	0x61, 0x79, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
-	0x61, 0xea, 0x02, 0x10, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79,
-	0x3a, 0x3a, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x64, 0x61, 0x74, 0x61, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5b,
+	0x0a, 0x0e, 0x47, 0x65, 0x74, 0x43, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79,
+	0x12, 0x22, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x47,
+	0x65, 0x74, 0x43, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65, 0x72, 0x2e,
+	0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x61, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x69, 0x74,
+	0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x5e, 0x0a, 0x0f, 0x53,
+	0x65, 0x6c, 0x65, 0x63, 0x74, 0x45, 0x78, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x72, 0x73, 0x12, 0x23,
+	0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x6c,
+	0x65, 0x63, 0x74, 0x45, 0x78, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x24, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65, 0x72, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x45, 0x78, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x72,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x64, 0x0a, 0x11, 0x53,
+	0x65, 0x6c, 0x65, 0x63, 0x74, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x42, 0x79, 0x49, 0x44,
+	0x12, 0x25, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x65, 0x6c, 0x65, 0x63, 0x74, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x42, 0x79, 0x49, 0x44,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74,
+	0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x50, 0x72, 0x6f, 0x66,
+	0x69, 0x6c, 0x65, 0x42, 0x79, 0x49, 0x44, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x00, 0x42, 0xd3, 0x01, 0x0a, 0x13, 0x63, 0x6f, 0x6d, 0x2e, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x67,
+	0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x2e, 0x76, 0x31, 0x42, 0x11, 0x53, 0x74, 0x6f, 0x72, 0x65,
+	0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x4c,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x72, 0x61, 0x66, 0x61,
+	0x6e, 0x61, 0x2f, 0x70, 0x79, 0x72, 0x6f, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x2f, 0x61, 0x70, 0x69,
+	0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x67, 0x6f, 0x2f, 0x73, 0x74,
+	0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x2f, 0x76, 0x31, 0x3b, 0x73, 0x74,
+	0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61, 0x79, 0x76, 0x31, 0xa2, 0x02, 0x03, 0x53,
+	0x58, 0x58, 0xaa, 0x02, 0x0f, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x77, 0x61,
+	0x79, 0x2e, 0x56, 0x31, 0xca, 0x02, 0x0f, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
+	0x77, 0x61, 0x79, 0x5c, 0x56, 0x31, 0xe2, 0x02, 0x1b, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61,
+	0x74, 0x65, 0x77, 0x61, 0x79, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61,
+	0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x10, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
+	0x77, 0x61, 0x79, 0x3a, 0x3a, 0x56, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var file_storegateway_v1_storegateway_proto_goTypes = []interface{}{
@@ -116,15 +134,21 @@ var file_storegateway_v1_storegateway_proto_goTypes = []interface{}{
 	(*v11.LabelNamesRequest)(nil),               // 6: types.v1.LabelNamesRequest
 	(*v1.SeriesRequest)(nil),                    // 7: ingester.v1.SeriesRequest
 	(*v1.BlockMetadataRequest)(nil),             // 8: ingester.v1.BlockMetadataRequest
-	(*v1.MergeProfilesStacktracesResponse)(nil), // 9: ingester.v1.MergeProfilesStacktracesResponse
-	(*v1.MergeProfilesLabelsResponse)(nil),      // 10: ingester.v1.MergeProfilesLabelsResponse
-	(*v1.MergeProfilesPprofResponse)(nil),       // 11: ingester.v1.MergeProfilesPprofResponse
-	(*v1.MergeSpanProfileResponse)(nil),         // 12: ingester.v1.MergeSpanProfileResponse
-	(*v1.ProfileTypesResponse)(nil),             // 13: ingester.v1.ProfileTypesResponse
-	(*v11.LabelValuesResponse)(nil),             // 14: types.v1.LabelValuesResponse
-	(*v11.LabelNamesResponse)(nil),              // 15: types.v1.LabelNamesResponse
-	(*v1.SeriesResponse)(nil),                   // 16: ingester.v1.SeriesResponse
-	(*v1.BlockMetadataResponse)(nil),            // 17: ingester.v1.BlockMetadataResponse
+	(*v1.GetCardinalityRequest)(nil),            // 9: ingester.v1.GetCardinalityRequest
+	(*v1.SelectExemplarsRequest)(nil),           // 10: ingester.v1.SelectExemplarsRequest
+	(*v1.SelectProfileByIDRequest)(nil),         // 11: ingester.v1.SelectProfileByIDRequest
+	(*v1.MergeProfilesStacktracesResponse)(nil), // 12: ingester.v1.MergeProfilesStacktracesResponse
+	(*v1.MergeProfilesLabelsResponse)(nil),      // 13: ingester.v1.MergeProfilesLabelsResponse
+	(*v1.MergeProfilesPprofResponse)(nil),       // 14: ingester.v1.MergeProfilesPprofResponse
+	(*v1.MergeSpanProfileResponse)(nil),         // 15: ingester.v1.MergeSpanProfileResponse
+	(*v1.ProfileTypesResponse)(nil),             // 16: ingester.v1.ProfileTypesResponse
+	(*v11.LabelValuesResponse)(nil),             // 17: types.v1.LabelValuesResponse
+	(*v11.LabelNamesResponse)(nil),              // 18: types.v1.LabelNamesResponse
+	(*v1.SeriesResponse)(nil),                   // 19: ingester.v1.SeriesResponse
+	(*v1.BlockMetadataResponse)(nil),            // 20: ingester.v1.BlockMetadataResponse
+	(*v1.GetCardinalityResponse)(nil),           // 21: ingester.v1.GetCardinalityResponse
+	(*v1.SelectExemplarsResponse)(nil),          // 22: ingester.v1.SelectExemplarsResponse
+	(*v1.SelectProfileByIDResponse)(nil),        // 23: ingester.v1.SelectProfileByIDResponse
 }
 var file_storegateway_v1_storegateway_proto_depIdxs = []int32{
 	0,  // 0: storegateway.v1.StoreGatewayService.MergeProfilesStacktraces:input_type -> ingester.v1.MergeProfilesStacktracesRequest
@@ -136,17 +160,23 @@ var file_storegateway_v1_storegateway_proto_depIdxs = []int32{
 	6,  // 6: storegateway.v1.StoreGatewayService.LabelNames:input_type -> types.v1.LabelNamesRequest
 	7,  // 7: storegateway.v1.StoreGatewayService.Series:input_type -> ingester.v1.SeriesRequest
 	8,  // 8: storegateway.v1.StoreGatewayService.BlockMetadata:input_type -> ingester.v1.BlockMetadataRequest
-	9,  // 9: storegateway.v1.StoreGatewayService.MergeProfilesStacktraces:output_type -> ingester.v1.MergeProfilesStacktracesResponse
-	10, // 10: storegateway.v1.StoreGatewayService.MergeProfilesLabels:output_type -> ingester.v1.MergeProfilesLabelsResponse
-	11, // 11: storegateway.v1.StoreGatewayService.MergeProfilesPprof:output_type -> ingester.v1.MergeProfilesPprofResponse
-	12, // 12: storegateway.v1.StoreGatewayService.MergeSpanProfile:output_type -> ingester.v1.MergeSpanProfileResponse
-	13, // 13: storegateway.v1.StoreGatewayService.ProfileTypes:output_type -> ingester.v1.ProfileTypesResponse
-	14, // 14: storegateway.v1.StoreGatewayService.LabelValues:output_type -> types.v1.LabelValuesResponse
-	15, // 15: storegateway.v1.StoreGatewayService.LabelNames:output_type -> types.v1.LabelNamesResponse
-	16, // 16: storegateway.v1.StoreGatewayService.Series:output_type -> ingester.v1.SeriesResponse
-	17, // 17: storegateway.v1.StoreGatewayService.BlockMetadata:output_type -> ingester.v1.BlockMetadataResponse
-	9,  // [9:18] is the sub-list for method output_type
-	0,  // [0:9] is the sub-list for method input_type
+	9,  // 9: storegateway.v1.StoreGatewayService.GetCardinality:input_type -> ingester.v1.GetCardinalityRequest
+	10, // 10: storegateway.v1.StoreGatewayService.SelectExemplars:input_type -> ingester.v1.SelectExemplarsRequest
+	11, // 11: storegateway.v1.StoreGatewayService.SelectProfileByID:input_type -> ingester.v1.SelectProfileByIDRequest
+	12, // 12: storegateway.v1.StoreGatewayService.MergeProfilesStacktraces:output_type -> ingester.v1.MergeProfilesStacktracesResponse
+	13, // 13: storegateway.v1.StoreGatewayService.MergeProfilesLabels:output_type -> ingester.v1.MergeProfilesLabelsResponse
+	14, // 14: storegateway.v1.StoreGatewayService.MergeProfilesPprof:output_type -> ingester.v1.MergeProfilesPprofResponse
+	15, // 15: storegateway.v1.StoreGatewayService.MergeSpanProfile:output_type -> ingester.v1.MergeSpanProfileResponse
+	16, // 16: storegateway.v1.StoreGatewayService.ProfileTypes:output_type -> ingester.v1.ProfileTypesResponse
+	17, // 17: storegateway.v1.StoreGatewayService.LabelValues:output_type -> types.v1.LabelValuesResponse
+	18, // 18: storegateway.v1.StoreGatewayService.LabelNames:output_type -> types.v1.LabelNamesResponse
+	19, // 19: storegateway.v1.StoreGatewayService.Series:output_type -> ingester.v1.SeriesResponse
+	20, // 20: storegateway.v1.StoreGatewayService.BlockMetadata:output_type -> ingester.v1.BlockMetadataResponse
+	21, // 21: storegateway.v1.StoreGatewayService.GetCardinality:output_type -> ingester.v1.GetCardinalityResponse
+	22, // 22: storegateway.v1.StoreGatewayService.SelectExemplars:output_type -> ingester.v1.SelectExemplarsResponse
+	23, // 23: storegateway.v1.StoreGatewayService.SelectProfileByID:output_type -> ingester.v1.SelectProfileByIDResponse
+	12, // [12:24] is the sub-list for method output_type
+	0,  // [0:12] is the sub-list for method input_type
 	0,  // [0:0] is the sub-list for extension type_name
 	0,  // [0:0] is the sub-list for extension extendee
 	0,  // [0:0] is the sub-list for field type_name
@@ -41,6 +41,9 @@ type StoreGatewayServiceClient interface {
 	LabelNames(ctx context.Context, in *v11.LabelNamesRequest, opts ...grpc.CallOption) (*v11.LabelNamesResponse, error)
 	Series(ctx context.Context, in *v1.SeriesRequest, opts ...grpc.CallOption) (*v1.SeriesResponse, error)
 	BlockMetadata(ctx context.Context, in *v1.BlockMetadataRequest, opts ...grpc.CallOption) (*v1.BlockMetadataResponse, error)
+	GetCardinality(ctx context.Context, in *v1.GetCardinalityRequest, opts ...grpc.CallOption) (*v1.GetCardinalityResponse, error)
+	SelectExemplars(ctx context.Context, in *v1.SelectExemplarsRequest, opts ...grpc.CallOption) (*v1.SelectExemplarsResponse, error)
+	SelectProfileByID(ctx context.Context, in *v1.SelectProfileByIDRequest, opts ...grpc.CallOption) (*v1.SelectProfileByIDResponse, error)
 }
 
 type storeGatewayServiceClient struct {
@@ -220,6 +223,33 @@ func (c *storeGatewayServiceClient) BlockMetadata(ctx context.Context, in *v1.Bl
 	return out, nil
 }
 
+func (c *storeGatewayServiceClient) GetCardinality(ctx context.Context, in *v1.GetCardinalityRequest, opts ...grpc.CallOption) (*v1.GetCardinalityResponse, error) {
+	out := new(v1.GetCardinalityResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/GetCardinality", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeGatewayServiceClient) SelectExemplars(ctx context.Context, in *v1.SelectExemplarsRequest, opts ...grpc.CallOption) (*v1.SelectExemplarsResponse, error) {
+	out := new(v1.SelectExemplarsResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/SelectExemplars", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storeGatewayServiceClient) SelectProfileByID(ctx context.Context, in *v1.SelectProfileByIDRequest, opts ...grpc.CallOption) (*v1.SelectProfileByIDResponse, error) {
+	out := new(v1.SelectProfileByIDResponse)
+	err := c.cc.Invoke(ctx, "/storegateway.v1.StoreGatewayService/SelectProfileByID", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StoreGatewayServiceServer is the server API for StoreGatewayService service.
 // All implementations must embed UnimplementedStoreGatewayServiceServer
 // for forward compatibility
@@ -235,6 +265,9 @@ type StoreGatewayServiceServer interface {
 	LabelNames(context.Context, *v11.LabelNamesRequest) (*v11.LabelNamesResponse, error)
 	Series(context.Context, *v1.SeriesRequest) (*v1.SeriesResponse, error)
 	BlockMetadata(context.Context, *v1.BlockMetadataRequest) (*v1.BlockMetadataResponse, error)
+	GetCardinality(context.Context, *v1.GetCardinalityRequest) (*v1.GetCardinalityResponse, error)
+	SelectExemplars(context.Context, *v1.SelectExemplarsRequest) (*v1.SelectExemplarsResponse, error)
+	SelectProfileByID(context.Context, *v1.SelectProfileByIDRequest) (*v1.SelectProfileByIDResponse, error)
 	mustEmbedUnimplementedStoreGatewayServiceServer()
 }
 
@@ -269,6 +302,15 @@ func (UnimplementedStoreGatewayServiceServer) Series(context.Context, *v1.Series
 func (UnimplementedStoreGatewayServiceServer) BlockMetadata(context.Context, *v1.BlockMetadataRequest) (*v1.BlockMetadataResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BlockMetadata not implemented")
 }
+func (UnimplementedStoreGatewayServiceServer) GetCardinality(context.Context, *v1.GetCardinalityRequest) (*v1.GetCardinalityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCardinality not implemented")
+}
+func (UnimplementedStoreGatewayServiceServer) SelectExemplars(context.Context, *v1.SelectExemplarsRequest) (*v1.SelectExemplarsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SelectExemplars not implemented")
+}
+func (UnimplementedStoreGatewayServiceServer) SelectProfileByID(context.Context, *v1.SelectProfileByIDRequest) (*v1.SelectProfileByIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SelectProfileByID not implemented")
+}
 func (UnimplementedStoreGatewayServiceServer) mustEmbedUnimplementedStoreGatewayServiceServer() {}
 
 // UnsafeStoreGatewayServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -476,6 +518,60 @@ func _StoreGatewayService_BlockMetadata_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StoreGatewayService_GetCardinality_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v1.GetCardinalityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).GetCardinality(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/GetCardinality",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).GetCardinality(ctx, req.(*v1.GetCardinalityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoreGatewayService_SelectExemplars_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v1.SelectExemplarsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).SelectExemplars(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/SelectExemplars",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).SelectExemplars(ctx, req.(*v1.SelectExemplarsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StoreGatewayService_SelectProfileByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(v1.SelectProfileByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StoreGatewayServiceServer).SelectProfileByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/storegateway.v1.StoreGatewayService/SelectProfileByID",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StoreGatewayServiceServer).SelectProfileByID(ctx, req.(*v1.SelectProfileByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // StoreGatewayService_ServiceDesc is the grpc.ServiceDesc for StoreGatewayService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -503,6 +599,18 @@ var StoreGatewayService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "BlockMetadata",
 			Handler:    _StoreGatewayService_BlockMetadata_Handler,
 		},
+		{
+			MethodName: "GetCardinality",
+			Handler:    _StoreGatewayService_GetCardinality_Handler,
+		},
+		{
+			MethodName: "SelectExemplars",
+			Handler:    _StoreGatewayService_SelectExemplars_Handler,
+		},
+		{
+			MethodName: "SelectProfileByID",
+			Handler:    _StoreGatewayService_SelectProfileByID_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -62,6 +62,15 @@ const (
 	// StoreGatewayServiceBlockMetadataProcedure is the fully-qualified name of the
 	// StoreGatewayService's BlockMetadata RPC.
 	StoreGatewayServiceBlockMetadataProcedure = "/storegateway.v1.StoreGatewayService/BlockMetadata"
+	// StoreGatewayServiceGetCardinalityProcedure is the fully-qualified name of the
+	// StoreGatewayService's GetCardinality RPC.
+	StoreGatewayServiceGetCardinalityProcedure = "/storegateway.v1.StoreGatewayService/GetCardinality"
+	// StoreGatewayServiceSelectExemplarsProcedure is the fully-qualified name of the
+	// StoreGatewayService's SelectExemplars RPC.
+	StoreGatewayServiceSelectExemplarsProcedure = "/storegateway.v1.StoreGatewayService/SelectExemplars"
+	// StoreGatewayServiceSelectProfileByIDProcedure is the fully-qualified name of the
+	// StoreGatewayService's SelectProfileByID RPC.
+	StoreGatewayServiceSelectProfileByIDProcedure = "/storegateway.v1.StoreGatewayService/SelectProfileByID"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
@@ -76,6 +85,9 @@ var (
 	storeGatewayServiceLabelNamesMethodDescriptor               = storeGatewayServiceServiceDescriptor.Methods().ByName("LabelNames")
 	storeGatewayServiceSeriesMethodDescriptor                   = storeGatewayServiceServiceDescriptor.Methods().ByName("Series")
 	storeGatewayServiceBlockMetadataMethodDescriptor            = storeGatewayServiceServiceDescriptor.Methods().ByName("BlockMetadata")
+	storeGatewayServiceGetCardinalityMethodDescriptor           = storeGatewayServiceServiceDescriptor.Methods().ByName("GetCardinality")
+	storeGatewayServiceSelectExemplarsMethodDescriptor          = storeGatewayServiceServiceDescriptor.Methods().ByName("SelectExemplars")
+	storeGatewayServiceSelectProfileByIDMethodDescriptor        = storeGatewayServiceServiceDescriptor.Methods().ByName("SelectProfileByID")
 )
 
 // StoreGatewayServiceClient is a client for the storegateway.v1.StoreGatewayService service.
@@ -91,6 +103,9 @@ type StoreGatewayServiceClient interface {
 	LabelNames(context.Context, *connect.Request[v12.LabelNamesRequest]) (*connect.Response[v12.LabelNamesResponse], error)
 	Series(context.Context, *connect.Request[v11.SeriesRequest]) (*connect.Response[v11.SeriesResponse], error)
 	BlockMetadata(context.Context, *connect.Request[v11.BlockMetadataRequest]) (*connect.Response[v11.BlockMetadataResponse], error)
+	GetCardinality(context.Context, *connect.Request[v11.GetCardinalityRequest]) (*connect.Response[v11.GetCardinalityResponse], error)
+	SelectExemplars(context.Context, *connect.Request[v11.SelectExemplarsRequest]) (*connect.Response[v11.SelectExemplarsResponse], error)
+	SelectProfileByID(context.Context, *connect.Request[v11.SelectProfileByIDRequest]) (*connect.Response[v11.SelectProfileByIDResponse], error)
 }
 
 // NewStoreGatewayServiceClient constructs a client for the storegateway.v1.StoreGatewayService
@@ -157,6 +172,24 @@ func NewStoreGatewayServiceClient(httpClient connect.HTTPClient, baseURL string,
 			connect.WithSchema(storeGatewayServiceBlockMetadataMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		getCardinality: connect.NewClient[v11.GetCardinalityRequest, v11.GetCardinalityResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceGetCardinalityProcedure,
+			connect.WithSchema(storeGatewayServiceGetCardinalityMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		selectExemplars: connect.NewClient[v11.SelectExemplarsRequest, v11.SelectExemplarsResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceSelectExemplarsProcedure,
+			connect.WithSchema(storeGatewayServiceSelectExemplarsMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		selectProfileByID: connect.NewClient[v11.SelectProfileByIDRequest, v11.SelectProfileByIDResponse](
+			httpClient,
+			baseURL+StoreGatewayServiceSelectProfileByIDProcedure,
+			connect.WithSchema(storeGatewayServiceSelectProfileByIDMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
@@ -171,6 +204,9 @@ type storeGatewayServiceClient struct {
 	labelNames               *connect.Client[v12.LabelNamesRequest, v12.LabelNamesResponse]
 	series                   *connect.Client[v11.SeriesRequest, v11.SeriesResponse]
 	blockMetadata            *connect.Client[v11.BlockMetadataRequest, v11.BlockMetadataResponse]
+	getCardinality           *connect.Client[v11.GetCardinalityRequest, v11.GetCardinalityResponse]
+	selectExemplars          *connect.Client[v11.SelectExemplarsRequest, v11.SelectExemplarsResponse]
+	selectProfileByID        *connect.Client[v11.SelectProfileByIDRequest, v11.SelectProfileByIDResponse]
 }
 
 // MergeProfilesStacktraces calls storegateway.v1.StoreGatewayService.MergeProfilesStacktraces.
@@ -218,6 +254,21 @@ func (c *storeGatewayServiceClient) BlockMetadata(ctx context.Context, req *conn
 	return c.blockMetadata.CallUnary(ctx, req)
 }
 
+// GetCardinality calls storegateway.v1.StoreGatewayService.GetCardinality.
+func (c *storeGatewayServiceClient) GetCardinality(ctx context.Context, req *connect.Request[v11.GetCardinalityRequest]) (*connect.Response[v11.GetCardinalityResponse], error) {
+	return c.getCardinality.CallUnary(ctx, req)
+}
+
+// SelectExemplars calls storegateway.v1.StoreGatewayService.SelectExemplars.
+func (c *storeGatewayServiceClient) SelectExemplars(ctx context.Context, req *connect.Request[v11.SelectExemplarsRequest]) (*connect.Response[v11.SelectExemplarsResponse], error) {
+	return c.selectExemplars.CallUnary(ctx, req)
+}
+
+// SelectProfileByID calls storegateway.v1.StoreGatewayService.SelectProfileByID.
+func (c *storeGatewayServiceClient) SelectProfileByID(ctx context.Context, req *connect.Request[v11.SelectProfileByIDRequest]) (*connect.Response[v11.SelectProfileByIDResponse], error) {
+	return c.selectProfileByID.CallUnary(ctx, req)
+}
+
 // StoreGatewayServiceHandler is an implementation of the storegateway.v1.StoreGatewayService
 // service.
 type StoreGatewayServiceHandler interface {
@@ -232,6 +283,9 @@ type StoreGatewayServiceHandler interface {
 	LabelNames(context.Context, *connect.Request[v12.LabelNamesRequest]) (*connect.Response[v12.LabelNamesResponse], error)
 	Series(context.Context, *connect.Request[v11.SeriesRequest]) (*connect.Response[v11.SeriesResponse], error)
 	BlockMetadata(context.Context, *connect.Request[v11.BlockMetadataRequest]) (*connect.Response[v11.BlockMetadataResponse], error)
+	GetCardinality(context.Context, *connect.Request[v11.GetCardinalityRequest]) (*connect.Response[v11.GetCardinalityResponse], error)
+	SelectExemplars(context.Context, *connect.Request[v11.SelectExemplarsRequest]) (*connect.Response[v11.SelectExemplarsResponse], error)
+	SelectProfileByID(context.Context, *connect.Request[v11.SelectProfileByIDRequest]) (*connect.Response[v11.SelectProfileByIDResponse], error)
 }
 
 // NewStoreGatewayServiceHandler builds an HTTP handler from the service implementation. It returns
@@ -294,6 +348,24 @@ func NewStoreGatewayServiceHandler(svc StoreGatewayServiceHandler, opts ...conne
 		connect.WithSchema(storeGatewayServiceBlockMetadataMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	storeGatewayServiceGetCardinalityHandler := connect.NewUnaryHandler(
+		StoreGatewayServiceGetCardinalityProcedure,
+		svc.GetCardinality,
+		connect.WithSchema(storeGatewayServiceGetCardinalityMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	storeGatewayServiceSelectExemplarsHandler := connect.NewUnaryHandler(
+		StoreGatewayServiceSelectExemplarsProcedure,
+		svc.SelectExemplars,
+		connect.WithSchema(storeGatewayServiceSelectExemplarsMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	storeGatewayServiceSelectProfileByIDHandler := connect.NewUnaryHandler(
+		StoreGatewayServiceSelectProfileByIDProcedure,
+		svc.SelectProfileByID,
+		connect.WithSchema(storeGatewayServiceSelectProfileByIDMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/storegateway.v1.StoreGatewayService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case StoreGatewayServiceMergeProfilesStacktracesProcedure:
@@ -314,6 +386,12 @@ func NewStoreGatewayServiceHandler(svc StoreGatewayServiceHandler, opts ...conne
 			storeGatewayServiceSeriesHandler.ServeHTTP(w, r)
 		case StoreGatewayServiceBlockMetadataProcedure:
 			storeGatewayServiceBlockMetadataHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceGetCardinalityProcedure:
+			storeGatewayServiceGetCardinalityHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceSelectExemplarsProcedure:
+			storeGatewayServiceSelectExemplarsHandler.ServeHTTP(w, r)
+		case StoreGatewayServiceSelectProfileByIDProcedure:
+			storeGatewayServiceSelectProfileByIDHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -358,3 +436,15 @@ func (UnimplementedStoreGatewayServiceHandler) Series(context.Context, *connect.
 func (UnimplementedStoreGatewayServiceHandler) BlockMetadata(context.Context, *connect.Request[v11.BlockMetadataRequest]) (*connect.Response[v11.BlockMetadataResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.BlockMetadata is not implemented"))
 }
+
+func (UnimplementedStoreGatewayServiceHandler) GetCardinality(context.Context, *connect.Request[v11.GetCardinalityRequest]) (*connect.Response[v11.GetCardinalityResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.GetCardinality is not implemented"))
+}
+
+func (UnimplementedStoreGatewayServiceHandler) SelectExemplars(context.Context, *connect.Request[v11.SelectExemplarsRequest]) (*connect.Response[v11.SelectExemplarsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.SelectExemplars is not implemented"))
+}
+
+func (UnimplementedStoreGatewayServiceHandler) SelectProfileByID(context.Context, *connect.Request[v11.SelectProfileByIDRequest]) (*connect.Response[v11.SelectProfileByIDResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("storegateway.v1.StoreGatewayService.SelectProfileByID is not implemented"))
+}
@@ -64,4 +64,19 @@ func RegisterStoreGatewayServiceHandler(mux *mux.Router, svc StoreGatewayService
 		svc.BlockMetadata,
 		opts...,
 	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/GetCardinality", connect.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/GetCardinality",
+		svc.GetCardinality,
+		opts...,
+	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/SelectExemplars", connect.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/SelectExemplars",
+		svc.SelectExemplars,
+		opts...,
+	))
+	mux.Handle("/storegateway.v1.StoreGatewayService/SelectProfileByID", connect.NewUnaryHandler(
+		"/storegateway.v1.StoreGatewayService/SelectProfileByID",
+		svc.SelectProfileByID,
+		opts...,
+	))
 }
@@ -74,3 +74,74 @@ func blocksList(ctx context.Context) error {
 
 	return nil
 }
+
+func blocksVerify(ctx context.Context) error {
+	bucket, err := filesystem.NewBucket(cfg.blocks.path)
+	if err != nil {
+		return err
+	}
+
+	ids := cfg.blocks.verify.ids
+	if len(ids) == 0 {
+		if err = bucket.Iter(ctx, "", func(n string) error {
+			if id, ok := block.IsBlockDir(n); ok {
+				ids = append(ids, id.String())
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	var failed int
+	table := tablewriter.NewWriter(output(ctx))
+	table.SetHeader([]string{"Block ID", "Status", "File", "Issue"})
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+	table.SetRowLine(true)
+	for _, id := range ids {
+		meta, err := block.ReadMetaFromDir(filepath.Join(cfg.blocks.path, id))
+		if err != nil {
+			table.Append([]string{id, "unrecoverable", block.MetaFilename, err.Error()})
+			failed++
+			continue
+		}
+		report, err := phlaredb.VerifyBlock(ctx, bucket, meta)
+		if err != nil {
+			return err
+		}
+		status := "ok"
+		switch {
+		case report.OK():
+		case !report.Recoverable():
+			status = "unrecoverable"
+			failed++
+		case !cfg.blocks.verify.repair:
+			status = "recoverable"
+			failed++
+		default:
+			repaired, err := phlaredb.RepairBlock(ctx, logger, bucket, report)
+			if err != nil {
+				level.Error(logger).Log("msg", "unable to repair block", "block", id, "err", err)
+				status = "repair failed"
+				failed++
+				break
+			}
+			status = "repaired"
+			if repaired.ULID != meta.ULID {
+				status = "repaired as " + repaired.ULID.String()
+			}
+		}
+		if report.OK() {
+			table.Append([]string{id, status, "", ""})
+		}
+		for _, issue := range report.Issues {
+			table.Append([]string{id, status, issue.File, issue.Err.Error()})
+		}
+	}
+	table.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d blocks failed verification", failed, len(ids))
+	}
+	return nil
+}
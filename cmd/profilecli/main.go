@@ -26,6 +26,10 @@ var cfg struct {
 			dst    string
 			shards int
 		}
+		verify struct {
+			ids    []string
+			repair bool
+		}
 	}
 }
 
@@ -56,6 +60,10 @@ func main() {
 	blocksCompactCmd.Arg("dest", "The destination where compacted blocks should be stored.").Required().StringVar(&cfg.blocks.compact.dst)
 	blocksCompactCmd.Flag("shards", "The amount of shards to split output blocks into.").Default("0").IntVar(&cfg.blocks.compact.shards)
 
+	blocksVerifyCmd := blocksCmd.Command("verify", "Verify the integrity of blocks.")
+	blocksVerifyCmd.Arg("block-id", "IDs of the blocks to verify. All the blocks are verified if none is specified.").StringsVar(&cfg.blocks.verify.ids)
+	blocksVerifyCmd.Flag("repair", "Repair the recoverable blocks: meta.json is rewritten to match the block files, and the profiles referring to missing series or stack traces are removed. In the latter case, a new block is created and the original one is marked for deletion.").Default("false").BoolVar(&cfg.blocks.verify.repair)
+
 	parquetCmd := adminCmd.Command("parquet", "Operate on a Parquet file.")
 	parquetInspectCmd := parquetCmd.Command("inspect", "Inspect a parquet file's structure.")
 	parquetInspectFiles := parquetInspectCmd.Arg("file", "parquet file path").Required().ExistingFiles()
@@ -66,8 +74,15 @@ func main() {
 
 	queryCmd := app.Command("query", "Query profile store.")
 	queryMergeCmd := queryCmd.Command("merge", "Request merged profile.")
-	queryMergeOutput := queryMergeCmd.Flag("output", "How to output the result, examples: console, raw, pprof=./my.pprof").Default("console").String()
+	queryMergeOutput := queryMergeCmd.Flag("output", "How to output the result, examples: console, raw, pprof=./my.pprof, collapsed, speedscope").Default("console").String()
 	queryMergeParams := addQueryMergeParams(queryMergeCmd)
+	queryFunctionsCmd := queryCmd.Command("functions", "Request the top functions of the merged profile.")
+	queryFunctionsParams := addQueryFunctionsParams(queryFunctionsCmd)
+	queryExemplarsCmd := queryCmd.Command("exemplars", "Request the individual profiles with the highest values.")
+	queryExemplarsParams := addQueryExemplarsParams(queryExemplarsCmd)
+	queryProfileCmd := queryCmd.Command("profile", "Request a single profile by its ID.")
+	queryProfileOutput := queryProfileCmd.Flag("output", "How to output the result, examples: console, raw, pprof=./my.pprof, collapsed, speedscope").Default("console").String()
+	queryProfileParams := addQueryProfileParams(queryProfileCmd)
 	querySeriesCmd := queryCmd.Command("series", "Request series labels.")
 	querySeriesParams := addQuerySeriesParams(querySeriesCmd)
 
@@ -95,6 +110,8 @@ func main() {
 	switch parsedCmd {
 	case blocksListCmd.FullCommand():
 		os.Exit(checkError(blocksList(ctx)))
+	case blocksVerifyCmd.FullCommand():
+		os.Exit(checkError(blocksVerify(ctx)))
 	case parquetInspectCmd.FullCommand():
 		for _, file := range *parquetInspectFiles {
 			if err := parquetInspect(ctx, file); err != nil {
@@ -111,6 +128,18 @@ func main() {
 		if err := queryMerge(ctx, queryMergeParams, *queryMergeOutput); err != nil {
 			os.Exit(checkError(err))
 		}
+	case queryFunctionsCmd.FullCommand():
+		if err := queryFunctions(ctx, queryFunctionsParams); err != nil {
+			os.Exit(checkError(err))
+		}
+	case queryExemplarsCmd.FullCommand():
+		if err := queryExemplars(ctx, queryExemplarsParams); err != nil {
+			os.Exit(checkError(err))
+		}
+	case queryProfileCmd.FullCommand():
+		if err := queryProfile(ctx, queryProfileParams, *queryProfileOutput); err != nil {
+			os.Exit(checkError(err))
+		}
 	case querySeriesCmd.FullCommand():
 		if err := querySeries(ctx, querySeriesParams); err != nil {
 			os.Exit(checkError(err))
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,23 +15,28 @@ import (
 	"github.com/go-kit/log/level"
 	gprofile "github.com/google/pprof/profile"
 	"github.com/grafana/dskit/runutil"
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1/ingesterv1connect"
 	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	"github.com/grafana/pyroscope/api/gen/proto/go/storegateway/v1/storegatewayv1connect"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/operations"
 	"github.com/k0kubun/pp/v3"
 	"github.com/klauspost/compress/gzip"
 	"github.com/mattn/go-isatty"
+	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
 )
 
 const (
-	outputConsole = "console"
-	outputRaw     = "raw"
-	outputPprof   = "pprof="
+	outputConsole    = "console"
+	outputRaw        = "raw"
+	outputPprof      = "pprof="
+	outputCollapsed  = "collapsed"
+	outputSpeedscope = "speedscope"
 )
 
 func (c *phlareClient) queryClient() querierv1connect.QuerierServiceClient {
@@ -121,12 +127,16 @@ func queryMerge(ctx context.Context, params *queryMergeParams, outputFlag string
 		return errors.Wrap(err, "failed to query")
 	}
 
+	return writeProfile(ctx, resp.Msg, params.ProfileType, outputFlag)
+}
+
+func writeProfile(ctx context.Context, profile *googlev1.Profile, profileTypeID string, outputFlag string) (err error) {
 	mypp := pp.New()
 	mypp.SetColoringEnabled(isatty.IsTerminal(os.Stdout.Fd()))
 	mypp.SetExportedOnly(true)
 
 	if outputFlag == outputConsole {
-		buf, err := resp.Msg.MarshalVT()
+		buf, err := profile.MarshalVT()
 		if err != nil {
 			return errors.Wrap(err, "failed to marshal protobuf")
 		}
@@ -142,7 +152,7 @@ func queryMerge(ctx context.Context, params *queryMergeParams, outputFlag string
 	}
 
 	if outputFlag == outputRaw {
-		mypp.Print(resp.Msg)
+		mypp.Print(profile)
 		return nil
 	}
 
@@ -151,7 +161,7 @@ func queryMerge(ctx context.Context, params *queryMergeParams, outputFlag string
 		if filePath == "" {
 			return errors.New("no file path specified after pprof=")
 		}
-		buf, err := resp.Msg.MarshalVT()
+		buf, err := profile.MarshalVT()
 		if err != nil {
 			return errors.Wrap(err, "failed to marshal protobuf")
 		}
@@ -173,9 +183,178 @@ func queryMerge(ctx context.Context, params *queryMergeParams, outputFlag string
 		return nil
 	}
 
+	if outputFlag == outputCollapsed {
+		phlaremodel.TreeFromProfile(profile).WriteCollapsed(output(ctx))
+		return nil
+	}
+
+	if outputFlag == outputSpeedscope {
+		profileType, err := phlaremodel.ParseProfileTypeSelector(profileTypeID)
+		if err != nil {
+			return err
+		}
+		return phlaremodel.TreeFromProfile(profile).WriteSpeedscope(output(ctx), profileType)
+	}
+
 	return errors.Errorf("unknown output %s", outputFlag)
 }
 
+type queryExemplarsParams struct {
+	*queryMergeParams
+	Limit int64
+}
+
+func addQueryExemplarsParams(queryCmd commander) *queryExemplarsParams {
+	params := new(queryExemplarsParams)
+	params.queryMergeParams = addQueryMergeParams(queryCmd)
+	queryCmd.Flag("limit", "Maximum number of exemplars to return.").Default("10").Int64Var(&params.Limit)
+	return params
+}
+
+func queryExemplars(ctx context.Context, params *queryExemplarsParams) (err error) {
+	from, to, err := params.parseFromTo()
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "query exemplars from profile store", "url", params.URL, "from", from, "to", to, "query", params.Query, "type", params.ProfileType)
+
+	qc := params.phlareClient.queryClient()
+	resp, err := qc.SelectExemplars(ctx, connect.NewRequest(&querierv1.SelectExemplarsRequest{
+		ProfileTypeID: params.ProfileType,
+		Start:         from.UnixMilli(),
+		End:           to.UnixMilli(),
+		LabelSelector: params.Query,
+		Limit:         &params.Limit,
+	}))
+	if err != nil {
+		return errors.Wrap(err, "failed to query")
+	}
+
+	table := tablewriter.NewWriter(output(ctx))
+	table.SetHeader([]string{"Timestamp", "Profile ID", "Value", "Labels"})
+	for _, e := range resp.Msg.Exemplars {
+		table.Append([]string{
+			time.UnixMilli(e.Timestamp).UTC().Format(time.RFC3339Nano),
+			e.ProfileId,
+			strconv.FormatInt(e.Value, 10),
+			phlaremodel.LabelPairsString(e.Labels),
+		})
+	}
+	table.Render()
+
+	return nil
+}
+
+type queryProfileParams struct {
+	*queryMergeParams
+	ProfileID string
+}
+
+func addQueryProfileParams(queryCmd commander) *queryProfileParams {
+	params := new(queryProfileParams)
+	params.queryMergeParams = addQueryMergeParams(queryCmd)
+	queryCmd.Flag("id", "ID of the profile, as returned by the exemplars query.").Required().StringVar(&params.ProfileID)
+	return params
+}
+
+func queryProfile(ctx context.Context, params *queryProfileParams, outputFlag string) (err error) {
+	from, to, err := params.parseFromTo()
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "query profile by id from profile store", "url", params.URL, "from", from, "to", to, "id", params.ProfileID, "type", params.ProfileType)
+
+	qc := params.phlareClient.queryClient()
+	resp, err := qc.SelectProfileByID(ctx, connect.NewRequest(&querierv1.SelectProfileByIDRequest{
+		ProfileTypeID: params.ProfileType,
+		Start:         from.UnixMilli(),
+		End:           to.UnixMilli(),
+		LabelSelector: params.Query,
+		ProfileId:     params.ProfileID,
+	}))
+	if err != nil {
+		return errors.Wrap(err, "failed to query")
+	}
+
+	return writeProfile(ctx, resp.Msg, params.ProfileType, outputFlag)
+}
+
+type queryFunctionsParams struct {
+	*queryMergeParams
+	MaxFunctions  int64
+	OrderBy       string
+	WithLocations bool
+}
+
+func addQueryFunctionsParams(queryCmd commander) *queryFunctionsParams {
+	params := new(queryFunctionsParams)
+	params.queryMergeParams = addQueryMergeParams(queryCmd)
+	queryCmd.Flag("max-functions", "Maximum number of functions to return. Zero means no limit.").Default("50").Int64Var(&params.MaxFunctions)
+	queryCmd.Flag("order-by", "Value to order the functions by (self or total).").Default("self").EnumVar(&params.OrderBy, "self", "total")
+	queryCmd.Flag("with-locations", "Resolve file names and start lines of the functions.").BoolVar(&params.WithLocations)
+	return params
+}
+
+func queryFunctions(ctx context.Context, params *queryFunctionsParams) (err error) {
+	from, to, err := params.parseFromTo()
+	if err != nil {
+		return err
+	}
+
+	level.Info(logger).Log("msg", "query functions from profile store", "url", params.URL, "from", from, "to", to, "query", params.Query, "type", params.ProfileType)
+
+	orderBy := querierv1.FunctionsOrderBy_FUNCTIONS_ORDER_BY_SELF
+	if params.OrderBy == "total" {
+		orderBy = querierv1.FunctionsOrderBy_FUNCTIONS_ORDER_BY_TOTAL
+	}
+
+	qc := params.phlareClient.queryClient()
+	resp, err := qc.SelectFunctions(ctx, connect.NewRequest(&querierv1.SelectFunctionsRequest{
+		ProfileTypeID: params.ProfileType,
+		Start:         from.UnixMilli(),
+		End:           to.UnixMilli(),
+		LabelSelector: params.Query,
+		MaxFunctions:  &params.MaxFunctions,
+		OrderBy:       orderBy,
+		WithLocations: params.WithLocations,
+	}))
+	if err != nil {
+		return errors.Wrap(err, "failed to query")
+	}
+
+	percent := func(v int64) string {
+		if resp.Msg.Total == 0 {
+			return "0.00%"
+		}
+		return fmt.Sprintf("%.2f%%", float64(v)*100/float64(resp.Msg.Total))
+	}
+
+	table := tablewriter.NewWriter(output(ctx))
+	header := []string{"Self", "Self%", "Total", "Total%", "Function"}
+	if params.WithLocations {
+		header = append(header, "Location")
+	}
+	table.SetHeader(header)
+	for _, f := range resp.Msg.Functions {
+		row := []string{
+			strconv.FormatInt(f.Self, 10),
+			percent(f.Self),
+			strconv.FormatInt(f.Total, 10),
+			percent(f.Total),
+			f.Name,
+		}
+		if params.WithLocations {
+			row = append(row, fmt.Sprintf("%s:%d", f.Filename, f.StartLine))
+		}
+		table.Append(row)
+	}
+	table.Render()
+
+	return nil
+}
+
 type querySeriesParams struct {
 	*queryParams
 	LabelNames []string
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: GPL-2.0-only
+
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_tracing.h"
+#include "bpf_core_read.h"
+#include "profile.bpf.h"
+#include "pid.h"
+
+struct global_config_t {
+    uint64_t ns_pid_ino;
+};
+
+const volatile struct global_config_t global_config;
+
+struct off_cpu_start {
+    u64 ts;
+    s64 kern_stack;
+    s64 user_stack;
+    u32 pid;
+    u32 padding_;
+};
+
+// Threads switched out, keyed by the host tid.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u32);
+    __type(value, struct off_cpu_start);
+    __uint(max_entries, PROFILE_MAPS_SIZE);
+} off_cpu_starts SEC(".maps");
+
+// Nanoseconds spent off-CPU per stack.
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, struct sample_key);
+    __type(value, u64);
+    __uint(max_entries, PROFILE_MAPS_SIZE);
+} off_cpu_counts SEC(".maps");
+
+SEC("raw_tp/sched_switch")
+int BPF_PROG(do_sched_switch, bool preempt, struct task_struct *prev, struct task_struct *next) {
+    // prev is the current task. Preempted threads are runnable, not off-CPU.
+    if (!preempt) {
+        u32 tgid = 0;
+        current_pid(global_config.ns_pid_ino, &tgid);
+        struct pid_config *config = tgid ? bpf_map_lookup_elem(&pids, &tgid) : NULL;
+        if (config != NULL && config->type == PROFILING_TYPE_FRAMEPOINTERS) {
+            struct off_cpu_start start = {
+                    .pid = tgid,
+                    .kern_stack = -1,
+                    .user_stack = -1,
+            };
+            if (config->collect_kernel) {
+                start.kern_stack = bpf_get_stackid(ctx, &stacks, KERN_STACKID_FLAGS);
+            }
+            if (config->collect_user) {
+                start.user_stack = bpf_get_stackid(ctx, &stacks, USER_STACKID_FLAGS);
+            }
+            start.ts = bpf_ktime_get_ns();
+            u32 tid = (u32) bpf_get_current_pid_tgid();
+            bpf_map_update_elem(&off_cpu_starts, &tid, &start, BPF_ANY);
+        }
+    }
+
+    // next is the task switched in.
+    u32 tid = BPF_CORE_READ(next, pid);
+    struct off_cpu_start *start = bpf_map_lookup_elem(&off_cpu_starts, &tid);
+    if (start == NULL) {
+        return 0;
+    }
+    struct sample_key key = {
+            .pid = start->pid,
+            .kern_stack = start->kern_stack,
+            .user_stack = start->user_stack,
+    };
+    u64 delta = bpf_ktime_get_ns() - start->ts;
+    bpf_map_delete_elem(&off_cpu_starts, &tid);
+
+    u64 *val = bpf_map_lookup_elem(&off_cpu_counts, &key);
+    if (val)
+        __sync_fetch_and_add(val, delta);
+    else
+        bpf_map_update_elem(&off_cpu_counts, &key, &delta, BPF_NOEXIST);
+    return 0;
+}
+
+char _license[] SEC("license") = "GPL";
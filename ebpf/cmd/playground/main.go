@@ -185,6 +185,14 @@ func convertTargetOptions() sd.TargetsOptions {
 }
 
 func convertSessionOptions() ebpfspy.SessionOptions {
+	symbolOptions := config.SymbolOptions
+	if config.Debuginfod != nil {
+		debuginfod, err := symtab.NewDebuginfod(logger, *config.Debuginfod, metrics.Symtab)
+		if err != nil {
+			panic(fmt.Errorf("debuginfod create: %w", err))
+		}
+		symbolOptions.Debuginfod = debuginfod
+	}
 	return ebpfspy.SessionOptions{
 		CollectUser:               config.CollectUser,
 		CollectKernel:             config.CollectKernel,
@@ -193,10 +201,12 @@ func convertSessionOptions() ebpfspy.SessionOptions {
 		UnknownSymbolModuleOffset: config.UnknownSymbolModuleOffset,
 		PythonEnabled:             config.PythonEnabled,
 		Metrics:                   metrics,
+		SymbolOptions:             symbolOptions,
 		CacheOptions:              config.CacheOptions,
 		VerifierLogSize:           1024 * 1024 * 20,
 		PythonBPFErrorLogEnabled:  config.PythonBPFLogErr,
 		PythonBPFDebugLogEnabled:  config.PythonBPFLogDebug,
+		OffCPUEnabled:             config.OffCPUEnabled,
 	}
 }
 
@@ -229,6 +239,7 @@ var defaultConfig = Config{
 		GoTableFallback:    true,
 		PythonFullFilePath: false,
 		DemangleOptions:    demangle.DemangleFull,
+		PerfMap:            true,
 	},
 	CacheOptions: symtab.CacheOptions{
 
@@ -261,6 +272,7 @@ type Config struct {
 	UnknownSymbolAddress      bool
 	PythonEnabled             bool
 	SymbolOptions             symtab.SymbolOptions
+	Debuginfod                *symtab.DebuginfodOptions
 	CacheOptions              symtab.CacheOptions
 	SampleRate                int
 	TargetsOnly               bool
@@ -269,6 +281,7 @@ type Config struct {
 	RelabelConfig             []*RelabelConfig
 	PythonBPFLogErr           bool
 	PythonBPFLogDebug         bool
+	OffCPUEnabled             bool
 }
 
 type RelabelConfig struct {
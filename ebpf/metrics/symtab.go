@@ -9,6 +9,9 @@ type SymtabMetrics struct {
 	UnknownSymbols *prometheus.CounterVec
 	UnknownModules *prometheus.CounterVec
 	UnknownStacks  *prometheus.CounterVec
+	PerfMapErrors  *prometheus.CounterVec
+	PerfMapSymbols *prometheus.CounterVec
+	Debuginfod     *prometheus.CounterVec
 }
 
 func NewSymtabMetrics(reg prometheus.Registerer) *SymtabMetrics {
@@ -37,6 +40,18 @@ func NewSymtabMetrics(reg prometheus.Registerer) *SymtabMetrics {
 			Name: "pyroscope_symtab_unknown_stacks_total",
 			Help: "Total number of stacks with unknowns > knowns",
 		}, []string{"service_name"}),
+		PerfMapErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_symtab_perf_map_errors_total",
+			Help: "Total number of errors while trying to read perf map and jitdump files",
+		}, []string{"format"}),
+		PerfMapSymbols: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_symtab_perf_map_symbols_total",
+			Help: "Total number of symbols read from perf map and jitdump files",
+		}, []string{"format"}),
+		Debuginfod: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_symtab_debuginfod_lookups_total",
+			Help: "Total number of debug file lookups in debuginfod by result",
+		}, []string{"result"}),
 	}
 
 	if reg != nil {
@@ -47,6 +62,9 @@ func NewSymtabMetrics(reg prometheus.Registerer) *SymtabMetrics {
 			m.UnknownSymbols,
 			m.UnknownModules,
 			m.UnknownStacks,
+			m.PerfMapErrors,
+			m.PerfMapSymbols,
+			m.Debuginfod,
 		)
 	}
 
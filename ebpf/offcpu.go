@@ -0,0 +1,211 @@
+//go:build linux
+
+package ebpfspy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/grafana/pyroscope/ebpf/pprof"
+	"github.com/grafana/pyroscope/ebpf/pyrobpf"
+	"github.com/grafana/pyroscope/ebpf/symtab"
+)
+
+// offCPUProfiler records the time the threads of the profiled processes
+// spend off-CPU, blocked on locks, I/O, sleeps, etc. A thread switched out
+// voluntarily has its stacks captured and the timestamp saved in the starts
+// map, when it is switched in again, the elapsed time is added to the
+// counts map. Preempted threads are not recorded, as they are runnable.
+//
+// The program shares the pids and the stacks maps with the on-CPU profiler,
+// so the off-CPU stacks are resolved and cleaned up the same way. The stacks
+// of the threads still off-CPU at a collection are not cleaned up, as they
+// are only added to the counts map when the threads are switched in.
+type offCPUProfiler struct {
+	starts *ebpf.Map
+	counts *ebpf.Map
+	prog   *ebpf.Program
+	link   link.Link
+}
+
+func newOffCPUProfiler(pids, stacks *ebpf.Map, nsIno uint64, opts ebpf.ProgramOptions) (*offCPUProfiler, error) {
+	spec, err := pyrobpf.LoadOffcpu()
+	if err != nil {
+		return nil, fmt.Errorf("off-cpu load %w", err)
+	}
+	err = spec.RewriteConstants(map[string]interface{}{
+		"global_config": pyrobpf.OffcpuGlobalConfigT{
+			NsPidIno: nsIno,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("off-cpu rewrite constants %w", err)
+	}
+	// Only the off-CPU maps are created, the program uses the pids and the
+	// stacks maps of the on-CPU profiler.
+	var objs struct {
+		pyrobpf.OffcpuPrograms
+		Starts *ebpf.Map `ebpf:"off_cpu_starts"`
+		Counts *ebpf.Map `ebpf:"off_cpu_counts"`
+	}
+	err = spec.LoadAndAssign(&objs, &ebpf.CollectionOptions{
+		Programs: opts,
+		MapReplacements: map[string]*ebpf.Map{
+			"pids":   pids,
+			"stacks": stacks,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("off-cpu load objects: %w", err)
+	}
+	p := &offCPUProfiler{
+		starts: objs.Starts,
+		counts: objs.Counts,
+		prog:   objs.DoSchedSwitch,
+	}
+	p.link, err = link.AttachRawTracepoint(link.RawTracepointOptions{
+		Name:    "sched_switch",
+		Program: p.prog,
+	})
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("attach sched_switch: %w", err)
+	}
+	return p, nil
+}
+
+func (p *offCPUProfiler) close() {
+	if p.link != nil {
+		_ = p.link.Close()
+	}
+	if p.prog != nil {
+		_ = p.prog.Close()
+	}
+	if p.counts != nil {
+		_ = p.counts.Close()
+	}
+	if p.starts != nil {
+		_ = p.starts.Close()
+	}
+}
+
+// getCountsMapValues returns and deletes the off-CPU time per stack.
+func (p *offCPUProfiler) getCountsMapValues() (keys []pyrobpf.ProfileSampleKey, values []uint64, err error) {
+	var (
+		m       = p.counts
+		mapSize = m.MaxEntries()
+		nextKey = pyrobpf.ProfileSampleKey{}
+	)
+	keys = make([]pyrobpf.ProfileSampleKey, mapSize)
+	values = make([]uint64, mapSize)
+	n, err := m.BatchLookupAndDelete(nil, &nextKey, keys, values, new(ebpf.BatchOptions))
+	if n > 0 {
+		return keys[:n], values[:n], nil
+	}
+	if errors.Is(err, ebpf.ErrKeyNotExist) {
+		return nil, nil, nil
+	}
+	// try iterating if batch failed
+	keys, values = keys[:0], values[:0]
+	it := m.Iterate()
+	k := pyrobpf.ProfileSampleKey{}
+	v := uint64(0)
+	for it.Next(&k, &v) {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	if err = it.Err(); err != nil {
+		return nil, nil, fmt.Errorf("map %s iteration : %w", m.String(), err)
+	}
+	for i := range keys {
+		if err = m.Delete(&keys[i]); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return nil, nil, err
+		}
+	}
+	return keys, values, nil
+}
+
+// pendingStacks returns the ids of the stacks captured at the switch-out
+// of the threads that are still off-CPU.
+func (p *offCPUProfiler) pendingStacks() (map[uint32]bool, error) {
+	stacks := make(map[uint32]bool)
+	it := p.starts.Iterate()
+	var (
+		tid   uint32
+		start pyrobpf.OffcpuOffCpuStart
+	)
+	for it.Next(&tid, &start) {
+		if start.UserStack >= 0 {
+			stacks[uint32(start.UserStack)] = true
+		}
+		if start.KernStack >= 0 {
+			stacks[uint32(start.KernStack)] = true
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("map %s iteration : %w", p.starts.String(), err)
+	}
+	return stacks, nil
+}
+
+// collectOffCPUProfile reports the off-CPU time per stack as a separate
+// profile type. The stack ids are added to knownStacks to be cleared with
+// the on-CPU ones.
+func (s *session) collectOffCPUProfile(cb pprof.CollectProfilesCallback, sb *stackBuilder, knownStacks map[uint32]bool) error {
+	keys, values, err := s.offcpu.getCountsMapValues()
+	if err != nil {
+		return fmt.Errorf("get off-cpu counts map: %w", err)
+	}
+	for i := range keys {
+		ck := &keys[i]
+		if ck.UserStack >= 0 {
+			knownStacks[uint32(ck.UserStack)] = true
+		}
+		if ck.KernStack >= 0 {
+			knownStacks[uint32(ck.KernStack)] = true
+		}
+		target := s.targetFinder.FindTarget(ck.Pid)
+		if target == nil {
+			continue
+		}
+		if _, ok := s.pids.dead[ck.Pid]; ok {
+			continue
+		}
+
+		stats := StackResolveStats{}
+		sb.reset()
+		sb.append(s.comm(ck.Pid))
+		if s.options.CollectUser {
+			pk := symtab.PidKey(ck.Pid)
+			proc := s.symCache.GetProcTableCached(pk)
+			if proc == nil {
+				proc = s.symCache.NewProcTable(pk, s.targetSymbolOptions(target))
+			}
+			if proc.Error() != nil {
+				s.pids.dead[uint32(proc.Pid())] = struct{}{}
+				continue
+			}
+			s.WalkStack(sb, s.GetStack(ck.UserStack), proc, &stats)
+		}
+		if s.options.CollectKernel {
+			s.WalkStack(sb, s.GetStack(ck.KernStack), s.symCache.GetKallsyms(), &stats)
+		}
+		if len(sb.stack) == 1 {
+			continue // only comm
+		}
+		sb.reverse(0, len(sb.stack))
+		cb(pprof.ProfileSample{
+			Target:          target,
+			Pid:             ck.Pid,
+			Aggregation:     pprof.SampleAggregated,
+			SampleType:      pprof.SampleTypeOffCpu,
+			Stack:           sb.stack,
+			SourceLocations: sb.sourceLocations,
+			Value:           values[i],
+		})
+		s.collectMetrics(target, &stats, sb)
+	}
+	return nil
+}
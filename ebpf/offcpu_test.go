@@ -0,0 +1,151 @@
+//go:build linux
+
+package ebpfspy
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/grafana/pyroscope/ebpf/pyrobpf"
+	"github.com/grafana/pyroscope/ebpf/rlimit"
+	"github.com/grafana/pyroscope/ebpf/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func newTestOffCPUProfiler(t *testing.T) (*offCPUProfiler, *ebpf.Map, uint32) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	require.NoError(t, rlimit.RemoveMemlock())
+	spec, err := pyrobpf.LoadProfile()
+	require.NoError(t, err)
+	pids, err := ebpf.NewMap(spec.Maps["pids"])
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = pids.Close() })
+	stacks, err := ebpf.NewMap(spec.Maps["stacks"])
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = stacks.Close() })
+
+	_, nsIno, err := getPIDNamespace()
+	require.NoError(t, err)
+	p, err := newOffCPUProfiler(pids, stacks, nsIno, ebpf.ProgramOptions{LogSize: 1 << 20})
+	require.NoError(t, err)
+	t.Cleanup(p.close)
+
+	pid := uint32(os.Getpid())
+	require.NoError(t, pids.Update(&pid, &pyrobpf.ProfilePidConfig{
+		Type:          uint8(pyrobpf.ProfilingTypeFramepointers),
+		CollectUser:   1,
+		CollectKernel: 1,
+	}, ebpf.UpdateAny))
+	return p, stacks, pid
+}
+
+func TestOffCPUProgramSpec(t *testing.T) {
+	spec, err := pyrobpf.LoadOffcpu()
+	require.NoError(t, err)
+	prog := spec.Programs["do_sched_switch"]
+	require.NotNil(t, prog)
+	require.Equal(t, ebpf.RawTracepoint, prog.Type)
+	require.Equal(t, "sched_switch", prog.AttachTo)
+
+	require.Equal(t, uint32(unsafe.Sizeof(pyrobpf.OffcpuOffCpuStart{})), spec.Maps["off_cpu_starts"].ValueSize)
+	require.Equal(t, uint32(unsafe.Sizeof(pyrobpf.ProfileSampleKey{})), spec.Maps["off_cpu_counts"].KeySize)
+	require.Equal(t, uint32(8), spec.Maps["off_cpu_counts"].ValueSize)
+
+	// The pids and the stacks maps are replaced with the on-CPU ones.
+	profile, err := pyrobpf.LoadProfile()
+	require.NoError(t, err)
+	for _, name := range []string{"pids", "stacks"} {
+		m, p := spec.Maps[name], profile.Maps[name]
+		require.Equal(t, p.Type, m.Type, name)
+		require.Equal(t, p.KeySize, m.KeySize, name)
+		require.Equal(t, p.ValueSize, m.ValueSize, name)
+		require.Equal(t, p.MaxEntries, m.MaxEntries, name)
+	}
+	require.NoError(t, spec.RewriteConstants(map[string]interface{}{
+		"global_config": pyrobpf.OffcpuGlobalConfigT{NsPidIno: 1},
+	}))
+}
+
+func TestOffCPUProfiler(t *testing.T) {
+	p, _, pid := newTestOffCPUProfiler(t)
+
+	const sleep = 50 * time.Millisecond
+	done := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		for i := 0; i < 4; i++ {
+			time.Sleep(sleep)
+		}
+		close(done)
+	}()
+	<-done
+
+	keys, values, err := p.getCountsMapValues()
+	require.NoError(t, err)
+	var total time.Duration
+	kernStacks := 0
+	for i, k := range keys {
+		require.Equal(t, pid, k.Pid)
+		total += time.Duration(values[i])
+		if k.KernStack >= 0 {
+			kernStacks++
+		}
+	}
+	require.GreaterOrEqual(t, total, 4*sleep)
+	require.Greater(t, kernStacks, 0)
+}
+
+func TestOffCPUProfilerWaitSpanningCollection(t *testing.T) {
+	p, stacks, pid := newTestOffCPUProfiler(t)
+
+	const sleep = 500 * time.Millisecond
+	tids := make(chan uint32)
+	done := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		tids <- uint32(unix.Gettid())
+		time.Sleep(sleep)
+		close(done)
+	}()
+	tid := <-tids
+	time.Sleep(sleep / 5)
+
+	// The thread is off-CPU during the collection, which clears all the stacks.
+	var start pyrobpf.OffcpuOffCpuStart
+	require.NoError(t, p.starts.Lookup(&tid, &start))
+	require.GreaterOrEqual(t, start.KernStack, int64(0))
+	pending, err := p.pendingStacks()
+	require.NoError(t, err)
+	require.True(t, pending[uint32(start.KernStack)])
+	s := &session{logger: util.TestLogger(t), roundNumber: 10}
+	require.NoError(t, s.clearStacksMap(nil, pending, stacks))
+	for _, id := range []int64{start.KernStack, start.UserStack} {
+		if id >= 0 {
+			stack, err := stacks.LookupBytes(uint32(id))
+			require.NoError(t, err)
+			require.NotEmpty(t, stack)
+		}
+	}
+	<-done
+
+	keys, values, err := p.getCountsMapValues()
+	require.NoError(t, err)
+	found := false
+	for i, k := range keys {
+		if k.Pid != pid || k.KernStack != start.KernStack || k.UserStack != start.UserStack {
+			continue
+		}
+		found = true
+		require.GreaterOrEqual(t, time.Duration(values[i]), sleep/2)
+	}
+	require.True(t, found)
+}
@@ -31,6 +31,7 @@ type SampleType uint32
 
 var SampleTypeCpu = SampleType(0)
 var SampleTypeMem = SampleType(1)
+var SampleTypeOffCpu = SampleType(2)
 
 type SampleAggregation bool
 
@@ -51,8 +52,19 @@ type ProfileSample struct {
 	SampleType  SampleType
 	Aggregation SampleAggregation
 	Stack       []string
-	Value       uint64
-	Value2      uint64
+	// SourceLocations, if set, holds the source locations of the Stack
+	// frames, in the same order.
+	SourceLocations []SourceLocation
+	Value           uint64
+	Value2          uint64
+}
+
+// SourceLocation is the source file and line of a stack frame. It is only
+// known for the frames expanded from the DWARF debug info; the zero value
+// means unknown.
+type SourceLocation struct {
+	File string
+	Line int
 }
 
 type BuildersOptions struct {
@@ -109,14 +121,18 @@ func (b *ProfileBuilders) BuilderForSample(sample *ProfileSample) *ProfileBuilde
 		sampleType = []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}}
 		periodType = &profile.ValueType{Type: "cpu", Unit: "nanoseconds"}
 		period = time.Second.Nanoseconds() / b.opt.SampleRate
+	} else if sample.SampleType == SampleTypeOffCpu {
+		sampleType = []*profile.ValueType{{Type: "off_cpu", Unit: "nanoseconds"}}
+		periodType = &profile.ValueType{Type: "off_cpu", Unit: "nanoseconds"}
+		period = 1
 	} else {
 		sampleType = []*profile.ValueType{{Type: "alloc_objects", Unit: "count"}, {Type: "alloc_space", Unit: "bytes"}}
 		periodType = &profile.ValueType{Type: "space", Unit: "bytes"}
 		period = 512 * 1024 // todo
 	}
 	builder := &ProfileBuilder{
-		locations:          make(map[string]*profile.Location),
-		functions:          make(map[string]*profile.Function),
+		locations:          make(map[locationKey]*profile.Location),
+		functions:          make(map[functionKey]*profile.Function),
 		sampleHashToSample: make(map[uint64]*profile.Sample),
 		Labels:             labels,
 		Profile: &profile.Profile{
@@ -138,9 +154,19 @@ func (b *ProfileBuilders) BuilderForSample(sample *ProfileSample) *ProfileBuilde
 	return res
 }
 
+type locationKey struct {
+	function string
+	SourceLocation
+}
+
+type functionKey struct {
+	name string
+	file string
+}
+
 type ProfileBuilder struct {
-	locations          map[string]*profile.Location
-	functions          map[string]*profile.Function
+	locations          map[locationKey]*profile.Location
+	functions          map[functionKey]*profile.Function
 	sampleHashToSample map[uint64]*profile.Sample
 	Profile            *profile.Profile
 	Labels             labels.Labels
@@ -153,7 +179,7 @@ func (p *ProfileBuilder) CreateSample(inputSample *ProfileSample) {
 	sample := p.newSample(inputSample)
 	p.addValue(inputSample, sample)
 	for i, s := range inputSample.Stack {
-		sample.Location[i] = p.addLocation(s)
+		sample.Location[i] = p.addLocation(locationKey{function: s, SourceLocation: inputSample.sourceLocation(i)})
 	}
 	p.Profile.Sample = append(p.Profile.Sample, sample)
 }
@@ -161,8 +187,8 @@ func (p *ProfileBuilder) CreateSample(inputSample *ProfileSample) {
 func (p *ProfileBuilder) CreateSampleOrAddValue(inputSample *ProfileSample) {
 	p.tmpLocations = p.tmpLocations[:0]
 	p.tmpLocationIDs = p.tmpLocationIDs[:0]
-	for _, s := range inputSample.Stack {
-		loc := p.addLocation(s)
+	for i, s := range inputSample.Stack {
+		loc := p.addLocation(locationKey{function: s, SourceLocation: inputSample.sourceLocation(i)})
 		p.tmpLocations = append(p.tmpLocations, loc)
 		p.tmpLocationIDs = append(p.tmpLocationIDs, loc.ID)
 	}
@@ -179,8 +205,15 @@ func (p *ProfileBuilder) CreateSampleOrAddValue(inputSample *ProfileSample) {
 	p.Profile.Sample = append(p.Profile.Sample, sample)
 }
 
-func (p *ProfileBuilder) addLocation(function string) *profile.Location {
-	loc, ok := p.locations[function]
+func (s *ProfileSample) sourceLocation(i int) SourceLocation {
+	if i < len(s.SourceLocations) {
+		return s.SourceLocations[i]
+	}
+	return SourceLocation{}
+}
+
+func (p *ProfileBuilder) addLocation(k locationKey) *profile.Location {
+	loc, ok := p.locations[k]
 	if ok {
 		return loc
 	}
@@ -191,28 +224,30 @@ func (p *ProfileBuilder) addLocation(function string) *profile.Location {
 		Mapping: p.Profile.Mapping[0],
 		Line: []profile.Line{
 			{
-				Function: p.addFunction(function),
+				Function: p.addFunction(functionKey{name: k.function, file: k.File}),
+				Line:     int64(k.Line),
 			},
 		},
 	}
 	p.Profile.Location = append(p.Profile.Location, loc)
-	p.locations[function] = loc
+	p.locations[k] = loc
 	return loc
 }
 
-func (p *ProfileBuilder) addFunction(function string) *profile.Function {
-	f, ok := p.functions[function]
+func (p *ProfileBuilder) addFunction(k functionKey) *profile.Function {
+	f, ok := p.functions[k]
 	if ok {
 		return f
 	}
 
 	id := uint64(len(p.Profile.Function) + 1)
 	f = &profile.Function{
-		ID:   id,
-		Name: function,
+		ID:       id,
+		Name:     k.name,
+		Filename: k.file,
 	}
 	p.Profile.Function = append(p.Profile.Function, f)
-	p.functions[function] = f
+	p.functions[k] = f
 	return f
 }
 
@@ -247,7 +282,7 @@ func uint64Bytes(s []uint64) []byte {
 }
 func (p *ProfileBuilder) newSample(inputSample *ProfileSample) *profile.Sample {
 	sample := new(profile.Sample)
-	if inputSample.SampleType == SampleTypeCpu {
+	if inputSample.SampleType == SampleTypeCpu || inputSample.SampleType == SampleTypeOffCpu {
 		sample.Value = []int64{0}
 	} else {
 		sample.Value = []int64{0, 0}
@@ -259,6 +294,8 @@ func (p *ProfileBuilder) newSample(inputSample *ProfileSample) *profile.Sample {
 func (p *ProfileBuilder) addValue(inputSample *ProfileSample, sample *profile.Sample) {
 	if inputSample.SampleType == SampleTypeCpu {
 		sample.Value[0] += int64(inputSample.Value) * p.Profile.Period
+	} else if inputSample.SampleType == SampleTypeOffCpu {
+		sample.Value[0] += int64(inputSample.Value)
 	} else {
 		sample.Value[0] += int64(inputSample.Value)
 		sample.Value[1] += int64(inputSample.Value2)
@@ -127,3 +127,50 @@ func stackCollapse(parsed *profile.Profile) map[string]int64 {
 	}
 	return stacks
 }
+
+func TestOffCpuSamples(t *testing.T) {
+	builders := NewProfileBuilders(BuildersOptions{
+		SampleRate: int64(97),
+	})
+	cpu := sample([]string{"a", "b", "c"}, 239)
+	offCpu := sample([]string{"a", "b", "d"}, 4242)
+	offCpu.SampleType = SampleTypeOffCpu
+	builders.AddSample(cpu)
+	builders.AddSample(offCpu)
+	require.Equal(t, 2, len(builders.Builders))
+
+	buf := bytes.NewBuffer(nil)
+	_, err := builders.BuilderForSample(offCpu).Write(buf)
+	require.NoError(t, err)
+	parsed, err := profile.Parse(buf)
+	require.NoError(t, err)
+	require.Equal(t, []*profile.ValueType{{Type: "off_cpu", Unit: "nanoseconds"}}, parsed.SampleType)
+	require.Equal(t, map[string]int64{"a;b;d": 4242}, stackCollapse(parsed))
+}
+
+func TestSourceLocations(t *testing.T) {
+	builders := NewProfileBuilders(BuildersOptions{
+		SampleRate: int64(97),
+	})
+	s1 := sample([]string{"main", "inlined", "leaf"}, 1)
+	s1.SourceLocations = []SourceLocation{{}, {File: "main.c", Line: 3}, {File: "leaf.h", Line: 7}}
+	s2 := sample([]string{"main", "inlined", "leaf"}, 2)
+	s2.SourceLocations = []SourceLocation{{}, {File: "main.c", Line: 4}, {File: "leaf.h", Line: 7}}
+	builders.AddSample(s1)
+	builders.AddSample(s2)
+
+	buf := bytes.NewBuffer(nil)
+	_, err := builders.BuilderForSample(s1).Write(buf)
+	require.NoError(t, err)
+	parsed, err := profile.Parse(buf)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(parsed.Function))
+	require.Equal(t, 4, len(parsed.Location))
+	require.Equal(t, 2, len(parsed.Sample))
+
+	loc := parsed.Sample[1].Location[1].Line[0]
+	require.Equal(t, "inlined", loc.Function.Name)
+	require.Equal(t, "main.c", loc.Function.Filename)
+	require.Equal(t, int64(4), loc.Line)
+	require.Equal(t, "", parsed.Sample[1].Location[0].Line[0].Function.Filename)
+}
@@ -44,6 +44,8 @@ const (
 	OptionPythonBPFDebugLogEnabled = labelMetaPyroscopeOptionsPrefix + "python_bpf_debug_log"
 	OptionPythonBPFErrorLogEnabled = labelMetaPyroscopeOptionsPrefix + "python_bpf_error_log"
 	OptionDemangle                 = labelMetaPyroscopeOptionsPrefix + "demangle"
+	OptionPerfMap                  = labelMetaPyroscopeOptionsPrefix + "perf_map"
+	OptionInlineFrames             = labelMetaPyroscopeOptionsPrefix + "inline_frames"
 )
 
 type Target struct {
@@ -48,6 +48,9 @@ type SessionOptions struct {
 	VerifierLogSize           int
 	PythonBPFErrorLogEnabled  bool
 	PythonBPFDebugLogEnabled  bool
+	// OffCPUEnabled enables the off-CPU profile, the time the threads spend
+	// blocked, collected from the sched_switch events. Only read on Start.
+	OffCPUEnabled bool
 }
 
 type Session interface {
@@ -108,6 +111,8 @@ type session struct {
 	pyperfBpf    python.PerfObjects
 	pyperfError  error
 
+	offcpu *offCPUProfiler
+
 	pids            pids
 	pidExecRequests chan uint32
 }
@@ -173,6 +178,14 @@ func (s *session) Start() error {
 		return fmt.Errorf("load bpf objects: %w", err)
 	}
 
+	if s.options.OffCPUEnabled {
+		s.offcpu, err = newOffCPUProfiler(s.bpf.Pids, s.bpf.Stacks, nsIno, s.progOptions())
+		if err != nil {
+			s.logVerifierError(err)
+			_ = level.Error(s.logger).Log("msg", "off-cpu profiling is disabled", "err", err)
+		}
+	}
+
 	btf.FlushKernelSpec() // save some memory
 
 	eventsReader, err := perf.NewReader(s.bpf.ProfileMaps.Events, 4*os.Getpagesize())
@@ -358,26 +371,39 @@ func (s *session) collectRegularProfile(cb pprof.CollectProfilesCallback) error
 		if len(sb.stack) == 1 {
 			continue // only comm
 		}
-		lo.Reverse(sb.stack)
+		sb.reverse(0, len(sb.stack))
 		cb(pprof.ProfileSample{
-			Target:      target,
-			Pid:         ck.Pid,
-			Aggregation: pprof.SampleAggregated,
-			SampleType:  pprof.SampleTypeCpu,
-			Stack:       sb.stack,
-			Value:       uint64(value),
+			Target:          target,
+			Pid:             ck.Pid,
+			Aggregation:     pprof.SampleAggregated,
+			SampleType:      pprof.SampleTypeCpu,
+			Stack:           sb.stack,
+			SourceLocations: sb.sourceLocations,
+			Value:           uint64(value),
 		})
 		s.collectMetrics(target, &stats, sb)
 	}
 
+	// The stacks of the threads blocked across the collection are kept:
+	// they are reported once the threads are switched in.
+	var pendingStacks map[uint32]bool
+	if s.offcpu != nil {
+		if err = s.collectOffCPUProfile(cb, sb, knownStacks); err != nil {
+			return fmt.Errorf("collect off-cpu profile: %w", err)
+		}
+		if pendingStacks, err = s.offcpu.pendingStacks(); err != nil {
+			return fmt.Errorf("get off-cpu pending stacks: %w", err)
+		}
+	}
+
 	if err = s.clearCountsMap(keys, batch); err != nil {
 		return fmt.Errorf("clear counts map %w", err)
 	}
-	if err = s.clearStacksMap(knownStacks, s.bpf.Stacks); err != nil {
+	if err = s.clearStacksMap(knownStacks, pendingStacks, s.bpf.Stacks); err != nil {
 		return fmt.Errorf("clear stacks map %w", err)
 	}
 	if s.pyperfBpf.PythonStacks != nil && len(knownPythonStacks) > 0 {
-		if err = s.clearStacksMap(knownPythonStacks, s.pyperfBpf.PythonStacks); err != nil { //todo use batchdelete
+		if err = s.clearStacksMap(knownPythonStacks, nil, s.pyperfBpf.PythonStacks); err != nil { //todo use batchdelete
 			return fmt.Errorf("clear stacks map %w", err)
 		}
 	}
@@ -422,6 +448,10 @@ func (s *session) stopLocked() {
 		_ = kprobe.Close()
 	}
 	s.kprobes = nil
+	if s.offcpu != nil {
+		s.offcpu.close()
+		s.offcpu = nil
+	}
 	_ = s.bpf.Close()
 	if s.pyperf != nil {
 		s.pyperf = nil
@@ -528,6 +558,14 @@ func (s *session) WalkStack(sb *stackBuilder, stack []byte, resolver symtab.Symb
 			break
 		}
 		sym := resolver.Resolve(instructionPointer)
+		if len(sym.Inline) > 0 {
+			// The stack is reversed below, so the innermost function goes first.
+			for _, frame := range sym.Inline {
+				sb.appendSourceLocation(frame.Name, pprof.SourceLocation{File: frame.File, Line: frame.Line})
+			}
+			stats.known++
+			continue
+		}
 		var name string
 		if sym.Name != "" {
 			name = sym.Name
@@ -551,8 +589,7 @@ func (s *session) WalkStack(sb *stackBuilder, stack []byte, resolver symtab.Symb
 		}
 		sb.append(name)
 	}
-	end := len(sb.stack)
-	lo.Reverse(sb.stack[begin:end])
+	sb.reverse(begin, len(sb.stack))
 
 }
 
@@ -879,6 +916,12 @@ func overrideSymbolOptions(t *sd.Target, opt *symtab.SymbolOptions) {
 	if v, present := t.Get(sd.OptionDemangle); present {
 		opt.DemangleOptions = demangle.ConvertDemangleOptions(v)
 	}
+	if v, present := t.GetFlag(sd.OptionPerfMap); present {
+		opt.PerfMap = v
+	}
+	if v, present := t.GetFlag(sd.OptionInlineFrames); present {
+		opt.InlineFrames = v
+	}
 }
 
 func (s *session) collectKernelEnabled(target *sd.Target) bool {
@@ -915,14 +958,27 @@ func (s *session) pythonBPFErrorLogEnabled(target *sd.Target) bool {
 
 type stackBuilder struct {
 	stack []string
+	// sourceLocations holds the source locations of the stack frames.
+	sourceLocations []pprof.SourceLocation
 }
 
 func (s *stackBuilder) reset() {
 	s.stack = s.stack[:0]
+	s.sourceLocations = s.sourceLocations[:0]
 }
 
 func (s *stackBuilder) append(sym string) {
+	s.appendSourceLocation(sym, pprof.SourceLocation{})
+}
+
+func (s *stackBuilder) appendSourceLocation(sym string, loc pprof.SourceLocation) {
 	s.stack = append(s.stack, sym)
+	s.sourceLocations = append(s.sourceLocations, loc)
+}
+
+func (s *stackBuilder) reverse(begin, end int) {
+	lo.Reverse(s.stack[begin:end])
+	lo.Reverse(s.sourceLocations[begin:end])
 }
 
 func getPIDNamespace() (dev uint64, ino uint64, err error) {
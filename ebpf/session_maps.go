@@ -84,7 +84,9 @@ func (s *session) clearCountsMap(keys []pyrobpf.ProfileSampleKey, batch bool) er
 	return nil
 }
 
-func (s *session) clearStacksMap(knownKeys map[uint32]bool, m *ebpf.Map) error {
+// clearStacksMap deletes the known stacks, or all the stacks once in a
+// while. The stacks in keepKeys are never deleted.
+func (s *session) clearStacksMap(knownKeys map[uint32]bool, keepKeys map[uint32]bool, m *ebpf.Map) error {
 	cnt := 0
 	errs := 0
 	if s.roundNumber%10 == 0 {
@@ -102,6 +104,9 @@ func (s *session) clearStacksMap(knownKeys map[uint32]bool, m *ebpf.Map) error {
 				}
 				break
 			}
+			if keepKeys[k] {
+				continue
+			}
 			keys = append(keys, k)
 		}
 		for i := range keys {
@@ -120,6 +125,9 @@ func (s *session) clearStacksMap(knownKeys map[uint32]bool, m *ebpf.Map) error {
 		return nil
 	}
 	for stackId := range knownKeys {
+		if keepKeys[stackId] {
+			continue
+		}
 		k := stackId
 		if err := m.Delete(&k); err != nil {
 			errs += 1
@@ -15,7 +15,6 @@ import (
 	"github.com/grafana/pyroscope/ebpf/pyrobpf"
 	"github.com/grafana/pyroscope/ebpf/python"
 	"github.com/grafana/pyroscope/ebpf/sd"
-	"github.com/samber/lo"
 )
 
 func (s *session) tryStartPythonProfiling(pid uint32, target *sd.Target, pi procInfoLite) {
@@ -206,8 +205,7 @@ func (s *session) WalkPythonStack(sb *stackBuilder, stack []byte, target *sd.Tar
 			stats.unknownSymbols += 1
 		}
 	}
-	end := len(sb.stack)
-	lo.Reverse(sb.stack[begin:end])
+	sb.reverse(begin, len(sb.stack))
 }
 
 func skipPythonFrame(classname string, filename string, name string) bool {
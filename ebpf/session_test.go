@@ -0,0 +1,62 @@
+//go:build linux
+
+package ebpfspy
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/grafana/pyroscope/ebpf/pprof"
+	"github.com/grafana/pyroscope/ebpf/sd"
+	"github.com/grafana/pyroscope/ebpf/symtab"
+	"github.com/grafana/pyroscope/ebpf/symtab/elf"
+	"github.com/stretchr/testify/require"
+)
+
+type testSymbolTable map[uint64]symtab.Symbol
+
+func (t testSymbolTable) Refresh()                          {}
+func (t testSymbolTable) Cleanup()                          {}
+func (t testSymbolTable) Resolve(addr uint64) symtab.Symbol { return t[addr] }
+
+func TestWalkStackInlineFrames(t *testing.T) {
+	resolver := testSymbolTable{
+		0x10: {Name: "leaf"},
+		0x20: {Name: "caller", Inline: []elf.InlineFrame{
+			{Name: "inlined", File: "inlined.h", Line: 3},
+			{Name: "caller", File: "caller.c", Line: 10},
+		}},
+		0x30: {Name: "main"},
+	}
+	stack := make([]byte, 127*8)
+	for i, pc := range []uint64{0x10, 0x20, 0x30} {
+		binary.LittleEndian.PutUint64(stack[i*8:], pc)
+	}
+
+	s := &session{}
+	sb := &stackBuilder{}
+	sb.append("comm")
+	stats := StackResolveStats{}
+	s.WalkStack(sb, stack, resolver, &stats)
+
+	require.Equal(t, []string{"comm", "main", "caller", "inlined", "leaf"}, sb.stack)
+	require.Equal(t, []pprof.SourceLocation{
+		{},
+		{},
+		{File: "caller.c", Line: 10},
+		{File: "inlined.h", Line: 3},
+		{},
+	}, sb.sourceLocations)
+	require.Equal(t, uint32(3), stats.known)
+}
+
+func TestOverrideSymbolOptions(t *testing.T) {
+	opt := &symtab.SymbolOptions{PerfMap: true}
+	target := sd.NewTargetForTesting("", 1, sd.DiscoveryTarget{
+		sd.OptionInlineFrames: "true",
+		sd.OptionPerfMap:      "false",
+	})
+	overrideSymbolOptions(target, opt)
+	require.True(t, opt.InlineFrames)
+	require.False(t, opt.PerfMap)
+}
@@ -0,0 +1,309 @@
+package symtab
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/pyroscope/ebpf/metrics"
+	elf2 "github.com/grafana/pyroscope/ebpf/symtab/elf"
+)
+
+var (
+	errDebugFileNotFound = errors.New("debug file not found")
+	errDebugFileTooLarge = errors.New("debug file too large")
+)
+
+type DebuginfodOptions struct {
+	// URL of the debuginfod-compatible server, the debug files are
+	// downloaded from {URL}/buildid/{build_id}/debuginfo.
+	URL string
+	// CacheDir is the directory the downloaded debug files are kept in,
+	// so they survive the restarts of the profiler.
+	CacheDir string
+	Timeout  time.Duration
+	// NotFoundTTL is for how long a build ID is not looked up again
+	// after the server failed to find it or responded with an error.
+	NotFoundTTL time.Duration
+	// MaxFileSize is the size limit of a debug file. The larger debug
+	// files are not downloaded, as if they were not found.
+	MaxFileSize int64
+	// MaxCacheSize bounds the total size of the cached debug files:
+	// the least recently used ones are removed once it is exceeded.
+	MaxCacheSize int64
+}
+
+// Debuginfod downloads the debug files by build ID. The files are cached
+// on disk as {CacheDir}/{build_id}/debuginfo; an empty file marks the
+// build ID not found by the server. A single Debuginfod may be shared
+// by all the sessions.
+type Debuginfod struct {
+	logger  log.Logger
+	options DebuginfodOptions
+	metrics *metrics.SymtabMetrics
+	client  *http.Client
+
+	mu sync.Mutex
+	// failed holds the build IDs that failed to download due to an error,
+	// until they can be retried. Unlike not found, errors are not persisted.
+	failed map[string]time.Time
+	// pending holds the build IDs being downloaded: each build ID is
+	// downloaded at most once at a time.
+	pending map[string]struct{}
+	// files holds the cached debug files, and size is their total size.
+	files map[string]*cachedDebugFile
+	size  int64
+}
+
+type cachedDebugFile struct {
+	size int64
+	used time.Time
+}
+
+func NewDebuginfod(logger log.Logger, options DebuginfodOptions, metrics *metrics.SymtabMetrics) (*Debuginfod, error) {
+	if options.URL == "" {
+		return nil, fmt.Errorf("debuginfod url is empty")
+	}
+	if options.CacheDir == "" {
+		return nil, fmt.Errorf("debuginfod cache dir is empty")
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 30 * time.Second
+	}
+	if options.NotFoundTTL <= 0 {
+		options.NotFoundTTL = time.Hour
+	}
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = 1 << 30
+	}
+	if options.MaxCacheSize <= 0 {
+		options.MaxCacheSize = 10 << 30
+	}
+	if err := os.MkdirAll(options.CacheDir, 0o755); err != nil {
+		return nil, fmt.Errorf("debuginfod cache dir: %w", err)
+	}
+	d := &Debuginfod{
+		logger:  logger,
+		options: options,
+		metrics: metrics,
+		client:  &http.Client{Timeout: options.Timeout},
+		failed:  make(map[string]time.Time),
+		pending: make(map[string]struct{}),
+		files:   make(map[string]*cachedDebugFile),
+	}
+	if err := d.loadCache(); err != nil {
+		return nil, fmt.Errorf("debuginfod cache dir: %w", err)
+	}
+	return d, nil
+}
+
+// loadCache accounts the debug files cached by the previous runs, the
+// last modification time is considered the last use.
+func (d *Debuginfod) loadCache() error {
+	entries, err := os.ReadDir(d.options.CacheDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		fi, err := os.Stat(d.debugFilePath(e.Name()))
+		if err != nil || fi.Size() == 0 {
+			continue
+		}
+		d.files[e.Name()] = &cachedDebugFile{size: fi.Size(), used: fi.ModTime()}
+		d.size += fi.Size()
+	}
+	d.removeFiles(d.evict())
+	return nil
+}
+
+// DebugFile returns the path of the cached debug file of the GNU build ID.
+// An empty string is returned if the debug file is not available. If the
+// debug file is not cached yet, it is downloaded in the background and
+// pending is true: the caller may look it up again once Pending reports
+// the download is over.
+func (d *Debuginfod) DebugFile(buildID string) (f string, pending bool) {
+	if _, err := hex.DecodeString(buildID); err != nil || buildID == "" {
+		return "", false
+	}
+	f = d.debugFilePath(buildID)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.pending[buildID]; ok {
+		return "", true
+	}
+	if c, ok := d.files[buildID]; ok {
+		c.used = time.Now()
+		d.metrics.Debuginfod.WithLabelValues("cached").Inc()
+		return f, false
+	}
+	if fi, err := os.Stat(f); err == nil && fi.Size() == 0 && time.Since(fi.ModTime()) < d.options.NotFoundTTL {
+		d.metrics.Debuginfod.WithLabelValues("cached_not_found").Inc()
+		return "", false
+	}
+	if retry, ok := d.failed[buildID]; ok {
+		if time.Now().Before(retry) {
+			d.metrics.Debuginfod.WithLabelValues("cached_error").Inc()
+			return "", false
+		}
+		delete(d.failed, buildID)
+	}
+
+	d.pending[buildID] = struct{}{}
+	go d.download(buildID, f)
+	return "", true
+}
+
+// Pending reports whether the debug file of the build ID is being downloaded.
+func (d *Debuginfod) Pending(buildID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.pending[buildID]
+	return ok
+}
+
+func (d *Debuginfod) debugFilePath(buildID string) string {
+	return filepath.Join(d.options.CacheDir, buildID, "debuginfo")
+}
+
+func (d *Debuginfod) download(buildID string, f string) {
+	err := os.MkdirAll(filepath.Dir(f), 0o755)
+	var size int64
+	if err == nil {
+		size, err = d.fetch(buildID, f)
+	}
+	notFound := errors.Is(err, errDebugFileNotFound) || errors.Is(err, errDebugFileTooLarge)
+	if notFound {
+		if err := os.WriteFile(f, nil, 0o644); err != nil {
+			level.Error(d.logger).Log("msg", "failed to cache debuginfod not found result", "build_id", buildID, "err", err)
+		}
+	}
+
+	var evicted []string
+	d.mu.Lock()
+	switch {
+	case err == nil:
+		d.metrics.Debuginfod.WithLabelValues("found").Inc()
+		level.Debug(d.logger).Log("msg", "downloaded debug file", "build_id", buildID, "f", f, "size", size)
+		d.files[buildID] = &cachedDebugFile{size: size, used: time.Now()}
+		d.size += size
+		evicted = d.evict()
+	case errors.Is(err, errDebugFileTooLarge):
+		d.metrics.Debuginfod.WithLabelValues("too_large").Inc()
+		level.Warn(d.logger).Log("msg", "debug file exceeds the max size", "build_id", buildID, "max_size", d.options.MaxFileSize)
+	case notFound:
+		d.metrics.Debuginfod.WithLabelValues("not_found").Inc()
+	default:
+		d.metrics.Debuginfod.WithLabelValues("error").Inc()
+		level.Error(d.logger).Log("msg", "failed to download debug file", "build_id", buildID, "err", err)
+		d.failed[buildID] = time.Now().Add(d.options.NotFoundTTL)
+	}
+	delete(d.pending, buildID)
+	d.mu.Unlock()
+	d.removeFiles(evicted)
+}
+
+// evict forgets the least recently used debug files until the cache size
+// is within the limit, and returns their build IDs. The files are removed
+// by the caller, without holding the lock. The mapped debug files remain
+// available to the tables using them.
+func (d *Debuginfod) evict() []string {
+	var evicted []string
+	for d.size > d.options.MaxCacheSize && len(d.files) > 0 {
+		var (
+			oldest string
+			used   time.Time
+		)
+		for id, c := range d.files {
+			if oldest == "" || c.used.Before(used) {
+				oldest, used = id, c.used
+			}
+		}
+		d.size -= d.files[oldest].size
+		delete(d.files, oldest)
+		evicted = append(evicted, oldest)
+	}
+	return evicted
+}
+
+func (d *Debuginfod) removeFiles(buildIDs []string) {
+	for _, id := range buildIDs {
+		if err := os.RemoveAll(filepath.Dir(d.debugFilePath(id))); err != nil {
+			level.Error(d.logger).Log("msg", "failed to remove cached debug file", "build_id", id, "err", err)
+			continue
+		}
+		level.Debug(d.logger).Log("msg", "evicted cached debug file", "build_id", id)
+	}
+}
+
+func (d *Debuginfod) fetch(buildID string, f string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.options.Timeout)
+	defer cancel()
+	url := strings.TrimSuffix(d.options.URL, "/") + "/buildid/" + buildID + "/debuginfo"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, errDebugFileNotFound
+	case resp.StatusCode != http.StatusOK:
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	case resp.ContentLength > d.options.MaxFileSize:
+		return 0, errDebugFileTooLarge
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f), "debuginfo-*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, io.LimitReader(resp.Body, d.options.MaxFileSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n > d.options.MaxFileSize {
+		return 0, errDebugFileTooLarge
+	}
+	if err = checkDebugFileBuildID(tmp.Name(), buildID); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp.Name(), f)
+}
+
+func checkDebugFileBuildID(f string, buildID string) error {
+	me, err := elf2.NewMMapedElfFile(f)
+	if err != nil {
+		return fmt.Errorf("invalid debug file: %w", err)
+	}
+	defer me.Close()
+	id, err := me.GNUBuildID()
+	if err != nil {
+		return fmt.Errorf("invalid debug file: %w", err)
+	}
+	if id.ID != buildID {
+		return fmt.Errorf("debug file build id mismatch: %s", id.ID)
+	}
+	return nil
+}
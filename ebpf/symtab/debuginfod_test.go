@@ -0,0 +1,172 @@
+package symtab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/pyroscope/ebpf/metrics"
+	"github.com/grafana/pyroscope/ebpf/util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testBuildID = "1fcfa068c5fdb9f31e6d9f3f89019beacb70182d"
+
+func newTestDebuginfod(t *testing.T, cacheDir string) (*Debuginfod, *int32) {
+	return newTestDebuginfodWithOptions(t, DebuginfodOptions{CacheDir: cacheDir})
+}
+
+func newTestDebuginfodWithOptions(t *testing.T, options DebuginfodOptions) (*Debuginfod, *int32) {
+	requests := new(int32)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		switch r.URL.Path {
+		case "/buildid/" + testBuildID + "/debuginfo":
+			http.ServeFile(w, r, "elf/testdata/elfs/elf.debug")
+		case "/buildid/ffff/debuginfo":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/buildid/eeee/debuginfo", "/buildid/dddd/debuginfo":
+			// Not the debug file of the requested build ID.
+			http.ServeFile(w, r, "elf/testdata/elfs/elf.debug")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	options.URL = srv.URL
+	options.NotFoundTTL = time.Minute
+	d, err := NewDebuginfod(util.TestLogger(t), options, metrics.NewSymtabMetrics(nil))
+	require.NoError(t, err)
+	return d, requests
+}
+
+// debugFile looks up the debug file and waits for its download.
+func debugFile(t *testing.T, d *Debuginfod, buildID string) string {
+	f, pending := d.DebugFile(buildID)
+	if !pending {
+		return f
+	}
+	require.Eventually(t, func() bool { return !d.Pending(buildID) }, 5*time.Second, time.Millisecond)
+	f, pending = d.DebugFile(buildID)
+	require.False(t, pending)
+	return f
+}
+
+func TestDebuginfod(t *testing.T) {
+	cacheDir := t.TempDir()
+	d, requests := newTestDebuginfod(t, cacheDir)
+
+	f, pending := d.DebugFile(testBuildID)
+	require.Equal(t, "", f)
+	require.True(t, pending)
+	f = debugFile(t, d, testBuildID)
+	require.Equal(t, path.Join(cacheDir, testBuildID, "debuginfo"), f)
+	require.Equal(t, f, debugFile(t, d, testBuildID))
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, "", debugFile(t, d, "abcd"))
+		require.Equal(t, "", debugFile(t, d, "ffff"))
+		require.Equal(t, "", debugFile(t, d, "eeee"))
+	}
+	require.Equal(t, "", debugFile(t, d, "../etc"))
+	require.Equal(t, int32(4), atomic.LoadInt32(requests))
+	// The lookups after the downloads are answered from the cache.
+	require.Equal(t, float64(1), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("found")))
+	require.Equal(t, float64(2), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("cached")))
+	require.Equal(t, float64(1), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("not_found")))
+	require.Equal(t, float64(2), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("cached_not_found")))
+	require.Equal(t, float64(2), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("error")))
+	require.Equal(t, float64(4), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("cached_error")))
+
+	// The cache is persistent: only the errors are retried after a restart.
+	d, requests = newTestDebuginfod(t, cacheDir)
+	require.Equal(t, f, debugFile(t, d, testBuildID))
+	require.Equal(t, "", debugFile(t, d, "abcd"))
+	require.Equal(t, "", debugFile(t, d, "ffff"))
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	// The not found result expires.
+	old := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(path.Join(cacheDir, "abcd", "debuginfo"), old, old))
+	require.Equal(t, "", debugFile(t, d, "abcd"))
+	require.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestElfTableDebuginfod(t *testing.T) {
+	d, requests := newTestDebuginfod(t, t.TempDir())
+	elfCache, _ := NewElfCache(testCacheOptions, testCacheOptions)
+	stripped := NewElfTable(util.TestLogger(t), &ProcMap{StartAddr: 0x1000, Offset: 0x1000}, ".", "elf/testdata/elfs/elf.stripped",
+		ElfTableOptions{
+			ElfCache:      elfCache,
+			Metrics:       metrics.NewSymtabMetrics(nil),
+			SymbolOptions: &SymbolOptions{Debuginfod: d},
+		})
+	defer stripped.Cleanup()
+	// The binary is used until the debug file is downloaded.
+	stripped.Resolve(0x1149)
+	require.Eventually(t, func() bool { return !d.Pending(testBuildID) }, 5*time.Second, time.Millisecond)
+	require.Equal(t, "iter", stripped.Resolve(0x1149))
+	require.NoError(t, stripped.err)
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	// The binaries with a symbol table are not looked up.
+	unstripped := NewElfTable(util.TestLogger(t), &ProcMap{StartAddr: 0x1000, Offset: 0x1000}, ".", "elf/testdata/elfs/elf.nopie",
+		ElfTableOptions{
+			ElfCache:      elfCache,
+			Metrics:       metrics.NewSymtabMetrics(nil),
+			SymbolOptions: &SymbolOptions{Debuginfod: d},
+		})
+	defer unstripped.Cleanup()
+	unstripped.Resolve(0x401136)
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestElfTableDebugDirectories(t *testing.T) {
+	elfCache, _ := NewElfCache(testCacheOptions, testCacheOptions)
+	stripped := NewElfTable(util.TestLogger(t), &ProcMap{StartAddr: 0x1000, Offset: 0x1000}, ".", "elf/testdata/elfs/elf.stripped",
+		ElfTableOptions{
+			ElfCache:      elfCache,
+			Metrics:       metrics.NewSymtabMetrics(nil),
+			SymbolOptions: &SymbolOptions{DebugDirectories: []string{t.TempDir(), "elf/testdata/usr/lib/debug"}},
+		})
+	defer stripped.Cleanup()
+	require.Equal(t, "iter", stripped.Resolve(0x1149))
+	require.Equal(t, "main", stripped.Resolve(0x115e))
+	require.NoError(t, stripped.err)
+}
+
+func TestDebuginfodSizeLimits(t *testing.T) {
+	fi, err := os.Stat("elf/testdata/elfs/elf.debug")
+	require.NoError(t, err)
+
+	d, requests := newTestDebuginfodWithOptions(t, DebuginfodOptions{CacheDir: t.TempDir(), MaxFileSize: fi.Size() - 1})
+	require.Equal(t, "", debugFile(t, d, testBuildID))
+	require.Equal(t, "", debugFile(t, d, testBuildID))
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+	require.Equal(t, float64(1), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("too_large")))
+	require.Equal(t, float64(2), testutil.ToFloat64(d.metrics.Debuginfod.WithLabelValues("cached_not_found")))
+
+	// The least recently used debug files are evicted.
+	cacheDir := t.TempDir()
+	for _, id := range []string{"cccc", "dddd"} {
+		require.NoError(t, os.MkdirAll(path.Join(cacheDir, id), 0o755))
+		require.NoError(t, os.WriteFile(path.Join(cacheDir, id, "debuginfo"), make([]byte, fi.Size()), 0o644))
+	}
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(path.Join(cacheDir, "cccc", "debuginfo"), old, old))
+	d, _ = newTestDebuginfodWithOptions(t, DebuginfodOptions{CacheDir: cacheDir, MaxCacheSize: 2 * fi.Size()})
+	require.Equal(t, path.Join(cacheDir, "cccc", "debuginfo"), debugFile(t, d, "cccc"))
+
+	f := debugFile(t, d, testBuildID)
+	require.Equal(t, path.Join(cacheDir, testBuildID, "debuginfo"), f)
+	require.FileExists(t, f)
+	require.FileExists(t, path.Join(cacheDir, "cccc", "debuginfo"))
+	require.NoDirExists(t, path.Join(cacheDir, "dddd"))
+	require.Equal(t, 2*fi.Size(), d.size)
+}
@@ -27,6 +27,9 @@ type ElfTable struct {
 	loaded       bool
 	loadedCached bool
 	err          error
+	// pendingBuildID is set while the debug file of the binary is being
+	// downloaded from debuginfod: the table is loaded again once it is over.
+	pendingBuildID string
 
 	options ElfTableOptions
 	logger  log.Logger
@@ -36,6 +39,22 @@ type SymbolOptions struct {
 	GoTableFallback    bool
 	PythonFullFilePath bool
 	DemangleOptions    []demangle.Option
+	// PerfMap enables resolving the symbols of JIT-compiled code
+	// from the perf map and jitdump files written by the runtime.
+	PerfMap bool
+	// DebugDirectories are the directories of the profiler filesystem
+	// searched for the separate debug files in addition to the
+	// /usr/lib/debug directory of the profiled process. The same layouts
+	// are expected: .build-id/ab/cdef1234.debug for the build IDs, and
+	// usr/bin/ls.debug for the debug links.
+	DebugDirectories []string
+	// Debuginfod, if set, is used to download the debug files of the
+	// binaries without a symbol table that are not found locally.
+	Debuginfod *Debuginfod `json:"-"`
+	// InlineFrames enables expanding the addresses into the inlined call
+	// chains using the DWARF debug info (.debug_info and .debug_line).
+	// The debug sections of the binaries are kept in memory.
+	InlineFrames bool
 }
 
 var DefaultSymbolOptions = &SymbolOptions{
@@ -132,7 +151,7 @@ func (et *ElfTable) load() {
 
 	debugFilePath := et.findDebugFile(buildID, me)
 	if debugFilePath != "" {
-		debugMe, err := elf2.NewMMapedElfFile(path.Join(et.fs, debugFilePath))
+		debugMe, err := elf2.NewMMapedElfFile(debugFilePath)
 		if err != nil {
 			et.onLoadError(err)
 			return
@@ -156,6 +175,11 @@ func (et *ElfTable) load() {
 	}
 
 	et.table = symbols
+	if et.pendingBuildID != "" {
+		// Not cached: the other tables of the binary should use the
+		// debug file once it is downloaded.
+		return
+	}
 	if buildID.Empty() {
 		et.options.ElfCache.CacheByStat(statFromFileInfo(fileInfo), symbols)
 	} else {
@@ -164,6 +188,19 @@ func (et *ElfTable) load() {
 }
 
 func (et *ElfTable) createSymbolTable(me *elf2.MMapedElfFile) (SymbolNameResolver, error) {
+	table, err := et.createNameResolver(me)
+	if err != nil || !et.options.SymbolOptions.InlineFrames {
+		return table, err
+	}
+	dwarfTable, err := me.NewDwarfTable(et.options.SymbolOptions.DemangleOptions)
+	if err != nil {
+		level.Debug(et.logger).Log("msg", "failed to create dwarf table", "path", me.FilePath(), "err", err)
+		return table, nil
+	}
+	return &inlineSymbolTable{SymbolNameResolver: table, dwarf: dwarfTable}, nil
+}
+
+func (et *ElfTable) createNameResolver(me *elf2.MMapedElfFile) (SymbolNameResolver, error) {
 	level.Debug(et.logger).Log("msg", "create symbol table", "path", me.FilePath())
 	goTable, goErr := me.NewGoTable()
 	if !et.options.SymbolOptions.GoTableFallback && goErr == nil {
@@ -198,6 +235,9 @@ func (et *ElfTable) createSymbolTable(me *elf2.MMapedElfFile) (SymbolNameResolve
 var errTableDead = fmt.Errorf("non cached table dead")
 
 func (et *ElfTable) Resolve(pc uint64) string {
+	if et.pendingBuildID != "" && !et.options.SymbolOptions.Debuginfod.Pending(et.pendingBuildID) {
+		et.reload()
+	}
 	if !et.loaded {
 		et.load()
 	}
@@ -226,6 +266,27 @@ func (et *ElfTable) Resolve(pc uint64) string {
 	return et.table.Resolve(pc)
 }
 
+// reload discards the table loaded while the debug file was being
+// downloaded, so that the next load picks up the downloaded file.
+func (et *ElfTable) reload() {
+	et.table.Cleanup()
+	et.table = &noopSymbolNameResolver{}
+	et.loaded = false
+	et.loadedCached = false
+	et.pendingBuildID = ""
+	et.err = nil
+}
+
+// ResolveInline returns the inlined call chain of the address resolved
+// with the DWARF debug info, if available. It should be called after Resolve.
+func (et *ElfTable) ResolveInline(pc uint64) []elf2.InlineFrame {
+	t, ok := et.table.(*inlineSymbolTable)
+	if !ok || et.err != nil {
+		return nil
+	}
+	return t.ResolveInline(pc - et.base)
+}
+
 func (et *ElfTable) Cleanup() {
 	if et.table != nil {
 		et.table.Cleanup()
@@ -238,16 +299,23 @@ func (et *ElfTable) findDebugFileWithBuildID(buildID elf2.BuildID) string {
 		return ""
 	}
 
-	debugFile := fmt.Sprintf("/usr/lib/debug/.build-id/%s/%s.debug", id[:2], id[2:])
-	fsDebugFile := path.Join(et.fs, debugFile)
-	_, err := os.Stat(fsDebugFile)
-	if err == nil {
-		return debugFile
+	debugFile := fmt.Sprintf(".build-id/%s/%s.debug", id[:2], id[2:])
+	fsDebugFile := path.Join(et.fs, "/usr/lib/debug", debugFile)
+	if fileExists(fsDebugFile) {
+		return fsDebugFile
+	}
+	for _, dir := range et.options.SymbolOptions.DebugDirectories {
+		fsDebugFile = path.Join(dir, debugFile)
+		if fileExists(fsDebugFile) {
+			return fsDebugFile
+		}
 	}
 
 	return ""
 }
 
+// findDebugFile returns the path of the debug file in the profiler
+// filesystem, or an empty string if the debug file is not found.
 func (et *ElfTable) findDebugFile(buildID elf2.BuildID, elfFile *elf2.MMapedElfFile) string {
 	// https://sourceware.org/gdb/onlinedocs/gdb/Separate-Debug-Files.html
 	// So, for example, suppose you ask GDB to debug /usr/bin/ls, which has a debug link that specifies the file
@@ -263,7 +331,10 @@ func (et *ElfTable) findDebugFile(buildID elf2.BuildID, elfFile *elf2.MMapedElfF
 		return debugFile
 	}
 	debugFile = et.findDebugFileWithDebugLink(elfFile)
-	return debugFile
+	if debugFile != "" {
+		return debugFile
+	}
+	return et.fetchDebugFile(buildID, elfFile)
 }
 
 func (et *ElfTable) findDebugFileWithDebugLink(elfFile *elf2.MMapedElfFile) string {
@@ -285,27 +356,51 @@ func (et *ElfTable) findDebugFileWithDebugLink(elfFile *elf2.MMapedElfFile) stri
 	debugLink := cString(data)
 
 	// /usr/bin/ls.debug
-	fsDebugFile := path.Join(path.Dir(elfFilePath), debugLink)
-	_, err = os.Stat(path.Join(fs, fsDebugFile))
-	if err == nil {
+	fsDebugFile := path.Join(fs, path.Dir(elfFilePath), debugLink)
+	if fileExists(fsDebugFile) {
 		return fsDebugFile
 	}
 	// /usr/bin/.debug/ls.debug
-	fsDebugFile = path.Join(path.Dir(elfFilePath), ".debug", debugLink)
-	_, err = os.Stat(path.Join(fs, fsDebugFile))
-	if err == nil {
+	fsDebugFile = path.Join(fs, path.Dir(elfFilePath), ".debug", debugLink)
+	if fileExists(fsDebugFile) {
 		return fsDebugFile
 	}
 	// /usr/lib/debug/usr/bin/ls.debug.
-	fsDebugFile = path.Join("/usr/lib/debug", path.Dir(elfFilePath), debugLink)
-	_, err = os.Stat(path.Join(fs, fsDebugFile))
-	if err == nil {
+	fsDebugFile = path.Join(fs, "/usr/lib/debug", path.Dir(elfFilePath), debugLink)
+	if fileExists(fsDebugFile) {
 		return fsDebugFile
 	}
+	for _, dir := range et.options.SymbolOptions.DebugDirectories {
+		fsDebugFile = path.Join(dir, path.Dir(elfFilePath), debugLink)
+		if fileExists(fsDebugFile) {
+			return fsDebugFile
+		}
+	}
 
 	return ""
 }
 
+// fetchDebugFile returns the debug file downloaded from debuginfod. Only
+// the binaries without a symbol table are looked up: the others are
+// symbolized well enough to not pay for the round trip. The download does
+// not block the symbolization: the binary is used until it is over.
+func (et *ElfTable) fetchDebugFile(buildID elf2.BuildID, elfFile *elf2.MMapedElfFile) string {
+	d := et.options.SymbolOptions.Debuginfod
+	if d == nil || !buildID.GNU() || elfFile.Section(".symtab") != nil {
+		return ""
+	}
+	f, pending := d.DebugFile(buildID.ID)
+	if pending {
+		et.pendingBuildID = buildID.ID
+	}
+	return f
+}
+
+func fileExists(f string) bool {
+	_, err := os.Stat(f)
+	return err == nil
+}
+
 func cString(bs []byte) string {
 	i := 0
 	for ; i < len(bs); i++ {
@@ -0,0 +1,261 @@
+package elf
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"sort"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/ianlancetaylor/demangle"
+)
+
+// inline frames from .debug_info, .debug_line
+
+const dwarfTableCacheSize = 4096
+
+// InlineFrame is a function of the inlined call chain of an address.
+type InlineFrame struct {
+	Name string
+	File string
+	Line int
+}
+
+type dwarfFunc struct {
+	low, high uint64
+	offset    dwarf.Offset
+	unit      int
+}
+
+// DwarfTable expands an address into the chain of the functions inlined
+// at the address. Only the ranges of the subprograms are indexed upfront,
+// the inlined subroutines are looked up in the subprogram entry children
+// on Resolve.
+type DwarfTable struct {
+	data  *dwarf.Data
+	funcs []dwarfFunc
+	units []*dwarf.Entry
+	lines map[int]*dwarf.LineReader
+	names map[dwarf.Offset]string
+	cache *lru.Cache[uint64, []InlineFrame]
+
+	demangleOptions []demangle.Option
+}
+
+func (f *MMapedElfFile) NewDwarfTable(demangleOptions []demangle.Option) (*DwarfTable, error) {
+	if f.Section(".debug_info") == nil {
+		return nil, errors.New(".debug_info not found")
+	}
+	if err := f.ensureOpen(); err != nil {
+		return nil, err
+	}
+	ef, err := elf.NewFile(f.fd)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ef.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("dwarf: %w", err)
+	}
+	cache, _ := lru.New[uint64, []InlineFrame](dwarfTableCacheSize)
+	res := &DwarfTable{
+		data:            data,
+		lines:           make(map[int]*dwarf.LineReader),
+		names:           make(map[dwarf.Offset]string),
+		cache:           cache,
+		demangleOptions: demangleOptions,
+	}
+	if err = res.index(); err != nil {
+		return nil, err
+	}
+	if len(res.funcs) == 0 {
+		return nil, errors.New("no subprograms found in .debug_info")
+	}
+	return res, nil
+}
+
+func (t *DwarfTable) index() error {
+	r := t.data.Reader()
+	unit := -1
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return fmt.Errorf("dwarf: %w", err)
+		}
+		if e == nil {
+			break
+		}
+		switch e.Tag {
+		case dwarf.TagCompileUnit:
+			t.units = append(t.units, e)
+			unit = len(t.units) - 1
+		case dwarf.TagSubprogram:
+			ranges, _ := t.data.Ranges(e)
+			for _, rng := range ranges {
+				// The ranges of the functions removed by the linker start at 0.
+				if rng[0] == 0 || rng[1] <= rng[0] {
+					continue
+				}
+				t.funcs = append(t.funcs, dwarfFunc{low: rng[0], high: rng[1], offset: e.Offset, unit: unit})
+			}
+			r.SkipChildren()
+		}
+	}
+	sort.Slice(t.funcs, func(i, j int) bool {
+		return t.funcs[i].low < t.funcs[j].low
+	})
+	return nil
+}
+
+// Resolve returns the inlined call chain of the address, starting with the
+// innermost function. The outermost function is the last one. The file and
+// line of a function are the location of the call to the next function, or
+// the location of the address for the innermost function. Nil is returned
+// if the address is not covered by the debug info.
+func (t *DwarfTable) Resolve(addr uint64) []InlineFrame {
+	if frames, ok := t.cache.Get(addr); ok {
+		return frames
+	}
+	frames := t.resolve(addr)
+	t.cache.Add(addr, frames)
+	return frames
+}
+
+func (t *DwarfTable) resolve(addr uint64) []InlineFrame {
+	i := sort.Search(len(t.funcs), func(i int) bool {
+		return t.funcs[i].low > addr
+	}) - 1
+	if i < 0 || addr >= t.funcs[i].high {
+		return nil
+	}
+	f := t.funcs[i]
+	r := t.data.Reader()
+	r.Seek(f.offset)
+	e, err := r.Next()
+	if err != nil || e == nil {
+		return nil
+	}
+	chain := []*dwarf.Entry{e}
+	if e.Children {
+		chain = t.inlineChain(r, addr, chain)
+	}
+
+	var files []*dwarf.LineFile
+	file, line := "", 0
+	if lr := t.lineReader(f.unit); lr != nil {
+		var le dwarf.LineEntry
+		if lr.SeekPC(addr, &le) == nil {
+			file, line = le.File.Name, le.Line
+		}
+		files = lr.Files()
+	}
+	frames := make([]InlineFrame, len(chain))
+	for j := len(chain) - 1; j >= 0; j-- {
+		frames[len(chain)-1-j] = InlineFrame{Name: t.name(chain[j]), File: file, Line: line}
+		file, line = "", 0
+		if idx, ok := chain[j].Val(dwarf.AttrCallFile).(int64); ok && idx >= 0 && idx < int64(len(files)) && files[idx] != nil {
+			file = files[idx].Name
+		}
+		if l, ok := chain[j].Val(dwarf.AttrCallLine).(int64); ok {
+			line = int(l)
+		}
+	}
+	return frames
+}
+
+// inlineChain appends the nested inlined subroutines containing the address
+// to the chain. The reader is positioned at the children of the last entry.
+func (t *DwarfTable) inlineChain(r *dwarf.Reader, addr uint64, chain []*dwarf.Entry) []*dwarf.Entry {
+	depth := 0
+	for {
+		e, err := r.Next()
+		if err != nil || e == nil {
+			return chain
+		}
+		switch {
+		case e.Tag == 0:
+			if depth == 0 {
+				return chain
+			}
+			depth--
+		case e.Tag == dwarf.TagInlinedSubroutine && t.contains(e, addr):
+			chain = append(chain, e)
+			if !e.Children {
+				return chain
+			}
+			depth = 0
+		case e.Tag == dwarf.TagLexDwarfBlock && e.Children:
+			depth++
+		case e.Children:
+			r.SkipChildren()
+		}
+	}
+}
+
+func (t *DwarfTable) contains(e *dwarf.Entry, addr uint64) bool {
+	ranges, err := t.data.Ranges(e)
+	if err != nil {
+		return false
+	}
+	for _, rng := range ranges {
+		if rng[0] <= addr && addr < rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *DwarfTable) lineReader(unit int) *dwarf.LineReader {
+	if unit < 0 {
+		return nil
+	}
+	if lr, ok := t.lines[unit]; ok {
+		return lr
+	}
+	lr, err := t.data.LineReader(t.units[unit])
+	if err != nil {
+		lr = nil
+	}
+	t.lines[unit] = lr
+	return lr
+}
+
+// name returns the name of the function, following the abstract origin and
+// the specification of the entry. The linkage name is preferred, as it is
+// qualified, unlike the name of C++ methods.
+func (t *DwarfTable) name(e *dwarf.Entry) string {
+	if name, ok := t.names[e.Offset]; ok {
+		return name
+	}
+	offset := e.Offset
+	name := ""
+	for i := 0; i < 8 && e != nil; i++ {
+		if linkageName, ok := e.Val(dwarf.AttrLinkageName).(string); ok {
+			name = linkageName
+			if len(t.demangleOptions) > 0 {
+				name = demangle.Filter(name, t.demangleOptions...)
+			}
+			break
+		}
+		if name == "" {
+			name, _ = e.Val(dwarf.AttrName).(string)
+		}
+		ref, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset)
+		if !ok {
+			ref, ok = e.Val(dwarf.AttrSpecification).(dwarf.Offset)
+		}
+		if !ok {
+			break
+		}
+		r := t.data.Reader()
+		r.Seek(ref)
+		e, _ = r.Next()
+	}
+	t.names[offset] = name
+	return name
+}
+
+func (t *DwarfTable) Size() int {
+	return len(t.funcs)
+}
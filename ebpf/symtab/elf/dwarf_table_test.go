@@ -0,0 +1,50 @@
+package elf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDwarfTable(t *testing.T) {
+	me, err := NewMMapedElfFile("testdata/elfs/go20")
+	require.NoError(t, err)
+	defer me.Close()
+	dt, err := me.NewDwarfTable(nil)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		pc     uint64
+		frames []InlineFrame
+	}{
+		{0x4817a0, []InlineFrame{
+			{Name: "main.main", File: "/go/hello.go", Line: 5},
+		}},
+		{0x4817d8, []InlineFrame{
+			{Name: "fmt.Println", File: "/usr/local/go/src/fmt/print.go", Line: 314},
+			{Name: "main.main", File: "/go/hello.go", Line: 6},
+		}},
+		{0x4817f8, []InlineFrame{
+			{Name: "main.main", File: "/go/hello.go", Line: 7},
+		}},
+		{0x46dd19, []InlineFrame{
+			{Name: "reflect.align", File: "/usr/local/go/src/reflect/value.go", Line: 932},
+			{Name: "reflect.(*abiSeq).stackAssign", File: "/usr/local/go/src/reflect/abi.go", Line: 317},
+			{Name: "reflect.(*abiSeq).addArg", File: "/usr/local/go/src/reflect/abi.go", Line: 153},
+		}},
+		{0x10, nil},
+	}
+	for _, tc := range testcases {
+		require.Equal(t, tc.frames, dt.Resolve(tc.pc))
+		// cached
+		require.Equal(t, tc.frames, dt.Resolve(tc.pc))
+	}
+}
+
+func TestDwarfTableNoDebugInfo(t *testing.T) {
+	me, err := NewMMapedElfFile("testdata/elfs/elf.debug")
+	require.NoError(t, err)
+	defer me.Close()
+	_, err = me.NewDwarfTable(nil)
+	require.Error(t, err)
+}
@@ -13,6 +13,7 @@ type GoTable struct {
 	File           *MMapedElfFile
 	gopclnSection  elf.SectionHeader
 	funcNameOffset uint64
+	pcln           *gosym2.LineTable
 }
 
 func (g *GoTable) IsDead() bool {
@@ -51,6 +52,34 @@ func (g *GoTable) Resolve(addr uint64) string {
 	return name
 }
 
+// ResolveFileLine returns the file of the function the address belongs to,
+// the line of the address, and the line the function starts at. The file
+// and line of an address within the code inlined from other functions are
+// the location of the inlined code: the line is only returned if it is in
+// the file of the function. The start line is only known for binaries
+// built with Go 1.20 or later. An empty file name is returned if the
+// address can't be resolved.
+func (g *GoTable) ResolveFileLine(addr uint64) (file string, line, startLine int) {
+	if len(g.Index.Name) == 0 || addr >= g.Index.End || g.pcln == nil {
+		return "", 0, 0
+	}
+	i := g.Index.Entry.FindIndex(addr)
+	if i == -1 {
+		return "", 0, 0
+	}
+	fileOffset, _, ok := g.pcln.FuncFileLine(i, g.Index.Entry.Get(i))
+	if !ok {
+		return "", 0, 0
+	}
+	if file, ok = g.File.getString(int(g.gopclnSection.Offset)+int(fileOffset), nil); !ok {
+		return "", 0, 0
+	}
+	if addrFileOffset, addrLine, ok := g.pcln.FuncFileLine(i, addr); ok && addrFileOffset == fileOffset {
+		line = addrLine
+	}
+	return file, line, g.pcln.FuncStartLine(i)
+}
+
 func (g *GoTable) Cleanup() {
 	g.File.Close()
 }
@@ -125,6 +154,7 @@ func (f *MMapedElfFile) NewGoTable() (*GoTable, error) {
 		File:           f,
 		gopclnSection:  *pclntab,
 		funcNameOffset: funcNameOffset,
+		pcln:           pcln,
 	}, nil
 }
 
@@ -67,3 +67,60 @@ func TestSelfGoSymbolComparison(t *testing.T) {
 		})
 	}
 }
+
+func TestGoTableResolveFileLine(t *testing.T) {
+	for _, f := range []string{
+		"./testdata/elfs/go12",
+		"./testdata/elfs/go16",
+		"./testdata/elfs/go18",
+		"./testdata/elfs/go20",
+		"./testdata/elfs/go12-static",
+		"./testdata/elfs/go16-static",
+		"./testdata/elfs/go18-static",
+		"./testdata/elfs/go20-static",
+	} {
+		t.Run(f, func(t *testing.T) {
+			patchGo20Magic := strings.Contains(f, "go20")
+			table, err := getGoSymbolTable(f, patchGo20Magic)
+			require.NoError(t, err)
+
+			me, err := NewMMapedElfFile(f)
+			require.NoError(t, err)
+			defer me.Close()
+			goTable, err := me.NewGoTable()
+			require.NoError(t, err)
+
+			var resolved int
+			for i, fn := range table.Funcs {
+				if i%16 != 0 {
+					continue
+				}
+				expectedFile, _, _ := table.PCToLine(fn.Entry)
+				for _, pc := range []uint64{fn.Entry, fn.Entry + (fn.End-fn.Entry)/2} {
+					file, line, startLine := goTable.ResolveFileLine(pc)
+					require.Equal(t, expectedFile, file, fn.Name)
+					if file == "" {
+						continue
+					}
+					resolved++
+					// The line is omitted within the code inlined from other files.
+					if pcFile, pcLine, _ := table.PCToLine(pc); pcFile == file {
+						require.Equal(t, pcLine, line, fn.Name)
+					} else {
+						require.Zero(t, line, fn.Name)
+					}
+					if patchGo20Magic {
+						require.Greater(t, startLine, 0, fn.Name)
+					} else {
+						require.Zero(t, startLine, fn.Name)
+					}
+				}
+			}
+			require.Greater(t, resolved, 100)
+
+			file, line, _ := goTable.ResolveFileLine(goTable.Index.End)
+			require.Empty(t, file)
+			require.Zero(t, line)
+		})
+	}
+}
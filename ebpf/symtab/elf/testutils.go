@@ -51,20 +51,27 @@ func GetELFSymbolsFromSymtab(elfFile *elf.File) []TestSym {
 }
 
 func GetGoSymbols(file string, patchGo20Magic bool) ([]TestSym, error) {
-	obj, err := elf.Open(file)
+	table, err := getGoSymbolTable(file, patchGo20Magic)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open elf file: %w", err)
+		return nil, err
 	}
-	defer obj.Close()
+	es := make([]TestSym, 0, len(table.Funcs))
+	for _, fun := range table.Funcs {
+		es = append(es, TestSym{Start: fun.Entry, Name: fun.Name})
+	}
+	return es, nil
+}
 
-	symbols, err := getGoSymbolsFromPCLN(obj, patchGo20Magic)
+func getGoSymbolTable(file string, patchGo20Magic bool) (*gosym.Table, error) {
+	obj, err := elf.Open(file)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to open elf file: %w", err)
 	}
-	return symbols, nil
+	defer obj.Close()
+	return getGoSymbolTableFromPCLN(obj, patchGo20Magic)
 }
 
-func getGoSymbolsFromPCLN(obj *elf.File, patchGo20Magic bool) ([]TestSym, error) {
+func getGoSymbolTableFromPCLN(obj *elf.File, patchGo20Magic bool) (*gosym.Table, error) {
 	var err error
 	var pclntab []byte
 	text := obj.Section(".text")
@@ -104,11 +111,5 @@ func getGoSymbolsFromPCLN(obj *elf.File, patchGo20Magic bool) ([]TestSym, error)
 	if len(table.Funcs) == 0 {
 		return nil, errors.New("gosymtab: no symbols found")
 	}
-
-	es := make([]TestSym, 0, len(table.Funcs))
-	for _, fun := range table.Funcs {
-		es = append(es, TestSym{Start: fun.Entry, Name: fun.Name})
-	}
-
-	return es, nil
+	return table, nil
 }
@@ -166,3 +166,26 @@ func TestFindBaseUnalignedSeparateCode(t *testing.T) {
 	assert.True(t, et.findBase(&ef))
 	assert.Equal(t, uint64(0x555e3d192000), et.base)
 }
+
+func TestElfInlineFrames(t *testing.T) {
+	for _, inlineFrames := range []bool{false, true} {
+		elfCache, _ := NewElfCache(testCacheOptions, testCacheOptions)
+		tab := NewElfTable(util.TestLogger(t), &ProcMap{StartAddr: 0x401000, Offset: 0x1000}, ".", "elf/testdata/elfs/go20",
+			ElfTableOptions{
+				ElfCache:      elfCache,
+				SymbolOptions: &SymbolOptions{InlineFrames: inlineFrames},
+				Metrics:       metrics.NewSymtabMetrics(nil),
+			})
+		require.Equal(t, "main.main", tab.Resolve(0x4817d8))
+		if !inlineFrames {
+			require.Nil(t, tab.ResolveInline(0x4817d8))
+			continue
+		}
+		require.Equal(t, []elf.InlineFrame{
+			{Name: "fmt.Println", File: "/usr/local/go/src/fmt/print.go", Line: 314},
+			{Name: "main.main", File: "/go/hello.go", Line: 6},
+		}, tab.ResolveInline(0x4817d8))
+		require.Nil(t, tab.ResolveInline(0x10))
+		tab.Cleanup()
+	}
+}
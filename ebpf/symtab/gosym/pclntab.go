@@ -6,6 +6,7 @@
 // https://github.com/golang/go/blob/go1.20.5/src/debug/gosym/pclntab.go
 // modified go12Funcs function to be exported return a FlatFuncIndex instead of []Func
 // added FuncNameOffset to export the funcnametabOffset
+// added FuncFileLine and FuncStartLine, based on go12PCToFile and go12PCToLine
 
 /*
  * Line tables
@@ -64,6 +65,9 @@ type LineTable struct {
 	functabOffset     uint64
 	nfunctab          uint32
 	funcnametabOffset uint64
+	cutabOffset       uint64
+	filetabOffset     uint64
+	pctabOffset       uint64
 	failed            bool
 	tmpbuf            [8]uint8
 }
@@ -200,18 +204,27 @@ func (t *LineTable) parsePclnTab() {
 		t.nfunctab = uint32(offset(0))
 		t.textStart = t.PC // use the start PC instead of reading from the table, which may be unrelocated
 		t.funcnametabOffset = offset(3)
+		t.cutabOffset = offset(4)
+		t.filetabOffset = offset(5)
+		t.pctabOffset = offset(6)
 		t.funcdataOffset = offset(7)
 		t.functabOffset = offset(7)
 	case ver116:
 		t.nfunctab = uint32(offset(0))
 		t.funcnametabOffset = offset(2)
+		t.cutabOffset = offset(3)
+		t.filetabOffset = offset(4)
+		t.pctabOffset = offset(5)
 		t.funcdataOffset = offset(6)
 		t.functabOffset = offset(6)
 	case ver12:
 		t.nfunctab = uint32(t.uintptrAt(8))
 		t.funcdataOffset = 0
 		t.funcnametabOffset = 0
+		t.pctabOffset = 0
 		t.functabOffset = uint64(8 + t.ptrsize)
+		functabsize := (uint64(t.nfunctab)*2 + 1) * uint64(t.ptrsize)
+		t.filetabOffset = uint64(t.uint32At(int(t.functabOffset + functabsize)))
 	default:
 		panic("unreachable")
 	}
@@ -328,12 +341,29 @@ func (t *LineTable) funcData(i uint32) funcData {
 	return funcData{t: t, dataOffset: dataOffset}
 }
 
+// entryPC returns the func's entry PC.
+func (f funcData) entryPC() uint64 {
+	dataOffset := int(f.dataOffset + f.t.funcdataOffset)
+	// In Go 1.18, the first field of _func changed
+	// from a uintptr entry PC to a uint32 entry offset.
+	if f.t.version >= ver118 {
+		// TODO: support multiple text sections.
+		// See runtime/symtab.go:(*moduledata).textAddr.
+		return uint64(f.t.uint32At(dataOffset)) + f.t.textStart
+	}
+	return f.t.uintptrAt(dataOffset)
+}
+
 // IsZero reports whether f is the zero value.
 //func (f funcData) IsZero() bool {
 //	return f.t == nil && f.data == nil
 //}
 
-func (f funcData) nameOff() uint32 { return f.field(1) }
+func (f funcData) nameOff() uint32   { return f.field(1) }
+func (f funcData) pcfile() uint32    { return f.field(5) }
+func (f funcData) pcln() uint32      { return f.field(6) }
+func (f funcData) cuOffset() uint32  { return f.field(8) }
+func (f funcData) startLine() uint32 { return f.field(9) }
 
 // field returns the nth field of the _func struct.
 // It panics if n == 0 or n > 9; for n == 0, call f.entryPC.
@@ -357,6 +387,118 @@ func (f funcData) field(n uint32) uint32 {
 	return f.t.binary.Uint32(data)
 }
 
+func (t *LineTable) uint32At(at int) uint32 {
+	data := t.tmpbuf[:4]
+	_ = t.PCLNData.ReadAt(data, at)
+	return t.binary.Uint32(data)
+}
+
+// step advances to the next pc, value pair in the encoded table.
+func (t *LineTable) step(at *int, pc *uint64, val *int32, first bool) bool {
+	uvdelta := t.readvarint(at)
+	if uvdelta == 0 && !first {
+		return false
+	}
+	if uvdelta&1 != 0 {
+		uvdelta = ^(uvdelta >> 1)
+	} else {
+		uvdelta >>= 1
+	}
+	vdelta := int32(uvdelta)
+	pcdelta := t.readvarint(at) * t.quantum
+	*pc += uint64(pcdelta)
+	*val += vdelta
+	return true
+}
+
+// readvarint reads, removes, and returns a varint from the table at the offset.
+// It panics if the table can't be read: the callers recover from it.
+func (t *LineTable) readvarint(at *int) uint32 {
+	var v, shift uint32
+	b := t.tmpbuf[:1]
+	for shift = 0; ; shift += 7 {
+		if err := t.PCLNData.ReadAt(b, *at); err != nil {
+			panic(err)
+		}
+		*at++
+		v |= (uint32(b[0]) & 0x7F) << shift
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+	return v
+}
+
+// pcvalue reports the value associated with the target pc.
+// off is the offset to the beginning of the pc-value table,
+// and entry is the start PC for the corresponding function.
+func (t *LineTable) pcvalue(off uint32, entry, targetpc uint64) int32 {
+	at := int(t.pctabOffset) + int(off)
+	val := int32(-1)
+	pc := entry
+	for t.step(&at, &pc, &val, pc == entry) {
+		if targetpc < pc {
+			return val
+		}
+	}
+	return -1
+}
+
+// FuncFileLine returns the offset of the file name, and the line of the
+// program counter within the i'th function. The file name offset is
+// relative to the start of the table. ok is false, if the file or the
+// line can't be found.
+func (t *LineTable) FuncFileLine(i int, pc uint64) (fileNameOffset uint64, line int, ok bool) {
+	if !disableRecover {
+		defer func() {
+			if r := recover(); r != nil {
+				fileNameOffset, line, ok = 0, 0, false
+			}
+		}()
+	}
+	if i < 0 || i >= int(t.nfunctab) {
+		return 0, 0, false
+	}
+	f := t.funcData(uint32(i))
+	entry := f.entryPC()
+	if line = int(t.pcvalue(f.pcln(), entry, pc)); line < 0 {
+		return 0, 0, false
+	}
+	fno := t.pcvalue(f.pcfile(), entry, pc)
+	if t.version == ver12 {
+		if fno <= 0 {
+			return 0, 0, false
+		}
+		return uint64(t.uint32At(int(t.filetabOffset) + 4*int(fno))), line, true
+	}
+	// Go ≥ 1.16
+	if fno < 0 { // 0 is valid for ≥ 1.16
+		return 0, 0, false
+	}
+	fnoff := t.uint32At(int(t.cutabOffset) + int(f.cuOffset()+uint32(fno))*4)
+	if fnoff == ^uint32(0) {
+		return 0, 0, false
+	}
+	return t.filetabOffset + uint64(fnoff), line, true
+}
+
+// FuncStartLine returns the line of the i'th function declaration.
+// The line is only recorded in the tables of Go 1.20 and later:
+// 0 is returned otherwise.
+func (t *LineTable) FuncStartLine(i int) (line int) {
+	if t.version < ver120 || i < 0 || i >= int(t.nfunctab) {
+		return 0
+	}
+	if !disableRecover {
+		defer func() {
+			if r := recover(); r != nil {
+				line = 0
+			}
+		}()
+	}
+	return int(t.funcData(uint32(i)).startLine())
+}
+
 func (t *LineTable) IsFailed() bool {
 	return t.failed
 }
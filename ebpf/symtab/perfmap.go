@@ -0,0 +1,440 @@
+package symtab
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/grafana/pyroscope/ebpf/metrics"
+)
+
+const (
+	perfMapFormatPerfMap = "perfmap"
+	perfMapFormatJitDump = "jitdump"
+)
+
+// PerfMapTable resolves symbols of the code generated by JIT compilers
+// (Node.js, JVM with perf-map-agent, .NET), which reside in anonymous
+// memory mappings. The runtimes report the symbols in perf map files
+// (/tmp/perf-<pid>.map) and in jitdump files. Both are append-only:
+// on refresh, only the entries added since the last refresh are read.
+//
+// If the code ranges of the symbols overlap, e.g. the code was moved or
+// recompiled, the symbol that starts closest to the address wins; if
+// the symbols start at the same address, the most recent one wins.
+type PerfMapTable struct {
+	logger  log.Logger
+	metrics *metrics.SymtabMetrics
+	files   []*perfMapFile
+	symbols []perfMapSymbol
+}
+
+type perfMapSymbol struct {
+	start  uint64
+	end    uint64
+	name   string
+	module string
+}
+
+type perfMapFile struct {
+	path   string
+	module string
+	format string
+	stat   Stat
+	offset int64
+}
+
+func NewPerfMapTable(logger log.Logger, m *metrics.SymtabMetrics) *PerfMapTable {
+	return &PerfMapTable{
+		logger:  logger,
+		metrics: m,
+	}
+}
+
+// SetFiles updates the list of the files to read the symbols from.
+// The paths must be accessible from the current mount namespace.
+func (t *PerfMapTable) SetFiles(perfMaps, jitDumps []string) {
+	files := make([]*perfMapFile, 0, len(perfMaps)+len(jitDumps))
+	known := make(map[string]*perfMapFile, len(t.files))
+	for _, f := range t.files {
+		known[f.path] = f
+	}
+	add := func(p, format string) {
+		if f, ok := known[p]; ok {
+			files = append(files, f)
+			return
+		}
+		files = append(files, &perfMapFile{path: p, module: path.Base(p), format: format})
+	}
+	for _, p := range perfMaps {
+		add(p, perfMapFormatPerfMap)
+	}
+	for _, p := range jitDumps {
+		add(p, perfMapFormatJitDump)
+	}
+	t.files = files
+}
+
+func (t *PerfMapTable) Refresh() {
+	if t.replaced() {
+		// A file has been replaced: the process restarted,
+		// or the pid was reused. Start over.
+		t.symbols = t.symbols[:0]
+		for _, f := range t.files {
+			f.offset = 0
+		}
+	}
+	var added []perfMapSymbol
+	for _, f := range t.files {
+		symbols, err := t.refreshFile(f)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				level.Debug(t.logger).Log("msg", "failed to read perf map", "path", f.path, "err", err)
+				t.metrics.PerfMapErrors.WithLabelValues(f.format).Inc()
+			}
+			continue
+		}
+		if len(symbols) > 0 {
+			t.metrics.PerfMapSymbols.WithLabelValues(f.format).Add(float64(len(symbols)))
+			added = append(added, symbols...)
+		}
+	}
+	if len(added) > 0 {
+		t.symbols = mergePerfMapSymbols(t.symbols, added)
+	}
+}
+
+// replaced reports whether any of the files read before
+// has been replaced or truncated since the last refresh.
+func (t *PerfMapTable) replaced() bool {
+	var replaced bool
+	for _, f := range t.files {
+		stat, err := os.Stat(f.path)
+		if err != nil {
+			continue
+		}
+		s := statFromFileInfo(stat)
+		if f.offset > 0 && (s != f.stat || stat.Size() < f.offset) {
+			replaced = true
+		}
+		f.stat = s
+	}
+	return replaced
+}
+
+func (t *PerfMapTable) refreshFile(f *perfMapFile) ([]perfMapSymbol, error) {
+	fd, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	stat, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() <= f.offset {
+		return nil, nil
+	}
+	if _, err = fd.Seek(f.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(io.LimitReader(fd, stat.Size()-f.offset))
+	var symbols []perfMapSymbol
+	var n int64
+	switch f.format {
+	case perfMapFormatPerfMap:
+		symbols, n, err = parsePerfMap(r, f.module)
+	case perfMapFormatJitDump:
+		symbols, n, err = parseJitDump(r, f.offset == 0, f.module, t.lookupExact)
+	}
+	f.offset += n
+	return symbols, err
+}
+
+func (t *PerfMapTable) lookupExact(addr uint64) string {
+	i := sort.Search(len(t.symbols), func(i int) bool { return t.symbols[i].start >= addr })
+	if i < len(t.symbols) && t.symbols[i].start == addr {
+		return t.symbols[i].name
+	}
+	return ""
+}
+
+func (t *PerfMapTable) Resolve(pc uint64) Symbol {
+	i := sort.Search(len(t.symbols), func(i int) bool { return t.symbols[i].start > pc })
+	if i == 0 {
+		return Symbol{}
+	}
+	s := t.symbols[i-1]
+	if pc >= s.end {
+		return Symbol{}
+	}
+	return Symbol{Start: s.start, Name: s.name, Module: s.module}
+}
+
+func (t *PerfMapTable) Cleanup() {}
+
+func (t *PerfMapTable) Size() int { return len(t.symbols) }
+
+// mergePerfMapSymbols merges the symbols added to the sorted table;
+// the added symbols replace the symbols that start at the same address.
+func mergePerfMapSymbols(symbols, added []perfMapSymbol) []perfMapSymbol {
+	sort.SliceStable(added, func(i, j int) bool { return added[i].start < added[j].start })
+	// Keep the last added symbol for each start address.
+	j := 0
+	for i := range added {
+		if j > 0 && added[j-1].start == added[i].start {
+			added[j-1] = added[i]
+			continue
+		}
+		added[j] = added[i]
+		j++
+	}
+	added = added[:j]
+	merged := make([]perfMapSymbol, 0, len(symbols)+len(added))
+	i := 0
+	for _, s := range added {
+		for i < len(symbols) && symbols[i].start < s.start {
+			merged = append(merged, symbols[i])
+			i++
+		}
+		if i < len(symbols) && symbols[i].start == s.start {
+			i++
+		}
+		merged = append(merged, s)
+	}
+	return append(merged, symbols[i:]...)
+}
+
+// parsePerfMap parses the perf map entries: "START SIZE symbolname",
+// where START and SIZE are hexadecimal numbers. It returns the number
+// of bytes consumed: an incomplete trailing line is not consumed.
+func parsePerfMap(r *bufio.Reader, module string) ([]perfMapSymbol, int64, error) {
+	var symbols []perfMapSymbol
+	var n int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return symbols, n, nil
+			}
+			return symbols, n, err
+		}
+		n += int64(len(line))
+		s, ok := parsePerfMapLine(line[:len(line)-1])
+		if ok {
+			s.module = module
+			symbols = append(symbols, s)
+		}
+	}
+}
+
+func parsePerfMapLine(line []byte) (perfMapSymbol, bool) {
+	fields := bytes.SplitN(bytes.TrimSpace(line), []byte{' '}, 3)
+	if len(fields) != 3 {
+		return perfMapSymbol{}, false
+	}
+	start, err := strconv.ParseUint(strings.TrimPrefix(string(fields[0]), "0x"), 16, 64)
+	if err != nil {
+		return perfMapSymbol{}, false
+	}
+	size, err := strconv.ParseUint(strings.TrimPrefix(string(fields[1]), "0x"), 16, 64)
+	if err != nil || size == 0 {
+		return perfMapSymbol{}, false
+	}
+	return perfMapSymbol{
+		start: start,
+		end:   start + size,
+		name:  string(fields[2]),
+	}, true
+}
+
+// https://raw.githubusercontent.com/torvalds/linux/master/tools/perf/Documentation/jitdump-specification.txt
+const (
+	jitDumpMagic          = 0x4A695444
+	jitDumpHeaderSize     = 40
+	jitDumpRecordHeader   = 16
+	jitDumpRecordCodeLoad = 0
+	jitDumpRecordCodeMove = 1
+	jitDumpCodeLoadSize   = 40 // pid, tid, vma, code_addr, code_size, code_index
+	jitDumpCodeMoveSize   = 48 // pid, tid, vma, old_code_addr, new_code_addr, code_size, code_index
+)
+
+var errInvalidJitDump = errors.New("invalid jitdump file")
+
+// parseJitDump parses the JIT_CODE_LOAD and JIT_CODE_MOVE records of the
+// jitdump file. Only little-endian files are supported. It
+// returns the number of bytes consumed: an incomplete trailing record is
+// not consumed. The lookup function returns the name of the symbol that
+// starts at the address, and is used to resolve the moved code.
+func parseJitDump(r *bufio.Reader, header bool, module string, lookup func(uint64) string) ([]perfMapSymbol, int64, error) {
+	var n int64
+	if header {
+		b, err := r.Peek(jitDumpHeaderSize)
+		if err != nil {
+			if err == io.EOF {
+				return nil, 0, nil
+			}
+			return nil, 0, err
+		}
+		if binary.LittleEndian.Uint32(b) != jitDumpMagic {
+			return nil, 0, errInvalidJitDump
+		}
+		size := binary.LittleEndian.Uint32(b[8:])
+		if size < jitDumpHeaderSize {
+			return nil, 0, errInvalidJitDump
+		}
+		if _, err = r.Discard(int(size)); err != nil {
+			if err == io.EOF {
+				return nil, 0, nil
+			}
+			return nil, 0, err
+		}
+		n += int64(size)
+	}
+	var symbols []perfMapSymbol
+	moved := make(map[uint64]string)
+	var buf []byte
+	for {
+		h, err := r.Peek(jitDumpRecordHeader)
+		if err != nil {
+			if err == io.EOF {
+				return symbols, n, nil
+			}
+			return symbols, n, err
+		}
+		id := binary.LittleEndian.Uint32(h)
+		size := int(binary.LittleEndian.Uint32(h[4:]))
+		if size < jitDumpRecordHeader {
+			return symbols, n, errInvalidJitDump
+		}
+		if _, err = r.Discard(jitDumpRecordHeader); err != nil {
+			return symbols, n, err
+		}
+		body := size - jitDumpRecordHeader
+		switch id {
+		case jitDumpRecordCodeLoad:
+			// The record holds the code that follows the name:
+			// only the fixed fields and the name are read.
+			if body < jitDumpCodeLoadSize+1 {
+				return symbols, n, errInvalidJitDump
+			}
+			buf = append(buf[:0], make([]byte, jitDumpCodeLoadSize)...)
+			if _, err = io.ReadFull(r, buf); err != nil {
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					return symbols, n, nil
+				}
+				return symbols, n, err
+			}
+			if buf, err = readJitDumpString(r, buf); err != nil {
+				if err == io.EOF {
+					return symbols, n, nil
+				}
+				return symbols, n, err
+			}
+			if len(buf) < jitDumpCodeLoadSize+1 || len(buf) > body {
+				return symbols, n, errInvalidJitDump
+			}
+			if _, err = r.Discard(body - len(buf)); err != nil {
+				if err == io.EOF {
+					return symbols, n, nil
+				}
+				return symbols, n, err
+			}
+			addr := binary.LittleEndian.Uint64(buf[16:])
+			codeSize := binary.LittleEndian.Uint64(buf[24:])
+			name := string(buf[jitDumpCodeLoadSize : len(buf)-1])
+			symbols = append(symbols, perfMapSymbol{start: addr, end: addr + codeSize, name: name, module: module})
+			moved[addr] = name
+
+		case jitDumpRecordCodeMove:
+			if body < jitDumpCodeMoveSize {
+				return symbols, n, errInvalidJitDump
+			}
+			b, err := r.Peek(jitDumpCodeMoveSize)
+			if err != nil {
+				if err == io.EOF {
+					return symbols, n, nil
+				}
+				return symbols, n, err
+			}
+			oldAddr := binary.LittleEndian.Uint64(b[16:])
+			newAddr := binary.LittleEndian.Uint64(b[24:])
+			codeSize := binary.LittleEndian.Uint64(b[32:])
+			if _, err = r.Discard(body); err != nil {
+				if err == io.EOF {
+					return symbols, n, nil
+				}
+				return symbols, n, err
+			}
+			name, ok := moved[oldAddr]
+			if !ok {
+				name = lookup(oldAddr)
+			}
+			if name != "" {
+				symbols = append(symbols, perfMapSymbol{start: newAddr, end: newAddr + codeSize, name: name, module: module})
+				moved[newAddr] = name
+			}
+
+		default:
+			if _, err = r.Discard(body); err != nil {
+				if err == io.EOF {
+					return symbols, n, nil
+				}
+				return symbols, n, err
+			}
+		}
+		n += int64(size)
+	}
+}
+
+// readJitDumpString appends the null-terminated string to buf.
+func readJitDumpString(r *bufio.Reader, buf []byte) ([]byte, error) {
+	for {
+		b, err := r.ReadSlice(0)
+		buf = append(buf, b...)
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return buf, err
+		}
+	}
+}
+
+// isJitDumpFile reports whether the mapped file is a jitdump file: the
+// runtimes map the file, so that the profilers could discover it.
+func isJitDumpFile(pathname string) bool {
+	base := path.Base(pathname)
+	return strings.HasSuffix(base, ".jitdump") ||
+		(strings.HasPrefix(base, "jit-") && strings.HasSuffix(base, ".dump"))
+}
+
+// nsPid returns the pid of the process in its own pid namespace,
+// which is the pid the runtime uses to name the perf map files.
+func nsPid(pid int) int {
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return pid
+	}
+	for _, line := range bytes.Split(status, []byte{'\n'}) {
+		if !bytes.HasPrefix(line, []byte("NSpid:")) {
+			continue
+		}
+		fields := bytes.Fields(line[len("NSpid:"):])
+		if len(fields) == 0 {
+			break
+		}
+		if v, err := strconv.Atoi(string(fields[len(fields)-1])); err == nil {
+			return v
+		}
+	}
+	return pid
+}
@@ -0,0 +1,171 @@
+package symtab
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/grafana/pyroscope/ebpf/metrics"
+	"github.com/grafana/pyroscope/ebpf/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePerfMap(t *testing.T) {
+	data := "7f0000001000 10 LazyCompile:~foo /app/index.js:1\n" +
+		"0x7f0000002000 0x20 bar\n" +
+		"invalid line\n" +
+		"7f0000003000 0 empty\n" +
+		"7f0000004000 10 incomplete"
+	symbols, n, err := parsePerfMap(bufio.NewReader(bytes.NewReader([]byte(data))), "perf-1.map")
+	require.NoError(t, err)
+	require.Equal(t, int64(bytes.LastIndexByte([]byte(data), '\n')+1), n)
+	require.Equal(t, []perfMapSymbol{
+		{start: 0x7f0000001000, end: 0x7f0000001010, name: "LazyCompile:~foo /app/index.js:1", module: "perf-1.map"},
+		{start: 0x7f0000002000, end: 0x7f0000002020, name: "bar", module: "perf-1.map"},
+	}, symbols)
+}
+
+type jitDumpWriter struct {
+	bytes.Buffer
+}
+
+func (w *jitDumpWriter) header() {
+	var h [jitDumpHeaderSize]byte
+	binary.LittleEndian.PutUint32(h[0:], jitDumpMagic)
+	binary.LittleEndian.PutUint32(h[4:], 1)
+	binary.LittleEndian.PutUint32(h[8:], jitDumpHeaderSize)
+	w.Write(h[:])
+}
+
+func (w *jitDumpWriter) codeLoad(addr, size uint64, name string) {
+	var r [jitDumpRecordHeader + jitDumpCodeLoadSize]byte
+	binary.LittleEndian.PutUint32(r[0:], jitDumpRecordCodeLoad)
+	binary.LittleEndian.PutUint32(r[4:], uint32(len(r)+len(name)+1+int(size)))
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+8:], addr)
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+16:], addr)
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+24:], size)
+	w.Write(r[:])
+	w.WriteString(name)
+	w.WriteByte(0)
+	w.Write(make([]byte, size))
+}
+
+func (w *jitDumpWriter) codeMove(oldAddr, newAddr, size uint64) {
+	var r [jitDumpRecordHeader + jitDumpCodeMoveSize]byte
+	binary.LittleEndian.PutUint32(r[0:], jitDumpRecordCodeMove)
+	binary.LittleEndian.PutUint32(r[4:], uint32(len(r)))
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+8:], newAddr)
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+16:], oldAddr)
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+24:], newAddr)
+	binary.LittleEndian.PutUint64(r[jitDumpRecordHeader+32:], size)
+	w.Write(r[:])
+}
+
+func TestParseJitDump(t *testing.T) {
+	w := new(jitDumpWriter)
+	w.header()
+	w.codeLoad(0x1000, 0x10, "foo")
+	w.codeLoad(0x2000, 0x20, "bar")
+	w.codeMove(0x2000, 0x3000, 0x20)
+	w.codeMove(0x5000, 0x6000, 0x20)
+	complete := w.Len()
+	w.codeLoad(0x4000, 0x40, "incomplete")
+	data := w.Bytes()[:w.Len()-1]
+
+	lookup := func(addr uint64) string {
+		if addr == 0x5000 {
+			return "baz"
+		}
+		return ""
+	}
+	symbols, n, err := parseJitDump(bufio.NewReader(bytes.NewReader(data)), true, "jit.dump", lookup)
+	require.NoError(t, err)
+	require.Equal(t, int64(complete), n)
+	require.Equal(t, []perfMapSymbol{
+		{start: 0x1000, end: 0x1010, name: "foo", module: "jit.dump"},
+		{start: 0x2000, end: 0x2020, name: "bar", module: "jit.dump"},
+		{start: 0x3000, end: 0x3020, name: "bar", module: "jit.dump"},
+		{start: 0x6000, end: 0x6020, name: "baz", module: "jit.dump"},
+	}, symbols)
+
+	_, _, err = parseJitDump(bufio.NewReader(bytes.NewReader(make([]byte, jitDumpHeaderSize))), true, "jit.dump", lookup)
+	require.ErrorIs(t, err, errInvalidJitDump)
+}
+
+func TestPerfMapTable_Refresh(t *testing.T) {
+	dir := t.TempDir()
+	perfMap := path.Join(dir, "perf-1.map")
+	appendFile := func(s string) {
+		f, err := os.OpenFile(perfMap, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = f.WriteString(s)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	table := NewPerfMapTable(util.TestLogger(t), metrics.NewSymtabMetrics(nil))
+	table.SetFiles([]string{perfMap}, []string{path.Join(dir, "perf-1.jitdump")})
+	table.Refresh()
+	require.Equal(t, 0, table.Size())
+
+	appendFile("1000 10 foo\n2000 10 bar\n3000 10 b")
+	table.Refresh()
+	require.Equal(t, 2, table.Size())
+	require.Equal(t, Symbol{Start: 0x1000, Name: "foo", Module: "perf-1.map"}, table.Resolve(0x1008))
+	require.Equal(t, Symbol{}, table.Resolve(0x1010))
+	require.Equal(t, Symbol{}, table.Resolve(0x3008))
+
+	appendFile("az\n1000 20 foo2\n")
+	table.Refresh()
+	require.Equal(t, 3, table.Size())
+	require.Equal(t, Symbol{Start: 0x3000, Name: "baz", Module: "perf-1.map"}, table.Resolve(0x3008))
+	require.Equal(t, Symbol{Start: 0x1000, Name: "foo2", Module: "perf-1.map"}, table.Resolve(0x1018))
+
+	// The process restarted with the same pid.
+	require.NoError(t, os.Remove(perfMap))
+	appendFile("4000 10 qux\n")
+	table.Refresh()
+	require.Equal(t, 1, table.Size())
+	require.Equal(t, Symbol{}, table.Resolve(0x1008))
+	require.Equal(t, Symbol{Start: 0x4000, Name: "qux", Module: "perf-1.map"}, table.Resolve(0x4008))
+}
+
+func TestProcPerfMap(t *testing.T) {
+	rootFS := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(rootFS, "tmp"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(rootFS, "tmp", "perf-239.map"), []byte("7f0000001000 100 LazyCompile:~main\n"), 0o644))
+	w := new(jitDumpWriter)
+	w.header()
+	w.codeLoad(0x7f0000002000, 0x100, "java.lang.String::hashCode")
+	require.NoError(t, os.WriteFile(path.Join(rootFS, "tmp", "jit-239.dump"), w.Bytes(), 0o644))
+
+	maps := `7f0000001000-7f0000003000 rwxp 00000000 00:00 0
+7f0000004000-7f0000005000 r-xp 00000000 09:00 1                          /tmp/jit-239.dump
+`
+	elfCache, _ := NewElfCache(testCacheOptions, testCacheOptions)
+	for _, enabled := range []bool{false, true} {
+		m := NewProcTable(util.TestLogger(t), ProcTableOptions{
+			Pid: 239,
+			ElfTableOptions: ElfTableOptions{
+				ElfCache:      elfCache,
+				Metrics:       metrics.NewSymtabMetrics(nil),
+				SymbolOptions: &SymbolOptions{PerfMap: enabled},
+			},
+		})
+		m.rootFS = rootFS
+		require.NoError(t, m.refreshProcMap([]byte(maps)))
+		if !enabled {
+			require.Nil(t, m.perfMap)
+			require.Equal(t, Symbol{}, m.Resolve(0x7f0000001008))
+			continue
+		}
+		require.Equal(t, Symbol{Start: 0x7f0000001000, Name: "LazyCompile:~main", Module: "perf-239.map"}, m.Resolve(0x7f0000001008))
+		require.Equal(t, Symbol{Start: 0x7f0000002000, Name: "java.lang.String::hashCode", Module: "jit-239.dump"}, m.Resolve(0x7f0000002008))
+		require.Equal(t, Symbol{}, m.Resolve(0x7f0000004008))
+		require.Equal(t, 2, m.DebugInfo().PerfMapSize)
+	}
+}
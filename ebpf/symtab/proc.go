@@ -22,11 +22,15 @@ type ProcTable struct {
 	options    ProcTableOptions
 	rootFS     string
 	err        error
+	// perfMap is created once the process is found
+	// to have JIT-compiled code. May be nil.
+	perfMap *PerfMapTable
 }
 
 type ProcTableDebugInfo struct {
 	ElfTables     map[string]elf.SymTabDebugInfo `river:"elfs,block,optional"`
 	Size          int                            `river:"size,attr,optional"`
+	PerfMapSize   int                            `river:"perf_map_size,attr,optional"`
 	Pid           int                            `river:"pid,attr,optional"`
 	LastUsedRound int                            `river:"last_used_round,attr,optional"`
 }
@@ -37,6 +41,9 @@ func (p *ProcTable) DebugInfo() ProcTableDebugInfo {
 		Size:      len(p.file2Table),
 		ElfTables: make(map[string]elf.SymTabDebugInfo),
 	}
+	if p.perfMap != nil {
+		res.PerfMapSize = p.perfMap.Size()
+	}
 	for f, e := range p.file2Table {
 		d := e.table.DebugInfo()
 		if d.Size != 0 {
@@ -91,7 +98,6 @@ func (p *ProcTable) Error() error {
 }
 
 func (p *ProcTable) refreshProcMap(procMaps []byte) error {
-	// todo support perf map files
 	for i := range p.ranges {
 		p.ranges[i].elfTable = nil
 	}
@@ -101,6 +107,7 @@ func (p *ProcTable) refreshProcMap(procMaps []byte) error {
 	if err != nil {
 		return err
 	}
+	p.refreshPerfMap(maps)
 
 	for _, m := range maps {
 		p.ranges = append(p.ranges, elfRange{
@@ -126,6 +133,36 @@ func (p *ProcTable) refreshProcMap(procMaps []byte) error {
 	return nil
 }
 
+// refreshPerfMap reads the symbols of the JIT-compiled code, if the
+// process has anonymous executable mappings or maps a jitdump file.
+func (p *ProcTable) refreshPerfMap(maps []*ProcMap) {
+	if p.options.SymbolOptions == nil || !p.options.SymbolOptions.PerfMap {
+		return
+	}
+	var anonymous bool
+	var jitDumps []string
+	for _, m := range maps {
+		switch {
+		case m.Pathname == "":
+			anonymous = true
+		case isJitDumpFile(m.Pathname):
+			jitDumps = append(jitDumps, path.Join(p.rootFS, m.Pathname))
+		}
+	}
+	if p.perfMap == nil {
+		if !anonymous && len(jitDumps) == 0 {
+			return
+		}
+		p.perfMap = NewPerfMapTable(p.logger, p.options.Metrics)
+	}
+	// The runtime names the files after the pid in its pid namespace.
+	pid := nsPid(p.options.Pid)
+	perfMaps := []string{path.Join(p.rootFS, "tmp", fmt.Sprintf("perf-%d.map", pid))}
+	jitDumps = append(jitDumps, path.Join(p.rootFS, "tmp", fmt.Sprintf("perf-%d.jitdump", pid)))
+	p.perfMap.SetFiles(perfMaps, jitDumps)
+	p.perfMap.Refresh()
+}
+
 func (p *ProcTable) getElfTable(r *elfRange) *ElfTable {
 	f := r.mapRange.file()
 	e, ok := p.file2Table[f]
@@ -144,12 +181,12 @@ func (p *ProcTable) Resolve(pc uint64) Symbol {
 	}
 	i, found := slices.BinarySearchFunc(p.ranges, pc, binarySearchElfRange)
 	if !found {
-		return Symbol{}
+		return p.resolvePerfMap(pc)
 	}
 	r := p.ranges[i]
 	t := r.elfTable
 	if t == nil {
-		return Symbol{}
+		return p.resolvePerfMap(pc)
 	}
 	s := t.Resolve(pc)
 	moduleOffset := pc - t.base
@@ -157,11 +194,18 @@ func (p *ProcTable) Resolve(pc uint64) Symbol {
 		return Symbol{Start: moduleOffset, Module: r.mapRange.Pathname}
 	}
 
-	return Symbol{Start: moduleOffset, Name: s, Module: r.mapRange.Pathname}
+	return Symbol{Start: moduleOffset, Name: s, Module: r.mapRange.Pathname, Inline: t.ResolveInline(pc)}
+}
+
+func (p *ProcTable) resolvePerfMap(pc uint64) Symbol {
+	if p.perfMap == nil {
+		return Symbol{}
+	}
+	return p.perfMap.Resolve(pc)
 }
 
 func (p *ProcTable) createElfTable(m *ProcMap) *ElfTable {
-	if !strings.HasPrefix(m.Pathname, "/") {
+	if !strings.HasPrefix(m.Pathname, "/") || isJitDumpFile(m.Pathname) {
 		return nil
 	}
 	e := NewElfTable(p.logger, m, p.rootFS, m.Pathname, p.options.ElfTableOptions)
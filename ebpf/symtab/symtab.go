@@ -37,3 +37,14 @@ func (n *noopSymbolNameResolver) Refresh() {
 func (n *noopSymbolNameResolver) Cleanup() {
 
 }
+
+// inlineSymbolTable resolves the names with the symbol table, and the
+// inlined call chains with the DWARF debug info of the same file.
+type inlineSymbolTable struct {
+	SymbolNameResolver
+	dwarf *elf.DwarfTable
+}
+
+func (t *inlineSymbolTable) ResolveInline(addr uint64) []elf.InlineFrame {
+	return t.dwarf.Resolve(addr)
+}
@@ -2,6 +2,8 @@ package symtab
 
 import (
 	"sort"
+
+	"github.com/grafana/pyroscope/ebpf/symtab/elf"
 )
 
 type SymbolTab struct {
@@ -17,6 +19,10 @@ type Symbol struct {
 	Start  uint64
 	Name   string
 	Module string
+	// Inline is the inlined call chain of the address, starting with the
+	// innermost function. It is only set if SymbolOptions.InlineFrames is
+	// enabled and the module has the debug info.
+	Inline []elf.InlineFrame
 }
 
 func NewSymbolTab(symbols []Symbol) *SymbolTab {
@@ -25,6 +25,7 @@ import (
 	"github.com/grafana/pyroscope/public"
 
 	"github.com/grafana/pyroscope/api/gen/proto/go/adhocprofiles/v1/adhocprofilesv1connect"
+	"github.com/grafana/pyroscope/api/gen/proto/go/deletion/v1/deletionv1connect"
 	"github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1/ingesterv1connect"
 	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
@@ -36,17 +37,21 @@ import (
 	"github.com/grafana/pyroscope/api/openapiv2"
 	"github.com/grafana/pyroscope/pkg/adhocprofiles"
 	"github.com/grafana/pyroscope/pkg/compactor"
+	"github.com/grafana/pyroscope/pkg/deletion"
 	"github.com/grafana/pyroscope/pkg/distributor"
 	"github.com/grafana/pyroscope/pkg/frontend"
 	"github.com/grafana/pyroscope/pkg/frontend/frontendpb/frontendpbconnect"
 	"github.com/grafana/pyroscope/pkg/ingester"
+	"github.com/grafana/pyroscope/pkg/ingester/otlp"
 	"github.com/grafana/pyroscope/pkg/ingester/pyroscope"
 	"github.com/grafana/pyroscope/pkg/operations"
 	"github.com/grafana/pyroscope/pkg/querier"
+	"github.com/grafana/pyroscope/pkg/ruler"
 	"github.com/grafana/pyroscope/pkg/scheduler"
 	"github.com/grafana/pyroscope/pkg/scheduler/schedulerpb/schedulerpbconnect"
 	"github.com/grafana/pyroscope/pkg/settings"
 	"github.com/grafana/pyroscope/pkg/storegateway"
+	"github.com/grafana/pyroscope/pkg/symbolizer"
 	"github.com/grafana/pyroscope/pkg/util"
 	"github.com/grafana/pyroscope/pkg/util/gziphandler"
 	"github.com/grafana/pyroscope/pkg/validation/exporter"
@@ -208,17 +213,25 @@ func (a *API) RegisterOverridesExporter(oe *exporter.OverridesExporter) {
 }
 
 // RegisterDistributor registers the endpoints associated with the distributor.
-func (a *API) RegisterDistributor(d *distributor.Distributor) {
+func (a *API) RegisterDistributor(d *distributor.Distributor, limits otlp.Limits) {
 	pyroscopeHandler := pyroscope.NewPyroscopeIngestHandler(d, a.logger)
 	a.RegisterRoute("/ingest", pyroscopeHandler, true, true, "POST")
 	a.RegisterRoute("/pyroscope/ingest", pyroscopeHandler, true, true, "POST")
 	pushv1connect.RegisterPusherServiceHandler(a.server.HTTP, d, a.grpcAuthMiddleware)
+	otlpHandler := otlp.NewOTLPIngestHandler(d, limits, a.logger)
+	a.server.HTTP.Handle(otlp.ExportProcedure, connect.NewUnaryHandler(otlp.ExportProcedure, otlpHandler.Export, a.grpcAuthMiddleware))
+	a.RegisterRoute(otlp.HTTPPath, otlpHandler, true, true, "POST")
 	a.RegisterRoute("/distributor/ring", d, false, true, "GET", "POST")
 	a.indexPage.AddLinks(defaultWeight, "Distributor", []IndexPageLink{
 		{Desc: "Ring status", Path: "/distributor/ring"},
 	})
 }
 
+// RegisterSymbolizer registers the endpoints associated with the symbolizer.
+func (a *API) RegisterSymbolizer(s *symbolizer.Symbolizer) {
+	a.RegisterRoute("/debuginfo/{build_id}", s, true, true, "PUT", "HEAD")
+}
+
 // RegisterMemberlistKV registers the endpoints associated with the memberlist KV store.
 func (a *API) RegisterMemberlistKV(pathPrefix string, kvs *memberlist.KVInitService) {
 	a.RegisterRoute("/memberlist", MemberlistStatusHandler(pathPrefix, kvs), false, true, "GET")
@@ -309,6 +322,18 @@ func (a *API) RegisterAdmin(ad *operations.Admin) {
 	})
 }
 
+// RegisterRuler registers the Prometheus-compatible endpoints of the ruler,
+// and the scrape target exposing samples of the recording rules.
+func (a *API) RegisterRuler(r *ruler.Ruler) {
+	a.RegisterRoute("/prometheus/api/v1/alerts", http.HandlerFunc(r.AlertsHandler), true, true, "GET")
+	a.RegisterRoute("/prometheus/api/v1/rules", http.HandlerFunc(r.RulesHandler), true, true, "GET")
+	a.RegisterRoute("/ruler/metrics", http.HandlerFunc(r.MetricsHandler), true, true, "GET")
+}
+
 func (a *API) RegisterAdHocProfiles(ahp *adhocprofiles.AdHocProfiles) {
 	adhocprofilesv1connect.RegisterAdHocProfileServiceHandler(a.server.HTTP, ahp, a.grpcAuthMiddleware)
 }
+
+func (a *API) RegisterDeleteRequests(d *deletion.DeleteRequests) {
+	deletionv1connect.RegisterDeletionServiceHandler(a.server.HTTP, d, a.grpcAuthMiddleware)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/phlaredb/bucketindex"
 	"github.com/grafana/pyroscope/pkg/test"
 	"github.com/grafana/pyroscope/pkg/util"
+	"github.com/grafana/pyroscope/pkg/validation"
 )
 
 type testBlocksCleanerOptions struct {
@@ -1022,6 +1023,8 @@ type mockConfigProvider struct {
 	userPartialBlockDelayInvalid map[string]bool
 	verifyChunks                 map[string]bool
 	downsamplerEnabled           map[string]bool
+	downsamplerConfig            map[string]block.DownsampleConfig
+	retentionStreams             map[string][]validation.RetentionStream
 }
 
 func newMockConfigProvider() *mockConfigProvider {
@@ -1037,6 +1040,7 @@ func newMockConfigProvider() *mockConfigProvider {
 		userPartialBlockDelayInvalid: make(map[string]bool),
 		verifyChunks:                 make(map[string]bool),
 		downsamplerEnabled:           make(map[string]bool),
+		retentionStreams:             make(map[string][]validation.RetentionStream),
 	}
 }
 
@@ -1099,6 +1103,17 @@ func (m *mockConfigProvider) CompactorDownsamplerEnabled(user string) bool {
 	return m.downsamplerEnabled[user]
 }
 
+func (m *mockConfigProvider) CompactorDownsamplerConfig(user string) block.DownsampleConfig {
+	if c, ok := m.downsamplerConfig[user]; ok {
+		return c
+	}
+	return block.DefaultDownsampleConfig()
+}
+
+func (m *mockConfigProvider) CompactorRetentionStreams(user string) []validation.RetentionStream {
+	return m.retentionStreams[user]
+}
+
 func (m *mockConfigProvider) S3SSEType(string) string {
 	return ""
 }
@@ -252,6 +252,7 @@ func getCompactionSplitBy(name string) phlaredb.SplitByFunc {
 type BlockCompactor struct {
 	blockOpenConcurrency int
 	downsamplerEnabled   bool
+	downsamplerConfig    block.DownsampleConfig
 	splitBy              phlaredb.SplitByFunc
 	logger               log.Logger
 	metrics              *CompactorMetrics
@@ -266,6 +267,7 @@ type CompactorMetrics struct {
 	Samples           *prometheus.HistogramVec
 	Range             *prometheus.HistogramVec
 	Split             *prometheus.HistogramVec
+	Deduplicated      *prometheus.CounterVec
 }
 
 func newCompactorMetrics(r prometheus.Registerer) *CompactorMetrics {
@@ -308,6 +310,10 @@ func newCompactorMetrics(r prometheus.Registerer) *CompactorMetrics {
 		Help:    "Compaction split factor by level.",
 		Buckets: []float64{1, 2, 4, 8, 16, 32, 64},
 	}, []string{"level"})
+	m.Deduplicated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pyroscope_compaction_deduplicated_profiles_total",
+		Help: "Total number of duplicate profiles removed by compaction of overlapping blocks per level.",
+	}, []string{"level"})
 
 	if r != nil {
 		r.MustRegister(
@@ -318,6 +324,7 @@ func newCompactorMetrics(r prometheus.Registerer) *CompactorMetrics {
 			m.Range,
 			m.Samples,
 			m.Size,
+			m.Deduplicated,
 		)
 	}
 	return m
@@ -400,7 +407,9 @@ func (c *BlockCompactor) CompactWithSplitting(ctx context.Context, dest string,
 		StageSize:          stageSize,
 		SplitBy:            c.splitBy,
 		DownsamplerEnabled: c.downsamplerEnabled,
+		DownsamplerConfig:  c.downsamplerConfig,
 		Logger:             c.logger,
+		DuplicateProfiles:  c.metrics.Deduplicated.WithLabelValues(fmt.Sprintf("%d", currentLevel)),
 	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "compact blocks %v", dirs)
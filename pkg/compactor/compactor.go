@@ -35,6 +35,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
 	"github.com/grafana/pyroscope/pkg/tenant"
 	"github.com/grafana/pyroscope/pkg/util"
+	"github.com/grafana/pyroscope/pkg/validation"
 )
 
 const (
@@ -57,6 +58,7 @@ var (
 	errInvalidCompactionOrder             = fmt.Errorf("unsupported compaction order (supported values: %s)", strings.Join(CompactionOrders, ", "))
 	errInvalidCompactionSplitBy           = fmt.Errorf("unsupported compaction split by (supported values: %s)", strings.Join(CompactionSplitBys, ", "))
 	errInvalidMaxOpeningBlocksConcurrency = fmt.Errorf("invalid max-opening-blocks-concurrency value, must be positive")
+	errInvalidDeleteRequestsRetention     = fmt.Errorf("invalid delete-requests-retention-period value, must not be less than the deletion delay")
 	RingOp                                = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
 )
 
@@ -102,6 +104,8 @@ type Config struct {
 	MaxCompactionTime          time.Duration `yaml:"max_compaction_time" category:"advanced"`
 	NoBlocksFileCleanupEnabled bool          `yaml:"no_blocks_file_cleanup_enabled" category:"experimental"`
 	DownsamplerEnabled         bool          `yaml:"downsampler_enabled" category:"advanced"`
+	DeleteRequestsGracePeriod  time.Duration `yaml:"delete_requests_grace_period" category:"advanced"`
+	DeleteRequestsRetention    time.Duration `yaml:"delete_requests_retention_period" category:"advanced"`
 
 	// Compactor concurrency options
 	MaxOpeningBlocksConcurrency int `yaml:"max_opening_blocks_concurrency" category:"advanced"` // Number of goroutines opening blocks before compaction.
@@ -155,6 +159,8 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet, logger log.Logger) {
 	// f.DurationVar(&cfg.TenantCleanupDelay, "compactor.tenant-cleanup-delay", 6*time.Hour, "For tenants marked for deletion, this is time between deleting of last block, and doing final cleanup (marker files, debug files) of the tenant.")
 	f.BoolVar(&cfg.NoBlocksFileCleanupEnabled, "compactor.no-blocks-file-cleanup-enabled", false, "If enabled, will delete the bucket-index, markers and debug files in the tenant bucket when there are no blocks left in the index.")
 	f.BoolVar(&cfg.DownsamplerEnabled, "compactor.downsampler-enabled", false, "If enabled, the compactor will downsample profiles in blocks at compaction level 3 and above. The original profiles are also kept.")
+	f.DurationVar(&cfg.DeleteRequestsGracePeriod, "compactor.delete-requests-grace-period", 3*time.Hour, "How long the compactor keeps applying a delete request to new blocks before marking it as processed. It should exceed the time it takes for ingesters to upload their blocks to the storage.")
+	f.DurationVar(&cfg.DeleteRequestsRetention, "compactor.delete-requests-retention-period", 24*time.Hour, "How long processed delete requests are kept, and applied at query time, before they are removed. It must exceed the deletion delay, so that the blocks rewritten are deleted first.")
 	// compactor concurrency options
 	f.IntVar(&cfg.MaxOpeningBlocksConcurrency, "compactor.max-opening-blocks-concurrency", 16, "Number of goroutines opening blocks before compaction.")
 
@@ -185,6 +191,10 @@ func (cfg *Config) Validate(maxBlockDuration time.Duration) error {
 		return errInvalidCompactionSplitBy
 	}
 
+	if cfg.DeleteRequestsRetention < cfg.DeletionDelay {
+		return errInvalidDeleteRequestsRetention
+	}
+
 	return nil
 }
 
@@ -215,6 +225,14 @@ type ConfigProvider interface {
 
 	// CompactorDownsamplerEnabled returns true if the downsampler is enabled for a given user.
 	CompactorDownsamplerEnabled(userId string) bool
+
+	// CompactorDownsamplerConfig returns the downsampled tables the compactor
+	// produces for a given user.
+	CompactorDownsamplerConfig(userId string) block.DownsampleConfig
+
+	// CompactorRetentionStreams returns the retention periods of the series matching
+	// the label selectors for a given user.
+	CompactorRetentionStreams(userID string) []validation.RetentionStream
 }
 
 // MultitenantCompactor is a multi-tenant TSDB blocks compactor based on Thanos.
@@ -263,6 +281,12 @@ type MultitenantCompactor struct {
 	compactionRunFailedTenants     prometheus.Gauge
 	compactionRunInterval          prometheus.Gauge
 	blocksMarkedForDeletion        prometheus.Counter
+	tombstonesBlocksRewritten      prometheus.Counter
+	tombstonesProcessed            prometheus.Counter
+	retentionBlocksRewritten       prometheus.Counter
+
+	// Retention streams already applied to the blocks of the tenants.
+	retentionStreamsApplied *appliedRetentionStreams
 
 	// Metrics shared across all BucketCompactor instances.
 	bucketCompactorMetrics *BucketCompactorMetrics
@@ -367,6 +391,19 @@ func newMultitenantCompactor(
 			Help:        blocksMarkedForDeletionHelp,
 			ConstLabels: prometheus.Labels{"reason": "compaction"},
 		}),
+		tombstonesBlocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_compactor_delete_requests_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten by compactor to remove the profiles of delete requests.",
+		}),
+		tombstonesProcessed: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_compactor_delete_requests_processed_total",
+			Help: "Total number of delete requests processed by compactor.",
+		}),
+		retentionBlocksRewritten: promauto.With(registerer).NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_compactor_retention_streams_blocks_rewritten_total",
+			Help: "Total number of blocks rewritten by compactor to remove the profiles of series exceeding their retention period.",
+		}),
+		retentionStreamsApplied: newAppliedRetentionStreams(),
 		blockUploadBlocks: promauto.With(registerer).NewGaugeVec(prometheus.GaugeOpts{
 			Name: "pyroscope_block_upload_api_blocks_total",
 			Help: "Total number of blocks successfully uploaded and validated using the block upload API.",
@@ -777,6 +814,20 @@ func (c *MultitenantCompactor) compactUser(ctx context.Context, userID string) e
 		return errors.Wrap(err, "compaction")
 	}
 
+	// Delete requests and retention streams are applied by a single compactor
+	// of the tenant shard.
+	owned, err := c.shardingStrategy.blocksCleanerOwnUser(userID)
+	if err != nil {
+		level.Info(userLogger).Log("msg", "skipped delete requests and retention streams because unable to check whether the tenant is owned by the compactor instance", "err", err)
+	} else if owned {
+		if err = c.applyTombstones(ctx, userID, userBucket, fetcher, userLogger); err != nil {
+			return errors.Wrap(err, "apply delete requests")
+		}
+		if err = c.applyRetentionStreams(ctx, userID, userBucket, fetcher, userLogger); err != nil {
+			return errors.Wrap(err, "apply retention streams")
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,133 @@
+package compactor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/pyroscope/pkg/objstore"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+// applyRetentionStreams rewrites the blocks of the tenant holding profiles
+// of series exceeding their retention period. The retention is applied with
+// the block granularity: the profiles of a series are only removed from the
+// blocks that are entirely older than the retention period, therefore each
+// block is rewritten at most once per retention stream. Blocks are immutable,
+// and the streams applied to them are remembered: a block is not opened again
+// for a stream it has been checked against.
+func (c *MultitenantCompactor) applyRetentionStreams(ctx context.Context, userID string, userBucket objstore.Bucket, fetcher *block.MetaFetcher, logger log.Logger) error {
+	streams := c.cfgProvider.CompactorRetentionStreams(userID)
+	if len(streams) == 0 {
+		return nil
+	}
+
+	metas, _, err := fetcher.FetchWithoutMarkedForDeletion(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetch blocks")
+	}
+
+	applied := c.retentionStreamsApplied.forTenant(userID, metas)
+	now := time.Now()
+	for id, meta := range metas {
+		expired, err := expiredStreams(applied.pending(id, streams), meta, now)
+		if err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			continue
+		}
+		rewritten, err := c.rewriteBlock(ctx, userID, userBucket, meta, expired, logger)
+		if err != nil {
+			return errors.Wrapf(err, "rewrite block %s", meta.ULID)
+		}
+		if rewritten {
+			c.retentionBlocksRewritten.Inc()
+		}
+		applied.add(id, expired)
+	}
+	return nil
+}
+
+// appliedRetentionStreams keeps track of the retention stream selectors
+// applied to the blocks, by tenant. The blocks that are no longer present
+// are forgotten.
+type appliedRetentionStreams struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantAppliedRetentionStreams
+}
+
+func newAppliedRetentionStreams() *appliedRetentionStreams {
+	return &appliedRetentionStreams{tenants: make(map[string]*tenantAppliedRetentionStreams)}
+}
+
+func (a *appliedRetentionStreams) forTenant(userID string, metas map[ulid.ULID]*block.Meta) *tenantAppliedRetentionStreams {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.tenants[userID]
+	if !ok {
+		t = &tenantAppliedRetentionStreams{blocks: make(map[ulid.ULID]map[string]struct{})}
+		a.tenants[userID] = t
+	}
+	for id := range t.blocks {
+		if _, ok = metas[id]; !ok {
+			delete(t.blocks, id)
+		}
+	}
+	return t
+}
+
+// tenantAppliedRetentionStreams is only accessed by the compactor of the
+// tenant, and is not safe for concurrent use.
+type tenantAppliedRetentionStreams struct {
+	blocks map[ulid.ULID]map[string]struct{}
+}
+
+// pending returns the streams not applied to the block yet.
+func (t *tenantAppliedRetentionStreams) pending(id ulid.ULID, streams []validation.RetentionStream) []validation.RetentionStream {
+	applied := t.blocks[id]
+	if len(applied) == 0 {
+		return streams
+	}
+	var r []validation.RetentionStream
+	for _, s := range streams {
+		if _, ok := applied[s.Selector]; !ok {
+			r = append(r, s)
+		}
+	}
+	return r
+}
+
+func (t *tenantAppliedRetentionStreams) add(id ulid.ULID, tombstones bucket.Tombstones) {
+	applied, ok := t.blocks[id]
+	if !ok {
+		applied = make(map[string]struct{}, len(tombstones))
+		t.blocks[id] = applied
+	}
+	for _, x := range tombstones {
+		applied[x.Selector] = struct{}{}
+	}
+}
+
+// expiredStreams returns tombstones deleting the series of the streams the
+// block exceeds the retention period of.
+func expiredStreams(streams []validation.RetentionStream, meta *block.Meta, now time.Time) (bucket.Tombstones, error) {
+	var tombstones bucket.Tombstones
+	for _, s := range streams {
+		if s.Period <= 0 || !meta.MaxTime.Time().Before(now.Add(-time.Duration(s.Period))) {
+			continue
+		}
+		t, err := bucket.NewTombstone(s.Selector, int64(meta.MinTime), int64(meta.MaxTime), now)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid retention stream")
+		}
+		tombstones = append(tombstones, t)
+	}
+	return tombstones, nil
+}
@@ -0,0 +1,82 @@
+package compactor
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	prom_testutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pyroscope_objstore "github.com/grafana/pyroscope/pkg/objstore"
+	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/pprof/testhelper"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func createEnvBlock(t *testing.T, bkt pyroscope_objstore.Bucket, userID string, ts time.Time) {
+	createCustomBlock(t, bkt, userID, nil, func() []*testhelper.ProfileBuilder {
+		var builders []*testhelper.ProfileBuilder
+		for _, env := range []string{"dev", "prod"} {
+			for i := 0; i < 3; i++ {
+				builders = append(builders, testhelper.NewProfileBuilder(ts.Add(time.Duration(i)*time.Minute).UnixNano()).
+					CPUProfile().
+					WithLabels("env", env).
+					ForStacktraceString("foo", "bar", "baz").AddSamples(1))
+			}
+		}
+		return builders
+	})
+}
+
+func TestMultitenantCompactor_applyRetentionStreams(t *testing.T) {
+	ctx := context.Background()
+	bucketClient, err := filesystem.NewBucket(t.TempDir())
+	require.NoError(t, err)
+
+	now := time.Now()
+	createEnvBlock(t, bucketClient, "user-1", now.Add(-48*time.Hour))
+	createEnvBlock(t, bucketClient, "user-1", now.Add(-time.Hour))
+
+	limits := newMockConfigProvider()
+	limits.retentionStreams["user-1"] = []validation.RetentionStream{
+		{Selector: `{env="dev"}`, Period: model.Duration(24 * time.Hour)},
+	}
+	c, _, _, _, _ := prepareWithConfigProvider(t, prepareConfig(t), bucketClient, limits)
+
+	userBucket := pyroscope_objstore.NewTenantBucketClient("user-1", block.BucketWithGlobalMarkers(c.bucketClient), nil)
+	fetcher, err := block.NewMetaFetcher(log.NewNopLogger(), 1, userBucket, t.TempDir(), nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, c.applyRetentionStreams(ctx, "user-1", userBucket, fetcher, log.NewNopLogger()))
+	assert.Equal(t, float64(1), prom_testutil.ToFloat64(c.retentionBlocksRewritten))
+
+	metas, _, err := fetcher.FetchWithoutMarkedForDeletion(ctx)
+	require.NoError(t, err)
+	require.Len(t, metas, 2)
+	for _, m := range metas {
+		if m.MaxTime.Time().Before(now.Add(-24 * time.Hour)) {
+			// Only the prod series remain in the expired block.
+			assert.Equal(t, uint64(3), m.Stats.NumProfiles)
+			assert.Equal(t, uint64(1), m.Stats.NumSeries)
+		} else {
+			assert.Equal(t, uint64(6), m.Stats.NumProfiles)
+		}
+	}
+
+	// The block is not rewritten again.
+	require.NoError(t, c.applyRetentionStreams(ctx, "user-1", userBucket, fetcher, log.NewNopLogger()))
+	assert.Equal(t, float64(1), prom_testutil.ToFloat64(c.retentionBlocksRewritten))
+
+	// Nor opened: the blocks are remembered as processed.
+	for id := range metas {
+		require.NoError(t, userBucket.Delete(ctx, path.Join(id.String(), block.IndexFilename)))
+	}
+	require.NoError(t, c.applyRetentionStreams(ctx, "user-1", userBucket, fetcher, log.NewNopLogger()))
+	assert.Equal(t, float64(1), prom_testutil.ToFloat64(c.retentionBlocksRewritten))
+}
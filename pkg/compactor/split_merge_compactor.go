@@ -33,6 +33,7 @@ func splitAndMergeCompactorFactory(_ context.Context, cfg Config, cfgProvider Co
 	return &BlockCompactor{
 		blockOpenConcurrency: cfg.MaxOpeningBlocksConcurrency,
 		downsamplerEnabled:   cfg.DownsamplerEnabled && cfgProvider.CompactorDownsamplerEnabled(userID),
+		downsamplerConfig:    cfgProvider.CompactorDownsamplerConfig(userID),
 		splitBy:              splitBy,
 		logger:               logger,
 		metrics:              metrics,
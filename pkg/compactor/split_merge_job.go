@@ -64,7 +64,7 @@ func (j *job) conflicts(other *job) bool {
 		if !labels.Equal(myLabels, otherLabels) {
 			return false
 		}
-		if j.blocksGroup.blocks[0].Downsample != other.blocksGroup.blocks[0].Downsample {
+		if j.blocksGroup.blocks[0].Downsample.Resolution != other.blocksGroup.blocks[0].Downsample.Resolution {
 			return false
 		}
 	}
@@ -0,0 +1,176 @@
+package compactor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/pyroscope/pkg/objstore"
+	"github.com/grafana/pyroscope/pkg/objstore/client"
+	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
+	"github.com/grafana/pyroscope/pkg/phlaredb"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+)
+
+// applyTombstones rewrites the blocks of the tenant holding profiles deleted
+// by pending tombstones. A tombstone is marked as processed once no block
+// had to be rewritten for it, and the grace period has elapsed: the latter
+// ensures the blocks uploaded by ingesters after the request are covered.
+// Processed tombstones are removed after the retention period.
+func (c *MultitenantCompactor) applyTombstones(ctx context.Context, userID string, userBucket objstore.Bucket, fetcher *block.MetaFetcher, logger log.Logger) error {
+	deleted, err := bucket.CleanupTombstones(ctx, userBucket, c.compactorCfg.DeleteRequestsRetention, time.Now())
+	if deleted > 0 {
+		level.Info(logger).Log("msg", "removed processed delete requests", "count", deleted)
+	}
+	if err != nil {
+		return errors.Wrap(err, "clean up tombstones")
+	}
+
+	tombstones, err := bucket.ReadTombstones(ctx, userBucket)
+	if err != nil {
+		return errors.Wrap(err, "read tombstones")
+	}
+	pending := tombstones.Pending()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	metas, _, err := fetcher.FetchWithoutMarkedForDeletion(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetch blocks")
+	}
+
+	dirty := make(map[string]struct{})
+	for _, meta := range metas {
+		overlapping := pending.Overlapping(meta.MinTime, meta.MaxTime)
+		if len(overlapping) == 0 {
+			continue
+		}
+		rewritten, err := c.rewriteBlock(ctx, userID, userBucket, meta, overlapping, logger)
+		if err != nil {
+			return errors.Wrapf(err, "rewrite block %s", meta.ULID)
+		}
+		if rewritten {
+			c.tombstonesBlocksRewritten.Inc()
+			for _, t := range overlapping {
+				dirty[t.RequestID] = struct{}{}
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, t := range pending {
+		if _, ok := dirty[t.RequestID]; ok {
+			continue
+		}
+		if now.Sub(time.UnixMilli(t.CreatedAt)) < c.compactorCfg.DeleteRequestsGracePeriod {
+			continue
+		}
+		if err = bucket.MarkTombstoneProcessed(ctx, userBucket, t, now); err != nil {
+			return errors.Wrapf(err, "mark tombstone %s as processed", t.RequestID)
+		}
+		c.tombstonesProcessed.Inc()
+		level.Info(logger).Log("msg", "delete request processed", "request_id", t.RequestID, "selector", t.Selector)
+	}
+	return nil
+}
+
+// rewriteBlock replaces the block with a copy that does not include the
+// profiles deleted by the tombstones. It reports whether the block has been
+// replaced: the block is left as is if it has no profiles to delete.
+func (c *MultitenantCompactor) rewriteBlock(ctx context.Context, userID string, userBucket objstore.Bucket, meta *block.Meta, tombstones bucket.Tombstones, logger log.Logger) (bool, error) {
+	b := phlaredb.NewSingleBlockQuerierFromMeta(ctx, userBucket, meta)
+	if err := b.Open(ctx); err != nil {
+		return false, errors.Wrap(err, "open block")
+	}
+	matches, err := phlaredb.IndexMatchesTombstones(b.Index(), tombstones)
+	if closeErr := b.Close(); closeErr != nil {
+		level.Warn(logger).Log("msg", "failed to close block", "block", meta.ULID, "err", closeErr)
+	}
+	if err != nil || !matches {
+		return false, err
+	}
+
+	dir := filepath.Join(c.compactorCfg.DataDir, "tombstones", userID)
+	if err = os.RemoveAll(dir); err != nil {
+		return false, errors.Wrap(err, "clean up directory")
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			level.Warn(logger).Log("msg", "failed to remove directory", "dir", dir, "err", err)
+		}
+	}()
+	srcDir := filepath.Join(dir, "src")
+	dstDir := filepath.Join(dir, "dst")
+	if err = block.Download(ctx, logger, userBucket, meta.ULID, filepath.Join(srcDir, meta.ULID.String())); err != nil {
+		return false, errors.Wrap(err, "download block")
+	}
+	localBucket, err := client.NewBucket(ctx, client.Config{
+		StorageBackendConfig: client.StorageBackendConfig{
+			Backend:    client.Filesystem,
+			Filesystem: filesystem.Config{Directory: srcDir},
+		},
+	}, "local-compactor")
+	if err != nil {
+		return false, errors.Wrap(err, "create local bucket")
+	}
+	defer localBucket.Close()
+
+	src := phlaredb.NewSingleBlockQuerierFromMeta(ctx, localBucket, meta)
+	if err = src.Open(ctx); err != nil {
+		return false, errors.Wrap(err, "open local block")
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			level.Warn(logger).Log("msg", "failed to close block", "block", meta.ULID, "err", err)
+		}
+	}()
+	splitBy := getCompactionSplitBy(c.compactorCfg.CompactionSplitBy)
+	if splitBy == nil {
+		return false, errInvalidCompactionSplitBy
+	}
+	metas, err := phlaredb.CompactWithSplitting(ctx, phlaredb.CompactWithSplittingOpts{
+		Src:                []phlaredb.BlockReader{src},
+		Dst:                dstDir,
+		SplitCount:         1,
+		SplitBy:            splitBy,
+		DownsamplerEnabled: c.compactorCfg.DownsamplerEnabled && c.cfgProvider.CompactorDownsamplerEnabled(userID),
+		DownsamplerConfig:  c.cfgProvider.CompactorDownsamplerConfig(userID),
+		Logger:             logger,
+		Tombstones:         tombstones,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "compact block")
+	}
+
+	var profiles uint64
+	for _, m := range metas {
+		profiles += m.Stats.NumProfiles
+	}
+	if profiles == meta.Stats.NumProfiles {
+		// The series matched, but none of their profiles did.
+		return false, nil
+	}
+
+	for _, m := range metas {
+		bdir := filepath.Join(dstDir, m.ULID.String())
+		if err = phlaredb.ValidateLocalBlock(ctx, bdir); err != nil {
+			return false, errors.Wrapf(err, "invalid result block %s", bdir)
+		}
+		if err = block.Upload(ctx, logger, userBucket, bdir); err != nil {
+			return false, errors.Wrapf(err, "upload of %s failed", m.ULID)
+		}
+	}
+	if err = block.MarkForDeletion(ctx, logger, userBucket, meta.ULID, "source of rewritten block", false, c.blocksMarkedForDeletion); err != nil {
+		return false, errors.Wrap(err, "mark block for deletion")
+	}
+	level.Info(logger).Log("msg", "rewritten block with deleted profiles removed", "block", meta.ULID, "result_blocks", len(metas),
+		"profiles_before", meta.Stats.NumProfiles, "profiles_after", profiles)
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package deletion
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/services"
+	"github.com/grafana/dskit/tenant"
+	"github.com/pkg/errors"
+
+	v1 "github.com/grafana/pyroscope/api/gen/proto/go/deletion/v1"
+	"github.com/grafana/pyroscope/pkg/objstore"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+)
+
+// DeleteRequests manages the delete requests of tenants. The requests are
+// stored in the tenant bucket as tombstones, honoured by ingesters and store
+// gateways at query time, and applied to the blocks by the compactor.
+type DeleteRequests struct {
+	services.Service
+
+	logger log.Logger
+	bucket objstore.Bucket
+}
+
+func New(bucket objstore.Bucket, logger log.Logger) *DeleteRequests {
+	d := &DeleteRequests{
+		logger: logger,
+		bucket: bucket,
+	}
+	d.Service = services.NewBasicService(nil, d.running, nil)
+	return d
+}
+
+func (d *DeleteRequests) running(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (d *DeleteRequests) CreateDeleteRequest(ctx context.Context, c *connect.Request[v1.CreateDeleteRequestRequest]) (*connect.Response[v1.CreateDeleteRequestResponse], error) {
+	tenantID, bkt, err := d.getBucketFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	end := c.Msg.End
+	if end == 0 {
+		end = time.Now().UnixMilli()
+	}
+	t, err := bucket.NewTombstone(c.Msg.LabelSelector, c.Msg.Start, end, time.Now())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if err = bucket.WriteTombstone(ctx, bkt, t); err != nil {
+		return nil, err
+	}
+	level.Info(d.logger).Log("msg", "delete request created", "tenant", tenantID, "request_id", t.RequestID,
+		"selector", t.Selector, "start", t.StartTime, "end", t.EndTime)
+	return connect.NewResponse(&v1.CreateDeleteRequestResponse{DeleteRequest: deleteRequest(t)}), nil
+}
+
+func (d *DeleteRequests) ListDeleteRequests(ctx context.Context, _ *connect.Request[v1.ListDeleteRequestsRequest]) (*connect.Response[v1.ListDeleteRequestsResponse], error) {
+	_, bkt, err := d.getBucketFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tombstones, err := bucket.ReadTombstones(ctx, bkt)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]*v1.DeleteRequest, 0, len(tombstones))
+	for _, t := range tombstones {
+		requests = append(requests, deleteRequest(t))
+	}
+	return connect.NewResponse(&v1.ListDeleteRequestsResponse{DeleteRequests: requests}), nil
+}
+
+func (d *DeleteRequests) CancelDeleteRequest(ctx context.Context, c *connect.Request[v1.CancelDeleteRequestRequest]) (*connect.Response[v1.CancelDeleteRequestResponse], error) {
+	tenantID, bkt, err := d.getBucketFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t, err := bucket.ReadTombstone(ctx, bkt, c.Msg.RequestId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if t == nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.Errorf("delete request %q not found", c.Msg.RequestId))
+	}
+	if t.Processed() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.Errorf("delete request %q has already been processed", c.Msg.RequestId))
+	}
+	// The compactor never writes the tombstone itself: if it marks the
+	// request as processed concurrently, the marker is left orphaned,
+	// and the request stays cancelled.
+	if err = bucket.DeleteTombstone(ctx, bkt, t.RequestID); err != nil {
+		return nil, err
+	}
+	level.Info(d.logger).Log("msg", "delete request cancelled", "tenant", tenantID, "request_id", t.RequestID)
+	return connect.NewResponse(&v1.CancelDeleteRequestResponse{}), nil
+}
+
+func (d *DeleteRequests) getBucketFromContext(ctx context.Context) (string, objstore.Bucket, error) {
+	tenantID, err := tenant.TenantID(ctx)
+	if err != nil {
+		return "", nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	return tenantID, objstore.NewTenantBucketClient(tenantID, d.bucket, nil), nil
+}
+
+func deleteRequest(t *bucket.Tombstone) *v1.DeleteRequest {
+	return &v1.DeleteRequest{
+		RequestId:     t.RequestID,
+		LabelSelector: t.Selector,
+		Start:         t.StartTime,
+		End:           t.EndTime,
+		CreatedAt:     t.CreatedAt,
+		ProcessedAt:   t.ProcessedAt,
+	}
+}
@@ -0,0 +1,86 @@
+package deletion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	thanosobjstore "github.com/thanos-io/objstore"
+
+	v1 "github.com/grafana/pyroscope/api/gen/proto/go/deletion/v1"
+	phlareobjstore "github.com/grafana/pyroscope/pkg/objstore"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+	"github.com/grafana/pyroscope/pkg/tenant"
+)
+
+func TestDeleteRequests(t *testing.T) {
+	bkt := phlareobjstore.NewBucket(thanosobjstore.NewInMemBucket())
+	d := New(bkt, log.NewNopLogger())
+	ctx := tenant.InjectTenantID(context.Background(), "tenant")
+
+	_, err := d.ListDeleteRequests(context.Background(), connect.NewRequest(&v1.ListDeleteRequestsRequest{}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+
+	_, err = d.CreateDeleteRequest(ctx, connect.NewRequest(&v1.CreateDeleteRequestRequest{
+		LabelSelector: `{service_name="foo"`,
+	}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+
+	created, err := d.CreateDeleteRequest(ctx, connect.NewRequest(&v1.CreateDeleteRequestRequest{
+		LabelSelector: `{service_name="foo"}`,
+		Start:         1000,
+		End:           2000,
+	}))
+	require.NoError(t, err)
+	req := created.Msg.DeleteRequest
+	assert.Equal(t, `{service_name="foo"}`, req.LabelSelector)
+	assert.Equal(t, int64(1000), req.Start)
+	assert.Equal(t, int64(2000), req.End)
+	assert.Zero(t, req.ProcessedAt)
+
+	// The requests are stored in the tenant bucket.
+	tombstones, err := bucket.ReadTombstones(ctx, phlareobjstore.NewTenantBucketClient("tenant", bkt, nil))
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	assert.Equal(t, req.RequestId, tombstones[0].RequestID)
+
+	list, err := d.ListDeleteRequests(ctx, connect.NewRequest(&v1.ListDeleteRequestsRequest{}))
+	require.NoError(t, err)
+	require.Len(t, list.Msg.DeleteRequests, 1)
+	assert.Equal(t, req, list.Msg.DeleteRequests[0])
+
+	other := tenant.InjectTenantID(context.Background(), "other")
+	list, err = d.ListDeleteRequests(other, connect.NewRequest(&v1.ListDeleteRequestsRequest{}))
+	require.NoError(t, err)
+	assert.Empty(t, list.Msg.DeleteRequests)
+	_, err = d.CancelDeleteRequest(other, connect.NewRequest(&v1.CancelDeleteRequestRequest{RequestId: req.RequestId}))
+	require.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+
+	_, err = d.CancelDeleteRequest(ctx, connect.NewRequest(&v1.CancelDeleteRequestRequest{RequestId: req.RequestId}))
+	require.NoError(t, err)
+	list, err = d.ListDeleteRequests(ctx, connect.NewRequest(&v1.ListDeleteRequestsRequest{}))
+	require.NoError(t, err)
+	assert.Empty(t, list.Msg.DeleteRequests)
+}
+
+func TestDeleteRequests_CancelProcessed(t *testing.T) {
+	bkt := phlareobjstore.NewBucket(thanosobjstore.NewInMemBucket())
+	d := New(bkt, log.NewNopLogger())
+	ctx := tenant.InjectTenantID(context.Background(), "tenant")
+
+	ts, err := bucket.NewTombstone(`{service_name="foo"}`, 0, 1000, time.Now())
+	require.NoError(t, err)
+	tenantBucket := phlareobjstore.NewTenantBucketClient("tenant", bkt, nil)
+	require.NoError(t, bucket.WriteTombstone(ctx, tenantBucket, ts))
+	require.NoError(t, bucket.MarkTombstoneProcessed(ctx, tenantBucket, ts, time.Now()))
+
+	_, err = d.CancelDeleteRequest(ctx, connect.NewRequest(&v1.CancelDeleteRequestRequest{RequestId: ts.RequestID}))
+	require.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+
+	_, err = d.CancelDeleteRequest(ctx, connect.NewRequest(&v1.CancelDeleteRequestRequest{RequestId: "invalid"}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+}
@@ -40,6 +40,7 @@ import (
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/pprof"
 	"github.com/grafana/pyroscope/pkg/slices"
+	"github.com/grafana/pyroscope/pkg/symbolizer"
 	"github.com/grafana/pyroscope/pkg/tenant"
 	"github.com/grafana/pyroscope/pkg/usagestats"
 	"github.com/grafana/pyroscope/pkg/util"
@@ -68,6 +69,8 @@ type Config struct {
 
 	// Distributors ring
 	DistributorRing util.CommonRingConfig `yaml:"ring" doc:"hidden"`
+
+	Symbolizer symbolizer.Config `yaml:"symbolizer"`
 }
 
 // RegisterFlags registers distributor-related flags.
@@ -75,6 +78,11 @@ func (cfg *Config) RegisterFlags(fs *flag.FlagSet, logger log.Logger) {
 	cfg.PoolConfig.RegisterFlagsWithPrefix("distributor", fs)
 	fs.DurationVar(&cfg.PushTimeout, "distributor.push.timeout", 5*time.Second, "Timeout when pushing data to ingester.")
 	cfg.DistributorRing.RegisterFlags("distributor.ring.", "collectors/", "distributors", fs, logger)
+	cfg.Symbolizer.RegisterFlagsWithPrefix("distributor.symbolizer.", fs)
+}
+
+func (cfg *Config) Validate() error {
+	return cfg.Symbolizer.Validate()
 }
 
 // Distributor coordinates replicates and distribution of log streams.
@@ -86,6 +94,7 @@ type Distributor struct {
 	limits        Limits
 	ingestersRing ring.ReadRing
 	pool          *ring_client.Pool
+	symbolizer    Symbolizer
 
 	// The global rate limiter requires a distributors ring to count
 	// the number of healthy instances
@@ -108,6 +117,11 @@ type Distributor struct {
 	profileSizeStats        *usagestats.MultiStatistics
 }
 
+// Symbolizer resolves the locations of unsymbolized native code in place.
+type Symbolizer interface {
+	Symbolize(tenantID string, p *googlev1.Profile)
+}
+
 type Limits interface {
 	IngestionRateBytes(tenantID string) float64
 	IngestionBurstSizeBytes(tenantID string) int
@@ -125,7 +139,7 @@ type Limits interface {
 	aggregator.Limits
 }
 
-func New(cfg Config, ingestersRing ring.ReadRing, factory ring_client.PoolFactory, limits Limits, reg prometheus.Registerer, logger log.Logger, clientsOptions ...connect.ClientOption) (*Distributor, error) {
+func New(cfg Config, ingestersRing ring.ReadRing, factory ring_client.PoolFactory, limits Limits, symbolizer Symbolizer, reg prometheus.Registerer, logger log.Logger, clientsOptions ...connect.ClientOption) (*Distributor, error) {
 	clients := promauto.With(reg).NewGauge(prometheus.GaugeOpts{
 		Namespace: "pyroscope",
 		Name:      "distributor_ingester_clients",
@@ -140,6 +154,7 @@ func New(cfg Config, ingestersRing ring.ReadRing, factory ring_client.PoolFactor
 		healthyInstancesCount:   atomic.NewUint32(0),
 		aggregator:              aggregator.NewMultiTenantAggregator[*pprof.ProfileMerge](limits, reg),
 		limits:                  limits,
+		symbolizer:              symbolizer,
 		rfStats:                 usagestats.NewInt("distributor_replication_factor"),
 		bytesReceivedStats:      usagestats.NewStatistics("distributor_bytes_received"),
 		bytesReceivedTotalStats: usagestats.NewCounter("distributor_bytes_received_total"),
@@ -305,6 +320,16 @@ func (d *Distributor) PushParsed(ctx context.Context, req *distributormodel.Push
 		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("no profiles received"))
 	}
 
+	// Symbolization must precede normalisation,
+	// which removes the addresses of symbolized locations.
+	if d.symbolizer != nil {
+		for _, series := range req.Series {
+			for _, sample := range series.Samples {
+				d.symbolizer.Symbolize(tenantID, sample.Profile.Profile)
+			}
+		}
+	}
+
 	// Normalisation is quite an expensive operation,
 	// therefore it should be done after the rate limit check.
 	for _, series := range req.Series {
@@ -626,7 +651,7 @@ func extractSampleSeries(req *distributormodel.PushRequest) []*distributormodel.
 		}
 		for _, raw := range series.Samples {
 			pprof.RenameLabel(raw.Profile.Profile, pprof.ProfileIDLabelName, pprof.SpanIDLabelName)
-			groups := pprof.GroupSamplesWithoutLabels(raw.Profile.Profile, pprof.SpanIDLabelName)
+			groups := pprof.GroupSamplesWithoutLabels(raw.Profile.Profile, pprof.SpanIDLabelName, pprof.TraceIDLabelName)
 			if len(groups) == 0 || (len(groups) == 1 && len(groups[0].Labels) == 0) {
 				// No sample labels in the profile.
 				// We do not modify the request.
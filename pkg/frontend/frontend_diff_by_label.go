@@ -0,0 +1,101 @@
+package frontend
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func (f *Frontend) DiffByLabel(ctx context.Context,
+	c *connect.Request[querierv1.DiffByLabelRequest]) (
+	*connect.Response[querierv1.DiffByLabelResponse], error,
+) {
+	// The sides are selected with SelectMergeStacktraces sub-requests.
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectMergeStacktracesProcedure)
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	maxNodes, err := validation.ValidateMaxNodes(f.limits, tenantIDs, c.Msg.GetMaxNodes())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	leftSelector, rightSelector, err := phlaremodel.DiffByLabelSelectors(c.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	sides := make([]querySelection, 2)
+	for i, selector := range []string{leftSelector, rightSelector} {
+		sides[i].tenantIDs, sides[i].selector = splitSelector(tenantIDs, selector)
+	}
+
+	// Both sides count towards the estimated cost limit.
+	var plans *queryPlans
+	if !validated.IsEmpty {
+		interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
+		_, plans, err = f.estimateQuery(ctx, tenantIDs, false,
+			NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval),
+			sides, f.mergeStacktracesEstimator(c.Header(), c.Msg.ProfileTypeID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The trees are not truncated before the diff: the functions below
+	// the cut would otherwise be merged into "other" on each side.
+	selectTree := func(ctx context.Context, side querySelection) (*phlaremodel.Tree, error) {
+		if len(side.tenantIDs) == 0 || validated.IsEmpty {
+			return new(phlaremodel.Tree), nil
+		}
+		req := connect.NewRequest(&querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID: c.Msg.ProfileTypeID,
+		})
+		for k, v := range c.Header() {
+			req.Header()[k] = v
+		}
+		return f.selectMergeTree(ctx, req, side.tenantIDs, side.selector, validated.Interval, -1, plans)
+	}
+
+	var left, right *phlaremodel.Tree
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		left, err = selectTree(gCtx, sides[0])
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		right, err = selectTree(gCtx, sides[1])
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	diff, err := phlaremodel.NewFlamegraphDiff(left, right, maxNodes)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connect.NewResponse(&querierv1.DiffByLabelResponse{
+		Flamegraph: diff,
+		Functions:  phlaremodel.NewFunctionsDiff(left, right, c.Msg.GetMaxFunctions()),
+	}), nil
+}
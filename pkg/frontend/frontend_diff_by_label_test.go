@@ -0,0 +1,69 @@
+package frontend
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/dskit/user"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/scheduler/schedulerpb"
+	"github.com/grafana/pyroscope/pkg/util/httpgrpc"
+)
+
+func TestFrontend_DiffByLabel(t *testing.T) {
+	const tenantID = "test"
+	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
+		var req querierv1.SelectMergeStacktracesRequest
+		require.NoError(t, proto.Unmarshal(msg.HttpRequest.Body, &req))
+		// Many small functions on both sides, "foo" regressed on the right one.
+		tree := new(phlaremodel.Tree)
+		for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			tree.InsertStack(10, "main", name)
+		}
+		if strings.Contains(req.LabelSelector, `version="2"`) {
+			tree.InsertStack(20, "main", "foo")
+		} else {
+			tree.InsertStack(10, "main", "foo")
+		}
+		body, _ := proto.Marshal(&querierv1.SelectMergeStacktracesResponse{
+			Flamegraph: phlaremodel.NewFlameGraph(tree, req.GetMaxNodes()),
+		})
+		go sendResponseWithDelay(f, 10*time.Millisecond, tenantID, msg.QueryID, &httpgrpc.HTTPResponse{
+			Code: 200,
+			Body: body,
+		})
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_OK}
+	})
+
+	// The range is not split: it's within a single 2h-aligned interval.
+	start := time.Now().Add(-24 * time.Hour).Truncate(2 * time.Hour)
+	maxNodes := int64(2)
+	resp, err := f.DiffByLabel(user.InjectOrgID(context.Background(), tenantID), connect.NewRequest(&querierv1.DiffByLabelRequest{
+		ProfileTypeID: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		LabelSelector: `{service_name="svc"}`,
+		LabelName:     "version",
+		LeftValue:     "1",
+		RightValue:    "2",
+		Start:         start.UnixMilli(),
+		End:           start.Add(time.Hour).UnixMilli(),
+		MaxNodes:      &maxNodes,
+	}))
+	require.NoError(t, err)
+
+	// The functions below the flame graph cut are still compared.
+	require.NotEmpty(t, resp.Msg.Functions)
+	foo := resp.Msg.Functions[0]
+	require.Equal(t, "foo", foo.Name)
+	require.Equal(t, int64(10), foo.LeftSelf)
+	require.Equal(t, int64(20), foo.RightSelf)
+	for _, fn := range resp.Msg.Functions {
+		require.NotEqual(t, "other", fn.Name)
+	}
+}
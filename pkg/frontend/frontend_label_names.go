@@ -2,11 +2,13 @@ package frontend
 
 import (
 	"context"
+	"sync"
 
 	"connectrpc.com/connect"
 	"github.com/grafana/dskit/tenant"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
@@ -41,5 +43,32 @@ func (f *Frontend) LabelNames(ctx context.Context, c *connect.Request[typesv1.La
 		c.Msg.End = int64(validated.End)
 	}
 
-	return connectgrpc.RoundTripUnary[typesv1.LabelNamesRequest, typesv1.LabelNamesResponse](ctx, f, c)
+	g, ctx := errgroup.WithContext(ctx)
+	var lock sync.Mutex
+	var names []string
+	if len(tenantIDs) > 1 {
+		names = append(names, phlaremodel.LabelNameTenantID)
+	}
+	for tenantID, matchers := range splitMatchers(tenantIDs, c.Msg.Matchers) {
+		tenantID, matchers := tenantID, matchers
+		g.Go(func() error {
+			ctx, req := tenantRequest(ctx, c, &typesv1.LabelNamesRequest{
+				Matchers: matchers,
+				Start:    c.Msg.Start,
+				End:      c.Msg.End,
+			}, tenantID)
+			resp, err := roundTripCached[typesv1.LabelNamesRequest, typesv1.LabelNamesResponse](ctx, f, req)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			names = append(names, resp.Msg.Names...)
+			lock.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&typesv1.LabelNamesResponse{Names: uniqueSortedStrings(names)}), nil
 }
@@ -2,11 +2,14 @@ package frontend
 
 import (
 	"context"
+	"sort"
+	"sync"
 
 	"connectrpc.com/connect"
 	"github.com/grafana/dskit/tenant"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
@@ -24,13 +27,13 @@ func (f *Frontend) LabelValues(ctx context.Context, c *connect.Request[typesv1.L
 
 	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceLabelValuesProcedure)
 
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
 	interval, ok := phlaremodel.GetTimeRange(c.Msg)
 	if ok {
-		tenantIDs, err := tenant.TenantIDs(ctx)
-		if err != nil {
-			return nil, connect.NewError(connect.CodeInvalidArgument, err)
-		}
-
 		validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, interval, model.Now())
 		if err != nil {
 			return nil, connect.NewError(connect.CodeInvalidArgument, err)
@@ -42,5 +45,39 @@ func (f *Frontend) LabelValues(ctx context.Context, c *connect.Request[typesv1.L
 		c.Msg.End = int64(validated.End)
 	}
 
-	return connectgrpc.RoundTripUnary[typesv1.LabelValuesRequest, typesv1.LabelValuesResponse](ctx, f, c)
+	selectors := splitMatchers(tenantIDs, c.Msg.Matchers)
+	if c.Msg.Name == phlaremodel.LabelNameTenantID {
+		names := make([]string, 0, len(selectors))
+		for tenantID := range selectors {
+			names = append(names, tenantID)
+		}
+		sort.Strings(names)
+		return connect.NewResponse(&typesv1.LabelValuesResponse{Names: names}), nil
+	}
+	g, ctx := errgroup.WithContext(ctx)
+	var lock sync.Mutex
+	var names []string
+	for tenantID, matchers := range selectors {
+		tenantID, matchers := tenantID, matchers
+		g.Go(func() error {
+			ctx, req := tenantRequest(ctx, c, &typesv1.LabelValuesRequest{
+				Name:     c.Msg.Name,
+				Matchers: matchers,
+				Start:    c.Msg.Start,
+				End:      c.Msg.End,
+			}, tenantID)
+			resp, err := roundTripCached[typesv1.LabelValuesRequest, typesv1.LabelValuesResponse](ctx, f, req)
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			names = append(names, resp.Msg.Names...)
+			lock.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&typesv1.LabelValuesResponse{Names: uniqueSortedStrings(names)}), nil
 }
@@ -0,0 +1,135 @@
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+// estimateQuery sums the estimated costs of the query intervals, as
+// reported by the queriers for the dry-run requests of each tenant. The
+// estimate is only computed for dry-run requests, or if the query cost is
+// limited: nil is returned otherwise. The query is rejected if it exceeds
+// the limit.
+//
+// The plans of the dry-run requests are returned along with the estimate:
+// the sub-requests of the query reuse them, so that the blocks to query
+// are only selected once.
+//
+// A query may read several selections, e.g., the two sides of a diff: the
+// limit applies to their total cost.
+func (f *Frontend) estimateQuery(
+	ctx context.Context,
+	tenantIDs []string,
+	dryRun bool,
+	intervals *TimeIntervalIterator,
+	selections []querySelection,
+	estimate func(ctx context.Context, tenantID, selector string, r TimeInterval) (*querierv1.QueryEstimate, *querierv1.QueryPlan, error),
+) (*querierv1.QueryEstimate, *queryPlans, error) {
+	if !dryRun && validation.MaxQueryEstimatedBytes(f.limits, tenantIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
+		g.SetLimit(maxConcurrent)
+	}
+
+	var (
+		lock  sync.Mutex
+		total querierv1.QueryEstimate
+		plans = &queryPlans{plans: make(map[queryPlanKey]*querierv1.QueryPlan)}
+	)
+	for intervals.Next() {
+		r := intervals.At()
+		for _, s := range selections {
+			selector := s.selector
+			for _, tenantID := range s.tenantIDs {
+				tenantID := tenantID
+				g.Go(func() error {
+					e, p, err := estimate(ctx, tenantID, selector, r)
+					if err != nil {
+						return err
+					}
+					lock.Lock()
+					defer lock.Unlock()
+					phlaremodel.MergeQueryEstimates(&total, e)
+					if p != nil {
+						plans.plans[queryPlanKey{tenantID: tenantID, selector: selector, start: r.Start.UnixMilli()}] = p
+					}
+					return nil
+				})
+			}
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	if !dryRun {
+		if err := validation.ValidateQueryEstimate(f.limits, tenantIDs, total.Bytes); err != nil {
+			return nil, nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+	return &total, plans, nil
+}
+
+// checkQueryEstimate enforces the estimated cost limit on the queries that
+// are not planned by dry-run requests, such as the span profile, functions
+// and exemplars queries. They are estimated as the merge stacktraces queries
+// reading the same blocks.
+func (f *Frontend) checkQueryEstimate(
+	ctx context.Context,
+	header http.Header,
+	tenantIDs []string,
+	profileTypeID string,
+	labelSelector string,
+	validated model.Interval,
+) error {
+	tenantIDs, selector := splitSelector(tenantIDs, labelSelector)
+	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
+	_, _, err := f.estimateQuery(ctx, tenantIDs, false,
+		NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval),
+		[]querySelection{{tenantIDs: tenantIDs, selector: selector}},
+		f.mergeStacktracesEstimator(header, profileTypeID))
+	return err
+}
+
+// querySelection is a label selector read by a query, and the tenants
+// it applies to.
+type querySelection struct {
+	tenantIDs []string
+	selector  string
+}
+
+// queryPlans holds the plans of the query intervals, by tenant and
+// selector. The intervals of the query and of its dry-run are split
+// the same way.
+type queryPlans struct {
+	plans map[queryPlanKey]*querierv1.QueryPlan
+}
+
+type queryPlanKey struct {
+	tenantID string
+	selector string
+	start    int64
+}
+
+// get returns the plan of the tenant sub-request, or nil, if the queriers
+// have to plan it.
+func (p *queryPlans) get(tenantID, selector string, r TimeInterval) *querierv1.QueryPlan {
+	if p == nil {
+		return nil
+	}
+	return p.plans[queryPlanKey{tenantID: tenantID, selector: selector, start: r.Start.UnixMilli()}]
+}
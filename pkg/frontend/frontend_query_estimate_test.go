@@ -0,0 +1,224 @@
+package frontend
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/dskit/user"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/scheduler/schedulerpb"
+	"github.com/grafana/pyroscope/pkg/util/httpgrpc"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func TestFrontend_SelectMergeProfile_ReusesDryRunPlan(t *testing.T) {
+	const tenantID = "test"
+	plan := &querierv1.QueryPlan{Replicas: []*querierv1.QueryPlanReplica{
+		{Addr: "store-gateway-1", Ulids: []string{"01HB7FE0JXH1Z6Y0X1X1X1X1X1"}},
+	}}
+
+	var (
+		mu       sync.Mutex
+		dryRuns  int
+		profiles []*querierv1.QueryPlan
+	)
+	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
+		var body []byte
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(msg.HttpRequest.Url, querierv1connect.QuerierServiceSelectMergeStacktracesProcedure):
+			var req querierv1.SelectMergeStacktracesRequest
+			require.NoError(t, proto.Unmarshal(msg.HttpRequest.Body, &req))
+			require.True(t, req.DryRun)
+			dryRuns++
+			body, _ = proto.Marshal(&querierv1.SelectMergeStacktracesResponse{
+				Estimate: &querierv1.QueryEstimate{Blocks: 1, Bytes: 100},
+				Plan:     plan,
+			})
+		case strings.HasSuffix(msg.HttpRequest.Url, querierv1connect.QuerierServiceSelectMergeProfileProcedure):
+			var req querierv1.SelectMergeProfileRequest
+			require.NoError(t, proto.Unmarshal(msg.HttpRequest.Body, &req))
+			profiles = append(profiles, req.Plan)
+			body, _ = proto.Marshal(&profilev1.Profile{})
+		default:
+			t.Errorf("unexpected request %s", msg.HttpRequest.Url)
+		}
+		go sendResponseWithDelay(f, 10*time.Millisecond, tenantID, msg.QueryID, &httpgrpc.HTTPResponse{
+			Code: 200,
+			Body: body,
+		})
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_OK}
+	})
+
+	// The range is not split: it's within a single 2h-aligned interval.
+	start := time.Now().Add(-24 * time.Hour).Truncate(2 * time.Hour)
+	req := &querierv1.SelectMergeProfileRequest{
+		ProfileTypeID: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		LabelSelector: `{service_name="svc"}`,
+		Start:         start.UnixMilli(),
+		End:           start.Add(time.Hour).UnixMilli(),
+	}
+	_, ctx := opentracing.StartSpanFromContext(user.InjectOrgID(context.Background(), tenantID), "test")
+
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1, MaxQueryEstimatedBytesValue: 1000}
+	_, err := f.SelectMergeProfile(ctx, connect.NewRequest(proto.Clone(req).(*querierv1.SelectMergeProfileRequest)))
+	require.NoError(t, err)
+	require.Equal(t, 1, dryRuns)
+	require.Len(t, profiles, 1)
+	require.True(t, proto.Equal(plan, profiles[0]))
+
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1, MaxQueryEstimatedBytesValue: 10}
+	_, err = f.SelectMergeProfile(ctx, connect.NewRequest(proto.Clone(req).(*querierv1.SelectMergeProfileRequest)))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	require.Equal(t, 2, dryRuns)
+	require.Len(t, profiles, 1)
+
+	// Without the limit, the queriers plan the query.
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1}
+	_, err = f.SelectMergeProfile(ctx, connect.NewRequest(proto.Clone(req).(*querierv1.SelectMergeProfileRequest)))
+	require.NoError(t, err)
+	require.Equal(t, 2, dryRuns)
+	require.Len(t, profiles, 2)
+	require.Nil(t, profiles[1])
+
+	// A dry-run request returns the estimate in the response header,
+	// whether or not it exceeds the limit.
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1, MaxQueryEstimatedBytesValue: 10}
+	dryRun := proto.Clone(req).(*querierv1.SelectMergeProfileRequest)
+	dryRun.DryRun = true
+	resp, err := f.SelectMergeProfile(ctx, connect.NewRequest(dryRun))
+	require.NoError(t, err)
+	require.Equal(t, 3, dryRuns)
+	require.Len(t, profiles, 2)
+	require.Empty(t, resp.Msg.Sample)
+	estimate, err := phlaremodel.QueryEstimateFromHeader(resp)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), estimate.Blocks)
+	require.Equal(t, uint64(100), estimate.Bytes)
+}
+
+func TestFrontend_DiffByLabel_EstimatesBothSides(t *testing.T) {
+	const tenantID = "test"
+	var (
+		mu      sync.Mutex
+		dryRuns []string
+		plans   = make(map[string]*querierv1.QueryPlan)
+	)
+	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
+		var req querierv1.SelectMergeStacktracesRequest
+		require.NoError(t, proto.Unmarshal(msg.HttpRequest.Body, &req))
+		mu.Lock()
+		defer mu.Unlock()
+		var resp querierv1.SelectMergeStacktracesResponse
+		if req.DryRun {
+			dryRuns = append(dryRuns, req.LabelSelector)
+			resp.Estimate = &querierv1.QueryEstimate{Blocks: 1, Bytes: 100}
+			resp.Plan = &querierv1.QueryPlan{Replicas: []*querierv1.QueryPlanReplica{{Addr: req.LabelSelector}}}
+		} else {
+			plans[req.LabelSelector] = req.Plan
+			resp.Flamegraph = &querierv1.FlameGraph{}
+		}
+		body, _ := proto.Marshal(&resp)
+		go sendResponseWithDelay(f, 10*time.Millisecond, tenantID, msg.QueryID, &httpgrpc.HTTPResponse{
+			Code: 200,
+			Body: body,
+		})
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_OK}
+	})
+
+	start := time.Now().Add(-24 * time.Hour).Truncate(2 * time.Hour)
+	req := &querierv1.DiffByLabelRequest{
+		ProfileTypeID: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		LabelSelector: `{service_name="svc"}`,
+		LabelName:     "version",
+		LeftValue:     "1",
+		RightValue:    "2",
+		Start:         start.UnixMilli(),
+		End:           start.Add(time.Hour).UnixMilli(),
+	}
+	ctx := user.InjectOrgID(context.Background(), tenantID)
+
+	// The sub-requests reuse the plans of their side.
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1, MaxQueryEstimatedBytesValue: 1000}
+	_, err := f.DiffByLabel(ctx, connect.NewRequest(proto.Clone(req).(*querierv1.DiffByLabelRequest)))
+	require.NoError(t, err)
+	require.Len(t, dryRuns, 2)
+	require.Len(t, plans, 2)
+	for selector, plan := range plans {
+		require.Equal(t, selector, plan.Replicas[0].Addr)
+	}
+
+	// The limit applies to the total of both sides.
+	plans = make(map[string]*querierv1.QueryPlan)
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1, MaxQueryEstimatedBytesValue: 150}
+	_, err = f.DiffByLabel(ctx, connect.NewRequest(proto.Clone(req).(*querierv1.DiffByLabelRequest)))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	require.Len(t, dryRuns, 4)
+	require.Empty(t, plans)
+}
+
+func TestFrontend_QueryEstimateLimit(t *testing.T) {
+	const tenantID = "test"
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasSuffix(msg.HttpRequest.Url, querierv1connect.QuerierServiceSelectMergeStacktracesProcedure) {
+			queries = append(queries, msg.HttpRequest.Url)
+			return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_ERROR}
+		}
+		body, _ := proto.Marshal(&querierv1.SelectMergeStacktracesResponse{
+			Estimate: &querierv1.QueryEstimate{Blocks: 1, Bytes: 100},
+		})
+		go sendResponseWithDelay(f, 10*time.Millisecond, tenantID, msg.QueryID, &httpgrpc.HTTPResponse{
+			Code: 200,
+			Body: body,
+		})
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_OK}
+	})
+	f.limits = validation.MockLimits{MaxQueryParallelismValue: 1, MaxQueryEstimatedBytesValue: 10}
+
+	start := time.Now().Add(-24 * time.Hour).Truncate(2 * time.Hour)
+	_, ctx := opentracing.StartSpanFromContext(user.InjectOrgID(context.Background(), tenantID), "test")
+	const (
+		profileType = "process_cpu:cpu:nanoseconds:cpu:nanoseconds"
+		selector    = `{service_name="svc"}`
+	)
+	_, err := f.SelectMergeSpanProfile(ctx, connect.NewRequest(&querierv1.SelectMergeSpanProfileRequest{
+		ProfileTypeID: profileType,
+		LabelSelector: selector,
+		Start:         start.UnixMilli(),
+		End:           start.Add(time.Hour).UnixMilli(),
+	}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	_, err = f.SelectFunctions(ctx, connect.NewRequest(&querierv1.SelectFunctionsRequest{
+		ProfileTypeID: profileType,
+		LabelSelector: selector,
+		Start:         start.UnixMilli(),
+		End:           start.Add(time.Hour).UnixMilli(),
+	}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	_, err = f.SelectExemplars(ctx, connect.NewRequest(&querierv1.SelectExemplarsRequest{
+		ProfileTypeID: profileType,
+		LabelSelector: selector,
+		Start:         start.UnixMilli(),
+		End:           start.Add(time.Hour).UnixMilli(),
+	}))
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	require.Empty(t, queries)
+}
@@ -0,0 +1,356 @@
+package frontend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/dskit/user"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"google.golang.org/protobuf/proto"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/objstore"
+	phlarebucket "github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+)
+
+type ResultsCacheConfig struct {
+	Enabled      bool          `yaml:"enabled" category:"experimental"`
+	MaxSizeBytes int64         `yaml:"max_size_bytes" category:"experimental"`
+	TTL          time.Duration `yaml:"ttl" category:"experimental"`
+}
+
+func (cfg *ResultsCacheConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, prefix+"enabled", false, "Cache the results of the split queries. Only the results of time ranges older than -querier.query-store-after are cached.")
+	f.Int64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", 256<<20, "Maximum size of the in-memory results cache, in bytes.")
+	f.DurationVar(&cfg.TTL, prefix+"ttl", 24*time.Hour, "TTL of the cached query results. The results expire earlier if the queried profiles may be removed by the retention of the tenant.")
+}
+
+// ResultsCacheBackend stores the encoded query results.
+type ResultsCacheBackend interface {
+	Fetch(ctx context.Context, key string) ([]byte, bool)
+	Store(ctx context.Context, key string, value []byte, ttl time.Duration)
+}
+
+// The query results are cached per tenant, query, and split interval:
+// the queried time range is aligned to the split interval, therefore
+// queries of the same window share most of the sub-queries.
+//
+// The cache is not consulted if the request has the Cache-Control
+// header with the no-cache directive; the no-store directive also
+// prevents the results from being stored.
+//
+// The delete requests of the tenant overlapping the query time range are
+// part of the cache key: the results cached before a request is created
+// or cancelled are not used. The results are not cached until the delete
+// request has been applied by ingesters and store-gateways. Profiles
+// removed by the retention of the tenant are handled by expiring the
+// results before the queried profiles can be removed.
+type resultsCache struct {
+	backend         ResultsCacheBackend
+	ttl             time.Duration
+	queryStoreAfter time.Duration
+	limits          Limits
+
+	// bucket is nil if the storage is not configured:
+	// delete requests can't be created in that case.
+	bucket       objstore.Bucket
+	tombstonesMu sync.Mutex
+	tombstones   map[string]*phlarebucket.TombstonesLoader
+
+	requests *prometheus.CounterVec
+	hits     *prometheus.CounterVec
+}
+
+func newResultsCache(cfg Config, limits Limits, bucket objstore.Bucket, reg prometheus.Registerer) *resultsCache {
+	if !cfg.ResultsCache.Enabled {
+		return nil
+	}
+	return &resultsCache{
+		backend:         newLRUResultsCache(cfg.ResultsCache.MaxSizeBytes),
+		ttl:             cfg.ResultsCache.TTL,
+		queryStoreAfter: cfg.QueryStoreAfter,
+		limits:          limits,
+		bucket:          bucket,
+		tombstones:      make(map[string]*phlarebucket.TombstonesLoader),
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_query_frontend_results_cache_requests_total",
+			Help: "Total number of requests to the query results cache.",
+		}, []string{"method"}),
+		hits: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_query_frontend_results_cache_hits_total",
+			Help: "Total number of requests to the query results cache that were a hit.",
+		}, []string{"method"}),
+	}
+}
+
+// roundTripCached round trips the request, unless the response is found
+// in the results cache. The request must be issued on behalf of a single
+// tenant.
+func roundTripCached[Req any, Res any](ctx context.Context, f *Frontend, req *connect.Request[Req]) (*connect.Response[Res], error) {
+	c := f.resultsCache
+	if c == nil {
+		return connectgrpc.RoundTripUnary[Req, Res](ctx, f, req)
+	}
+	msg, ok := any(req.Msg).(proto.Message)
+	if !ok || !c.cacheable(msg) {
+		return connectgrpc.RoundTripUnary[Req, Res](ctx, f, req)
+	}
+	tenantID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	ttl, tombstones, err := c.entryTTL(ctx, tenantID, msg)
+	if err != nil || ttl <= 0 {
+		return connectgrpc.RoundTripUnary[Req, Res](ctx, f, req)
+	}
+	procedure := connectgrpc.ProcedureFromContext(ctx)
+	key := resultsCacheKey(tenantID, procedure, msg, tombstones)
+	noCache, noStore := cacheControl(req.Header())
+	method := procedure[strings.LastIndexByte(procedure, '/')+1:]
+
+	if !noCache {
+		c.requests.WithLabelValues(method).Inc()
+		if b, ok := c.backend.Fetch(ctx, key); ok {
+			var resp Res
+			if err = proto.Unmarshal(b, any(&resp).(proto.Message)); err == nil {
+				c.hits.WithLabelValues(method).Inc()
+				return connect.NewResponse(&resp), nil
+			}
+		}
+	}
+
+	resp, err := connectgrpc.RoundTripUnary[Req, Res](ctx, f, req)
+	if err != nil || noStore {
+		return resp, err
+	}
+	if b, err := proto.Marshal(any(resp.Msg).(proto.Message)); err == nil {
+		c.backend.Store(ctx, key, b, ttl)
+	}
+	return resp, nil
+}
+
+// cacheable reports whether the query results can be cached: the time
+// range must not overlap the ingesters lookback window, as the data may
+// still change. If the ingesters are queried regardless of the time range,
+// the results are never cached.
+func (c *resultsCache) cacheable(msg proto.Message) bool {
+	if c.queryStoreAfter <= 0 {
+		return false
+	}
+	_, end, ok := queryTimeRange(msg)
+	if !ok {
+		return false
+	}
+	if d, ok := msg.(interface{ GetDryRun() bool }); ok && d.GetDryRun() {
+		return false
+	}
+	return end.Before(time.Now().Add(-c.queryStoreAfter))
+}
+
+func queryTimeRange(msg proto.Message) (start, end time.Time, ok bool) {
+	r, ok := msg.(interface {
+		GetStart() int64
+		GetEnd() int64
+	})
+	if !ok || r.GetStart() == 0 || r.GetEnd() == 0 {
+		return start, end, false
+	}
+	return time.UnixMilli(r.GetStart()), time.UnixMilli(r.GetEnd()), true
+}
+
+// entryTTL returns the TTL of the query results, and the IDs of the delete
+// requests overlapping the query time range. The results must not be cached
+// if the TTL is not positive.
+//
+// The retention of the tenant removes the profiles of blocks entirely older
+// than the retention period: any block holding profiles of the query time
+// range ends after the range start, therefore the results expire when the
+// range start exceeds the shortest retention period.
+func (c *resultsCache) entryTTL(ctx context.Context, tenantID string, msg proto.Message) (time.Duration, []string, error) {
+	start, end, _ := queryTimeRange(msg)
+	now := time.Now()
+	ttl := c.ttl
+	if p := c.retentionPeriod(tenantID); p > 0 {
+		if d := start.Add(p).Sub(now); d < ttl {
+			ttl = d
+		}
+	}
+	if ttl <= 0 {
+		return 0, nil, nil
+	}
+	tombstones, err := c.loadTombstones(ctx, tenantID)
+	if err != nil {
+		return 0, nil, err
+	}
+	var ids []string
+	for _, t := range tombstones.Overlapping(model.TimeFromUnixNano(start.UnixNano()), model.TimeFromUnixNano(end.UnixNano())) {
+		// The delete request may not have been applied by all the
+		// ingesters and store-gateways yet.
+		if now.Sub(time.UnixMilli(t.CreatedAt)) < 2*phlarebucket.TombstonesRefreshInterval {
+			return 0, nil, nil
+		}
+		ids = append(ids, t.RequestID)
+	}
+	sort.Strings(ids)
+	return ttl, ids, nil
+}
+
+// retentionPeriod returns the shortest retention period of the tenant
+// profiles, or 0 if the profiles are retained indefinitely.
+func (c *resultsCache) retentionPeriod(tenantID string) time.Duration {
+	p := c.limits.CompactorBlocksRetentionPeriod(tenantID)
+	for _, s := range c.limits.CompactorRetentionStreams(tenantID) {
+		if d := time.Duration(s.Period); d > 0 && (p <= 0 || d < p) {
+			p = d
+		}
+	}
+	return p
+}
+
+func (c *resultsCache) loadTombstones(ctx context.Context, tenantID string) (phlarebucket.Tombstones, error) {
+	if c.bucket == nil {
+		return nil, nil
+	}
+	c.tombstonesMu.Lock()
+	l, ok := c.tombstones[tenantID]
+	if !ok {
+		l = phlarebucket.NewTombstonesLoader(
+			objstore.NewTenantBucketClient(tenantID, c.bucket, nil),
+			phlarebucket.TombstonesRefreshInterval,
+		)
+		c.tombstones[tenantID] = l
+	}
+	c.tombstonesMu.Unlock()
+	return l.Load(ctx)
+}
+
+func cacheControl(h map[string][]string) (noCache, noStore bool) {
+	for _, v := range h["Cache-Control"] {
+		for _, d := range strings.Split(v, ",") {
+			switch strings.ToLower(strings.TrimSpace(d)) {
+			case "no-cache":
+				noCache = true
+			case "no-store":
+				noCache, noStore = true, true
+			}
+		}
+	}
+	return noCache, noStore
+}
+
+// resultsCacheKey returns the cache key of the query. Label selectors are
+// normalized, so that equivalent queries share the key. The plan of the
+// query does not change the results, unlike the delete requests.
+func resultsCacheKey(tenantID, procedure string, msg proto.Message, tombstones []string) string {
+	msg = proto.Clone(msg)
+	switch m := msg.(type) {
+	case *querierv1.SelectMergeStacktracesRequest:
+		m.LabelSelector = normalizeSelector(m.LabelSelector)
+		m.Plan = nil
+	case *querierv1.SelectSeriesRequest:
+		m.LabelSelector = normalizeSelector(m.LabelSelector)
+		m.Plan = nil
+	case *typesv1.LabelNamesRequest:
+		m.Matchers = normalizeSelectors(m.Matchers)
+	case *typesv1.LabelValuesRequest:
+		m.Matchers = normalizeSelectors(m.Matchers)
+	}
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	h := sha256.New()
+	h.Write([]byte(tenantID))
+	h.Write([]byte{0})
+	h.Write([]byte(procedure))
+	h.Write([]byte{0})
+	h.Write(b)
+	for _, id := range tombstones {
+		h.Write([]byte{0})
+		h.Write([]byte(id))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func normalizeSelector(s string) string {
+	matchers, err := parser.ParseMetricSelector(s)
+	if err != nil {
+		return s
+	}
+	sort.Slice(matchers, func(i, j int) bool {
+		return matchers[i].String() < matchers[j].String()
+	})
+	v := parser.VectorSelector{LabelMatchers: matchers}
+	return v.String()
+}
+
+func normalizeSelectors(s []string) []string {
+	n := make([]string, len(s))
+	for i := range s {
+		n[i] = normalizeSelector(s[i])
+	}
+	sort.Strings(n)
+	return n
+}
+
+// lruResultsCache is an in-process LRU cache limited
+// by the total size of the items.
+type lruResultsCache struct {
+	maxSize int64
+
+	mu   sync.Mutex
+	lru  *simplelru.LRU[string, resultsCacheItem]
+	size int64
+}
+
+type resultsCacheItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func newLRUResultsCache(maxSize int64) *lruResultsCache {
+	c := &lruResultsCache{maxSize: maxSize}
+	c.lru, _ = simplelru.NewLRU[string, resultsCacheItem](math.MaxInt32, func(k string, v resultsCacheItem) {
+		c.size -= int64(len(k) + len(v.value))
+	})
+	return c
+}
+
+func (c *lruResultsCache) Fetch(_ context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.lru.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(v.expiresAt) {
+		c.lru.Remove(key)
+		return nil, false
+	}
+	return v.value, true
+}
+
+func (c *lruResultsCache) Store(_ context.Context, key string, value []byte, ttl time.Duration) {
+	s := int64(len(key) + len(value))
+	if s > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Add(key, resultsCacheItem{value: value, expiresAt: time.Now().Add(ttl)})
+	c.size += s
+	for c.size > c.maxSize {
+		c.lru.RemoveOldest()
+	}
+}
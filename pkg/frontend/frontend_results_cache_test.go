@@ -0,0 +1,236 @@
+package frontend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	thanosobjstore "github.com/thanos-io/objstore"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/objstore"
+	phlarebucket "github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+	"github.com/grafana/pyroscope/pkg/scheduler/schedulerpb"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+	"github.com/grafana/pyroscope/pkg/util/httpgrpc"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+func Test_resultsCacheKey(t *testing.T) {
+	const procedure = querierv1connect.QuerierServiceSelectSeriesProcedure
+	req := func(selector string, start int64) *querierv1.SelectSeriesRequest {
+		return &querierv1.SelectSeriesRequest{
+			ProfileTypeID: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+			LabelSelector: selector,
+			Start:         start,
+			End:           start + 1000,
+			Step:          15,
+		}
+	}
+	a := resultsCacheKey("tenant", procedure, req(`{service_name="a", namespace="b"}`, 0), nil)
+	require.Equal(t, a, resultsCacheKey("tenant", procedure, req(`{namespace="b",service_name="a"}`, 0), nil))
+	require.NotEqual(t, a, resultsCacheKey("other", procedure, req(`{service_name="a", namespace="b"}`, 0), nil))
+	require.NotEqual(t, a, resultsCacheKey("tenant", procedure, req(`{service_name="a"}`, 0), nil))
+	require.NotEqual(t, a, resultsCacheKey("tenant", procedure, req(`{service_name="a", namespace="b"}`, 1000), nil))
+	require.NotEqual(t, a, resultsCacheKey("tenant", procedure, req(`{service_name="a", namespace="b"}`, 0), []string{"request"}))
+}
+
+func Test_resultsCache_cacheable(t *testing.T) {
+	c := &resultsCache{queryStoreAfter: time.Hour}
+	now := time.Now()
+	for _, tc := range []struct {
+		name      string
+		msg       proto.Message
+		cacheable bool
+	}{
+		{
+			name: "historic",
+			msg: &querierv1.SelectMergeStacktracesRequest{
+				Start: now.Add(-3 * time.Hour).UnixMilli(),
+				End:   now.Add(-2 * time.Hour).UnixMilli(),
+			},
+			cacheable: true,
+		},
+		{
+			name: "within query store after",
+			msg: &querierv1.SelectMergeStacktracesRequest{
+				Start: now.Add(-2 * time.Hour).UnixMilli(),
+				End:   now.Add(-time.Minute).UnixMilli(),
+			},
+		},
+		{
+			name: "dry run",
+			msg: &querierv1.SelectMergeStacktracesRequest{
+				Start:  now.Add(-3 * time.Hour).UnixMilli(),
+				End:    now.Add(-2 * time.Hour).UnixMilli(),
+				DryRun: true,
+			},
+		},
+		{
+			name: "no time range",
+			msg:  &typesv1.LabelNamesRequest{},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.cacheable, c.cacheable(tc.msg))
+		})
+	}
+
+	// The ingesters are queried regardless of the time range.
+	c = &resultsCache{queryStoreAfter: 0}
+	require.False(t, c.cacheable(&querierv1.SelectMergeStacktracesRequest{
+		Start: now.Add(-3 * time.Hour).UnixMilli(),
+		End:   now.Add(-2 * time.Hour).UnixMilli(),
+	}))
+}
+
+func Test_resultsCache_entryTTL(t *testing.T) {
+	const tenantID = "test"
+	ctx := context.Background()
+	bkt := objstore.NewBucket(thanosobjstore.NewInMemBucket())
+	// The tombstones are loaded once per refresh interval.
+	newCache := func() *resultsCache {
+		return newResultsCache(Config{
+			ResultsCache:    ResultsCacheConfig{Enabled: true, MaxSizeBytes: 1 << 20, TTL: 24 * time.Hour},
+			QueryStoreAfter: time.Hour,
+		}, validation.MockLimits{}, bkt, prometheus.NewRegistry())
+	}
+	c := newCache()
+
+	now := time.Now()
+	req := func(start time.Time) *querierv1.SelectMergeStacktracesRequest {
+		return &querierv1.SelectMergeStacktracesRequest{
+			Start: start.UnixMilli(),
+			End:   start.Add(time.Hour).UnixMilli(),
+		}
+	}
+	ttl, tombstones, err := c.entryTTL(ctx, tenantID, req(now.Add(-48*time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, 24*time.Hour, ttl)
+	require.Empty(t, tombstones)
+
+	// The results expire before the profiles can be removed by the retention.
+	c.limits = validation.MockLimits{
+		BlocksRetentionPeriodValue: 30 * 24 * time.Hour,
+		RetentionStreamsValue: []validation.RetentionStream{
+			{Selector: `{env="dev"}`, Period: model.Duration(72 * time.Hour)},
+		},
+	}
+	ttl, _, err = c.entryTTL(ctx, tenantID, req(now.Add(-60*time.Hour)))
+	require.NoError(t, err)
+	require.InDelta(t, 12*time.Hour, ttl, float64(time.Minute))
+	ttl, _, err = c.entryTTL(ctx, tenantID, req(now.Add(-80*time.Hour)))
+	require.NoError(t, err)
+	require.LessOrEqual(t, ttl, time.Duration(0))
+
+	// The results are not cached until the delete request is applied.
+	c = newCache()
+	userBucket := objstore.NewTenantBucketClient(tenantID, bkt, nil)
+	recent, err := phlarebucket.NewTombstone(`{service_name="a"}`, now.Add(-49*time.Hour).UnixMilli(), now.Add(-47*time.Hour).UnixMilli(), now)
+	require.NoError(t, err)
+	require.NoError(t, phlarebucket.WriteTombstone(ctx, userBucket, recent))
+	ttl, _, err = c.entryTTL(ctx, tenantID, req(now.Add(-48*time.Hour)))
+	require.NoError(t, err)
+	require.LessOrEqual(t, ttl, time.Duration(0))
+
+	// Afterwards, the delete requests overlapping the query are part of the key.
+	c = newCache()
+	require.NoError(t, phlarebucket.DeleteTombstone(ctx, userBucket, recent.RequestID))
+	applied, err := phlarebucket.NewTombstone(`{service_name="a"}`, now.Add(-49*time.Hour).UnixMilli(), now.Add(-47*time.Hour).UnixMilli(), now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, phlarebucket.WriteTombstone(ctx, userBucket, applied))
+	ttl, tombstones, err = c.entryTTL(ctx, tenantID, req(now.Add(-48*time.Hour)))
+	require.NoError(t, err)
+	require.Equal(t, 24*time.Hour, ttl)
+	require.Equal(t, []string{applied.RequestID}, tombstones)
+	_, tombstones, err = c.entryTTL(ctx, tenantID, req(now.Add(-24*time.Hour)))
+	require.NoError(t, err)
+	require.Empty(t, tombstones)
+}
+
+func Test_cacheControl(t *testing.T) {
+	noCache, noStore := cacheControl(map[string][]string{"Cache-Control": {"max-age=0, No-Cache"}})
+	require.True(t, noCache)
+	require.False(t, noStore)
+	noCache, noStore = cacheControl(map[string][]string{"Cache-Control": {"no-store"}})
+	require.True(t, noCache)
+	require.True(t, noStore)
+	noCache, noStore = cacheControl(nil)
+	require.False(t, noCache)
+	require.False(t, noStore)
+}
+
+func Test_lruResultsCache(t *testing.T) {
+	ctx := context.Background()
+	c := newLRUResultsCache(10)
+	c.Store(ctx, "a", []byte("1234"), time.Hour)
+	c.Store(ctx, "b", []byte("1234"), time.Hour)
+	_, ok := c.Fetch(ctx, "a")
+	require.True(t, ok)
+	c.Store(ctx, "c", []byte("1234"), time.Hour)
+	_, ok = c.Fetch(ctx, "b")
+	require.False(t, ok)
+	v, ok := c.Fetch(ctx, "a")
+	require.True(t, ok)
+	require.Equal(t, []byte("1234"), v)
+	require.Equal(t, int64(10), c.size)
+
+	c.Store(ctx, "d", []byte("1"), -time.Second)
+	_, ok = c.Fetch(ctx, "d")
+	require.False(t, ok)
+}
+
+func TestFrontend_resultsCache(t *testing.T) {
+	const tenantID = "test"
+	var queries atomic.Int64
+	f, _ := setupFrontend(t, nil, func(f *Frontend, msg *schedulerpb.FrontendToScheduler) *schedulerpb.SchedulerToFrontend {
+		queries.Inc()
+		body, _ := proto.Marshal(&typesv1.LabelNamesResponse{Names: []string{"foo"}})
+		go sendResponseWithDelay(f, 10*time.Millisecond, tenantID, msg.QueryID, &httpgrpc.HTTPResponse{
+			Code: 200,
+			Body: body,
+		})
+		return &schedulerpb.SchedulerToFrontend{Status: schedulerpb.SchedulerToFrontendStatus_OK}
+	})
+	f.resultsCache = newResultsCache(Config{
+		ResultsCache:    ResultsCacheConfig{Enabled: true, MaxSizeBytes: 1 << 20, TTL: time.Hour},
+		QueryStoreAfter: time.Hour,
+	}, f.limits, nil, prometheus.NewRegistry())
+
+	ctx := user.InjectOrgID(context.Background(), tenantID)
+	ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceLabelNamesProcedure)
+	query := func(start time.Time, cacheControl string) {
+		req := connect.NewRequest(&typesv1.LabelNamesRequest{
+			Start: start.UnixMilli(),
+			End:   start.Add(time.Hour).UnixMilli(),
+		})
+		if cacheControl != "" {
+			req.Header().Set("Cache-Control", cacheControl)
+		}
+		resp, err := roundTripCached[typesv1.LabelNamesRequest, typesv1.LabelNamesResponse](ctx, f, req)
+		require.NoError(t, err)
+		require.Equal(t, []string{"foo"}, resp.Msg.Names)
+	}
+
+	historic := time.Now().Add(-24 * time.Hour)
+	query(historic, "")
+	query(historic, "")
+	require.Equal(t, int64(1), queries.Load())
+	query(historic, "no-cache")
+	require.Equal(t, int64(2), queries.Load())
+
+	recent := time.Now().Add(-30 * time.Minute)
+	query(recent, "")
+	query(recent, "")
+	require.Equal(t, int64(4), queries.Load())
+}
@@ -14,6 +14,7 @@ import (
 	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/pprof"
 	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
 	validationutil "github.com/grafana/pyroscope/pkg/util/validation"
@@ -33,6 +34,10 @@ func (f *Frontend) SelectMergeProfile(ctx context.Context, c *connect.Request[qu
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+	tenantIDs, selector := splitSelector(tenantIDs, c.Msg.LabelSelector)
+	if len(tenantIDs) == 0 {
+		return connect.NewResponse(&profilev1.Profile{}), nil
+	}
 	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
@@ -43,41 +48,59 @@ func (f *Frontend) SelectMergeProfile(ctx context.Context, c *connect.Request[qu
 	c.Msg.Start = int64(validated.Start)
 	c.Msg.End = int64(validated.End)
 
+	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
+	estimate, plans, err := f.estimateQuery(ctx, tenantIDs, c.Msg.DryRun,
+		NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval),
+		[]querySelection{{tenantIDs: tenantIDs, selector: selector}},
+		f.mergeStacktracesEstimator(c.Header(), c.Msg.ProfileTypeID))
+	if err != nil {
+		return nil, err
+	}
+	if c.Msg.DryRun {
+		resp := connect.NewResponse(&profilev1.Profile{})
+		if err = phlaremodel.SetQueryEstimateHeader(resp, estimate); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
 		g.SetLimit(maxConcurrent)
 	}
 
-	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
-	intervals := NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval)
-
 	// NOTE: Max nodes limit is not set by default:
 	//   the method is used for pprof export and
 	//   truncation is not applicable for that.
 
 	var lock sync.Mutex
 	var m pprof.ProfileMerge
-	for intervals.Next() {
-		r := intervals.At()
-		g.Go(func() error {
-			req := connectgrpc.CloneRequest(c, &querierv1.SelectMergeProfileRequest{
-				ProfileTypeID:      c.Msg.ProfileTypeID,
-				LabelSelector:      c.Msg.LabelSelector,
-				Start:              r.Start.UnixMilli(),
-				End:                r.End.UnixMilli(),
-				MaxNodes:           c.Msg.MaxNodes,
-				StackTraceSelector: c.Msg.StackTraceSelector,
+	for _, tenantID := range tenantIDs {
+		tenantID := tenantID
+		intervals := NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval)
+		for intervals.Next() {
+			r := intervals.At()
+			g.Go(func() error {
+				ctx, req := tenantRequest(ctx, c, &querierv1.SelectMergeProfileRequest{
+					ProfileTypeID:      c.Msg.ProfileTypeID,
+					LabelSelector:      selector,
+					Start:              r.Start.UnixMilli(),
+					End:                r.End.UnixMilli(),
+					MaxNodes:           c.Msg.MaxNodes,
+					StackTraceSelector: c.Msg.StackTraceSelector,
+					Plan:               plans.get(tenantID, selector, r),
+				}, tenantID)
+				resp, err := connectgrpc.RoundTripUnary[
+					querierv1.SelectMergeProfileRequest,
+					profilev1.Profile](ctx, f, req)
+				if err != nil {
+					return err
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				return m.Merge(resp.Msg)
 			})
-			resp, err := connectgrpc.RoundTripUnary[
-				querierv1.SelectMergeProfileRequest,
-				profilev1.Profile](ctx, f, req)
-			if err != nil {
-				return err
-			}
-			lock.Lock()
-			defer lock.Unlock()
-			return m.Merge(resp.Msg)
-		})
+		}
 	}
 
 	if err = g.Wait(); err != nil {
@@ -46,6 +46,9 @@ func (f *Frontend) SelectMergeSpanProfile(ctx context.Context,
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+	if err = f.checkQueryEstimate(ctx, c.Header(), tenantIDs, c.Msg.ProfileTypeID, c.Msg.LabelSelector, validated.Interval); err != nil {
+		return nil, err
+	}
 
 	g, ctx := errgroup.WithContext(ctx)
 	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
@@ -66,6 +69,7 @@ func (f *Frontend) SelectMergeSpanProfile(ctx context.Context,
 				End:           r.End.UnixMilli(),
 				MaxNodes:      &maxNodes,
 				SpanSelector:  c.Msg.SpanSelector,
+				TraceSelector: c.Msg.TraceSelector,
 			})
 			resp, err := connectgrpc.RoundTripUnary[
 				querierv1.SelectMergeSpanProfileRequest,
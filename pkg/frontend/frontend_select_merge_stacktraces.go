@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"connectrpc.com/connect"
@@ -34,6 +35,10 @@ func (f *Frontend) SelectMergeStacktraces(ctx context.Context,
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+	tenantIDs, selector := splitSelector(tenantIDs, c.Msg.LabelSelector)
+	if len(tenantIDs) == 0 {
+		return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{}), nil
+	}
 
 	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
 	if err != nil {
@@ -47,42 +52,99 @@ func (f *Frontend) SelectMergeStacktraces(ctx context.Context,
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
 
+	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
+	estimate, plans, err := f.estimateQuery(ctx, tenantIDs, c.Msg.DryRun,
+		NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval),
+		[]querySelection{{tenantIDs: tenantIDs, selector: selector}},
+		f.mergeStacktracesEstimator(c.Header(), c.Msg.ProfileTypeID))
+	if err != nil {
+		return nil, err
+	}
+	if c.Msg.DryRun {
+		return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{Estimate: estimate}), nil
+	}
+
+	t, err := f.selectMergeTree(ctx, c, tenantIDs, selector, validated.Interval, maxNodes, plans)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{
+		Flamegraph: phlaremodel.NewFlameGraph(t, c.Msg.GetMaxNodes()),
+	}), nil
+}
+
+// mergeStacktracesEstimator returns the estimate function sending the
+// dry-run requests of the tenant intervals. The queries reading the same
+// blocks as the merge stacktraces ones, such as the merge profile, diff,
+// span profile, functions and exemplars queries, are estimated and
+// planned the same way.
+func (f *Frontend) mergeStacktracesEstimator(header http.Header, profileTypeID string) func(
+	ctx context.Context, tenantID, selector string, r TimeInterval,
+) (*querierv1.QueryEstimate, *querierv1.QueryPlan, error) {
+	return func(ctx context.Context, tenantID, selector string, r TimeInterval) (*querierv1.QueryEstimate, *querierv1.QueryPlan, error) {
+		base := connect.NewRequest(&querierv1.SelectMergeStacktracesRequest{})
+		for k, v := range header {
+			base.Header()[k] = v
+		}
+		ctx, req := tenantRequest(ctx, base, &querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID: profileTypeID,
+			LabelSelector: selector,
+			Start:         r.Start.UnixMilli(),
+			End:           r.End.UnixMilli(),
+			DryRun:        true,
+		}, tenantID)
+		ctx = connectgrpc.WithProcedure(ctx, querierv1connect.QuerierServiceSelectMergeStacktracesProcedure)
+		resp, err := connectgrpc.RoundTripUnary[
+			querierv1.SelectMergeStacktracesRequest,
+			querierv1.SelectMergeStacktracesResponse](ctx, f, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Msg.Estimate, resp.Msg.Plan, nil
+	}
+}
+
+// selectMergeTree merges the flame graphs of the split sub-requests into a
+// tree. The sub-request flame graphs are truncated to maxNodes, if positive.
+func (f *Frontend) selectMergeTree(ctx context.Context,
+	c *connect.Request[querierv1.SelectMergeStacktracesRequest],
+	tenantIDs []string, selector string, validated model.Interval, maxNodes int64, plans *queryPlans,
+) (*phlaremodel.Tree, error) {
 	g, ctx := errgroup.WithContext(ctx)
 	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
 		g.SetLimit(maxConcurrent)
 	}
 
-	m := phlaremodel.NewFlameGraphMerger()
 	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
-	intervals := NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval)
-
-	for intervals.Next() {
-		r := intervals.At()
-		g.Go(func() error {
-			req := connectgrpc.CloneRequest(c, &querierv1.SelectMergeStacktracesRequest{
-				ProfileTypeID: c.Msg.ProfileTypeID,
-				LabelSelector: c.Msg.LabelSelector,
-				Start:         r.Start.UnixMilli(),
-				End:           r.End.UnixMilli(),
-				MaxNodes:      &maxNodes,
+	m := phlaremodel.NewFlameGraphMerger()
+	for _, tenantID := range tenantIDs {
+		tenantID := tenantID
+		intervals := NewTimeIntervalIterator(time.UnixMilli(int64(validated.Start)), time.UnixMilli(int64(validated.End)), interval)
+		for intervals.Next() {
+			r := intervals.At()
+			g.Go(func() error {
+				ctx, req := tenantRequest(ctx, c, &querierv1.SelectMergeStacktracesRequest{
+					ProfileTypeID: c.Msg.ProfileTypeID,
+					LabelSelector: selector,
+					Start:         r.Start.UnixMilli(),
+					End:           r.End.UnixMilli(),
+					MaxNodes:      &maxNodes,
+					Plan:          plans.get(tenantID, selector, r),
+				}, tenantID)
+				resp, err := roundTripCached[
+					querierv1.SelectMergeStacktracesRequest,
+					querierv1.SelectMergeStacktracesResponse](ctx, f, req)
+				if err != nil {
+					return err
+				}
+				m.MergeFlameGraph(resp.Msg.Flamegraph)
+				return nil
 			})
-			resp, err := connectgrpc.RoundTripUnary[
-				querierv1.SelectMergeStacktracesRequest,
-				querierv1.SelectMergeStacktracesResponse](ctx, f, req)
-			if err != nil {
-				return err
-			}
-			m.MergeFlameGraph(resp.Msg.Flamegraph)
-			return nil
-		})
+		}
 	}
 
-	if err = g.Wait(); err != nil {
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
-
-	t := m.Tree()
-	return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{
-		Flamegraph: phlaremodel.NewFlameGraph(t, c.Msg.GetMaxNodes()),
-	}), nil
+	return m.Tree(), nil
 }
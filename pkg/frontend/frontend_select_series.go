@@ -35,6 +35,13 @@ func (f *Frontend) SelectSeries(ctx context.Context,
 	if err != nil {
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
 	}
+	// The series of queries spanning multiple tenants
+	// are labeled with the tenant they belong to.
+	federated := len(tenantIDs) > 1
+	tenantIDs, selector := splitSelector(tenantIDs, c.Msg.LabelSelector)
+	if len(tenantIDs) == 0 {
+		return connect.NewResponse(&querierv1.SelectSeriesResponse{}), nil
+	}
 
 	validated, err := validation.ValidateRangeRequest(f.limits, tenantIDs, model.Interval{Start: model.Time(c.Msg.Start), End: model.Time(c.Msg.End)}, model.Now())
 	if err != nil {
@@ -46,38 +53,73 @@ func (f *Frontend) SelectSeries(ctx context.Context,
 	c.Msg.Start = int64(validated.Start)
 	c.Msg.End = int64(validated.End)
 
-	g, ctx := errgroup.WithContext(ctx)
-	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
-		g.SetLimit(maxConcurrent)
-	}
-
-	m := phlaremodel.NewSeriesMerger(false)
 	interval := validationutil.MaxDurationOrZeroPerTenant(tenantIDs, f.limits.QuerySplitDuration)
-	intervals := NewTimeIntervalIterator(time.UnixMilli(c.Msg.Start), time.UnixMilli(c.Msg.End), interval,
-		WithAlignment(time.Second*time.Duration(c.Msg.Step)))
-
-	for intervals.Next() {
-		r := intervals.At()
-		g.Go(func() error {
-			req := connectgrpc.CloneRequest(c, &querierv1.SelectSeriesRequest{
-				ProfileTypeID:      c.Msg.ProfileTypeID,
-				LabelSelector:      c.Msg.LabelSelector,
-				Start:              r.Start.UnixMilli(),
-				End:                r.End.UnixMilli(),
-				GroupBy:            c.Msg.GroupBy,
-				Step:               c.Msg.Step,
-				Aggregation:        c.Msg.Aggregation,
-				StackTraceSelector: c.Msg.StackTraceSelector,
-			})
+	estimate, plans, err := f.estimateQuery(ctx, tenantIDs, c.Msg.DryRun,
+		NewTimeIntervalIterator(time.UnixMilli(c.Msg.Start), time.UnixMilli(c.Msg.End), interval,
+			WithAlignment(time.Second*time.Duration(c.Msg.Step))),
+		[]querySelection{{tenantIDs: tenantIDs, selector: selector}},
+		func(ctx context.Context, tenantID, selector string, r TimeInterval) (*querierv1.QueryEstimate, *querierv1.QueryPlan, error) {
+			ctx, req := tenantRequest(ctx, c, &querierv1.SelectSeriesRequest{
+				ProfileTypeID: c.Msg.ProfileTypeID,
+				LabelSelector: selector,
+				Start:         r.Start.UnixMilli(),
+				End:           r.End.UnixMilli(),
+				Step:          c.Msg.Step,
+				DryRun:        true,
+			}, tenantID)
 			resp, err := connectgrpc.RoundTripUnary[
 				querierv1.SelectSeriesRequest,
 				querierv1.SelectSeriesResponse](ctx, f, req)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
-			m.MergeSeries(resp.Msg.Series)
-			return nil
+			return resp.Msg.Estimate, resp.Msg.Plan, nil
 		})
+	if err != nil {
+		return nil, err
+	}
+	if c.Msg.DryRun {
+		return connect.NewResponse(&querierv1.SelectSeriesResponse{Estimate: estimate}), nil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if maxConcurrent := validationutil.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueryParallelism); maxConcurrent > 0 {
+		g.SetLimit(maxConcurrent)
+	}
+
+	m := phlaremodel.NewSeriesMerger(false)
+	groupBy := withoutTenantLabel(c.Msg.GroupBy)
+	for _, tenantID := range tenantIDs {
+		tenantID := tenantID
+		intervals := NewTimeIntervalIterator(time.UnixMilli(c.Msg.Start), time.UnixMilli(c.Msg.End), interval,
+			WithAlignment(time.Second*time.Duration(c.Msg.Step)))
+		for intervals.Next() {
+			r := intervals.At()
+			g.Go(func() error {
+				ctx, req := tenantRequest(ctx, c, &querierv1.SelectSeriesRequest{
+					ProfileTypeID:      c.Msg.ProfileTypeID,
+					LabelSelector:      selector,
+					Start:              r.Start.UnixMilli(),
+					End:                r.End.UnixMilli(),
+					GroupBy:            groupBy,
+					Step:               c.Msg.Step,
+					Aggregation:        c.Msg.Aggregation,
+					StackTraceSelector: c.Msg.StackTraceSelector,
+					Plan:               plans.get(tenantID, selector, r),
+				}, tenantID)
+				resp, err := roundTripCached[
+					querierv1.SelectSeriesRequest,
+					querierv1.SelectSeriesResponse](ctx, f, req)
+				if err != nil {
+					return err
+				}
+				if federated {
+					withTenantLabel(resp.Msg.Series, tenantID)
+				}
+				m.MergeSeries(resp.Msg.Series)
+				return nil
+			})
+		}
 	}
 
 	if err = g.Wait(); err != nil {
@@ -0,0 +1,131 @@
+package frontend
+
+import (
+	"context"
+	"sort"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/util/connectgrpc"
+)
+
+// Queriers serve a single tenant per request: queries spanning multiple
+// tenants (X-Scope-OrgID: a|b|c) are split by tenant in the frontend,
+// and the results are merged. Tenants can be selected with matchers of
+// the synthetic __tenant_id__ label, which is also added to the series
+// returned by such queries.
+
+// tenantRequest returns the context and the request of the query
+// issued on behalf of the tenant.
+func tenantRequest[Req any](ctx context.Context, base *connect.Request[Req], msg *Req, tenantID string) (context.Context, *connect.Request[Req]) {
+	if base.Header().Get(user.OrgIDHeaderName) == tenantID {
+		return ctx, connectgrpc.CloneRequest(base, msg)
+	}
+	req := connect.NewRequest(msg)
+	for k, v := range base.Header() {
+		req.Header()[k] = v
+	}
+	req.Header().Set(user.OrgIDHeaderName, tenantID)
+	return user.InjectOrgID(ctx, tenantID), req
+}
+
+// splitSelector returns the tenants selected by the __tenant_id__ matchers
+// of the label selector, and the selector without them. Invalid selectors
+// are returned as is: the error is reported by the querier.
+func splitSelector(tenantIDs []string, selector string) ([]string, string) {
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return tenantIDs, selector
+	}
+	var tenantMatchers []*labels.Matcher
+	seriesMatchers := matchers[:0:0]
+	for _, m := range matchers {
+		if m.Name == phlaremodel.LabelNameTenantID {
+			tenantMatchers = append(tenantMatchers, m)
+			continue
+		}
+		seriesMatchers = append(seriesMatchers, m)
+	}
+	if len(tenantMatchers) == 0 {
+		return tenantIDs, selector
+	}
+	selected := make([]string, 0, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		if matchesAll(tenantMatchers, tenantID) {
+			selected = append(selected, tenantID)
+		}
+	}
+	if len(seriesMatchers) == 0 {
+		return selected, "{}"
+	}
+	s := parser.VectorSelector{LabelMatchers: seriesMatchers}
+	return selected, s.String()
+}
+
+// splitMatchers returns the label selectors to query for each of the
+// tenants, see splitSelector. If no selectors are specified, all the
+// tenants are queried without selectors.
+func splitMatchers(tenantIDs []string, selectors []string) map[string][]string {
+	m := make(map[string][]string, len(tenantIDs))
+	if len(selectors) == 0 {
+		for _, tenantID := range tenantIDs {
+			m[tenantID] = nil
+		}
+		return m
+	}
+	for _, s := range selectors {
+		tenants, selector := splitSelector(tenantIDs, s)
+		for _, tenantID := range tenants {
+			m[tenantID] = append(m[tenantID], selector)
+		}
+	}
+	return m
+}
+
+func matchesAll(matchers []*labels.Matcher, v string) bool {
+	for _, m := range matchers {
+		if !m.Matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// withTenantLabel adds the __tenant_id__ label to the series.
+func withTenantLabel(series []*typesv1.Series, tenantID string) []*typesv1.Series {
+	for _, s := range series {
+		s.Labels = phlaremodel.NewLabelsBuilder(s.Labels).
+			Set(phlaremodel.LabelNameTenantID, tenantID).
+			Labels()
+	}
+	return series
+}
+
+// withoutTenantLabel removes the __tenant_id__ label from the list.
+func withoutTenantLabel(names []string) []string {
+	r := names[:0:0]
+	for _, n := range names {
+		if n != phlaremodel.LabelNameTenantID {
+			r = append(r, n)
+		}
+	}
+	return r
+}
+
+func uniqueSortedStrings(s []string) []string {
+	sort.Strings(s)
+	j := 0
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i] == s[i-1] {
+			continue
+		}
+		s[j] = s[i]
+		j++
+	}
+	return s[:j]
+}
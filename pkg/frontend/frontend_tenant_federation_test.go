@@ -0,0 +1,84 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_splitSelector(t *testing.T) {
+	tenants := []string{"a", "b", "c"}
+	for _, tc := range []struct {
+		selector         string
+		expectedTenants  []string
+		expectedSelector string
+	}{
+		{
+			selector:         `{service_name="foo"}`,
+			expectedTenants:  tenants,
+			expectedSelector: `{service_name="foo"}`,
+		},
+		{
+			selector:         `{}`,
+			expectedTenants:  tenants,
+			expectedSelector: `{}`,
+		},
+		{
+			selector:         `{__tenant_id__="b", service_name="foo"}`,
+			expectedTenants:  []string{"b"},
+			expectedSelector: `{service_name="foo"}`,
+		},
+		{
+			selector:         `{__tenant_id__=~"a|c"}`,
+			expectedTenants:  []string{"a", "c"},
+			expectedSelector: `{}`,
+		},
+		{
+			selector:         `{__tenant_id__!="a", __tenant_id__!="b"}`,
+			expectedTenants:  []string{"c"},
+			expectedSelector: `{}`,
+		},
+		{
+			selector:         `{__tenant_id__="d"}`,
+			expectedTenants:  []string{},
+			expectedSelector: `{}`,
+		},
+		{
+			selector:         `{invalid`,
+			expectedTenants:  tenants,
+			expectedSelector: `{invalid`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.selector, func(t *testing.T) {
+			selected, selector := splitSelector(tenants, tc.selector)
+			require.Equal(t, tc.expectedTenants, selected)
+			require.Equal(t, tc.expectedSelector, selector)
+		})
+	}
+}
+
+func Test_splitMatchers(t *testing.T) {
+	tenants := []string{"a", "b"}
+	require.Equal(t, map[string][]string{"a": nil, "b": nil}, splitMatchers(tenants, nil))
+	require.Equal(t, map[string][]string{
+		"a": {`{service_name="foo"}`},
+		"b": {`{service_name="foo"}`, `{service_name="bar"}`},
+	}, splitMatchers(tenants, []string{
+		`{service_name="foo"}`,
+		`{__tenant_id__="b", service_name="bar"}`,
+	}))
+}
+
+func Test_withTenantLabel(t *testing.T) {
+	series := withTenantLabel([]*typesv1.Series{
+		{Labels: []*typesv1.LabelPair{{Name: "service_name", Value: "foo"}}},
+	}, "a")
+	require.Equal(t, []*typesv1.LabelPair{
+		{Name: "__tenant_id__", Value: "a"},
+		{Name: "service_name", Value: "foo"},
+	}, series[0].Labels)
+	require.Equal(t, []string{"service_name"}, withoutTenantLabel([]string{"__tenant_id__", "service_name"}))
+}
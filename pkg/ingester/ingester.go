@@ -4,6 +4,8 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -146,9 +148,37 @@ func New(phlarectx context.Context, cfg Config, dbConfig phlaredb.Config, storag
 }
 
 func (i *Ingester) starting(ctx context.Context) error {
+	if err := i.recoverInstances(); err != nil {
+		return err
+	}
 	return services.StartManagerAndAwaitHealthy(ctx, i.subservices)
 }
 
+// recoverInstances creates instances of the tenants that have heads
+// left behind by the previous process, so that their WAL is replayed
+// before the ingester joins the ring.
+func (i *Ingester) recoverInstances() error {
+	entries, err := os.ReadDir(i.dbConfig.DataPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err = os.Stat(filepath.Join(i.dbConfig.DataPath, e.Name(), phlaredb.PathHead)); err != nil {
+			continue
+		}
+		if _, err = i.GetOrCreateInstance(e.Name()); err != nil {
+			return fmt.Errorf("recover instance of tenant %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
 func (i *Ingester) running(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -14,6 +14,7 @@ import (
 	phlarecontext "github.com/grafana/pyroscope/pkg/phlare/context"
 	"github.com/grafana/pyroscope/pkg/phlaredb"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
 	"github.com/grafana/pyroscope/pkg/phlaredb/shipper"
 )
 
@@ -51,15 +52,17 @@ func newInstance(phlarectx context.Context, cfg phlaredb.Config, tenantID string
 	}
 	// Todo we should not ship when using filesystem storage.
 	if storageBucket != nil {
+		tenantBucket := phlareobj.NewTenantBucketClient(tenantID, storageBucket, nil)
 		inst.shipper = shipper.New(
 			inst.logger,
 			inst.reg,
 			db,
-			phlareobj.NewTenantBucketClient(tenantID, storageBucket, nil),
+			tenantBucket,
 			block.IngesterSource,
 			false,
 			false,
 		)
+		db.SetTombstonesLoader(bucket.NewTombstonesLoader(tenantBucket, bucket.TombstonesRefreshInterval))
 	}
 	go inst.loop(ctx)
 	return inst, nil
@@ -0,0 +1,266 @@
+package otlp
+
+import (
+	"strconv"
+	"strings"
+
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otelprofile "go.opentelemetry.io/proto/otlp/profiles/v1experimental"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/util/strutil"
+)
+
+// ConvertOtelToGoogle converts an OTLP profile to the pprof format.
+// OTLP profiles refer to mappings, locations, and functions by their
+// index in the corresponding table, whereas pprof uses identifiers:
+// the identifier of an entry is its index plus one.
+func ConvertOtelToGoogle(src *otelprofile.Profile) *profilev1.Profile {
+	dst := &profilev1.Profile{
+		SampleType:        convertValueTypes(src.SampleType),
+		StringTable:       append([]string(nil), src.StringTable...),
+		DropFrames:        src.DropFrames,
+		KeepFrames:        src.KeepFrames,
+		TimeNanos:         src.TimeNanos,
+		DurationNanos:     src.DurationNanos,
+		Period:            src.Period,
+		Comment:           append([]int64(nil), src.Comment...),
+		DefaultSampleType: src.DefaultSampleType,
+	}
+	if len(dst.StringTable) == 0 {
+		dst.StringTable = []string{""}
+	}
+	if src.PeriodType != nil {
+		dst.PeriodType = &profilev1.ValueType{Type: src.PeriodType.Type, Unit: src.PeriodType.Unit}
+	}
+
+	dst.Mapping = make([]*profilev1.Mapping, len(src.Mapping))
+	for i, m := range src.Mapping {
+		dst.Mapping[i] = &profilev1.Mapping{
+			Id:              uint64(i + 1),
+			MemoryStart:     m.MemoryStart,
+			MemoryLimit:     m.MemoryLimit,
+			FileOffset:      m.FileOffset,
+			Filename:        m.Filename,
+			BuildId:         m.BuildId,
+			HasFunctions:    m.HasFunctions,
+			HasFilenames:    m.HasFilenames,
+			HasLineNumbers:  m.HasLineNumbers,
+			HasInlineFrames: m.HasInlineFrames,
+		}
+	}
+
+	dst.Function = make([]*profilev1.Function, len(src.Function))
+	for i, f := range src.Function {
+		dst.Function[i] = &profilev1.Function{
+			Id:         uint64(i + 1),
+			Name:       f.Name,
+			SystemName: f.SystemName,
+			Filename:   f.Filename,
+			StartLine:  f.StartLine,
+		}
+	}
+
+	dst.Location = make([]*profilev1.Location, len(src.Location))
+	for i, l := range src.Location {
+		loc := &profilev1.Location{
+			Id:       uint64(i + 1),
+			Address:  l.Address,
+			IsFolded: l.IsFolded,
+			Line:     make([]*profilev1.Line, len(l.Line)),
+		}
+		// The mapping index can't be distinguished from an
+		// unset one, if the profile has any mappings.
+		if int(l.MappingIndex) < len(src.Mapping) {
+			loc.MappingId = l.MappingIndex + 1
+		}
+		for j, line := range l.Line {
+			loc.Line[j] = &profilev1.Line{
+				FunctionId: line.FunctionIndex + 1,
+				Line:       line.Line,
+			}
+		}
+		dst.Location[i] = loc
+	}
+
+	attrStrings := stringTable{p: dst}
+	dst.Sample = make([]*profilev1.Sample, 0, len(src.Sample))
+	for _, s := range src.Sample {
+		sample := &profilev1.Sample{
+			LocationId: sampleLocations(src, s),
+			Value:      s.Value,
+		}
+		// Samples may be represented by their timestamps only.
+		if len(sample.Value) == 0 && len(src.SampleType) == 1 {
+			sample.Value = []int64{int64(len(s.TimestampsUnixNano))}
+		}
+		for _, l := range s.Label {
+			sample.Label = append(sample.Label, &profilev1.Label{Key: l.Key, Str: l.Str, Num: l.Num, NumUnit: l.NumUnit})
+		}
+		for _, idx := range s.Attributes {
+			if int(idx) >= len(src.AttributeTable) {
+				continue
+			}
+			attr := src.AttributeTable[idx]
+			value, ok := attributeValue(attr.Value)
+			if !ok {
+				continue
+			}
+			sample.Label = append(sample.Label, &profilev1.Label{
+				Key: attrStrings.add(attr.Key),
+				Str: attrStrings.add(value),
+			})
+		}
+		dst.Sample = append(dst.Sample, sample)
+	}
+
+	return dst
+}
+
+// sampleLocations returns identifiers of the sample locations, leaf first.
+func sampleLocations(p *otelprofile.Profile, s *otelprofile.Sample) []uint64 {
+	if s.LocationsLength == 0 {
+		ids := make([]uint64, len(s.LocationIndex))
+		for i, idx := range s.LocationIndex {
+			ids[i] = idx + 1
+		}
+		return ids
+	}
+	ids := make([]uint64, 0, s.LocationsLength)
+	for i := s.LocationsStartIndex; i < s.LocationsStartIndex+s.LocationsLength; i++ {
+		// The slice refers to the location_indices array, if present,
+		// or to the location table itself, otherwise.
+		if len(p.LocationIndices) == 0 {
+			ids = append(ids, i+1)
+		} else if int(i) < len(p.LocationIndices) {
+			ids = append(ids, uint64(p.LocationIndices[i])+1)
+		}
+	}
+	return ids
+}
+
+func convertValueTypes(src []*otelprofile.ValueType) []*profilev1.ValueType {
+	dst := make([]*profilev1.ValueType, len(src))
+	for i, t := range src {
+		dst[i] = &profilev1.ValueType{Type: t.Type, Unit: t.Unit}
+	}
+	return dst
+}
+
+// stringTable adds strings to the profile string table, reusing the
+// entries already present: sample attributes repeat a lot.
+type stringTable struct {
+	p *profilev1.Profile
+	m map[string]int64
+}
+
+func (t *stringTable) add(s string) int64 {
+	if t.m == nil {
+		t.m = make(map[string]int64, len(t.p.StringTable))
+		for i, x := range t.p.StringTable {
+			if _, ok := t.m[x]; !ok {
+				t.m[x] = int64(i)
+			}
+		}
+	}
+	if i, ok := t.m[s]; ok {
+		return i
+	}
+	i := int64(len(t.p.StringTable))
+	t.p.StringTable = append(t.p.StringTable, s)
+	t.m[s] = i
+	return i
+}
+
+// SeriesLabels returns labels of the profile series. Resource, scope,
+// and profile attributes are converted to labels, with the attribute
+// keys sanitized: for example, "service.name" becomes "service_name".
+func SeriesLabels(p *otelprofile.Profile, attributeSets ...[]*commonv1.KeyValue) []*typesv1.LabelPair {
+	lbls := make(map[string]string)
+	for _, attrs := range attributeSets {
+		for _, attr := range attrs {
+			value, ok := attributeValue(attr.Value)
+			if !ok || value == "" {
+				continue
+			}
+			name := strutil.SanitizeLabelName(attr.Key)
+			if !phlaremodel.IsLabelAllowedForIngestion(name) {
+				continue
+			}
+			lbls[name] = value
+		}
+	}
+	lbls[labels.MetricName] = profileName(p)
+	if !isCumulative(p) {
+		lbls[phlaremodel.LabelNameDelta] = "false"
+	}
+	series := make([]*typesv1.LabelPair, 0, len(lbls))
+	for name, value := range lbls {
+		series = append(series, &typesv1.LabelPair{Name: name, Value: value})
+	}
+	return series
+}
+
+func attributeValue(v *commonv1.AnyValue) (string, bool) {
+	switch v := v.GetValue().(type) {
+	case *commonv1.AnyValue_StringValue:
+		return v.StringValue, true
+	case *commonv1.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10), true
+	case *commonv1.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue), true
+	case *commonv1.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// profileName determines the profile name from its sample types, the
+// same way it's done for pprof profiles ingested with the legacy API.
+func profileName(p *otelprofile.Profile) string {
+	var st string
+	for _, t := range p.SampleType {
+		st = stringAt(p, t.Type)
+		switch {
+		case st == "wall":
+			return "wall"
+		case strings.Contains(st, "cpu") || st == "samples":
+			return "process_cpu"
+		case strings.Contains(st, "alloc_") || strings.Contains(st, "inuse_"):
+			return "memory"
+		case strings.Contains(st, "mutex_"):
+			return "mutex"
+		case strings.Contains(st, "block_"):
+			return "block"
+		case strings.Contains(st, "goroutine"):
+			return "goroutines"
+		}
+	}
+	if st == "" {
+		return "unknown"
+	}
+	return strutil.SanitizeLabelName(st)
+}
+
+// isCumulative reports whether the profile values are cumulative,
+// and therefore deltas are to be computed at ingestion.
+func isCumulative(p *otelprofile.Profile) bool {
+	for _, t := range p.SampleType {
+		if t.AggregationTemporality == otelprofile.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+			return true
+		}
+	}
+	return false
+}
+
+func stringAt(p *otelprofile.Profile, i int64) string {
+	if i < 0 || int(i) >= len(p.StringTable) {
+		return ""
+	}
+	return p.StringTable[i]
+}
@@ -0,0 +1,104 @@
+package otlp
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otelprofile "go.opentelemetry.io/proto/otlp/profiles/v1experimental"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+func testProfile() *otelprofile.Profile {
+	return &otelprofile.Profile{
+		StringTable: []string{"", "samples", "count", "cpu", "nanoseconds", "main", "main.go", "foo", "libc.so", "thread.name"},
+		SampleType:  []*otelprofile.ValueType{{Type: 1, Unit: 2}},
+		PeriodType:  &otelprofile.ValueType{Type: 3, Unit: 4},
+		Period:      10000000,
+		TimeNanos:   1e9,
+		Mapping:     []*otelprofile.Mapping{{MemoryStart: 0x1000, MemoryLimit: 0x2000, Filename: 8}},
+		Function: []*otelprofile.Function{
+			{Name: 5, Filename: 6},
+			{Name: 7, Filename: 6},
+		},
+		Location: []*otelprofile.Location{
+			{Address: 0x1100, Line: []*otelprofile.Line{{FunctionIndex: 0, Line: 10}}},
+			{Address: 0x1200, Line: []*otelprofile.Line{{FunctionIndex: 1, Line: 20}}},
+		},
+		// foo is called by main.
+		LocationIndices: []int64{1, 0},
+		Sample: []*otelprofile.Sample{
+			{LocationsStartIndex: 0, LocationsLength: 2, Value: []int64{3}, Attributes: []uint64{0}},
+			{LocationsStartIndex: 1, LocationsLength: 1, Value: []int64{1}, Attributes: []uint64{0}},
+		},
+		AttributeTable: []*commonv1.KeyValue{
+			{Key: "thread.name", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "worker"}}},
+		},
+	}
+}
+
+func Test_ConvertOtelToGoogle(t *testing.T) {
+	p := ConvertOtelToGoogle(testProfile())
+
+	assert.Equal(t, []*profilev1.ValueType{{Type: 1, Unit: 2}}, p.SampleType)
+	assert.Equal(t, &profilev1.ValueType{Type: 3, Unit: 4}, p.PeriodType)
+	assert.Equal(t, int64(1e9), p.TimeNanos)
+	require.Len(t, p.Mapping, 1)
+	assert.Equal(t, uint64(1), p.Mapping[0].Id)
+	require.Len(t, p.Location, 2)
+	assert.Equal(t, uint64(1), p.Location[0].MappingId)
+	assert.Equal(t, uint64(2), p.Location[1].Line[0].FunctionId)
+	require.Len(t, p.Sample, 2)
+	assert.Equal(t, []uint64{2, 1}, p.Sample[0].LocationId)
+	assert.Equal(t, []int64{3}, p.Sample[0].Value)
+	assert.Equal(t, []uint64{1}, p.Sample[1].LocationId)
+	require.Len(t, p.Sample[0].Label, 1)
+	assert.Equal(t, "thread.name", p.StringTable[p.Sample[0].Label[0].Key])
+	assert.Equal(t, "worker", p.StringTable[p.Sample[0].Label[0].Str])
+	// The attribute strings are interned.
+	assert.Equal(t, p.Sample[0].Label, p.Sample[1].Label)
+	assert.Equal(t, int64(9), p.Sample[0].Label[0].Key)
+	assert.Len(t, p.StringTable, len(testProfile().StringTable)+1)
+}
+
+func Test_ConvertOtelToGoogle_LocationIndex(t *testing.T) {
+	src := testProfile()
+	src.LocationIndices = nil
+	src.Sample = []*otelprofile.Sample{
+		// Deprecated location_index.
+		{LocationIndex: []uint64{1, 0}, Value: []int64{1}},
+		// Locations slice without location_indices.
+		{LocationsStartIndex: 0, LocationsLength: 2, Value: []int64{1}},
+	}
+	p := ConvertOtelToGoogle(src)
+	require.Len(t, p.Sample, 2)
+	assert.Equal(t, []uint64{2, 1}, p.Sample[0].LocationId)
+	assert.Equal(t, []uint64{1, 2}, p.Sample[1].LocationId)
+}
+
+func Test_SeriesLabels(t *testing.T) {
+	p := testProfile()
+	lbls := SeriesLabels(p,
+		[]*commonv1.KeyValue{
+			{Key: "service.name", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "my-service"}}},
+			{Key: "process.pid", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_IntValue{IntValue: 42}}},
+			{Key: "__private", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "x"}}},
+		},
+		[]*commonv1.KeyValue{
+			{Key: "list", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_ArrayValue{}}},
+		},
+	)
+	sort.Sort(phlaremodel.Labels(lbls))
+	assert.Equal(t, `{__delta__="false", __name__="process_cpu", process_pid="42", service_name="my-service"}`,
+		phlaremodel.LabelPairsString(lbls))
+
+	p.SampleType[0].AggregationTemporality = otelprofile.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	p.StringTable[1] = "alloc_space"
+	lbls = SeriesLabels(p)
+	sort.Sort(phlaremodel.Labels(lbls))
+	assert.Equal(t, `{__name__="memory"}`, phlaremodel.LabelPairsString(lbls))
+}
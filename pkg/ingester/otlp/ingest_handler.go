@@ -0,0 +1,183 @@
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	pprofileotlp "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	"github.com/grafana/pyroscope/pkg/pprof"
+	"github.com/grafana/pyroscope/pkg/tenant"
+	httputil "github.com/grafana/pyroscope/pkg/util/http"
+)
+
+const (
+	// ExportProcedure is the OTLP/gRPC profiles export procedure.
+	ExportProcedure = "/opentelemetry.proto.collector.profiles.v1experimental.ProfilesService/Export"
+	// HTTPPath is the OTLP/HTTP profiles export path.
+	HTTPPath = "/v1experimental/profiles"
+)
+
+type PushService interface {
+	PushParsed(ctx context.Context, req *distributormodel.PushRequest) (*connect.Response[pushv1.PushResponse], error)
+}
+
+type Limits interface {
+	MaxProfileSizeBytes(tenantID string) int
+}
+
+// Handler receives OTLP profiles over gRPC and HTTP, converts
+// them to pprof, and pushes them to the distributor.
+type Handler struct {
+	svc    PushService
+	limits Limits
+	log    log.Logger
+}
+
+func NewOTLPIngestHandler(svc PushService, limits Limits, logger log.Logger) *Handler {
+	return &Handler{svc: svc, limits: limits, log: logger}
+}
+
+// Export implements the OTLP/gRPC ProfilesService.
+func (h *Handler) Export(ctx context.Context, req *connect.Request[pprofileotlp.ExportProfilesServiceRequest]) (*connect.Response[pprofileotlp.ExportProfilesServiceResponse], error) {
+	if err := h.export(ctx, req.Msg); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&pprofileotlp.ExportProfilesServiceResponse{}), nil
+}
+
+// ServeHTTP implements the OTLP/HTTP profiles endpoint. Both binary
+// protobuf and JSON encoded requests are supported; the response is
+// encoded the same way as the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var (
+		unmarshal func([]byte, proto.Message) error
+		marshal   func(proto.Message) ([]byte, error)
+	)
+	switch contentType {
+	case "application/x-protobuf":
+		unmarshal, marshal = proto.Unmarshal, proto.Marshal
+	case "application/json":
+		unmarshal, marshal = protojson.Unmarshal, protojson.Marshal
+	default:
+		httputil.ErrorWithStatus(w, fmt.Errorf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	tenantID, err := tenant.ExtractTenantIDFromContext(r.Context())
+	if err != nil {
+		httputil.ErrorWithStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+	// The request body, compressed or not, may not exceed the
+	// maximum profile size of the tenant. 0 disables the limit.
+	maxSize := int64(h.limits.MaxProfileSizeBytes(tenantID))
+	body := r.Body
+	if maxSize > 0 {
+		body = http.MaxBytesReader(w, body, maxSize)
+	}
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			httputil.ErrorWithStatus(w, err, requestErrorStatus(err))
+			return
+		}
+		defer gr.Close()
+		body = gr
+	}
+	var src io.Reader = body
+	if maxSize > 0 {
+		src = io.LimitReader(body, maxSize+1)
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		httputil.ErrorWithStatus(w, err, requestErrorStatus(err))
+		return
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		httputil.ErrorWithStatus(w, fmt.Errorf("request body exceeds the maximum profile size of %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var req pprofileotlp.ExportProfilesServiceRequest
+	if err = unmarshal(data, &req); err != nil {
+		httputil.ErrorWithStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err = h.export(r.Context(), &req); err != nil {
+		httputil.Error(w, err)
+		return
+	}
+	resp, err := marshal(&pprofileotlp.ExportProfilesServiceResponse{})
+	if err != nil {
+		httputil.Error(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(resp)
+}
+
+func requestErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func (h *Handler) export(ctx context.Context, req *pprofileotlp.ExportProfilesServiceRequest) error {
+	push := &distributormodel.PushRequest{
+		RawProfileSize: proto.Size(req),
+		RawProfileType: distributormodel.RawProfileTypeOTEL,
+	}
+	for _, rp := range req.ResourceProfiles {
+		for _, sp := range rp.ScopeProfiles {
+			for _, pc := range sp.Profiles {
+				if pc.Profile == nil {
+					continue
+				}
+				p := ConvertOtelToGoogle(pc.Profile)
+				if p.TimeNanos == 0 {
+					p.TimeNanos = int64(pc.StartTimeUnixNano)
+				}
+				if p.DurationNanos == 0 && pc.EndTimeUnixNano > pc.StartTimeUnixNano {
+					p.DurationNanos = int64(pc.EndTimeUnixNano - pc.StartTimeUnixNano)
+				}
+				push.Series = append(push.Series, &distributormodel.ProfileSeries{
+					Labels: SeriesLabels(pc.Profile,
+						rp.GetResource().GetAttributes(),
+						sp.GetScope().GetAttributes(),
+						pc.Attributes,
+					),
+					Samples: []*distributormodel.ProfileSample{{
+						Profile: pprof.RawFromProto(p),
+						ID:      uuid.NewString(),
+					}},
+				})
+			}
+		}
+	}
+	if len(push.Series) == 0 {
+		return nil
+	}
+	if _, err := h.svc.PushParsed(ctx, push); err != nil {
+		tenantID, _ := tenant.ExtractTenantIDFromContext(ctx)
+		level.Debug(h.log).Log("msg", "failed to push OTLP profiles", "err", err, "orgID", tenantID)
+		return err
+	}
+	return nil
+}
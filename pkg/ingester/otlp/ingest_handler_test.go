@@ -0,0 +1,163 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pprofileotlp "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
+	commonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otelprofile "go.opentelemetry.io/proto/otlp/profiles/v1experimental"
+	resourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	distributormodel "github.com/grafana/pyroscope/pkg/distributor/model"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/tenant"
+	"github.com/grafana/pyroscope/pkg/validation"
+)
+
+var testLimits = validation.MockLimits{MaxProfileSizeBytesValue: 4 << 20}
+
+type mockPushService struct {
+	requests []*distributormodel.PushRequest
+}
+
+func (m *mockPushService) PushParsed(_ context.Context, req *distributormodel.PushRequest) (*connect.Response[pushv1.PushResponse], error) {
+	m.requests = append(m.requests, req)
+	return connect.NewResponse(&pushv1.PushResponse{}), nil
+}
+
+func testExportRequest() *pprofileotlp.ExportProfilesServiceRequest {
+	return &pprofileotlp.ExportProfilesServiceRequest{
+		ResourceProfiles: []*otelprofile.ResourceProfiles{{
+			Resource: &resourcev1.Resource{Attributes: []*commonv1.KeyValue{
+				{Key: "service.name", Value: &commonv1.AnyValue{Value: &commonv1.AnyValue_StringValue{StringValue: "my-service"}}},
+			}},
+			ScopeProfiles: []*otelprofile.ScopeProfiles{{
+				Profiles: []*otelprofile.ProfileContainer{{
+					StartTimeUnixNano: 1e9,
+					EndTimeUnixNano:   11e9,
+					Profile:           testProfile(),
+				}},
+			}},
+		}},
+	}
+}
+
+func assertPushRequest(t *testing.T, req *distributormodel.PushRequest) {
+	t.Helper()
+	require.Len(t, req.Series, 1)
+	assert.Equal(t, "my-service", phlaremodel.Labels(req.Series[0].Labels).Get("service_name"))
+	assert.Equal(t, "process_cpu", phlaremodel.Labels(req.Series[0].Labels).Get(phlaremodel.LabelNameProfileName))
+	require.Len(t, req.Series[0].Samples, 1)
+	p := req.Series[0].Samples[0].Profile
+	assert.Len(t, p.Sample, 2)
+	assert.Equal(t, int64(1e9), p.TimeNanos)
+	assert.Equal(t, int64(10e9), p.DurationNanos)
+}
+
+func Test_Handler_Export(t *testing.T) {
+	svc := new(mockPushService)
+	h := NewOTLPIngestHandler(svc, testLimits, log.NewNopLogger())
+	mux := http.NewServeMux()
+	mux.Handle(ExportProcedure, connect.NewUnaryHandler(ExportProcedure, h.Export))
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := connect.NewClient[pprofileotlp.ExportProfilesServiceRequest, pprofileotlp.ExportProfilesServiceResponse](
+		server.Client(), server.URL+ExportProcedure, connect.WithGRPC())
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(testExportRequest()))
+	require.NoError(t, err)
+	require.Len(t, svc.requests, 1)
+	assertPushRequest(t, svc.requests[0])
+}
+
+func Test_Handler_HTTP(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		marshal     func(proto.Message) ([]byte, error)
+	}{
+		{contentType: "application/x-protobuf", marshal: proto.Marshal},
+		{contentType: "application/json", marshal: protojson.Marshal},
+	} {
+		t.Run(tc.contentType, func(t *testing.T) {
+			svc := new(mockPushService)
+			h := NewOTLPIngestHandler(svc, testLimits, log.NewNopLogger())
+			body, err := tc.marshal(testExportRequest())
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, HTTPPath, bytes.NewReader(body))
+			req = req.WithContext(tenant.InjectTenantID(req.Context(), "foo"))
+			req.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			require.Len(t, svc.requests, 1)
+			assertPushRequest(t, svc.requests[0])
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, HTTPPath, nil)
+	req.Header.Set("Content-Type", "text/plain")
+	w := httptest.NewRecorder()
+	NewOTLPIngestHandler(new(mockPushService), testLimits, log.NewNopLogger()).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func Test_Handler_HTTP_MaxProfileSize(t *testing.T) {
+	body, err := proto.Marshal(testExportRequest())
+	require.NoError(t, err)
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, err = gw.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	// Repeated values compress well, so that only the decompressed
+	// body exceeds the limit.
+	large := append(body, bytes.Repeat([]byte{0}, 100*len(body))...)
+	var compressedLarge bytes.Buffer
+	gw = gzip.NewWriter(&compressedLarge)
+	_, err = gw.Write(large)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	limit := compressedLarge.Len()
+	require.Less(t, limit, len(large))
+
+	for _, tc := range []struct {
+		name     string
+		body     []byte
+		encoding string
+		limit    int
+		code     int
+	}{
+		{name: "within limit", body: body, limit: len(body), code: http.StatusOK},
+		{name: "no limit", body: body, limit: 0, code: http.StatusOK},
+		{name: "body exceeds limit", body: large, limit: limit, code: http.StatusRequestEntityTooLarge},
+		{name: "compressed within limit", body: compressed.Bytes(), encoding: "gzip", limit: len(body), code: http.StatusOK},
+		{name: "decompressed body exceeds limit", body: compressedLarge.Bytes(), encoding: "gzip", limit: limit, code: http.StatusRequestEntityTooLarge},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := new(mockPushService)
+			h := NewOTLPIngestHandler(svc, validation.MockLimits{MaxProfileSizeBytesValue: tc.limit}, log.NewNopLogger())
+			req := httptest.NewRequest(http.MethodPost, HTTPPath, bytes.NewReader(tc.body))
+			req = req.WithContext(tenant.InjectTenantID(req.Context(), "foo"))
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			req.Header.Set("Content-Encoding", tc.encoding)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, tc.code, w.Code, w.Body.String())
+		})
+	}
+}
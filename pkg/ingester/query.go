@@ -37,6 +37,27 @@ func (i *Ingester) Series(ctx context.Context, req *connect.Request[ingestv1.Ser
 	})
 }
 
+// GetCardinality returns the label values and series read from the TSDB index.
+func (i *Ingester) GetCardinality(ctx context.Context, req *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error) {
+	return forInstanceUnary(ctx, i, func(instance *instance) (*connect.Response[ingestv1.GetCardinalityResponse], error) {
+		return instance.GetCardinality(ctx, req)
+	})
+}
+
+// SelectExemplars returns the profiles with the highest total values.
+func (i *Ingester) SelectExemplars(ctx context.Context, req *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error) {
+	return forInstanceUnary(ctx, i, func(instance *instance) (*connect.Response[ingestv1.SelectExemplarsResponse], error) {
+		return instance.SelectExemplars(ctx, req)
+	})
+}
+
+// SelectProfileByID returns a single profile in the pprof format.
+func (i *Ingester) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	return forInstanceUnary(ctx, i, func(instance *instance) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+		return instance.SelectProfileByID(ctx, req)
+	})
+}
+
 // BlockMetadata returns the metadata of the instance's blocks
 func (i *Ingester) BlockMetadata(ctx context.Context, req *connect.Request[ingestv1.BlockMetadataRequest]) (*connect.Response[ingestv1.BlockMetadataResponse], error) {
 	return forInstanceUnary(ctx, i, func(instance *instance) (*connect.Response[ingestv1.BlockMetadataResponse], error) {
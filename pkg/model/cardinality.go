@@ -0,0 +1,71 @@
+package model
+
+import (
+	"math"
+	"sort"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	pmath "github.com/grafana/pyroscope/pkg/util/math"
+)
+
+// CardinalitySketchSize is the maximum number of hashes a cardinality
+// sketch holds. The cardinality of larger sets is estimated with the
+// relative standard error of about 1/sqrt(CardinalitySketchSize), 3%.
+const CardinalitySketchSize = 1024
+
+// NewCardinalitySketch returns the sketch of the set of the hashes.
+// The hashes are sorted in place, and may contain duplicates.
+func NewCardinalitySketch(hashes []uint64) *ingestv1.CardinalitySketch {
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	s := &ingestv1.CardinalitySketch{Hashes: make([]uint64, 0, pmath.Min(len(hashes), CardinalitySketchSize))}
+	for i, h := range hashes {
+		if i > 0 && h == hashes[i-1] {
+			continue
+		}
+		if len(s.Hashes) == CardinalitySketchSize {
+			s.Truncated = true
+			break
+		}
+		s.Hashes = append(s.Hashes, h)
+	}
+	return s
+}
+
+// MergeCardinalitySketches returns the sketch of the union of the sets.
+func MergeCardinalitySketches(a, b *ingestv1.CardinalitySketch) *ingestv1.CardinalitySketch {
+	x, y := a.GetHashes(), b.GetHashes()
+	s := &ingestv1.CardinalitySketch{
+		Hashes:    make([]uint64, 0, pmath.Min(len(x)+len(y), CardinalitySketchSize)),
+		Truncated: a.GetTruncated() || b.GetTruncated(),
+	}
+	for len(x) > 0 || len(y) > 0 {
+		var h uint64
+		switch {
+		case len(y) == 0 || (len(x) > 0 && x[0] < y[0]):
+			h, x = x[0], x[1:]
+		case len(x) == 0 || y[0] < x[0]:
+			h, y = y[0], y[1:]
+		default:
+			h, x, y = x[0], x[1:], y[1:]
+		}
+		if len(s.Hashes) == CardinalitySketchSize {
+			s.Truncated = true
+			break
+		}
+		s.Hashes = append(s.Hashes, h)
+	}
+	return s
+}
+
+// CardinalityEstimate returns the estimated number of the set elements.
+// Unless the sketch is truncated, the number is exact.
+func CardinalityEstimate(s *ingestv1.CardinalitySketch) uint64 {
+	n := len(s.GetHashes())
+	if !s.GetTruncated() || n < 2 {
+		return uint64(n)
+	}
+	// The hashes are uniformly distributed: the k-th smallest
+	// one is expected to be about k/cardinality of the range.
+	r := float64(s.Hashes[n-1]) / math.MaxUint64
+	return uint64(float64(n-1) / r)
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CardinalitySketch(t *testing.T) {
+	s := NewCardinalitySketch([]uint64{3, 1, 2, 1})
+	assert.Equal(t, []uint64{1, 2, 3}, s.Hashes)
+	assert.False(t, s.Truncated)
+	assert.Equal(t, uint64(3), CardinalityEstimate(s))
+
+	m := MergeCardinalitySketches(s, NewCardinalitySketch([]uint64{2, 4}))
+	assert.Equal(t, []uint64{1, 2, 3, 4}, m.Hashes)
+	assert.Equal(t, uint64(4), CardinalityEstimate(m))
+	assert.Equal(t, m, MergeCardinalitySketches(nil, m))
+	assert.Equal(t, uint64(0), CardinalityEstimate(nil))
+}
+
+func Test_CardinalitySketch_Estimate(t *testing.T) {
+	const n = 100000
+	r := rand.New(rand.NewSource(1))
+	hashes := make([]uint64, n)
+	for i := range hashes {
+		hashes[i] = r.Uint64()
+	}
+	// Overlapping halves, as reported by replicas.
+	a := NewCardinalitySketch(append([]uint64(nil), hashes[:n*3/4]...))
+	b := NewCardinalitySketch(append([]uint64(nil), hashes[n/4:]...))
+	require.True(t, a.Truncated)
+	require.Len(t, a.Hashes, CardinalitySketchSize)
+
+	m := MergeCardinalitySketches(a, b)
+	require.Len(t, m.Hashes, CardinalitySketchSize)
+	assert.InEpsilon(t, n, CardinalityEstimate(m), 0.1)
+	assert.Equal(t, NewCardinalitySketch(hashes), m)
+}
@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 )
 
 func Test_Diff_Tree(t *testing.T) {
@@ -123,3 +126,18 @@ func Test_Diff_No_Common_Root(t *testing.T) {
 	_, err := NewFlamegraphDiff(tr, tr2, 1024)
 	assert.NoError(t, err)
 }
+
+func Test_DiffByLabelSelectors(t *testing.T) {
+	left, right, err := DiffByLabelSelectors(&querierv1.DiffByLabelRequest{
+		LabelSelector: `{service_name="foo"}`,
+		LabelName:     "version",
+		LeftValue:     "v1",
+		RightValue:    "v2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{service_name="foo",version="v1"}`, left)
+	assert.Equal(t, `{service_name="foo",version="v2"}`, right)
+
+	_, _, err = DiffByLabelSelectors(&querierv1.DiffByLabelRequest{LabelSelector: `{}`})
+	require.Error(t, err)
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"sort"
+	"sync"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+// FunctionValues holds values of a function aggregated over all the
+// stack traces the function is present in.
+type FunctionValues struct {
+	Self  int64
+	Total int64
+}
+
+// Functions returns self and total values of every function in the tree.
+// If a function is called recursively, the total value accounts each
+// stack trace only once.
+func (t *Tree) Functions() map[string]*FunctionValues {
+	type frame struct {
+		node *node
+		exit bool
+	}
+	functions := make(map[string]*FunctionValues)
+	// Number of occurrences of the function in the current stack.
+	inStack := make(map[string]int)
+	nodes := make([]frame, 0, defaultDFSSize)
+	for _, n := range t.root {
+		nodes = append(nodes, frame{node: n})
+	}
+	var f frame
+	for len(nodes) > 0 {
+		f, nodes = nodes[len(nodes)-1], nodes[:len(nodes)-1]
+		n := f.node
+		if f.exit {
+			inStack[n.name]--
+			continue
+		}
+		v, ok := functions[n.name]
+		if !ok {
+			v = new(FunctionValues)
+			functions[n.name] = v
+		}
+		v.Self += n.self
+		if inStack[n.name] == 0 {
+			v.Total += n.total
+		}
+		inStack[n.name]++
+		nodes = append(nodes, frame{node: n, exit: true})
+		for _, c := range n.children {
+			nodes = append(nodes, frame{node: c})
+		}
+	}
+	return functions
+}
+
+// NewFunctionsDiff returns per-function deltas of the two trees, ordered
+// by the relative self delta in descending order: the functions which
+// regressed the most come first. If maxFunctions is positive, only the
+// top maxFunctions functions are returned.
+func NewFunctionsDiff(left, right *Tree, maxFunctions int64) []*querierv1.FunctionDiff {
+	leftFunctions := left.Functions()
+	rightFunctions := right.Functions()
+	leftTotal := float64(left.Total())
+	rightTotal := float64(right.Total())
+
+	diff := make([]*querierv1.FunctionDiff, 0, len(rightFunctions))
+	add := func(name string) {
+		var d querierv1.FunctionDiff
+		d.Name = name
+		if v, ok := leftFunctions[name]; ok {
+			d.LeftSelf, d.LeftTotal = v.Self, v.Total
+		}
+		if v, ok := rightFunctions[name]; ok {
+			d.RightSelf, d.RightTotal = v.Self, v.Total
+		}
+		d.SelfDelta = d.RightSelf - d.LeftSelf
+		d.RelativeSelfDelta = 100 * (share(d.RightSelf, rightTotal) - share(d.LeftSelf, leftTotal))
+		diff = append(diff, &d)
+	}
+	for name := range rightFunctions {
+		add(name)
+	}
+	for name := range leftFunctions {
+		if _, ok := rightFunctions[name]; !ok {
+			add(name)
+		}
+	}
+
+	sort.Slice(diff, func(i, j int) bool {
+		if diff[i].RelativeSelfDelta != diff[j].RelativeSelfDelta {
+			return diff[i].RelativeSelfDelta > diff[j].RelativeSelfDelta
+		}
+		if diff[i].SelfDelta != diff[j].SelfDelta {
+			return diff[i].SelfDelta > diff[j].SelfDelta
+		}
+		return diff[i].Name < diff[j].Name
+	})
+	if maxFunctions > 0 && int64(len(diff)) > maxFunctions {
+		diff = diff[:maxFunctions]
+	}
+	return diff
+}
+
+func share(v int64, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) / total
+}
+
+type functionKey struct {
+	name      string
+	filename  string
+	startLine int64
+}
+
+// FunctionsMerger aggregates functions of trees, profiles,
+// and function tables into a single functions table.
+type FunctionsMerger struct {
+	mu        sync.Mutex
+	functions map[functionKey]*querierv1.Function
+	total     int64
+}
+
+func NewFunctionsMerger() *FunctionsMerger {
+	return &FunctionsMerger{functions: make(map[functionKey]*querierv1.Function)}
+}
+
+func (m *FunctionsMerger) add(k functionKey, self, total int64) {
+	f, ok := m.functions[k]
+	if !ok {
+		f = &querierv1.Function{Name: k.name, Filename: k.filename, StartLine: k.startLine}
+		m.functions[k] = f
+	}
+	f.Self += self
+	f.Total += total
+}
+
+// MergeTree adds functions of the tree to the table.
+// Functions of a tree have no file name and start line.
+func (m *FunctionsMerger) MergeTree(t *Tree) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, v := range t.Functions() {
+		m.add(functionKey{name: name}, v.Self, v.Total)
+	}
+	m.total += t.Total()
+}
+
+// MergeProfile adds functions of the profile to the table. Functions are
+// identified by name, file name, and start line. The first sample value is
+// used; the profile is expected to have a single sample type, as returned
+// by SelectMergeProfile.
+func (m *FunctionsMerger) MergeProfile(p *profilev1.Profile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	functions := make(map[uint64]functionKey, len(p.Function))
+	for _, f := range p.Function {
+		functions[f.Id] = functionKey{
+			name:      p.StringTable[f.Name],
+			filename:  p.StringTable[f.Filename],
+			startLine: f.StartLine,
+		}
+	}
+	locations := make(map[uint64]*profilev1.Location, len(p.Location))
+	for _, l := range p.Location {
+		locations[l.Id] = l
+	}
+	seen := make(map[functionKey]struct{})
+	for _, s := range p.Sample {
+		if len(s.Value) == 0 || s.Value[0] == 0 {
+			continue
+		}
+		v := s.Value[0]
+		m.total += v
+		for k := range seen {
+			delete(seen, k)
+		}
+		for i, id := range s.LocationId {
+			loc, ok := locations[id]
+			if !ok {
+				continue
+			}
+			for j, line := range loc.Line {
+				k, ok := functions[line.FunctionId]
+				if !ok {
+					continue
+				}
+				var self int64
+				// The first line of the leaf location
+				// is the innermost (inlined) function.
+				if i == 0 && j == 0 {
+					self = v
+				}
+				if _, ok = seen[k]; ok {
+					m.add(k, self, 0)
+					continue
+				}
+				seen[k] = struct{}{}
+				m.add(k, self, v)
+			}
+		}
+	}
+}
+
+// MergeFunctions adds the functions table to the table.
+func (m *FunctionsMerger) MergeFunctions(resp *querierv1.SelectFunctionsResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range resp.Functions {
+		m.add(functionKey{name: f.Name, filename: f.Filename, startLine: f.StartLine}, f.Self, f.Total)
+	}
+	m.total += resp.Total
+}
+
+// SelectFunctionsResponse returns the functions table ordered by the
+// given value in descending order. If maxFunctions is positive, only
+// the top maxFunctions functions are returned.
+func (m *FunctionsMerger) SelectFunctionsResponse(orderBy querierv1.FunctionsOrderBy, maxFunctions int64) *querierv1.SelectFunctionsResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	functions := make([]*querierv1.Function, 0, len(m.functions))
+	for _, f := range m.functions {
+		functions = append(functions, f)
+	}
+	value := func(f *querierv1.Function) int64 { return f.Self }
+	if orderBy == querierv1.FunctionsOrderBy_FUNCTIONS_ORDER_BY_TOTAL {
+		value = func(f *querierv1.Function) int64 { return f.Total }
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if a, b := value(functions[i]), value(functions[j]); a != b {
+			return a > b
+		}
+		if functions[i].Name != functions[j].Name {
+			return functions[i].Name < functions[j].Name
+		}
+		if functions[i].Filename != functions[j].Filename {
+			return functions[i].Filename < functions[j].Filename
+		}
+		return functions[i].StartLine < functions[j].StartLine
+	})
+	if maxFunctions > 0 && int64(len(functions)) > maxFunctions {
+		functions = functions[:maxFunctions]
+	}
+	return &querierv1.SelectFunctionsResponse{
+		Functions: functions,
+		Total:     m.total,
+	}
+}
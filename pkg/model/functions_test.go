@@ -0,0 +1,127 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+func Test_Tree_Functions(t *testing.T) {
+	tr := newTree([]stacktraces{
+		{locations: []string{"c", "b", "a"}, value: 1},
+		{locations: []string{"b", "a"}, value: 2},
+		// Recursive call.
+		{locations: []string{"b", "c", "b", "a"}, value: 4},
+	})
+
+	assert.Equal(t, map[string]*FunctionValues{
+		"a": {Self: 0, Total: 7},
+		"b": {Self: 6, Total: 7},
+		"c": {Self: 1, Total: 5},
+	}, tr.Functions())
+	assert.Empty(t, emptyTree().Functions())
+}
+
+func Test_NewFunctionsDiff(t *testing.T) {
+	left := newTree([]stacktraces{
+		{locations: []string{"b", "a"}, value: 1},
+		{locations: []string{"c", "a"}, value: 3},
+	})
+	right := newTree([]stacktraces{
+		{locations: []string{"b", "a"}, value: 4},
+		{locations: []string{"c", "a"}, value: 2},
+		{locations: []string{"d", "a"}, value: 2},
+	})
+
+	diff := NewFunctionsDiff(left, right, 0)
+	require.Len(t, diff, 4)
+	expected := []*querierv1.FunctionDiff{
+		{Name: "b", LeftSelf: 1, RightSelf: 4, LeftTotal: 1, RightTotal: 4, SelfDelta: 3, RelativeSelfDelta: 25},
+		{Name: "d", LeftSelf: 0, RightSelf: 2, LeftTotal: 0, RightTotal: 2, SelfDelta: 2, RelativeSelfDelta: 25},
+		{Name: "a", LeftSelf: 0, RightSelf: 0, LeftTotal: 4, RightTotal: 8, SelfDelta: 0, RelativeSelfDelta: 0},
+		{Name: "c", LeftSelf: 3, RightSelf: 2, LeftTotal: 3, RightTotal: 2, SelfDelta: -1, RelativeSelfDelta: -50},
+	}
+	for i := range expected {
+		assert.Equal(t, expected[i].String(), diff[i].String())
+	}
+
+	diff = NewFunctionsDiff(left, right, 1)
+	require.Len(t, diff, 1)
+	assert.Equal(t, "b", diff[0].Name)
+
+	// Empty left side.
+	diff = NewFunctionsDiff(emptyTree(), right, 0)
+	require.Len(t, diff, 4)
+	assert.Equal(t, "b", diff[0].Name)
+	assert.Equal(t, float64(50), diff[0].RelativeSelfDelta)
+}
+
+func Test_FunctionsMerger_Tree(t *testing.T) {
+	m := NewFunctionsMerger()
+	m.MergeTree(newTree([]stacktraces{
+		{locations: []string{"c", "b", "a"}, value: 1},
+		{locations: []string{"b", "a"}, value: 2},
+	}))
+	m.MergeTree(newTree([]stacktraces{
+		{locations: []string{"c", "a"}, value: 4},
+	}))
+
+	resp := m.SelectFunctionsResponse(querierv1.FunctionsOrderBy_FUNCTIONS_ORDER_BY_SELF, 0)
+	assert.Equal(t, int64(7), resp.Total)
+	expected := []*querierv1.Function{
+		{Name: "c", Self: 5, Total: 5},
+		{Name: "b", Self: 2, Total: 3},
+		{Name: "a", Self: 0, Total: 7},
+	}
+	require.Len(t, resp.Functions, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i].String(), resp.Functions[i].String())
+	}
+
+	resp = m.SelectFunctionsResponse(querierv1.FunctionsOrderBy_FUNCTIONS_ORDER_BY_TOTAL, 2)
+	require.Len(t, resp.Functions, 2)
+	assert.Equal(t, "a", resp.Functions[0].Name)
+	assert.Equal(t, "c", resp.Functions[1].Name)
+}
+
+func Test_FunctionsMerger_Profile(t *testing.T) {
+	p := &profilev1.Profile{
+		SampleType:  []*profilev1.ValueType{{Type: 1, Unit: 2}},
+		StringTable: []string{"", "cpu", "nanoseconds", "main", "main.go", "foo", "foo.go", "bar"},
+		Function: []*profilev1.Function{
+			{Id: 1, Name: 3, Filename: 4, StartLine: 10},
+			{Id: 2, Name: 5, Filename: 6, StartLine: 20},
+			{Id: 3, Name: 7, Filename: 6, StartLine: 30},
+		},
+		Location: []*profilev1.Location{
+			{Id: 1, Line: []*profilev1.Line{{FunctionId: 1, Line: 12}}},
+			{Id: 2, Line: []*profilev1.Line{{FunctionId: 2, Line: 22}}},
+			// bar is inlined into foo.
+			{Id: 3, Line: []*profilev1.Line{{FunctionId: 3, Line: 31}, {FunctionId: 2, Line: 25}}},
+		},
+		Sample: []*profilev1.Sample{
+			{LocationId: []uint64{2, 1}, Value: []int64{1}},
+			{LocationId: []uint64{3, 1}, Value: []int64{2}},
+			// Recursive call.
+			{LocationId: []uint64{2, 2, 1}, Value: []int64{4}},
+		},
+	}
+
+	m := NewFunctionsMerger()
+	m.MergeProfile(p)
+	resp := m.SelectFunctionsResponse(querierv1.FunctionsOrderBy_FUNCTIONS_ORDER_BY_SELF, 0)
+	assert.Equal(t, int64(7), resp.Total)
+	expected := []*querierv1.Function{
+		{Name: "foo", Filename: "foo.go", StartLine: 20, Self: 5, Total: 7},
+		{Name: "bar", Filename: "foo.go", StartLine: 30, Self: 2, Total: 2},
+		{Name: "main", Filename: "main.go", StartLine: 10, Self: 0, Total: 7},
+	}
+	require.Len(t, resp.Functions, len(expected))
+	for i := range expected {
+		assert.Equal(t, expected[i].String(), resp.Functions[i].String())
+	}
+}
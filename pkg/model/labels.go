@@ -14,6 +14,7 @@ import (
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
 	"github.com/grafana/pyroscope/pkg/slices"
 	"github.com/grafana/pyroscope/pkg/util"
@@ -30,6 +31,9 @@ const (
 	LabelNameDelta       = "__delta__"
 	LabelNameProfileName = pmodel.MetricNameLabel
 	LabelNameSessionID   = "__session_id__"
+	// LabelNameTenantID is the synthetic label identifying the tenant
+	// of the series returned by queries spanning multiple tenants.
+	LabelNameTenantID = "__tenant_id__"
 
 	LabelNameServiceName       = "service_name"
 	LabelNameServiceRepository = "service_repository"
@@ -263,6 +267,35 @@ func StringToLabelsPairs(s string) ([]*typesv1.LabelPair, error) {
 	return result, nil
 }
 
+// SelectorWithLabel adds the name="value" matcher to the label selector.
+func SelectorWithLabel(selector, name, value string) (string, error) {
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return "", err
+	}
+	m, err := labels.NewMatcher(labels.MatchEqual, name, value)
+	if err != nil {
+		return "", err
+	}
+	s := parser.VectorSelector{LabelMatchers: append(matchers, m)}
+	return s.String(), nil
+}
+
+// DiffByLabelSelectors returns label selectors of the left
+// and right sides of the diff by label request.
+func DiffByLabelSelectors(req *querierv1.DiffByLabelRequest) (left, right string, err error) {
+	if req.LabelName == "" {
+		return "", "", fmt.Errorf("label name is required")
+	}
+	if left, err = SelectorWithLabel(req.LabelSelector, req.LabelName, req.LeftValue); err != nil {
+		return "", "", err
+	}
+	if right, err = SelectorWithLabel(req.LabelSelector, req.LabelName, req.RightValue); err != nil {
+		return "", "", err
+	}
+	return left, right, nil
+}
+
 // LabelsFromStrings creates new labels from pairs of strings.
 func LabelsFromStrings(ss ...string) Labels {
 	if len(ss)%2 != 0 {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLabelsUnique(t *testing.T) {
@@ -133,3 +134,16 @@ func Test_SessionID_Parse(t *testing.T) {
 	_, err = ParseSessionID("not-a-session-id-either")
 	assert.NotNil(t, err)
 }
+
+func Test_SelectorWithLabel(t *testing.T) {
+	s, err := SelectorWithLabel(`{service_name="foo"}`, "version", "v1")
+	require.NoError(t, err)
+	assert.Equal(t, `{service_name="foo",version="v1"}`, s)
+
+	s, err = SelectorWithLabel(`{}`, "version", "v1")
+	require.NoError(t, err)
+	assert.Equal(t, `{version="v1"}`, s)
+
+	_, err = SelectorWithLabel(`{`, "version", "v1")
+	require.Error(t, err)
+}
@@ -53,21 +53,78 @@ func SelectorFromProfileType(profileType *typesv1.ProfileType) *labels.Matcher {
 	}
 }
 
-type SpanSelector map[uint64]struct{}
+// SpanSelector selects samples by their span ID or by their trace ID.
+// Trace IDs are matched by their lower 64 bits.
+type SpanSelector struct {
+	spans  map[uint64]struct{}
+	traces map[uint64]struct{}
+}
 
 func NewSpanSelector(spans []string) (SpanSelector, error) {
-	m := make(map[uint64]struct{}, len(spans))
-	b := make([]byte, 8)
-	for _, s := range spans {
-		if len(s) != 16 {
-			return nil, fmt.Errorf("invalid span id length: %q", s)
+	return NewSpanAndTraceSelector(spans, nil)
+}
+
+func NewSpanAndTraceSelector(spans, traces []string) (SpanSelector, error) {
+	s := SpanSelector{
+		spans:  make(map[uint64]struct{}, len(spans)),
+		traces: make(map[uint64]struct{}, len(traces)),
+	}
+	b := make([]byte, 16)
+	for _, x := range spans {
+		if len(x) != 16 {
+			return SpanSelector{}, fmt.Errorf("invalid span id length: %q", x)
+		}
+		if _, err := hex.Decode(b, util.YoloBuf(x)); err != nil {
+			return SpanSelector{}, err
+		}
+		s.spans[binary.LittleEndian.Uint64(b)] = struct{}{}
+	}
+	for _, x := range traces {
+		id, ok := DecodeTraceID(b, x)
+		if !ok {
+			return SpanSelector{}, fmt.Errorf("invalid trace id: %q", x)
+		}
+		s.traces[id] = struct{}{}
+	}
+	return s, nil
+}
+
+// SelectsTraces reports whether the selector matches any trace.
+func (s SpanSelector) SelectsTraces() bool { return len(s.traces) > 0 }
+
+// Match reports whether a sample with the given span and trace IDs is
+// selected. Zero IDs never match.
+func (s SpanSelector) Match(spanID, traceID uint64) bool {
+	if spanID != 0 {
+		if _, ok := s.spans[spanID]; ok {
+			return true
+		}
+	}
+	if traceID != 0 {
+		if _, ok := s.traces[traceID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeTraceID decodes the lower 64 bits of a hex encoded 128-bit or
+// 64-bit trace ID; tmp must be at least 16 bytes long. A 64-bit trace ID
+// has the same value as the 128-bit ID it is extended to.
+func DecodeTraceID(tmp []byte, s string) (uint64, bool) {
+	switch len(s) {
+	case 32:
+		if _, err := hex.Decode(tmp[:16], util.YoloBuf(s)); err != nil {
+			return 0, false
 		}
-		if _, err := hex.Decode(b, util.YoloBuf(s)); err != nil {
-			return nil, err
+		return binary.LittleEndian.Uint64(tmp[8:16]), true
+	case 16:
+		if _, err := hex.Decode(tmp[:8], util.YoloBuf(s)); err != nil {
+			return 0, false
 		}
-		m[binary.LittleEndian.Uint64(b)] = struct{}{}
+		return binary.LittleEndian.Uint64(tmp[:8]), true
 	}
-	return m, nil
+	return 0, false
 }
 
 func StacktracePartitionFromProfile(lbls []Labels, p *profilev1.Profile) uint64 {
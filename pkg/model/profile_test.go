@@ -34,3 +34,41 @@ func Test_filename_extraction(t *testing.T) {
 	assert.Equal(t, "", extractMappingFilename(`[vsyscall]`))
 	assert.Equal(t, "not a path actually", extractMappingFilename(`not a path actually`))
 }
+
+func Test_SpanAndTraceSelector(t *testing.T) {
+	s, err := NewSpanAndTraceSelector(
+		[]string{"badbadbadbadbadb"},
+		[]string{"0123456789abcdef0123456789abcdef", "fedcba9876543210"},
+	)
+	assert.NoError(t, err)
+	assert.True(t, s.SelectsTraces())
+
+	tmp := make([]byte, 16)
+	span, ok := DecodeTraceID(tmp, "badbadbadbadbadb")
+	assert.True(t, ok)
+	long, ok := DecodeTraceID(tmp, "0123456789abcdef0123456789abcdef")
+	assert.True(t, ok)
+	short, ok := DecodeTraceID(tmp, "fedcba9876543210")
+	assert.True(t, ok)
+	extended, ok := DecodeTraceID(tmp, "0000000000000000fedcba9876543210")
+	assert.True(t, ok)
+	assert.Equal(t, short, extended)
+
+	assert.True(t, s.Match(span, 0))
+	assert.True(t, s.Match(0, long))
+	assert.True(t, s.Match(0, extended))
+	assert.False(t, s.Match(long, 0))
+	assert.False(t, s.Match(0, span))
+	assert.False(t, s.Match(0, 0))
+
+	_, ok = DecodeTraceID(tmp, "0123")
+	assert.False(t, ok)
+	_, ok = DecodeTraceID(tmp, "not-a-hex-trace!")
+	assert.False(t, ok)
+	_, err = NewSpanAndTraceSelector(nil, []string{"0123"})
+	assert.Error(t, err)
+
+	s, err = NewSpanSelector([]string{"badbadbadbadbadb"})
+	assert.NoError(t, err)
+	assert.False(t, s.SelectsTraces())
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"sort"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+// QueryEstimateHeader is the header of the dry-run SelectMergeProfile
+// responses holding the JSON encoded estimate of the query.
+const QueryEstimateHeader = "X-Pyroscope-Query-Estimate"
+
+// SetQueryEstimateHeader sets the estimate header of the response.
+func SetQueryEstimateHeader[T any](resp *connect.Response[T], estimate *querierv1.QueryEstimate) error {
+	data, err := protojson.Marshal(estimate)
+	if err != nil {
+		return err
+	}
+	resp.Header().Set(QueryEstimateHeader, string(data))
+	return nil
+}
+
+// QueryEstimateFromHeader returns the estimate of the dry-run response.
+func QueryEstimateFromHeader[T any](resp *connect.Response[T]) (*querierv1.QueryEstimate, error) {
+	var estimate querierv1.QueryEstimate
+	if v := resp.Header().Get(QueryEstimateHeader); v != "" {
+		if err := protojson.Unmarshal([]byte(v), &estimate); err != nil {
+			return nil, err
+		}
+	}
+	return &estimate, nil
+}
+
+// MergeQueryEstimates adds the estimate src to dst.
+func MergeQueryEstimates(dst, src *querierv1.QueryEstimate) {
+	if src == nil {
+		return
+	}
+	dst.Blocks += src.Blocks
+	dst.Rows += src.Rows
+	dst.Bytes += src.Bytes
+	for _, r := range src.DownsampledResolutions {
+		i := sort.Search(len(dst.DownsampledResolutions), func(i int) bool {
+			return dst.DownsampledResolutions[i] >= r
+		})
+		if i < len(dst.DownsampledResolutions) && dst.DownsampledResolutions[i] == r {
+			continue
+		}
+		dst.DownsampledResolutions = append(dst.DownsampledResolutions, 0)
+		copy(dst.DownsampledResolutions[i+1:], dst.DownsampledResolutions[i:])
+		dst.DownsampledResolutions[i] = r
+	}
+}
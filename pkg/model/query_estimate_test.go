@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+)
+
+func TestMergeQueryEstimates(t *testing.T) {
+	var e querierv1.QueryEstimate
+	MergeQueryEstimates(&e, &querierv1.QueryEstimate{Blocks: 1, Rows: 10, Bytes: 100, DownsampledResolutions: []int64{3600000}})
+	MergeQueryEstimates(&e, nil)
+	MergeQueryEstimates(&e, &querierv1.QueryEstimate{Blocks: 2, Rows: 20, Bytes: 200, DownsampledResolutions: []int64{300000, 3600000}})
+	require.Equal(t, uint64(3), e.Blocks)
+	require.Equal(t, uint64(30), e.Rows)
+	require.Equal(t, uint64(300), e.Bytes)
+	require.Equal(t, []int64{300000, 3600000}, e.DownsampledResolutions)
+}
+
+func TestQueryEstimateHeader(t *testing.T) {
+	expected := &querierv1.QueryEstimate{Blocks: 1, Rows: 10, Bytes: 100, DownsampledResolutions: []int64{300000}}
+	resp := connect.NewResponse(&profilev1.Profile{})
+	require.NoError(t, SetQueryEstimateHeader(resp, expected))
+	actual, err := QueryEstimateFromHeader(resp)
+	require.NoError(t, err)
+	require.Equal(t, expected.String(), actual.String())
+}
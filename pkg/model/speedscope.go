@@ -0,0 +1,87 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+// The types below describe a subset of the speedscope file format.
+// See https://github.com/jlfwong/speedscope/blob/main/src/lib/file-format-spec.ts
+const speedscopeSchema = "https://www.speedscope.app/file-format-schema.json"
+
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+// WriteSpeedscope writes the tree as a sampled speedscope profile:
+// every stack trace becomes a sample weighted by its self value.
+func (t *Tree) WriteSpeedscope(dst io.Writer, profileType *typesv1.ProfileType) error {
+	name := profileType.GetID()
+	p := speedscopeProfile{
+		Type:     "sampled",
+		Name:     name,
+		Unit:     speedscopeUnit(profileType.GetSampleUnit()),
+		EndValue: t.Total(),
+		Samples:  make([][]int, 0),
+		Weights:  make([]int64, 0),
+	}
+	frames := make([]speedscopeFrame, 0)
+	frameIndex := make(map[string]int)
+	t.IterateStacks(func(_ string, self int64, stack []string) {
+		// The stack is ordered leaf first, whereas
+		// samples are expected to be ordered root first.
+		sample := make([]int, len(stack))
+		for i, frame := range stack {
+			idx, ok := frameIndex[frame]
+			if !ok {
+				idx = len(frames)
+				frameIndex[frame] = idx
+				frames = append(frames, speedscopeFrame{Name: frame})
+			}
+			sample[len(stack)-1-i] = idx
+		}
+		p.Samples = append(p.Samples, sample)
+		p.Weights = append(p.Weights, self)
+	})
+	return json.NewEncoder(dst).Encode(speedscopeFile{
+		Schema:   speedscopeSchema,
+		Shared:   speedscopeShared{Frames: frames},
+		Profiles: []speedscopeProfile{p},
+		Name:     name,
+		Exporter: "pyroscope",
+	})
+}
+
+func speedscopeUnit(sampleUnit string) string {
+	switch sampleUnit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return sampleUnit
+	default:
+		return "none"
+	}
+}
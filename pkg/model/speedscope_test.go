@@ -0,0 +1,39 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_Tree_WriteSpeedscope(t *testing.T) {
+	tr := newTree([]stacktraces{
+		{locations: []string{"c", "b", "a"}, value: 3},
+		{locations: []string{"b", "a"}, value: 1},
+	})
+	var buf bytes.Buffer
+	require.NoError(t, tr.WriteSpeedscope(&buf, &typesv1.ProfileType{
+		ID:         "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		SampleUnit: "nanoseconds",
+	}))
+	assert.JSONEq(t, `{
+  "$schema": "https://www.speedscope.app/file-format-schema.json",
+  "shared": {"frames": [{"name": "b"}, {"name": "a"}, {"name": "c"}]},
+  "profiles": [{
+    "type": "sampled",
+    "name": "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+    "unit": "nanoseconds",
+    "startValue": 0,
+    "endValue": 4,
+    "samples": [[1, 0], [1, 0, 2]],
+    "weights": [1, 3]
+  }],
+  "name": "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+  "activeProfileIndex": 0,
+  "exporter": "pyroscope"
+}`, buf.String())
+}
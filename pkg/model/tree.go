@@ -11,6 +11,7 @@ import (
 	dvarint "github.com/dennwc/varint"
 	"github.com/xlab/treeprint"
 
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 	"github.com/grafana/pyroscope/pkg/og/util/varint"
 	"github.com/grafana/pyroscope/pkg/slices"
 )
@@ -477,3 +478,42 @@ func (m *TreeMerger) Tree() *Tree {
 	}
 	return m.t
 }
+
+// TreeFromProfile builds a tree of the profile stack traces, using the
+// first sample value. Inlined functions are expanded into separate
+// frames. Locations that have not been symbolized are named after
+// their address.
+func TreeFromProfile(p *profilev1.Profile) *Tree {
+	functions := make(map[uint64]string, len(p.Function))
+	for _, f := range p.Function {
+		functions[f.Id] = p.StringTable[f.Name]
+	}
+	locations := make(map[uint64]*profilev1.Location, len(p.Location))
+	for _, l := range p.Location {
+		locations[l.Id] = l
+	}
+	t := new(Tree)
+	stack := make([]string, 0, 64)
+	for _, s := range p.Sample {
+		if len(s.Value) == 0 || s.Value[0] == 0 {
+			continue
+		}
+		stack = stack[:0]
+		// Locations and lines are ordered leaf first.
+		for i := len(s.LocationId) - 1; i >= 0; i-- {
+			loc, ok := locations[s.LocationId[i]]
+			if !ok {
+				continue
+			}
+			if len(loc.Line) == 0 {
+				stack = append(stack, fmt.Sprintf("0x%x", loc.Address))
+				continue
+			}
+			for j := len(loc.Line) - 1; j >= 0; j-- {
+				stack = append(stack, functions[loc.Line[j].FunctionId])
+			}
+		}
+		t.InsertStack(s.Value[0], stack...)
+	}
+	return t
+}
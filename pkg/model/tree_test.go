@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 )
 
 func Test_Tree(t *testing.T) {
@@ -303,6 +305,38 @@ func Test_FormatNames(t *testing.T) {
 	require.Equal(t, expected.String(), x.String())
 }
 
+func Test_TreeFromProfile(t *testing.T) {
+	p := &profilev1.Profile{
+		SampleType:  []*profilev1.ValueType{{Type: 1, Unit: 2}},
+		StringTable: []string{"", "cpu", "nanoseconds", "a", "b", "c"},
+		Function: []*profilev1.Function{
+			{Id: 1, Name: 3},
+			{Id: 2, Name: 4},
+			{Id: 3, Name: 5},
+		},
+		Location: []*profilev1.Location{
+			{Id: 1, Line: []*profilev1.Line{{FunctionId: 1}}},
+			// c is inlined into b.
+			{Id: 2, Line: []*profilev1.Line{{FunctionId: 3}, {FunctionId: 2}}},
+			{Id: 3, Address: 0x10},
+		},
+		Sample: []*profilev1.Sample{
+			{LocationId: []uint64{2, 1}, Value: []int64{3}},
+			{LocationId: []uint64{3, 1}, Value: []int64{2}},
+			{LocationId: []uint64{1}, Value: []int64{0}},
+		},
+	}
+	expected := newTree([]stacktraces{
+		{locations: []string{"c", "b", "a"}, value: 3},
+		{locations: []string{"0x10", "a"}, value: 2},
+	})
+	require.Equal(t, expected.String(), TreeFromProfile(p).String())
+
+	var buf bytes.Buffer
+	TreeFromProfile(p).WriteCollapsed(&buf)
+	assert.Equal(t, "a;0x10 2\na;b;c 3\n", buf.String())
+}
+
 func emptyTree() *Tree {
 	return &Tree{}
 }
@@ -0,0 +1,244 @@
+package objstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type DiskCacheConfig struct {
+	Directory    string `yaml:"directory" category:"experimental"`
+	MaxSizeBytes int64  `yaml:"max_size_bytes" category:"experimental"`
+}
+
+func (cfg *DiskCacheConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Directory, prefix+"directory", "./data/objstore-cache", "Directory of the on-disk cache. The directory is not required to be persisted between restarts.")
+	f.Int64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", 10<<30, "Maximum size of the on-disk cache, in bytes.")
+}
+
+func (cfg *DiskCacheConfig) Validate() error {
+	if cfg.Directory == "" {
+		return errors.New("on-disk cache directory is required")
+	}
+	if cfg.MaxSizeBytes <= 0 {
+		return errors.New("on-disk cache size must be positive")
+	}
+	return nil
+}
+
+// DiskCache is an LRU cache storing items as files in a local directory.
+// An item file is named after the hash of the key, and holds the item
+// expiration time, followed by the data. The cache is limited by the
+// total size of the files; on start, existing files are loaded in the
+// order of their modification time.
+type DiskCache struct {
+	dir     string
+	maxSize int64
+	logger  log.Logger
+
+	mu   sync.Mutex
+	lru  *simplelru.LRU[string, int64]
+	size int64
+
+	items prometheus.GaugeFunc
+	bytes prometheus.GaugeFunc
+}
+
+const diskCacheHeaderSize = 8
+
+func NewDiskCache(cfg DiskCacheConfig, logger log.Logger, reg prometheus.Registerer) (*DiskCache, error) {
+	c := &DiskCache{
+		dir:     cfg.Directory,
+		maxSize: cfg.MaxSizeBytes,
+		logger:  logger,
+	}
+	c.lru, _ = simplelru.NewLRU[string, int64](math.MaxInt32, func(name string, size int64) {
+		c.size -= size
+		if err := os.Remove(c.path(name)); err != nil && !os.IsNotExist(err) {
+			level.Warn(c.logger).Log("msg", "failed to remove cache file", "name", name, "err", err)
+		}
+	})
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"cache": c.Name()}, reg)
+	c.items = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "pyroscope_objstore_cache_items",
+		Help: "Number of items in the object storage cache.",
+	}, func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return float64(c.lru.Len())
+	})
+	c.bytes = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "pyroscope_objstore_cache_size_bytes",
+		Help: "Size of the items in the object storage cache.",
+	}, func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return float64(c.size)
+	})
+	return c, nil
+}
+
+func (c *DiskCache) Name() string { return CacheBackendDisk }
+
+func (c *DiskCache) load() error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	type file struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	err := filepath.WalkDir(c.dir, func(path string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+		name := filepath.Base(path)
+		if filepath.Ext(name) == ".tmp" {
+			// Leftovers of interrupted writes.
+			return os.Remove(path)
+		}
+		if len(name) != sha256.Size*2 {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, file{name: name, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		c.lru.Add(f.name, f.size)
+		c.size += f.size
+	}
+	for c.size > c.maxSize {
+		c.lru.RemoveOldest()
+	}
+	return nil
+}
+
+func (c *DiskCache) path(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+func diskCacheFileName(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *DiskCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	expiresAt := time.Now().Add(ttl)
+	for k, v := range data {
+		if err := c.store(diskCacheFileName(k), v, expiresAt); err != nil {
+			level.Warn(c.logger).Log("msg", "failed to store cache item", "key", k, "err", err)
+		}
+	}
+}
+
+func (c *DiskCache) store(name string, data []byte, expiresAt time.Time) error {
+	size := int64(diskCacheHeaderSize + len(data))
+	if size > c.maxSize {
+		return nil
+	}
+	c.mu.Lock()
+	ok := c.lru.Contains(name)
+	c.mu.Unlock()
+	if ok {
+		// Cached objects are immutable.
+		return nil
+	}
+	path := c.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	var header [diskCacheHeaderSize]byte
+	binary.BigEndian.PutUint64(header[:], uint64(expiresAt.UnixNano()))
+	_, err = f.Write(header[:])
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru.Contains(name) {
+		// Stored concurrently. Note that replacing an item would
+		// remove the file in the eviction callback.
+		return os.Remove(tmp)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	c.lru.Add(name, size)
+	c.size += size
+	for c.size > c.maxSize {
+		c.lru.RemoveOldest()
+	}
+	return nil
+}
+
+func (c *DiskCache) Fetch(_ context.Context, keys []string) map[string][]byte {
+	now := time.Now()
+	found := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		name := diskCacheFileName(k)
+		c.mu.Lock()
+		_, ok := c.lru.Get(name)
+		c.mu.Unlock()
+		if !ok {
+			continue
+		}
+		b, err := os.ReadFile(c.path(name))
+		if err != nil {
+			// The file might have been evicted concurrently.
+			continue
+		}
+		if len(b) < diskCacheHeaderSize || now.UnixNano() > int64(binary.BigEndian.Uint64(b)) {
+			c.mu.Lock()
+			c.lru.Remove(name)
+			c.mu.Unlock()
+			continue
+		}
+		found[k] = b[diskCacheHeaderSize:]
+	}
+	return found
+}
@@ -0,0 +1,113 @@
+package objstore
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type InMemoryCacheConfig struct {
+	MaxSizeBytes int64 `yaml:"max_size_bytes" category:"experimental"`
+}
+
+func (cfg *InMemoryCacheConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.Int64Var(&cfg.MaxSizeBytes, prefix+"max-size-bytes", 1<<30, "Maximum size of the in-memory cache, in bytes.")
+}
+
+func (cfg *InMemoryCacheConfig) Validate() error {
+	if cfg.MaxSizeBytes <= 0 {
+		return errors.New("in-memory cache size must be positive")
+	}
+	return nil
+}
+
+// InMemoryCache is an LRU cache limited by the total size of the items.
+type InMemoryCache struct {
+	maxSize int64
+
+	mu   sync.Mutex
+	lru  *simplelru.LRU[string, cacheItem]
+	size int64
+
+	items prometheus.GaugeFunc
+	bytes prometheus.GaugeFunc
+}
+
+type cacheItem struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func NewInMemoryCache(cfg InMemoryCacheConfig, reg prometheus.Registerer) *InMemoryCache {
+	c := &InMemoryCache{maxSize: cfg.MaxSizeBytes}
+	// The number of items is only limited by their size. Note that
+	// the callback is also invoked when an item is replaced.
+	c.lru, _ = simplelru.NewLRU[string, cacheItem](math.MaxInt32, func(k string, v cacheItem) {
+		c.size -= itemSize(k, v.data)
+	})
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"cache": c.Name()}, reg)
+	c.items = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "pyroscope_objstore_cache_items",
+		Help: "Number of items in the object storage cache.",
+	}, func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return float64(c.lru.Len())
+	})
+	c.bytes = promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "pyroscope_objstore_cache_size_bytes",
+		Help: "Size of the items in the object storage cache.",
+	}, func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return float64(c.size)
+	})
+	return c
+}
+
+func itemSize(key string, data []byte) int64 { return int64(len(key) + len(data)) }
+
+func (c *InMemoryCache) Name() string { return CacheBackendInMemory }
+
+func (c *InMemoryCache) Store(_ context.Context, data map[string][]byte, ttl time.Duration) {
+	expiresAt := time.Now().Add(ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range data {
+		s := itemSize(k, v)
+		if s > c.maxSize {
+			continue
+		}
+		c.lru.Add(k, cacheItem{data: v, expiresAt: expiresAt})
+		c.size += s
+		for c.size > c.maxSize {
+			c.lru.RemoveOldest()
+		}
+	}
+}
+
+func (c *InMemoryCache) Fetch(_ context.Context, keys []string) map[string][]byte {
+	now := time.Now()
+	found := make(map[string][]byte, len(keys))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range keys {
+		v, ok := c.lru.Get(k)
+		if !ok {
+			continue
+		}
+		if now.After(v.expiresAt) {
+			c.lru.Remove(k)
+			continue
+		}
+		found[k] = v.data
+	}
+	return found
+}
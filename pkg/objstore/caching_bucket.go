@@ -0,0 +1,374 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/thanos-io/objstore"
+)
+
+// Cache is a key-value store backing the CachingBucket.
+// The interface allows for remote implementations.
+type Cache interface {
+	// Store writes the data into the cache. The cache may retain
+	// the byte slices: the caller must not modify them.
+	Store(ctx context.Context, data map[string][]byte, ttl time.Duration)
+	// Fetch returns the cached data. Keys that are not found
+	// in the cache are not present in the returned map.
+	Fetch(ctx context.Context, keys []string) map[string][]byte
+	Name() string
+}
+
+const (
+	CacheBackendNone     = ""
+	CacheBackendInMemory = "inmemory"
+	CacheBackendDisk     = "disk"
+)
+
+var supportedCacheBackends = []string{CacheBackendNone, CacheBackendInMemory, CacheBackendDisk}
+
+// Cached file types.
+const (
+	cacheFileParquet   = "parquet"
+	cacheFileTSDBIndex = "tsdb_index"
+	cacheFileSymbols   = "symbols"
+)
+
+type CachingBucketConfig struct {
+	Backend  string              `yaml:"backend" category:"experimental"`
+	InMemory InMemoryCacheConfig `yaml:"inmemory"`
+	Disk     DiskCacheConfig     `yaml:"disk"`
+
+	SubrangeSize  int64 `yaml:"subrange_size" category:"advanced"`
+	MaxObjectSize int64 `yaml:"max_object_size" category:"advanced"`
+
+	ParquetTTL    time.Duration `yaml:"parquet_ttl" category:"advanced"`
+	TSDBIndexTTL  time.Duration `yaml:"tsdb_index_ttl" category:"advanced"`
+	SymbolsTTL    time.Duration `yaml:"symbols_ttl" category:"advanced"`
+	AttributesTTL time.Duration `yaml:"attributes_ttl" category:"advanced"`
+}
+
+func (cfg *CachingBucketConfig) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.StringVar(&cfg.Backend, prefix+"backend", CacheBackendNone, fmt.Sprintf("Backend of the object storage cache. Supported backends are: %s. An empty value disables the cache.", strings.Join(supportedCacheBackends[1:], ", ")))
+	cfg.InMemory.RegisterFlagsWithPrefix(prefix+"inmemory.", f)
+	cfg.Disk.RegisterFlagsWithPrefix(prefix+"disk.", f)
+	f.Int64Var(&cfg.SubrangeSize, prefix+"subrange-size", 64<<10, "Size of the object ranges cached: range reads are aligned to subranges of the size.")
+	f.Int64Var(&cfg.MaxObjectSize, prefix+"max-object-size", 64<<20, "Maximum size of an object read at once to be cached.")
+	f.DurationVar(&cfg.ParquetTTL, prefix+"parquet-ttl", 24*time.Hour, "TTL of the cached parquet file pages. 0 disables caching of the file type.")
+	f.DurationVar(&cfg.TSDBIndexTTL, prefix+"tsdb-index-ttl", 24*time.Hour, "TTL of the cached TSDB index files. 0 disables caching of the file type.")
+	f.DurationVar(&cfg.SymbolsTTL, prefix+"symbols-ttl", 24*time.Hour, "TTL of the cached symbols (symdb) files. 0 disables caching of the file type.")
+	f.DurationVar(&cfg.AttributesTTL, prefix+"attributes-ttl", 24*time.Hour, "TTL of the cached object attributes, such as the object size.")
+}
+
+func (cfg *CachingBucketConfig) Validate() error {
+	switch cfg.Backend {
+	case CacheBackendNone:
+		return nil
+	case CacheBackendInMemory:
+		if err := cfg.InMemory.Validate(); err != nil {
+			return err
+		}
+	case CacheBackendDisk:
+		if err := cfg.Disk.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported cache backend: %q", cfg.Backend)
+	}
+	if cfg.SubrangeSize <= 0 {
+		return errors.New("cache subrange size must be positive")
+	}
+	return nil
+}
+
+func (cfg *CachingBucketConfig) ttl(fileType string) time.Duration {
+	switch fileType {
+	case cacheFileParquet:
+		return cfg.ParquetTTL
+	case cacheFileTSDBIndex:
+		return cfg.TSDBIndexTTL
+	case cacheFileSymbols:
+		return cfg.SymbolsTTL
+	}
+	return 0
+}
+
+// cachedFileType returns the type of the block file, or an empty
+// string, if the object is not cached. Only immutable block files
+// are cached.
+func cachedFileType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".symdb"):
+		return cacheFileSymbols
+	case strings.HasSuffix(name, ".parquet"):
+		return cacheFileParquet
+	case strings.HasSuffix(name, "index.tsdb"):
+		return cacheFileTSDBIndex
+	}
+	return ""
+}
+
+// NewCache creates the cache specified in the configuration,
+// or returns nil, if no cache is configured.
+func NewCache(cfg CachingBucketConfig, logger log.Logger, reg prometheus.Registerer) (Cache, error) {
+	switch cfg.Backend {
+	case CacheBackendNone:
+		return nil, nil
+	case CacheBackendInMemory:
+		return NewInMemoryCache(cfg.InMemory, reg), nil
+	case CacheBackendDisk:
+		return NewDiskCache(cfg.Disk, logger, reg)
+	}
+	return nil, fmt.Errorf("unsupported cache backend: %q", cfg.Backend)
+}
+
+// CachingBucket caches reads of block files: whole objects, object
+// ranges, and object attributes. Ranges are split into subranges of
+// a fixed size, which are cached independently.
+type CachingBucket struct {
+	Bucket
+	cache   Cache
+	cfg     CachingBucketConfig
+	metrics *cachingBucketMetrics
+}
+
+// NewCachingBucket wraps the bucket with a cache specified in the
+// configuration. If no cache is configured, the bucket is returned.
+func NewCachingBucket(bkt Bucket, cfg CachingBucketConfig, logger log.Logger, reg prometheus.Registerer) (Bucket, error) {
+	c, err := NewCache(cfg, logger, reg)
+	if err != nil || c == nil {
+		return bkt, err
+	}
+	return NewCachingBucketWithCache(bkt, c, cfg, reg), nil
+}
+
+func NewCachingBucketWithCache(bkt Bucket, c Cache, cfg CachingBucketConfig, reg prometheus.Registerer) *CachingBucket {
+	return &CachingBucket{
+		Bucket:  bkt,
+		cache:   c,
+		cfg:     cfg,
+		metrics: newCachingBucketMetrics(c.Name(), reg),
+	}
+}
+
+type cachingBucketMetrics struct {
+	requests *prometheus.CounterVec
+	hits     *prometheus.CounterVec
+}
+
+func newCachingBucketMetrics(name string, reg prometheus.Registerer) *cachingBucketMetrics {
+	reg = prometheus.WrapRegistererWith(prometheus.Labels{"cache": name}, reg)
+	return &cachingBucketMetrics{
+		requests: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_objstore_cache_requests_total",
+			Help: "Total number of object storage cache requests. Range reads count a request per subrange.",
+		}, []string{"operation", "file_type"}),
+		hits: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_objstore_cache_hits_total",
+			Help: "Total number of object storage cache requests served from the cache.",
+		}, []string{"operation", "file_type"}),
+	}
+}
+
+func (m *cachingBucketMetrics) observe(op, fileType string, requests, hits int) {
+	m.requests.WithLabelValues(op, fileType).Add(float64(requests))
+	m.hits.WithLabelValues(op, fileType).Add(float64(hits))
+}
+
+func (b *CachingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	fileType := cachedFileType(name)
+	ttl := b.cfg.ttl(fileType)
+	if ttl <= 0 {
+		return b.Bucket.Get(ctx, name)
+	}
+	key := "content:" + name
+	if data, ok := b.cache.Fetch(ctx, []string{key})[key]; ok {
+		b.metrics.observe(objstore.OpGet, fileType, 1, 1)
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	b.metrics.observe(objstore.OpGet, fileType, 1, 0)
+	rc, err := b.Bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, b.cfg.MaxObjectSize+1))
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	if int64(len(data)) > b.cfg.MaxObjectSize {
+		// The object is too large to be cached.
+		return readCloser{
+			Reader: io.MultiReader(bytes.NewReader(data), rc),
+			Closer: rc,
+		}, nil
+	}
+	if err = rc.Close(); err != nil {
+		return nil, err
+	}
+	b.cache.Store(ctx, map[string][]byte{key: data}, ttl)
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (b *CachingBucket) ReaderAt(ctx context.Context, name string) (ReaderAtCloser, error) {
+	if b.cfg.ttl(cachedFileType(name)) <= 0 {
+		return b.Bucket.ReaderAt(ctx, name)
+	}
+	return &ReaderAt{
+		GetRangeReader: b,
+		name:           name,
+		ctx:            ctx,
+	}, nil
+}
+
+func (b *CachingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	fileType := cachedFileType(name)
+	ttl := b.cfg.ttl(fileType)
+	if ttl <= 0 || off < 0 || length <= 0 {
+		return b.Bucket.GetRange(ctx, name, off, length)
+	}
+	size, err := b.objectSize(ctx, name, fileType)
+	if err != nil {
+		return nil, err
+	}
+	if off >= size {
+		return b.Bucket.GetRange(ctx, name, off, length)
+	}
+	end := off + length
+	if end > size {
+		end = size
+	}
+
+	s := b.cfg.SubrangeSize
+	first := off / s * s
+	n := int((end-1)/s - first/s + 1)
+	keys := make([]string, n)
+	for i := range keys {
+		start := first + int64(i)*s
+		keys[i] = subrangeKey(name, start, subrangeEnd(start, s, size))
+	}
+	hits := b.cache.Fetch(ctx, keys)
+	subranges := make([][]byte, n)
+	var hit int
+	for i, k := range keys {
+		start := first + int64(i)*s
+		if d, ok := hits[k]; ok && int64(len(d)) == subrangeEnd(start, s, size)-start {
+			subranges[i] = d
+			hit++
+		}
+	}
+	b.metrics.observe(objstore.OpGetRange, fileType, n, hit)
+
+	// Fetch runs of missing subranges with a single request each.
+	store := make(map[string][]byte, n-hit)
+	for i := 0; i < n; {
+		if subranges[i] != nil {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < n && subranges[j] == nil {
+			j++
+		}
+		start := first + int64(i)*s
+		buf, err := b.readRange(ctx, name, start, subrangeEnd(first+int64(j-1)*s, s, size)-start)
+		if err != nil {
+			return nil, err
+		}
+		for ; i < j; i++ {
+			l := subrangeEnd(first+int64(i)*s, s, size) - (first + int64(i)*s)
+			subranges[i], buf = buf[:l:l], buf[l:]
+			store[keys[i]] = subranges[i]
+		}
+	}
+	if len(store) > 0 {
+		b.cache.Store(ctx, store, ttl)
+	}
+
+	subranges[n-1] = subranges[n-1][:end-(first+int64(n-1)*s)]
+	subranges[0] = subranges[0][off-first:]
+	readers := make([]io.Reader, n)
+	for i, d := range subranges {
+		readers[i] = bytes.NewReader(d)
+	}
+	return io.NopCloser(io.MultiReader(readers...)), nil
+}
+
+func (b *CachingBucket) readRange(ctx context.Context, name string, off, length int64) ([]byte, error) {
+	rc, err := b.Bucket.GetRange(ctx, name, off, length)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	buf := make([]byte, length)
+	if _, err = io.ReadFull(rc, buf); err != nil {
+		return nil, errors.Wrapf(err, "reading range %d-%d of %s", off, off+length, name)
+	}
+	return buf, nil
+}
+
+func subrangeEnd(start, subrangeSize, size int64) int64 {
+	if end := start + subrangeSize; end < size {
+		return end
+	}
+	return size
+}
+
+func subrangeKey(name string, start, end int64) string {
+	return "subrange:" + name + ":" + strconv.FormatInt(start, 10) + ":" + strconv.FormatInt(end, 10)
+}
+
+func (b *CachingBucket) objectSize(ctx context.Context, name, fileType string) (int64, error) {
+	key := "size:" + name
+	if d, ok := b.cache.Fetch(ctx, []string{key})[key]; ok && len(d) == 8 {
+		b.metrics.observe(objstore.OpAttributes, fileType, 1, 1)
+		return int64(binary.BigEndian.Uint64(d)), nil
+	}
+	b.metrics.observe(objstore.OpAttributes, fileType, 1, 0)
+	attrs, err := b.Bucket.Attributes(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	if b.cfg.AttributesTTL > 0 {
+		d := make([]byte, 8)
+		binary.BigEndian.PutUint64(d, uint64(attrs.Size))
+		b.cache.Store(ctx, map[string][]byte{key: d}, b.cfg.AttributesTTL)
+	}
+	return attrs.Size, nil
+}
+
+// ReaderWithExpectedErrs implements objstore.Bucket.
+func (b *CachingBucket) ReaderWithExpectedErrs(fn IsOpFailureExpectedFunc) BucketReader {
+	return b.WithExpectedErrs(fn)
+}
+
+// WithExpectedErrs implements objstore.Bucket.
+func (b *CachingBucket) WithExpectedErrs(fn IsOpFailureExpectedFunc) Bucket {
+	if ib, ok := b.Bucket.(InstrumentedBucket); ok {
+		return &CachingBucket{
+			Bucket:  ib.WithExpectedErrs(fn),
+			cache:   b.cache,
+			cfg:     b.cfg,
+			metrics: b.metrics,
+		}
+	}
+	return b
+}
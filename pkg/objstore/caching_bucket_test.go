@@ -0,0 +1,177 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+)
+
+type countingBucket struct {
+	objstore.Bucket
+	gets       int
+	getRanges  int
+	attributes int
+}
+
+func (b *countingBucket) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	b.gets++
+	return b.Bucket.Get(ctx, name)
+}
+
+func (b *countingBucket) GetRange(ctx context.Context, name string, off, length int64) (io.ReadCloser, error) {
+	b.getRanges++
+	return b.Bucket.GetRange(ctx, name, off, length)
+}
+
+func (b *countingBucket) Attributes(ctx context.Context, name string) (objstore.ObjectAttributes, error) {
+	b.attributes++
+	return b.Bucket.Attributes(ctx, name)
+}
+
+func newTestCachingBucket(t *testing.T, backend string) (*CachingBucket, *countingBucket) {
+	t.Helper()
+	cfg := CachingBucketConfig{
+		Backend:       backend,
+		InMemory:      InMemoryCacheConfig{MaxSizeBytes: 1 << 20},
+		Disk:          DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 1 << 20},
+		SubrangeSize:  10,
+		MaxObjectSize: 64,
+		ParquetTTL:    time.Hour,
+		TSDBIndexTTL:  time.Hour,
+		AttributesTTL: time.Hour,
+	}
+	require.NoError(t, cfg.Validate())
+	reg := prometheus.NewRegistry()
+	c, err := NewCache(cfg, log.NewNopLogger(), reg)
+	require.NoError(t, err)
+	counting := &countingBucket{Bucket: objstore.NewInMemBucket()}
+	return NewCachingBucketWithCache(NewBucket(counting), c, cfg, reg), counting
+}
+
+func Test_CachingBucket(t *testing.T) {
+	for _, backend := range []string{CacheBackendInMemory, CacheBackendDisk} {
+		backend := backend
+		t.Run(backend, func(t *testing.T) {
+			ctx := context.Background()
+			b, counting := newTestCachingBucket(t, backend)
+			content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+			for _, name := range []string{"a/profiles.parquet", "a/index.tsdb", "a/meta.json"} {
+				require.NoError(t, b.Upload(ctx, name, bytes.NewReader(content)))
+			}
+
+			readRange := func(name string, off, length int64) []byte {
+				rc, err := b.GetRange(ctx, name, off, length)
+				require.NoError(t, err)
+				defer rc.Close()
+				data, err := io.ReadAll(rc)
+				require.NoError(t, err)
+				return data
+			}
+
+			t.Run("GetRange", func(t *testing.T) {
+				require.Equal(t, content[5:25], readRange("a/profiles.parquet", 5, 20))
+				require.Equal(t, 1, counting.getRanges)
+				require.Equal(t, 1, counting.attributes)
+				// Served from the cache.
+				require.Equal(t, content[12:18], readRange("a/profiles.parquet", 12, 6))
+				require.Equal(t, content[10:30], readRange("a/profiles.parquet", 10, 20))
+				require.Equal(t, 1, counting.getRanges)
+				// Partially cached: the range exceeds the object.
+				require.Equal(t, content[20:], readRange("a/profiles.parquet", 20, 100))
+				require.Equal(t, 2, counting.getRanges)
+				require.Equal(t, 1, counting.attributes)
+			})
+
+			t.Run("ReaderAt", func(t *testing.T) {
+				r, err := b.ReaderAt(ctx, "a/profiles.parquet")
+				require.NoError(t, err)
+				p := make([]byte, 8)
+				n, err := r.ReadAt(p, 3)
+				require.NoError(t, err)
+				require.Equal(t, 8, n)
+				require.Equal(t, content[3:11], p)
+				require.Equal(t, 2, counting.getRanges)
+				require.NoError(t, r.Close())
+			})
+
+			t.Run("Get", func(t *testing.T) {
+				for i := 0; i < 2; i++ {
+					rc, err := b.Get(ctx, "a/index.tsdb")
+					require.NoError(t, err)
+					data, err := io.ReadAll(rc)
+					require.NoError(t, err)
+					require.NoError(t, rc.Close())
+					require.Equal(t, content, data)
+				}
+				require.Equal(t, 1, counting.gets)
+			})
+
+			t.Run("Not cached", func(t *testing.T) {
+				require.Equal(t, content[:5], readRange("a/meta.json", 0, 5))
+				require.Equal(t, content[:5], readRange("a/meta.json", 0, 5))
+				require.Equal(t, 4, counting.getRanges)
+			})
+
+			require.Equal(t, float64(6), testutil.ToFloat64(b.metrics.hits.WithLabelValues(objstore.OpGetRange, cacheFileParquet)))
+			require.Equal(t, float64(10), testutil.ToFloat64(b.metrics.requests.WithLabelValues(objstore.OpGetRange, cacheFileParquet)))
+			require.Equal(t, float64(1), testutil.ToFloat64(b.metrics.hits.WithLabelValues(objstore.OpGet, cacheFileTSDBIndex)))
+		})
+	}
+}
+
+func Test_CachingBucket_LargeObject(t *testing.T) {
+	ctx := context.Background()
+	b, counting := newTestCachingBucket(t, CacheBackendInMemory)
+	content := bytes.Repeat([]byte("x"), 100)
+	require.NoError(t, b.Upload(ctx, "index.tsdb", bytes.NewReader(content)))
+	for i := 0; i < 2; i++ {
+		rc, err := b.Get(ctx, "index.tsdb")
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		require.Equal(t, content, data)
+	}
+	require.Equal(t, 2, counting.gets)
+}
+
+func Test_InMemoryCache_Eviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewInMemoryCache(InMemoryCacheConfig{MaxSizeBytes: 20}, nil)
+	c.Store(ctx, map[string][]byte{"a": make([]byte, 9)}, time.Hour)
+	c.Store(ctx, map[string][]byte{"b": make([]byte, 9)}, time.Hour)
+	require.Len(t, c.Fetch(ctx, []string{"a", "b"}), 2)
+	// "a" is the least recently used item.
+	c.Fetch(ctx, []string{"b"})
+	c.Store(ctx, map[string][]byte{"c": make([]byte, 9)}, time.Hour)
+	require.Len(t, c.Fetch(ctx, []string{"a"}), 0)
+	require.Len(t, c.Fetch(ctx, []string{"b", "c"}), 2)
+	require.Equal(t, int64(20), c.size)
+
+	c.Store(ctx, map[string][]byte{"d": {}}, -time.Second)
+	require.Len(t, c.Fetch(ctx, []string{"d"}), 0)
+}
+
+func Test_DiskCache_Reload(t *testing.T) {
+	ctx := context.Background()
+	cfg := DiskCacheConfig{Directory: t.TempDir(), MaxSizeBytes: 40}
+	c, err := NewDiskCache(cfg, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	c.Store(ctx, map[string][]byte{"a": make([]byte, 10)}, time.Hour)
+	c.Store(ctx, map[string][]byte{"b": make([]byte, 10)}, time.Hour)
+	require.Len(t, c.Fetch(ctx, []string{"a", "b"}), 2)
+
+	cfg.MaxSizeBytes = 20
+	c, err = NewDiskCache(cfg, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, c.lru.Len())
+	require.Equal(t, int64(18), c.size)
+}
@@ -31,10 +31,12 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	"gopkg.in/yaml.v3"
 
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	statusv1 "github.com/grafana/pyroscope/api/gen/proto/go/status/v1"
 	"github.com/grafana/pyroscope/pkg/adhocprofiles"
 	apiversion "github.com/grafana/pyroscope/pkg/api/version"
 	"github.com/grafana/pyroscope/pkg/compactor"
+	"github.com/grafana/pyroscope/pkg/deletion"
 	"github.com/grafana/pyroscope/pkg/distributor"
 	"github.com/grafana/pyroscope/pkg/frontend"
 	"github.com/grafana/pyroscope/pkg/ingester"
@@ -44,9 +46,11 @@ import (
 	phlarecontext "github.com/grafana/pyroscope/pkg/phlare/context"
 	"github.com/grafana/pyroscope/pkg/querier"
 	"github.com/grafana/pyroscope/pkg/querier/worker"
+	"github.com/grafana/pyroscope/pkg/ruler"
 	"github.com/grafana/pyroscope/pkg/scheduler"
 	"github.com/grafana/pyroscope/pkg/settings"
 	"github.com/grafana/pyroscope/pkg/storegateway"
+	"github.com/grafana/pyroscope/pkg/symbolizer"
 	"github.com/grafana/pyroscope/pkg/usagestats"
 	"github.com/grafana/pyroscope/pkg/util"
 	"github.com/grafana/pyroscope/pkg/util/build"
@@ -78,6 +82,8 @@ const (
 	Admin             string = "admin"
 	TenantSettings    string = "tenant-settings"
 	AdHocProfiles     string = "ad-hoc-profiles"
+	DeleteRequests    string = "delete-requests"
+	Ruler             string = "ruler"
 
 	// QueryFrontendTripperware string = "query-frontend-tripperware"
 	// IndexGateway             string = "index-gateway"
@@ -100,7 +106,8 @@ func (f *Phlare) initQueryFrontend() (services.Service, error) {
 		f.Cfg.Frontend.Port = f.Cfg.Server.HTTPListenPort
 	}
 
-	frontendSvc, err := frontend.NewFrontend(f.Cfg.Frontend, f.Overrides, log.With(f.logger, "component", "frontend"), f.reg)
+	f.Cfg.Frontend.QueryStoreAfter = f.Cfg.Querier.QueryStoreAfter
+	frontendSvc, err := frontend.NewFrontend(f.Cfg.Frontend, f.Overrides, f.storageBucket, log.With(f.logger, "component", "frontend"), f.reg)
 	if err != nil {
 		return nil, err
 	}
@@ -172,6 +179,38 @@ func (f *Phlare) initAdHocProfiles() (services.Service, error) {
 	return a, nil
 }
 
+func (f *Phlare) initDeleteRequests() (services.Service, error) {
+	if f.storageBucket == nil {
+		level.Warn(f.logger).Log("msg", "no storage bucket configured, delete requests are not available")
+		return nil, nil
+	}
+
+	d := deletion.New(f.storageBucket, f.logger)
+	f.API.RegisterDeleteRequests(d)
+	return d, nil
+}
+
+func (f *Phlare) initRuler() (services.Service, error) {
+	if f.Cfg.Ruler.RulePath == "" {
+		level.Debug(f.logger).Log("msg", "no rule path configured, ruler is disabled")
+		return nil, nil
+	}
+
+	addr := f.Cfg.Ruler.QueryAddress
+	if addr == "" {
+		addr = fmt.Sprintf("http://localhost:%d", f.Cfg.Server.HTTPListenPort)
+	}
+	client := querierv1connect.NewQuerierServiceClient(util.InstrumentedHTTPClient(), addr, f.auth)
+
+	r, err := ruler.New(f.Cfg.Ruler, client, log.With(f.logger, "component", Ruler), f.reg)
+	if err != nil {
+		return nil, err
+	}
+
+	f.API.RegisterRuler(r)
+	return r, nil
+}
+
 func (f *Phlare) initOverrides() (serv services.Service, err error) {
 	f.Overrides, err = validation.NewOverrides(f.Cfg.LimitsConfig, f.TenantLimits)
 	// overrides don't have operational state, nor do they need to do anything more in starting/stopping phase,
@@ -314,12 +353,24 @@ func (f *Phlare) initGRPCGateway() (services.Service, error) {
 
 func (f *Phlare) initDistributor() (services.Service, error) {
 	f.Cfg.Distributor.DistributorRing.ListenPort = f.Cfg.Server.HTTPListenPort
-	d, err := distributor.New(f.Cfg.Distributor, f.ring, nil, f.Overrides, f.reg, log.With(f.logger, "component", "distributor"), f.auth)
+	var sym distributor.Symbolizer
+	if f.Cfg.Distributor.Symbolizer.Enabled {
+		if f.storageBucket == nil {
+			return nil, errors.New("storage bucket configuration is required for the symbolizer")
+		}
+		s, err := symbolizer.New(f.Cfg.Distributor.Symbolizer, f.storageBucket, log.With(f.logger, "component", "symbolizer"), f.reg)
+		if err != nil {
+			return nil, err
+		}
+		f.API.RegisterSymbolizer(s)
+		sym = s
+	}
+	d, err := distributor.New(f.Cfg.Distributor, f.ring, nil, f.Overrides, sym, f.reg, log.With(f.logger, "component", "distributor"), f.auth)
 	if err != nil {
 		return nil, err
 	}
 
-	f.API.RegisterDistributor(d)
+	f.API.RegisterDistributor(d, f.Overrides)
 	return d, nil
 }
 
@@ -51,6 +51,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/phlaredb"
 	"github.com/grafana/pyroscope/pkg/querier"
 	"github.com/grafana/pyroscope/pkg/querier/worker"
+	"github.com/grafana/pyroscope/pkg/ruler"
 	"github.com/grafana/pyroscope/pkg/scheduler"
 	"github.com/grafana/pyroscope/pkg/scheduler/schedulerdiscovery"
 	"github.com/grafana/pyroscope/pkg/storegateway"
@@ -81,6 +82,7 @@ type Config struct {
 	OverridesExporter exporter.Config        `yaml:"overrides_exporter" doc:"hidden"`
 	RuntimeConfig     runtimeconfig.Config   `yaml:"runtime_config"`
 	Compactor         compactor.Config       `yaml:"compactor"`
+	Ruler             ruler.Config           `yaml:"ruler"`
 
 	Storage       StorageConfig       `yaml:"storage"`
 	SelfProfiling SelfProfilingConfig `yaml:"self_profiling,omitempty"`
@@ -148,6 +150,7 @@ func (c *Config) RegisterFlagsWithContext(ctx context.Context, f *flag.FlagSet)
 	c.Analytics.RegisterFlags(f)
 	c.LimitsConfig.RegisterFlags(f)
 	c.Compactor.RegisterFlags(f, log.NewLogfmtLogger(os.Stderr))
+	c.Ruler.RegisterFlags(f)
 	c.API.RegisterFlags(f)
 }
 
@@ -186,6 +189,12 @@ func (c *Config) Validate() error {
 	if err := c.Compactor.Validate(c.PhlareDB.MaxBlockDuration); err != nil {
 		return err
 	}
+	if err := c.LimitsConfig.Validate(); err != nil {
+		return err
+	}
+	if err := c.Distributor.Validate(); err != nil {
+		return err
+	}
 	return c.Ingester.Validate()
 }
 
@@ -306,16 +315,18 @@ func (f *Phlare) setupModuleManager() error {
 	mm.RegisterModule(All, nil)
 	mm.RegisterModule(TenantSettings, f.initTenantSettings)
 	mm.RegisterModule(AdHocProfiles, f.initAdHocProfiles)
+	mm.RegisterModule(DeleteRequests, f.initDeleteRequests)
+	mm.RegisterModule(Ruler, f.initRuler)
 
 	// Add dependencies
 	deps := map[string][]string{
-		All: {Ingester, Distributor, QueryScheduler, QueryFrontend, Querier, StoreGateway, Admin, TenantSettings, Compactor, AdHocProfiles},
+		All: {Ingester, Distributor, QueryScheduler, QueryFrontend, Querier, StoreGateway, Admin, TenantSettings, Compactor, AdHocProfiles, DeleteRequests, Ruler},
 
 		Server:            {GRPCGateway},
 		API:               {Server},
-		Distributor:       {Overrides, Ring, API, UsageReport},
+		Distributor:       {Overrides, Ring, API, Storage, UsageReport},
 		Querier:           {Overrides, API, MemberlistKV, Ring, UsageReport, Version},
-		QueryFrontend:     {OverridesExporter, API, MemberlistKV, UsageReport, Version},
+		QueryFrontend:     {OverridesExporter, API, MemberlistKV, Storage, UsageReport, Version},
 		QueryScheduler:    {Overrides, API, MemberlistKV, UsageReport},
 		Ingester:          {Overrides, API, MemberlistKV, Storage, UsageReport, Version},
 		StoreGateway:      {API, Storage, Overrides, MemberlistKV, UsageReport, Admin, Version},
@@ -330,6 +341,8 @@ func (f *Phlare) setupModuleManager() error {
 		Version:           {API, MemberlistKV},
 		TenantSettings:    {API, Storage},
 		AdHocProfiles:     {API, Overrides, Storage},
+		DeleteRequests:    {API, Storage},
+		Ruler:             {API},
 	}
 
 	for mod, targets := range deps {
@@ -0,0 +1,98 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	DownsampleAggregationSum           = "sum"
+	DownsampleAggregationMax           = "max"
+	DownsampleAggregationAvgPerProfile = "avg-per-profile"
+)
+
+// DownsampleTable describes a downsampled profile table: the profiles
+// of each series are aggregated over periods of the resolution.
+type DownsampleTable struct {
+	Resolution  model.Duration `json:"resolution"`
+	Aggregation string         `json:"aggregation"`
+}
+
+// FileName returns the name of the table file.
+func (t DownsampleTable) FileName() string {
+	return profileTableName + "_" + t.Resolution.String() + "_" + t.Aggregation + ParquetSuffix
+}
+
+// DownsampleConfig specifies the downsampled profile tables: a table is
+// produced for each of the resolutions and aggregations.
+type DownsampleConfig struct {
+	Resolutions  []time.Duration
+	Aggregations []string
+}
+
+// DefaultDownsampleConfig returns the config of the downsampled tables
+// used by default: 5m and 1h resolutions, with sum aggregation.
+func DefaultDownsampleConfig() DownsampleConfig {
+	return DownsampleConfig{
+		Resolutions:  []time.Duration{5 * time.Minute, time.Hour},
+		Aggregations: []string{DownsampleAggregationSum},
+	}
+}
+
+func (c DownsampleConfig) Validate() error {
+	if len(c.Resolutions) == 0 {
+		return errors.New("at least one downsampling resolution must be specified")
+	}
+	if len(c.Aggregations) == 0 {
+		return errors.New("at least one downsampling aggregation must be specified")
+	}
+	resolutions := make(map[time.Duration]struct{}, len(c.Resolutions))
+	for _, r := range c.Resolutions {
+		if r <= 0 || r%time.Second != 0 {
+			return fmt.Errorf("invalid downsampling resolution %v: must be a positive number of seconds", r)
+		}
+		if _, ok := resolutions[r]; ok {
+			return fmt.Errorf("duplicate downsampling resolution %v", r)
+		}
+		resolutions[r] = struct{}{}
+	}
+	names := make(map[string]struct{}, len(c.Aggregations))
+	for _, a := range c.Aggregations {
+		if !isDownsampleAggregation(a) {
+			return fmt.Errorf("unknown downsampling aggregation %q, supported: %s, %s, %s",
+				a, DownsampleAggregationSum, DownsampleAggregationMax, DownsampleAggregationAvgPerProfile)
+		}
+		if _, ok := names[a]; ok {
+			return fmt.Errorf("duplicate downsampling aggregation %q", a)
+		}
+		names[a] = struct{}{}
+	}
+	return nil
+}
+
+func isDownsampleAggregation(a string) bool {
+	switch a {
+	case DownsampleAggregationSum,
+		DownsampleAggregationMax,
+		DownsampleAggregationAvgPerProfile:
+		return true
+	}
+	return false
+}
+
+// Tables returns the downsampled tables produced with the config.
+func (c DownsampleConfig) Tables() []DownsampleTable {
+	tables := make([]DownsampleTable, 0, len(c.Resolutions)*len(c.Aggregations))
+	for _, r := range c.Resolutions {
+		for _, a := range c.Aggregations {
+			tables = append(tables, DownsampleTable{
+				Resolution:  model.Duration(r),
+				Aggregation: a,
+			})
+		}
+	}
+	return tables
+}
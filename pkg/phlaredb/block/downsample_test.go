@@ -0,0 +1,51 @@
+package block
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownsampleConfig_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config DownsampleConfig
+		err    bool
+	}{
+		{name: "default", config: DefaultDownsampleConfig()},
+		{
+			name: "all aggregations",
+			config: DownsampleConfig{
+				Resolutions:  []time.Duration{time.Minute, 15 * time.Minute},
+				Aggregations: []string{DownsampleAggregationSum, DownsampleAggregationMax, DownsampleAggregationAvgPerProfile},
+			},
+		},
+		{name: "no resolutions", config: DownsampleConfig{Aggregations: []string{DownsampleAggregationSum}}, err: true},
+		{name: "no aggregations", config: DownsampleConfig{Resolutions: []time.Duration{time.Hour}}, err: true},
+		{
+			name:   "sub-second resolution",
+			config: DownsampleConfig{Resolutions: []time.Duration{1500 * time.Millisecond}, Aggregations: []string{DownsampleAggregationSum}},
+			err:    true,
+		},
+		{
+			name:   "duplicate resolution",
+			config: DownsampleConfig{Resolutions: []time.Duration{time.Hour, time.Hour}, Aggregations: []string{DownsampleAggregationSum}},
+			err:    true,
+		},
+		{
+			name:   "unknown aggregation",
+			config: DownsampleConfig{Resolutions: []time.Duration{time.Hour}, Aggregations: []string{"min"}},
+			err:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package block
+
+import (
+	"sort"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// LabelSummaryNames lists the labels which values are summarized
+// in the block meta: the labels selected by most queries.
+var LabelSummaryNames = []string{
+	phlaremodel.LabelNameServiceName,
+	phlaremodel.LabelNameProfileType,
+}
+
+// MaxLabelSummaryValues is the maximum number of values of a label
+// in the summary: labels with more values are not summarized, as
+// the summary is unlikely to help pruning the block.
+const MaxLabelSummaryValues = 256
+
+// LabelSummary maps the label names to the sorted values the label
+// has in the block series. A label which is not in the summary may
+// have any value.
+type LabelSummary map[string][]string
+
+// Set records the values of the label, unless there are too many.
+func (s LabelSummary) Set(name string, values []string) {
+	if len(values) > MaxLabelSummaryValues {
+		delete(s, name)
+		return
+	}
+	v := make([]string, len(values))
+	copy(v, values)
+	sort.Strings(v)
+	s[name] = v
+}
+
+// Matches reports whether the block may contain series selected by
+// the matchers. The result is only negative if there is a matcher
+// that rejects every value of a summarized label.
+func (s LabelSummary) Matches(matchers ...*labels.Matcher) bool {
+	for _, m := range matchers {
+		values, ok := s[m.Name]
+		if !ok || m.Matches("") {
+			// Series without the label would match.
+			continue
+		}
+		if !matchesAny(m, values) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(m *labels.Matcher, values []string) bool {
+	for _, v := range values {
+		if m.Matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// LabelSummaryFromBlockInfo returns the label summary of the block,
+// or nil, if it's not known.
+func LabelSummaryFromBlockInfo(info *typesv1.BlockInfo) LabelSummary {
+	if len(info.LabelSummary) == 0 {
+		return nil
+	}
+	s := make(LabelSummary, len(info.LabelSummary))
+	for _, l := range info.LabelSummary {
+		s[l.Name] = l.Values
+	}
+	return s
+}
+
+func (s LabelSummary) writeBlockInfo(info *typesv1.BlockInfo) {
+	info.LabelSummary = nil
+	if len(s) == 0 {
+		return
+	}
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	info.LabelSummary = make([]*typesv1.LabelSummary, len(names))
+	for i, name := range names {
+		info.LabelSummary[i] = &typesv1.LabelSummary{
+			Name:   name,
+			Values: s[name],
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package block
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelSummary_Matches(t *testing.T) {
+	s := make(LabelSummary)
+	s.Set("service_name", []string{"b", "a"})
+	require.Equal(t, []string{"a", "b"}, s["service_name"])
+
+	for _, tc := range []struct {
+		matcher  *labels.Matcher
+		expected bool
+	}{
+		{labels.MustNewMatcher(labels.MatchEqual, "service_name", "a"), true},
+		{labels.MustNewMatcher(labels.MatchEqual, "service_name", "c"), false},
+		{labels.MustNewMatcher(labels.MatchRegexp, "service_name", "c|b"), true},
+		{labels.MustNewMatcher(labels.MatchRegexp, "service_name", "c.+"), false},
+		// Matchers selecting series without the label.
+		{labels.MustNewMatcher(labels.MatchNotEqual, "service_name", "a"), true},
+		{labels.MustNewMatcher(labels.MatchRegexp, "service_name", "c|"), true},
+		// Labels which are not summarized.
+		{labels.MustNewMatcher(labels.MatchEqual, "namespace", "a"), true},
+	} {
+		require.Equal(t, tc.expected, s.Matches(tc.matcher), tc.matcher.String())
+	}
+}
+
+func TestLabelSummary_MaxValues(t *testing.T) {
+	s := make(LabelSummary)
+	values := make([]string, MaxLabelSummaryValues+1)
+	s.Set("service_name", values)
+	require.NotContains(t, s, "service_name")
+	require.True(t, s.Matches(labels.MustNewMatcher(labels.MatchEqual, "service_name", "a")))
+}
+
+func TestLabelSummary_BlockInfo(t *testing.T) {
+	meta := &Meta{
+		ULID: generateULID(),
+		LabelSummary: LabelSummary{
+			"service_name":     {"a", "b"},
+			"__profile_type__": {"cpu"},
+		},
+	}
+	require.Equal(t, meta.LabelSummary, LabelSummaryFromBlockInfo(meta.BlockInfo()))
+	require.Nil(t, LabelSummaryFromBlockInfo((&Meta{}).BlockInfo()))
+}
@@ -8,6 +8,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
@@ -155,10 +156,17 @@ type Meta struct {
 
 	// Downsample is a downsampling resolution of the block. 0 means no downsampling.
 	Downsample `json:"downsample"`
+
+	// LabelSummary lists the values of the labels commonly used in
+	// queries, allowing to skip blocks that can't contain the series.
+	LabelSummary LabelSummary `json:"labelSummary,omitempty"`
 }
 
 type Downsample struct {
 	Resolution int64 `json:"resolution"`
+	// Tables lists the downsampled profile tables of the block, which
+	// are kept along with the table of the original resolution.
+	Tables []DownsampleTable `json:"tables,omitempty"`
 }
 
 func (m *Meta) FileByRelPath(name string) *File {
@@ -170,6 +178,26 @@ func (m *Meta) FileByRelPath(name string) *File {
 	return nil
 }
 
+const profileTableName = "profiles"
+
+// ParseProfileTableName parses the name of a profile table file: either
+// the table of the original resolution, or a downsampled one, such as
+// "profiles_5m_sum.parquet". The resolution of the former is 0.
+func ParseProfileTableName(n string) (resolution time.Duration, aggregation string, ok bool) {
+	if n == profileTableName+ParquetSuffix {
+		return 0, "", true
+	}
+	parts := strings.Split(strings.TrimSuffix(n, ParquetSuffix), "_")
+	if len(parts) != 3 || parts[0] != profileTableName {
+		return 0, "", false
+	}
+	r, err := model.ParseDuration(parts[1])
+	if err != nil || r <= 0 {
+		return 0, "", false
+	}
+	return time.Duration(r), parts[2], true
+}
+
 func (m *Meta) InRange(start, end model.Time) bool {
 	return InRange(m.MinTime, m.MaxTime, start, end)
 }
@@ -223,6 +251,27 @@ func (m *Meta) WriteBlockInfo(info *typesv1.BlockInfo) {
 			Value: v,
 		})
 	}
+	info.Stats = &typesv1.BlockStats{
+		NumSeries:   m.Stats.NumSeries,
+		NumProfiles: m.Stats.NumProfiles,
+		NumSamples:  m.Stats.NumSamples,
+	}
+	for _, f := range m.Files {
+		resolution, aggregation, ok := ParseProfileTableName(f.RelPath)
+		if !ok {
+			continue
+		}
+		t := &typesv1.ProfileTableStats{
+			Resolution:  resolution.Milliseconds(),
+			Aggregation: aggregation,
+			SizeBytes:   f.SizeBytes,
+		}
+		if f.Parquet != nil {
+			t.NumRows = f.Parquet.NumRows
+		}
+		info.Stats.ProfileTables = append(info.Stats.ProfileTables, t)
+	}
+	m.LabelSummary.writeBlockInfo(info)
 }
 
 func generateULID() ulid.ULID {
@@ -2,6 +2,7 @@ package block
 
 import (
 	"testing"
+	"time"
 
 	"github.com/oklog/ulid"
 	"github.com/prometheus/common/model"
@@ -49,3 +50,51 @@ func TestClone(t *testing.T) {
 	}
 	require.Equal(t, expected, expected.Clone())
 }
+
+func TestParseProfileTableName(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		resolution  time.Duration
+		aggregation string
+		ok          bool
+	}{
+		{name: "profiles.parquet", ok: true},
+		{name: "profiles_5m_sum.parquet", resolution: 5 * time.Minute, aggregation: "sum", ok: true},
+		{name: "profiles_1h_sum.parquet", resolution: time.Hour, aggregation: "sum", ok: true},
+		{name: "profiles_5x_sum.parquet"},
+		{name: "profiles_5m.parquet"},
+		{name: "index.tsdb"},
+		{name: "symbols/index.symdb"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resolution, aggregation, ok := ParseProfileTableName(tc.name)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.resolution, resolution)
+			require.Equal(t, tc.aggregation, aggregation)
+		})
+	}
+}
+
+func TestWriteBlockInfo_Stats(t *testing.T) {
+	meta := &Meta{
+		ULID:  generateULID(),
+		Stats: BlockStats{NumSamples: 1, NumSeries: 2, NumProfiles: 3},
+		Files: []File{
+			{RelPath: "index.tsdb", SizeBytes: 10},
+			{RelPath: "profiles.parquet", SizeBytes: 100, Parquet: &ParquetFile{NumRows: 3}},
+			{RelPath: "profiles_5m_sum.parquet", SizeBytes: 20, Parquet: &ParquetFile{NumRows: 2}},
+		},
+	}
+	info := meta.BlockInfo()
+	require.Equal(t, uint64(1), info.Stats.NumSamples)
+	require.Equal(t, uint64(2), info.Stats.NumSeries)
+	require.Equal(t, uint64(3), info.Stats.NumProfiles)
+	require.Len(t, info.Stats.ProfileTables, 2)
+	require.Equal(t, int64(0), info.Stats.ProfileTables[0].Resolution)
+	require.Equal(t, uint64(3), info.Stats.ProfileTables[0].NumRows)
+	require.Equal(t, uint64(100), info.Stats.ProfileTables[0].SizeBytes)
+	require.Equal(t, (5 * time.Minute).Milliseconds(), info.Stats.ProfileTables[1].Resolution)
+	require.Equal(t, "sum", info.Stats.ProfileTables[1].Aggregation)
+	require.Equal(t, uint64(2), info.Stats.ProfileTables[1].NumRows)
+	require.Equal(t, uint64(20), info.Stats.ProfileTables[1].SizeBytes)
+}
@@ -566,6 +566,9 @@ type Querier interface {
 	MergeBySpans(ctx context.Context, rows iter.Iterator[Profile], spans phlaremodel.SpanSelector) (*phlaremodel.Tree, error)
 	MergeByLabels(ctx context.Context, rows iter.Iterator[Profile], s *typesv1.StackTraceSelector, by ...string) ([]*typesv1.Series, error)
 	MergePprof(ctx context.Context, rows iter.Iterator[Profile], maxNodes int64, s *typesv1.StackTraceSelector) (*profilev1.Profile, error)
+	// ProfileExemplars returns the exemplars of the profiles, at most the
+	// top limit ones, if limit is positive, in no particular order.
+	ProfileExemplars(ctx context.Context, rows iter.Iterator[Profile], limit int) ([]ProfileExemplar, error)
 	Series(ctx context.Context, params *ingestv1.SeriesRequest) ([]*typesv1.Labels, error)
 
 	SelectMatchingProfiles(ctx context.Context, params *ingestv1.SelectProfilesRequest) (iter.Iterator[Profile], error)
@@ -693,6 +696,37 @@ func (queriers Queriers) Series(ctx context.Context, req *connect.Request[ingest
 	return connect.NewResponse(res), nil
 }
 
+func (queriers Queriers) GetCardinality(ctx context.Context, req *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error) {
+	blockGetter := queriers.forTimeRange
+	// Requests without a range account the series of all the queriers.
+	if req.Msg.Start == 0 || req.Msg.End == 0 {
+		blockGetter = func(_ context.Context, _, _ model.Time, _ *ingestv1.Hints) (Queriers, error) {
+			return queriers, nil
+		}
+	}
+	res, err := GetCardinality(ctx, req.Msg, blockGetter)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (queriers Queriers) SelectExemplars(ctx context.Context, req *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error) {
+	res, err := SelectExemplars(ctx, req.Msg, queriers.forTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (queriers Queriers) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	res, err := SelectProfileByID(ctx, req.Msg, queriers.forTimeRange)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
 func (queriers Queriers) MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesStacktracesRequest, ingestv1.MergeProfilesStacktracesResponse]) error {
 	return MergeProfilesStacktraces(ctx, stream, queriers.forTimeRange)
 }
@@ -941,7 +975,7 @@ func MergeSpanProfile(ctx context.Context, stream *connect.BidiStream[ingestv1.M
 		otlog.Object("hints", request.Hints),
 	)
 
-	spanSelector, err := phlaremodel.NewSpanSelector(request.SpanSelector)
+	spanSelector, err := phlaremodel.NewSpanAndTraceSelector(request.SpanSelector, request.TraceSelector)
 	if err != nil {
 		return err
 	}
@@ -1793,7 +1827,7 @@ func (b *singleBlockQuerier) SelectMergeBySpans(ctx context.Context, params *ing
 	if params.Type == nil {
 		return nil, errors.New("no profileType given")
 	}
-	spans, err := phlaremodel.NewSpanSelector(params.SpanSelector)
+	spans, err := phlaremodel.NewSpanAndTraceSelector(params.SpanSelector, params.TraceSelector)
 	if err != nil {
 		return nil, err
 	}
@@ -2150,6 +2184,8 @@ func (b *singleBlockQuerier) profileTable(resolution time.Duration, aggregation
 	return b.profiles[profileTableKey{}]
 }
 
+// downsampleResolutions returns the resolutions of the downsampled
+// profile tables used by queries: only the sum aggregation is.
 func (b *singleBlockQuerier) downsampleResolutions() []time.Duration {
 	if len(b.profiles) < 2 {
 		// b.profiles contains only the table of original resolution.
@@ -2157,39 +2193,28 @@ func (b *singleBlockQuerier) downsampleResolutions() []time.Duration {
 	}
 	resolutions := make([]time.Duration, 0, len(b.profiles)-1)
 	for k := range b.profiles {
-		if k.resolution > 0 {
+		if k.resolution > 0 && k.aggregation == block.DownsampleAggregationSum {
 			resolutions = append(resolutions, k.resolution)
 		}
 	}
-	return resolutions
+	slices.Sort(resolutions)
+	return slices.Compact(resolutions)
 }
 
 func downsampleAggregation(v typesv1.TimeSeriesAggregationType) string {
 	switch v {
 	case typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM:
-		return "sum"
+		return block.DownsampleAggregationSum
 	}
 	return ""
 }
 
-const profileTableName = "profiles"
-
 func parseProfileTableName(n string) (profileTableKey, bool) {
-	if n == profileTableName+block.ParquetSuffix {
-		return profileTableKey{}, true
-	}
-	parts := strings.Split(strings.TrimSuffix(n, block.ParquetSuffix), "_")
-	if len(parts) != 3 || parts[0] != profileTableName {
-		return profileTableKey{}, false
-	}
-	r, err := time.ParseDuration(parts[1])
-	if err != nil {
-		return profileTableKey{}, false
-	}
+	resolution, aggregation, ok := block.ParseProfileTableName(n)
 	return profileTableKey{
-		resolution:  r,
-		aggregation: parts[2],
-	}, true
+		resolution:  resolution,
+		aggregation: aggregation,
+	}, ok
 }
 
 type parquetReader[P schemav1.PersisterName] struct {
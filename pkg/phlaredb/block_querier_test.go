@@ -25,6 +25,7 @@ import (
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
 	"github.com/grafana/pyroscope/pkg/phlaredb/tsdb/index"
 	"github.com/grafana/pyroscope/pkg/pprof/testhelper"
 )
@@ -1373,3 +1374,21 @@ func TestSelectMergeByStacktracesRace(t *testing.T) {
 	require.NoError(t, g.Wait())
 	require.NoError(t, querier.Close())
 }
+
+func Test_singleBlockQuerier_downsampleResolutions(t *testing.T) {
+	q := &singleBlockQuerier{profiles: map[profileTableKey]*parquetReader[*schemav1.ProfilePersister]{
+		{}: nil,
+		{resolution: time.Hour, aggregation: block.DownsampleAggregationSum}:             nil,
+		{resolution: time.Hour, aggregation: block.DownsampleAggregationMax}:             nil,
+		{resolution: 5 * time.Minute, aggregation: block.DownsampleAggregationSum}:       nil,
+		{resolution: 5 * time.Minute, aggregation: block.DownsampleAggregationMax}:       nil,
+		{resolution: time.Minute, aggregation: block.DownsampleAggregationAvgPerProfile}: nil,
+	}}
+	assert.Equal(t, []time.Duration{5 * time.Minute, time.Hour}, q.downsampleResolutions())
+
+	q = &singleBlockQuerier{profiles: map[profileTableKey]*parquetReader[*schemav1.ProfilePersister]{
+		{}: nil,
+		{resolution: time.Hour, aggregation: block.DownsampleAggregationMax}: nil,
+	}}
+	assert.Empty(t, q.downsampleResolutions())
+}
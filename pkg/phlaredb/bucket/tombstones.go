@@ -0,0 +1,403 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/objstore"
+	util_log "github.com/grafana/pyroscope/pkg/util"
+)
+
+const (
+	// Relative to user-specific prefix.
+	TombstonesPath = "tombstones"
+
+	// The compactor marks a tombstone as processed with a separate object,
+	// so that the tombstone itself is never written after creation: a
+	// cancelled request can't be brought back by the compactor.
+	tombstoneProcessedMarkerSuffix = ".processed.json"
+
+	// TombstonesRefreshInterval is how often the tombstones applied at
+	// query time are reloaded from the bucket.
+	TombstonesRefreshInterval = time.Minute
+)
+
+// Tombstone is a delete request: the profiles of the series matching the
+// selector within the time range are not returned by queries, and are
+// eventually removed from the blocks by the compactor.
+type Tombstone struct {
+	RequestID string `json:"request_id"`
+	Selector  string `json:"selector"`
+
+	// Unix timestamps in milliseconds, both ends are inclusive.
+	StartTime int64 `json:"start_time"`
+	EndTime   int64 `json:"end_time"`
+
+	// Unix timestamp in milliseconds when the request was created.
+	CreatedAt int64 `json:"created_at"`
+
+	// Unix timestamp in milliseconds when the compactor finished
+	// removing the profiles from the blocks. Stored in the processed
+	// marker of the tombstone.
+	ProcessedAt int64 `json:"-"`
+
+	matchers []*labels.Matcher
+}
+
+// NewTombstone creates a new tombstone with a unique request ID.
+func NewTombstone(selector string, start, end int64, createdAt time.Time) (*Tombstone, error) {
+	t := &Tombstone{
+		RequestID: ulid.MustNew(ulid.Timestamp(createdAt), rand.Reader).String(),
+		Selector:  selector,
+		StartTime: start,
+		EndTime:   end,
+		CreatedAt: createdAt.UnixMilli(),
+	}
+	return t, t.init()
+}
+
+func (t *Tombstone) init() error {
+	if _, err := ulid.Parse(t.RequestID); err != nil {
+		return errors.Wrapf(err, "invalid request id %q", t.RequestID)
+	}
+	if t.EndTime < t.StartTime {
+		return errors.Errorf("end time %d is before start time %d", t.EndTime, t.StartTime)
+	}
+	matchers, err := parser.ParseMetricSelector(t.Selector)
+	if err != nil {
+		return errors.Wrapf(err, "invalid selector %q", t.Selector)
+	}
+	// Prevent accidental deletion of all the series.
+	var nonEmpty bool
+	for _, m := range matchers {
+		if !m.Matches("") {
+			nonEmpty = true
+			break
+		}
+	}
+	if !nonEmpty {
+		return errors.Errorf("invalid selector %q: at least one matcher must not match empty values", t.Selector)
+	}
+	t.matchers = matchers
+	return nil
+}
+
+// Matchers returns the label matchers of the selector.
+func (t *Tombstone) Matchers() []*labels.Matcher { return t.matchers }
+
+// Processed reports whether the compactor has removed the profiles.
+func (t *Tombstone) Processed() bool { return t.ProcessedAt > 0 }
+
+// Overlaps reports whether the tombstone time range overlaps [start, end].
+func (t *Tombstone) Overlaps(start, end model.Time) bool {
+	return int64(start) <= t.EndTime && int64(end) >= t.StartTime
+}
+
+// Matches reports whether the series labels match the selector.
+func (t *Tombstone) Matches(lbls phlaremodel.Labels) bool {
+	for _, m := range t.matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Deletes reports whether the profile of the series at the given time
+// is deleted.
+func (t *Tombstone) Deletes(lbls phlaremodel.Labels, ts model.Time) bool {
+	return int64(ts) >= t.StartTime && int64(ts) <= t.EndTime && t.Matches(lbls)
+}
+
+type Tombstones []*Tombstone
+
+// Overlapping returns the tombstones overlapping [start, end].
+func (t Tombstones) Overlapping(start, end model.Time) Tombstones {
+	var r Tombstones
+	for _, x := range t {
+		if x.Overlaps(start, end) {
+			r = append(r, x)
+		}
+	}
+	return r
+}
+
+// Pending returns the tombstones the compactor has not processed yet.
+func (t Tombstones) Pending() Tombstones {
+	var r Tombstones
+	for _, x := range t {
+		if !x.Processed() {
+			r = append(r, x)
+		}
+	}
+	return r
+}
+
+// Deletes reports whether any of the tombstones deletes the profile of
+// the series at the given time.
+func (t Tombstones) Deletes(lbls phlaremodel.Labels, ts model.Time) bool {
+	for _, x := range t {
+		if x.Deletes(lbls, ts) {
+			return true
+		}
+	}
+	return false
+}
+
+func tombstonePath(requestID string) string {
+	return path.Join(TombstonesPath, requestID+".json")
+}
+
+func tombstoneProcessedMarkerPath(requestID string) string {
+	return path.Join(TombstonesPath, requestID+tombstoneProcessedMarkerSuffix)
+}
+
+type tombstoneProcessedMarker struct {
+	ProcessedAt int64 `json:"processed_at"`
+}
+
+// WriteTombstone uploads the tombstone to the tenant bucket.
+func WriteTombstone(ctx context.Context, bkt objstore.Bucket, t *Tombstone) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return errors.Wrap(err, "serialize tombstone")
+	}
+	return errors.Wrap(bkt.Upload(ctx, tombstonePath(t.RequestID), bytes.NewReader(data)), "upload tombstone")
+}
+
+// MarkTombstoneProcessed uploads the processed marker of the tombstone.
+// If the tombstone has been deleted in the meantime, the marker is ignored
+// and eventually removed by CleanupTombstones.
+func MarkTombstoneProcessed(ctx context.Context, bkt objstore.Bucket, t *Tombstone, processedAt time.Time) error {
+	data, err := json.Marshal(tombstoneProcessedMarker{ProcessedAt: processedAt.UnixMilli()})
+	if err != nil {
+		return errors.Wrap(err, "serialize tombstone processed marker")
+	}
+	if err = bkt.Upload(ctx, tombstoneProcessedMarkerPath(t.RequestID), bytes.NewReader(data)); err != nil {
+		return errors.Wrap(err, "upload tombstone processed marker")
+	}
+	t.ProcessedAt = processedAt.UnixMilli()
+	return nil
+}
+
+// DeleteTombstone removes the tombstone and its processed marker from the
+// tenant bucket.
+func DeleteTombstone(ctx context.Context, bkt objstore.Bucket, requestID string) error {
+	if _, err := ulid.Parse(requestID); err != nil {
+		return errors.Wrapf(err, "invalid request id %q", requestID)
+	}
+	if err := bkt.Delete(ctx, tombstonePath(requestID)); err != nil {
+		return errors.Wrap(err, "delete tombstone")
+	}
+	return deleteTombstoneProcessedMarker(ctx, bkt, requestID)
+}
+
+func deleteTombstoneProcessedMarker(ctx context.Context, bkt objstore.Bucket, requestID string) error {
+	err := bkt.Delete(ctx, tombstoneProcessedMarkerPath(requestID))
+	if err != nil && !bkt.IsObjNotFoundErr(errors.Cause(err)) {
+		return errors.Wrap(err, "delete tombstone processed marker")
+	}
+	return nil
+}
+
+// ReadTombstone returns the tombstone with the given request ID from the
+// tenant bucket. If it doesn't exist, returns nil tombstone, and no error.
+func ReadTombstone(ctx context.Context, bkt objstore.BucketReader, requestID string) (*Tombstone, error) {
+	if _, err := ulid.Parse(requestID); err != nil {
+		return nil, errors.Wrapf(err, "invalid request id %q", requestID)
+	}
+	t, err := readTombstone(ctx, bkt, tombstonePath(requestID))
+	if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.ProcessedAt, err = readTombstoneProcessedAt(ctx, bkt, tombstoneProcessedMarkerPath(requestID)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func readTombstone(ctx context.Context, bkt objstore.BucketReader, name string) (*Tombstone, error) {
+	t := &Tombstone{}
+	if err := readJSON(ctx, bkt, name, t); err != nil {
+		return nil, errors.Wrapf(err, "failed to read tombstone object: %s", name)
+	}
+	if err := t.init(); err != nil {
+		return nil, errors.Wrapf(err, "invalid tombstone object: %s", name)
+	}
+	return t, nil
+}
+
+// readTombstoneProcessedAt returns the processing time of the tombstone,
+// or zero, if the processed marker does not exist.
+func readTombstoneProcessedAt(ctx context.Context, bkt objstore.BucketReader, name string) (int64, error) {
+	var m tombstoneProcessedMarker
+	err := readJSON(ctx, bkt, name, &m)
+	if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read tombstone processed marker: %s", name)
+	}
+	return m.ProcessedAt, nil
+}
+
+func readJSON(ctx context.Context, bkt objstore.BucketReader, name string, v interface{}) error {
+	r, err := bkt.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewDecoder(r).Decode(v)
+
+	// Close reader before dealing with decode error.
+	if closeErr := r.Close(); closeErr != nil {
+		level.Warn(util_log.Logger).Log("msg", "failed to close bucket reader", "err", closeErr)
+	}
+
+	return errors.Wrap(err, "decode")
+}
+
+// listTombstones returns the request IDs of the tombstones, and of the
+// processed markers found in the tenant bucket.
+func listTombstones(ctx context.Context, bkt objstore.BucketReader) (tombstones []string, processed map[string]struct{}, err error) {
+	processed = make(map[string]struct{})
+	err = bkt.Iter(ctx, TombstonesPath+"/", func(name string) error {
+		base := path.Base(name)
+		switch {
+		case strings.HasSuffix(base, tombstoneProcessedMarkerSuffix):
+			processed[strings.TrimSuffix(base, tombstoneProcessedMarkerSuffix)] = struct{}{}
+		case strings.HasSuffix(base, ".json"):
+			tombstones = append(tombstones, strings.TrimSuffix(base, ".json"))
+		}
+		return nil
+	})
+	return tombstones, processed, err
+}
+
+// ReadTombstones returns all the tombstones of the tenant bucket, ordered
+// by creation time.
+func ReadTombstones(ctx context.Context, bkt objstore.BucketReader) (Tombstones, error) {
+	ids, processed, err := listTombstones(ctx, bkt)
+	if err != nil {
+		return nil, err
+	}
+	tombstones := make(Tombstones, 0, len(ids))
+	for _, id := range ids {
+		t, err := readTombstone(ctx, bkt, tombstonePath(id))
+		if err != nil {
+			if bkt.IsObjNotFoundErr(errors.Cause(err)) {
+				// Deleted in the meantime.
+				continue
+			}
+			return nil, err
+		}
+		if _, ok := processed[id]; ok {
+			if t.ProcessedAt, err = readTombstoneProcessedAt(ctx, bkt, tombstoneProcessedMarkerPath(id)); err != nil {
+				return nil, err
+			}
+		}
+		tombstones = append(tombstones, t)
+	}
+	sort.Slice(tombstones, func(i, j int) bool {
+		if tombstones[i].CreatedAt != tombstones[j].CreatedAt {
+			return tombstones[i].CreatedAt < tombstones[j].CreatedAt
+		}
+		return tombstones[i].RequestID < tombstones[j].RequestID
+	})
+	return tombstones, nil
+}
+
+// CleanupTombstones removes the tombstones processed before the retention
+// period, and the processed markers of the tombstones that don't exist
+// anymore: those are left behind when a request is cancelled while the
+// compactor marks it as processed. Returns the number of tombstones deleted.
+//
+// The profiles deleted by a processed tombstone are hidden at query time
+// until the tombstone is removed: the retention period must exceed the
+// time it takes for the blocks rewritten to be deleted.
+func CleanupTombstones(ctx context.Context, bkt objstore.Bucket, retention time.Duration, now time.Time) (int, error) {
+	ids, processed, err := listTombstones(ctx, bkt)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		delete(processed, id)
+	}
+	for id := range processed {
+		if err = deleteTombstoneProcessedMarker(ctx, bkt, id); err != nil {
+			return 0, err
+		}
+	}
+	var deleted int
+	for _, id := range ids {
+		processedAt, err := readTombstoneProcessedAt(ctx, bkt, tombstoneProcessedMarkerPath(id))
+		if err != nil {
+			return deleted, err
+		}
+		if processedAt == 0 || now.Sub(time.UnixMilli(processedAt)) < retention {
+			continue
+		}
+		if err = DeleteTombstone(ctx, bkt, id); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// TombstonesLoader reads the tombstones of a tenant bucket, and caches them
+// for the refresh interval.
+type TombstonesLoader struct {
+	bkt             objstore.BucketReader
+	refreshInterval time.Duration
+
+	mu         sync.Mutex
+	tombstones Tombstones
+	loadedAt   time.Time
+}
+
+func NewTombstonesLoader(bkt objstore.BucketReader, refreshInterval time.Duration) *TombstonesLoader {
+	return &TombstonesLoader{
+		bkt:             bkt,
+		refreshInterval: refreshInterval,
+	}
+}
+
+// Load returns the tombstones of the tenant. If the tombstones can't be
+// refreshed, the ones loaded previously are returned, if any.
+func (l *TombstonesLoader) Load(ctx context.Context) (Tombstones, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.loadedAt.IsZero() && time.Since(l.loadedAt) < l.refreshInterval {
+		return l.tombstones, nil
+	}
+	tombstones, err := ReadTombstones(ctx, l.bkt)
+	if err != nil {
+		if l.loadedAt.IsZero() {
+			return nil, errors.Wrap(err, "failed to load tombstones")
+		}
+		level.Warn(util_log.Logger).Log("msg", "failed to refresh tombstones", "err", err)
+		return l.tombstones, nil
+	}
+	l.tombstones = tombstones
+	l.loadedAt = time.Now()
+	return tombstones, nil
+}
@@ -0,0 +1,174 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	thanosobjstore "github.com/thanos-io/objstore"
+
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/objstore"
+)
+
+func TestTombstone_Deletes(t *testing.T) {
+	ts, err := NewTombstone(`{service_name="foo", user=~"1|2"}`, 1000, 2000, time.Now())
+	require.NoError(t, err)
+
+	foo1 := phlaremodel.LabelsFromStrings("service_name", "foo", "user", "1")
+	foo3 := phlaremodel.LabelsFromStrings("service_name", "foo", "user", "3")
+	bar1 := phlaremodel.LabelsFromStrings("service_name", "bar", "user", "1")
+
+	assert.True(t, ts.Deletes(foo1, 1000))
+	assert.True(t, ts.Deletes(foo1, 2000))
+	assert.False(t, ts.Deletes(foo1, 999))
+	assert.False(t, ts.Deletes(foo1, 2001))
+	assert.False(t, ts.Deletes(foo3, 1500))
+	assert.False(t, ts.Deletes(bar1, 1500))
+
+	assert.True(t, ts.Overlaps(0, 1000))
+	assert.True(t, ts.Overlaps(2000, 3000))
+	assert.False(t, ts.Overlaps(0, 999))
+	assert.False(t, ts.Overlaps(2001, 3000))
+}
+
+func TestNewTombstone_Invalid(t *testing.T) {
+	_, err := NewTombstone(`{service_name="foo"`, 0, 1, time.Now())
+	require.Error(t, err)
+	_, err = NewTombstone(`{service_name=~".*"}`, 0, 1, time.Now())
+	require.Error(t, err)
+	_, err = NewTombstone(`{service_name="foo"}`, 2, 1, time.Now())
+	require.Error(t, err)
+}
+
+func TestTombstones_ReadWrite(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewBucket(thanosobjstore.NewInMemBucket())
+	now := time.Now()
+
+	a, err := NewTombstone(`{service_name="a"}`, 0, 1, now)
+	require.NoError(t, err)
+	b, err := NewTombstone(`{service_name="b"}`, 0, 1, now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, WriteTombstone(ctx, bkt, a))
+	require.NoError(t, WriteTombstone(ctx, bkt, b))
+	// Objects other than tombstones are ignored.
+	require.NoError(t, bkt.Upload(ctx, TombstonesPath+"/README", bytes.NewReader(nil)))
+
+	tombstones, err := ReadTombstones(ctx, bkt)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 2)
+	assert.Equal(t, b.RequestID, tombstones[0].RequestID)
+	assert.Equal(t, a.RequestID, tombstones[1].RequestID)
+	assert.True(t, tombstones[1].Matches(phlaremodel.LabelsFromStrings("service_name", "a")))
+	assert.Len(t, tombstones.Pending(), 2)
+
+	require.NoError(t, MarkTombstoneProcessed(ctx, bkt, a, now))
+	x, err := ReadTombstone(ctx, bkt, a.RequestID)
+	require.NoError(t, err)
+	require.NotNil(t, x)
+	assert.True(t, x.Processed())
+	tombstones, err = ReadTombstones(ctx, bkt)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 2)
+	assert.Len(t, tombstones.Pending(), 1)
+
+	require.NoError(t, DeleteTombstone(ctx, bkt, a.RequestID))
+	x, err = ReadTombstone(ctx, bkt, a.RequestID)
+	require.NoError(t, err)
+	assert.Nil(t, x)
+
+	tombstones, err = ReadTombstones(ctx, bkt)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	assert.Len(t, tombstones.Overlapping(model.Time(0), model.Time(1)), 1)
+	assert.Len(t, tombstones.Overlapping(model.Time(2), model.Time(3)), 0)
+}
+
+func TestTombstones_MarkProcessedCancelled(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewBucket(thanosobjstore.NewInMemBucket())
+	now := time.Now()
+
+	a, err := NewTombstone(`{service_name="a"}`, 0, 1, now)
+	require.NoError(t, err)
+	require.NoError(t, WriteTombstone(ctx, bkt, a))
+
+	// The request is cancelled after the compactor has read it,
+	// but before it's marked as processed.
+	require.NoError(t, DeleteTombstone(ctx, bkt, a.RequestID))
+	require.NoError(t, MarkTombstoneProcessed(ctx, bkt, a, now))
+
+	x, err := ReadTombstone(ctx, bkt, a.RequestID)
+	require.NoError(t, err)
+	assert.Nil(t, x)
+	tombstones, err := ReadTombstones(ctx, bkt)
+	require.NoError(t, err)
+	assert.Empty(t, tombstones)
+
+	// The orphaned marker is removed.
+	deleted, err := CleanupTombstones(ctx, bkt, time.Hour, now)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	exists, err := bkt.Exists(ctx, tombstoneProcessedMarkerPath(a.RequestID))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCleanupTombstones(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewBucket(thanosobjstore.NewInMemBucket())
+	now := time.Now()
+
+	pending, err := NewTombstone(`{service_name="a"}`, 0, 1, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+	processed, err := NewTombstone(`{service_name="b"}`, 0, 1, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+	expired, err := NewTombstone(`{service_name="c"}`, 0, 1, now.Add(-48*time.Hour))
+	require.NoError(t, err)
+	for _, x := range []*Tombstone{pending, processed, expired} {
+		require.NoError(t, WriteTombstone(ctx, bkt, x))
+	}
+	require.NoError(t, MarkTombstoneProcessed(ctx, bkt, processed, now.Add(-time.Hour)))
+	require.NoError(t, MarkTombstoneProcessed(ctx, bkt, expired, now.Add(-25*time.Hour)))
+
+	deleted, err := CleanupTombstones(ctx, bkt, 24*time.Hour, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	tombstones, err := ReadTombstones(ctx, bkt)
+	require.NoError(t, err)
+	require.Len(t, tombstones, 2)
+	assert.Equal(t, pending.RequestID, tombstones.Pending()[0].RequestID)
+	exists, err := bkt.Exists(ctx, tombstoneProcessedMarkerPath(expired.RequestID))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestTombstonesLoader(t *testing.T) {
+	ctx := context.Background()
+	bkt := objstore.NewBucket(thanosobjstore.NewInMemBucket())
+	loader := NewTombstonesLoader(bkt, time.Hour)
+
+	tombstones, err := loader.Load(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tombstones)
+
+	x, err := NewTombstone(`{service_name="a"}`, 0, 1, time.Now())
+	require.NoError(t, err)
+	require.NoError(t, WriteTombstone(ctx, bkt, x))
+
+	// Cached until the refresh interval elapses.
+	tombstones, err = loader.Load(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, tombstones)
+
+	loader.refreshInterval = 0
+	tombstones, err = loader.Load(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tombstones, 1)
+}
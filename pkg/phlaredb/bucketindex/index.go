@@ -24,6 +24,7 @@ const (
 	IndexVersion1           = 1
 	IndexVersion2           = 2 // Added CompactorShardID field.
 	IndexVersion3           = 3 // Added CompactionLevel field.
+	IndexVersion4           = 4 // Added LabelSummary field.
 )
 
 // Index contains all known blocks and markers of a tenant.
@@ -80,6 +81,10 @@ type Block struct {
 	// Block's compactor shard ID, copied from tsdb.CompactorShardIDExternalLabel label.
 	CompactorShardID string `json:"compactor_shard_id,omitempty"`
 	CompactionLevel  int    `json:"compaction_level,omitempty"`
+
+	// LabelSummary lists the values of the labels commonly
+	// used in queries, copied from the block meta.
+	LabelSummary block.LabelSummary `json:"label_summary,omitempty"`
 }
 
 // Within returns whether the block contains samples within the provided range.
@@ -118,6 +123,7 @@ func (m *Block) Meta() *block.Meta {
 		Compaction: block.BlockMetaCompaction{
 			Level: m.CompactionLevel,
 		},
+		LabelSummary: m.LabelSummary,
 	}
 }
 
@@ -128,6 +134,7 @@ func BlockFromMeta(meta block.Meta) *Block {
 		MaxTime:          meta.MaxTime,
 		CompactorShardID: meta.Labels[sharding.CompactorShardIDLabel],
 		CompactionLevel:  meta.Compaction.Level,
+		LabelSummary:     meta.LabelSummary,
 	}
 }
 
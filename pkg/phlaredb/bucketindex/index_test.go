@@ -54,6 +54,24 @@ func TestBlockFromMeta(t *testing.T) {
 				CompactionLevel:  0,
 			},
 		},
+		"meta.json with label summary": {
+			meta: block.Meta{
+				ULID:    blockID,
+				MinTime: model.Time(10),
+				MaxTime: model.Time(20),
+				LabelSummary: block.LabelSummary{
+					"service_name": {"a", "b"},
+				},
+			},
+			expected: Block{
+				ID:      blockID,
+				MinTime: model.Time(10),
+				MaxTime: model.Time(20),
+				LabelSummary: block.LabelSummary{
+					"service_name": {"a", "b"},
+				},
+			},
+		},
 	}
 
 	for testName, testData := range tests {
@@ -49,7 +49,7 @@ func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid
 	var oldBlockDeletionMarks []*BlockDeletionMark
 
 	// Use the old index if provided, and it is using the latest version format.
-	if old != nil && old.Version == IndexVersion3 {
+	if old != nil && old.Version == IndexVersion4 {
 		oldBlocks = old.Blocks
 		oldBlockDeletionMarks = old.BlockDeletionMarks
 	}
@@ -65,7 +65,7 @@ func (w *Updater) UpdateIndex(ctx context.Context, old *Index) (*Index, map[ulid
 	}
 
 	return &Index{
-		Version:            IndexVersion3,
+		Version:            IndexVersion4,
 		Blocks:             blocks,
 		BlockDeletionMarks: blockDeletionMarks,
 		UpdatedAt:          time.Now().Unix(),
@@ -166,7 +166,7 @@ func TestUpdater_UpdateIndex_NoTenantInTheBucket(t *testing.T) {
 		idx, partials, err := w.UpdateIndex(ctx, oldIdx)
 
 		require.NoError(t, err)
-		assert.Equal(t, IndexVersion3, idx.Version)
+		assert.Equal(t, IndexVersion4, idx.Version)
 		assert.InDelta(t, time.Now().Unix(), idx.UpdatedAt, 2)
 		assert.Len(t, idx.Blocks, 0)
 		assert.Len(t, idx.BlockDeletionMarks, 0)
@@ -290,7 +290,7 @@ func getBlockUploadedAt(t testing.TB, bkt objstore.Bucket, userID string, blockI
 }
 
 func assertBucketIndexEqual(t testing.TB, idx *Index, bkt objstore.Bucket, userID string, expectedBlocks []block.Meta, expectedDeletionMarks []*block.DeletionMark) {
-	assert.Equal(t, IndexVersion3, idx.Version)
+	assert.Equal(t, IndexVersion4, idx.Version)
 	assert.InDelta(t, time.Now().Unix(), idx.UpdatedAt, 2)
 
 	// Build the list of expected block index entries.
@@ -303,6 +303,7 @@ func assertBucketIndexEqual(t testing.TB, idx *Index, bkt objstore.Bucket, userI
 			UploadedAt:       getBlockUploadedAt(t, bkt, userID, b.ULID),
 			CompactorShardID: b.Labels[sharding.CompactorShardIDLabel],
 			CompactionLevel:  b.Compaction.Level,
+			LabelSummary:     b.LabelSummary,
 		})
 	}
 
@@ -0,0 +1,106 @@
+package phlaredb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/samber/lo"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	pmath "github.com/grafana/pyroscope/pkg/util/math"
+)
+
+// GetCardinality returns the label names with the most distinct values and
+// the services and profile types with the most series, read from the TSDB
+// index of the blocks returned by the block getter.
+func GetCardinality(ctx context.Context, req *ingestv1.GetCardinalityRequest, blockGetter BlockGetter) (*ingestv1.GetCardinalityResponse, error) {
+	res, err := Series(ctx, &ingestv1.SeriesRequest{
+		Matchers: req.Matchers,
+		Start:    req.Start,
+		End:      req.End,
+	}, blockGetter)
+	if err != nil {
+		return nil, err
+	}
+	return CardinalityFromSeries(res.LabelsSet, int(req.Limit)), nil
+}
+
+// CardinalityFromSeries builds the cardinality response from the given
+// distinct series. Only the limit label names and series groups with the
+// most values and series are returned, unless the limit is not positive.
+// Values and series are reported as sketches, which responses of replicas
+// are merged with without counting a value or a series twice.
+func CardinalityFromSeries(series []*typesv1.Labels, limit int) *ingestv1.GetCardinalityResponse {
+	type seriesKey struct{ serviceName, profileType string }
+	values := make(map[string]map[string]struct{})
+	fingerprints := make(map[seriesKey][]uint64)
+	all := make([]uint64, 0, len(series))
+	for _, s := range series {
+		ls := phlaremodel.Labels(s.Labels)
+		for _, l := range ls {
+			v, ok := values[l.Name]
+			if !ok {
+				v = make(map[string]struct{})
+				values[l.Name] = v
+			}
+			v[l.Value] = struct{}{}
+		}
+		k := seriesKey{
+			serviceName: ls.Get(phlaremodel.LabelNameServiceName),
+			profileType: ls.Get(phlaremodel.LabelNameProfileType),
+		}
+		fp := ls.Hash()
+		fingerprints[k] = append(fingerprints[k], fp)
+		all = append(all, fp)
+	}
+
+	names := lo.Keys(values)
+	sort.Slice(names, func(i, j int) bool {
+		if len(values[names[i]]) != len(values[names[j]]) {
+			return len(values[names[i]]) > len(values[names[j]])
+		}
+		return names[i] < names[j]
+	})
+	keys := lo.Keys(fingerprints)
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if len(fingerprints[a]) != len(fingerprints[b]) {
+			return len(fingerprints[a]) > len(fingerprints[b])
+		}
+		if a.serviceName != b.serviceName {
+			return a.serviceName < b.serviceName
+		}
+		return a.profileType < b.profileType
+	})
+	if limit > 0 {
+		names = names[:pmath.Min(limit, len(names))]
+		keys = keys[:pmath.Min(limit, len(keys))]
+	}
+
+	res := &ingestv1.GetCardinalityResponse{
+		LabelValues: make([]*ingestv1.LabelNameValues, 0, len(names)),
+		Series:      make([]*ingestv1.SeriesFingerprints, 0, len(keys)),
+		AllSeries:   phlaremodel.NewCardinalitySketch(all),
+	}
+	for _, name := range names {
+		hashes := make([]uint64, 0, len(values[name]))
+		for v := range values[name] {
+			hashes = append(hashes, xxhash.Sum64String(v))
+		}
+		res.LabelValues = append(res.LabelValues, &ingestv1.LabelNameValues{
+			Name:   name,
+			Values: phlaremodel.NewCardinalitySketch(hashes),
+		})
+	}
+	for _, k := range keys {
+		res.Series = append(res.Series, &ingestv1.SeriesFingerprints{
+			ServiceName:  k.serviceName,
+			ProfileType:  k.profileType,
+			Fingerprints: phlaremodel.NewCardinalitySketch(fingerprints[k]),
+		})
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package phlaredb
+
+import (
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+func Test_CardinalityFromSeries(t *testing.T) {
+	series := []*typesv1.Labels{
+		{Labels: phlaremodel.LabelsFromStrings("service_name", "a", "__profile_type__", "cpu", "pod", "1")},
+		{Labels: phlaremodel.LabelsFromStrings("service_name", "a", "__profile_type__", "cpu", "pod", "2")},
+		{Labels: phlaremodel.LabelsFromStrings("service_name", "b", "__profile_type__", "memory", "pod", "3")},
+	}
+	res := CardinalityFromSeries(series, 0)
+
+	require.Len(t, res.LabelValues, 3)
+	assert.Equal(t, "pod", res.LabelValues[0].Name)
+	assert.Len(t, res.LabelValues[0].Values.Hashes, 3)
+	assert.Equal(t, "__profile_type__", res.LabelValues[1].Name)
+	assert.ElementsMatch(t, []uint64{xxhash.Sum64String("cpu"), xxhash.Sum64String("memory")}, res.LabelValues[1].Values.Hashes)
+	assert.Equal(t, "service_name", res.LabelValues[2].Name)
+
+	require.Len(t, res.Series, 2)
+	assert.Equal(t, "a", res.Series[0].ServiceName)
+	assert.Equal(t, "cpu", res.Series[0].ProfileType)
+	assert.ElementsMatch(t, []uint64{
+		phlaremodel.Labels(series[0].Labels).Hash(),
+		phlaremodel.Labels(series[1].Labels).Hash(),
+	}, res.Series[0].Fingerprints.Hashes)
+	assert.Equal(t, "b", res.Series[1].ServiceName)
+	assert.Equal(t, "memory", res.Series[1].ProfileType)
+	assert.Len(t, res.Series[1].Fingerprints.Hashes, 1)
+	assert.Len(t, res.AllSeries.Hashes, 3)
+
+	// Only the top label names and series groups are returned.
+	res = CardinalityFromSeries(series, 1)
+	require.Len(t, res.LabelValues, 1)
+	assert.Equal(t, "pod", res.LabelValues[0].Name)
+	require.Len(t, res.Series, 1)
+	assert.Equal(t, "a", res.Series[0].ServiceName)
+	assert.Len(t, res.AllSeries.Hashes, 3)
+}
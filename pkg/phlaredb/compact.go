@@ -20,6 +20,7 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/parquet-go/parquet-go"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/storage"
 
@@ -27,6 +28,7 @@ import (
 	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	phlareparquet "github.com/grafana/pyroscope/pkg/parquet"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
 	"github.com/grafana/pyroscope/pkg/phlaredb/downsample"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
 	"github.com/grafana/pyroscope/pkg/phlaredb/sharding"
@@ -59,7 +61,22 @@ type CompactWithSplittingOpts struct {
 	StageSize          uint64
 	SplitBy            SplitByFunc
 	DownsamplerEnabled bool
-	Logger             log.Logger
+	// DownsamplerConfig specifies the downsampled tables to produce,
+	// block.DefaultDownsampleConfig is used if none is specified.
+	DownsamplerConfig block.DownsampleConfig
+	Logger            log.Logger
+	// Tombstones of the profiles to remove from the compacted blocks.
+	Tombstones bucket.Tombstones
+	// DuplicateProfiles, if set, counts the profiles removed from the
+	// compacted blocks as duplicates: blocks of replicated ingesters
+	// overlap and store the same profiles (the same series, timestamp
+	// and profile ID).
+	DuplicateProfiles prometheus.Counter
+
+	// skipProfile, if set, excludes the source profiles from the
+	// compacted blocks: used to remove the invalid profiles when a
+	// block is repaired.
+	skipProfile func(schemav1.ProfileRow) bool
 }
 
 func Compact(ctx context.Context, src []BlockReader, dst string) (meta block.Meta, err error) {
@@ -81,7 +98,9 @@ func Compact(ctx context.Context, src []BlockReader, dst string) (meta block.Met
 func CompactWithSplitting(ctx context.Context, opts CompactWithSplittingOpts) (
 	[]block.Meta, error,
 ) {
-	if len(opts.Src) <= 1 && opts.SplitCount == 1 {
+	// A single block can be rewritten to remove the deleted or invalid profiles.
+	rewrite := len(opts.Src) == 1 && (len(opts.Tombstones) > 0 || opts.skipProfile != nil)
+	if len(opts.Src) <= 1 && opts.SplitCount == 1 && !rewrite {
 		return nil, errors.New("not enough blocks to compact")
 	}
 	if opts.SplitCount == 0 {
@@ -104,7 +123,16 @@ func CompactWithSplitting(ctx context.Context, opts CompactWithSplittingOpts) (
 	defer runutil.CloseWithLogOnErr(util.Logger, symbolsCompactor, "close symbols compactor")
 
 	outMeta := compactMetas(srcMetas...)
-	for _, stage := range splitStages(len(writers), int(opts.StageSize)) {
+	if rewrite {
+		outMeta.Compaction.Level = srcMetas[0].Compaction.Level
+	}
+	for i, stage := range splitStages(len(writers), int(opts.StageSize)) {
+		// Every stage iterates over all the source profiles:
+		// the duplicates are only counted once.
+		duplicates := opts.DuplicateProfiles
+		if i > 0 {
+			duplicates = nil
+		}
 		for _, idx := range stage {
 			if writers[idx], err = createBlockWriter(blockWriterOpts{
 				dst:                opts.Dst,
@@ -113,6 +141,7 @@ func CompactWithSplitting(ctx context.Context, opts CompactWithSplittingOpts) (
 				shard:              idx,
 				rewriterFn:         symbolsCompactor.Rewriter,
 				downsamplerEnabled: opts.DownsamplerEnabled,
+				downsamplerConfig:  opts.DownsamplerConfig,
 				logger:             opts.Logger,
 			}); err != nil {
 				return nil, fmt.Errorf("create block writer: %w", err)
@@ -120,7 +149,7 @@ func CompactWithSplitting(ctx context.Context, opts CompactWithSplittingOpts) (
 		}
 		var metas []block.Meta
 		sp, ctx := opentracing.StartSpanFromContext(ctx, "compact.Stage", opentracing.Tag{Key: "stage", Value: stage})
-		if metas, err = compact(ctx, writers, opts.Src, opts.SplitBy, opts.SplitCount); err != nil {
+		if metas, err = compact(ctx, writers, opts.Src, opts.SplitBy, opts.SplitCount, opts.Tombstones, opts.skipProfile, duplicates); err != nil {
 			sp.Finish()
 			ext.LogError(sp, err)
 			return nil, err
@@ -166,15 +195,21 @@ func createBlockWriter(opts blockWriterOpts) (*blockWriter, error) {
 	return newBlockWriter(opts)
 }
 
-func compact(ctx context.Context, writers []*blockWriter, readers []BlockReader, splitBy SplitByFunc, splitCount uint64) ([]block.Meta, error) {
-	rowsIt, err := newMergeRowProfileIterator(readers)
+func compact(ctx context.Context, writers []*blockWriter, readers []BlockReader, splitBy SplitByFunc, splitCount uint64, tombstones bucket.Tombstones, skip func(schemav1.ProfileRow) bool, duplicates prometheus.Counter) ([]block.Meta, error) {
+	rowsIt, err := newMergeRowProfileIterator(readers, skip)
 	if err != nil {
 		return nil, err
 	}
 	defer runutil.CloseWithLogOnErr(util.Logger, rowsIt, "close rows iterator")
+	if it, ok := rowsIt.(*dedupeProfileRowIterator); ok && duplicates != nil {
+		defer func() { duplicates.Add(float64(it.duplicates)) }()
+	}
 	// iterate and splits the rows into series.
 	for rowsIt.Next() {
 		r := rowsIt.At()
+		if tombstones.Deletes(r.labels, model.TimeFromUnixNano(r.timeNanos)) {
+			continue
+		}
 		shard := int(splitBy(r, splitCount))
 		w := writers[shard]
 		if w == nil {
@@ -249,6 +284,7 @@ type blockWriterOpts struct {
 	meta               block.Meta
 	rewriterFn         SymbolsRewriterFn
 	downsamplerEnabled bool
+	downsamplerConfig  block.DownsampleConfig
 	logger             log.Logger
 }
 
@@ -267,10 +303,15 @@ func newBlockWriter(opts blockWriterOpts) (*blockWriter, error) {
 	var downsampler *downsample.Downsampler
 	if opts.downsamplerEnabled && opts.meta.Compaction.Level > 2 {
 		level.Debug(opts.logger).Log("msg", "downsampling enabled for block writer", "path", blockPath)
-		downsampler, err = downsample.NewDownsampler(blockPath, opts.logger)
+		config := opts.downsamplerConfig
+		if len(config.Resolutions) == 0 && len(config.Aggregations) == 0 {
+			config = block.DefaultDownsampleConfig()
+		}
+		downsampler, err = downsample.NewDownsampler(blockPath, config, opts.logger)
 		if err != nil {
 			return nil, err
 		}
+		opts.meta.Downsample.Tables = config.Tables()
 	}
 
 	return &blockWriter{
@@ -327,6 +368,9 @@ func (bw *blockWriter) Close(ctx context.Context) error {
 		return err
 	}
 	bw.meta.Files = metaFiles
+	if bw.meta.LabelSummary, err = labelSummaryFromIndex(filepath.Join(bw.path, block.IndexFilename)); err != nil {
+		return err
+	}
 	bw.meta.Stats.NumProfiles = bw.totalProfiles
 	bw.meta.Stats.NumSeries = bw.indexRewriter.NumSeries()
 	bw.meta.Stats.NumSamples = numSamples
@@ -499,6 +543,24 @@ func tsdbMetaFile(filePath string) (block.File, error) {
 	return idxReader.FileInfo(), nil
 }
 
+// labelSummaryFromIndex returns the summary of the block labels.
+func labelSummaryFromIndex(filePath string) (block.LabelSummary, error) {
+	idxReader, err := index.NewFileReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer idxReader.Close()
+	summary := make(block.LabelSummary, len(block.LabelSummaryNames))
+	for _, name := range block.LabelSummaryNames {
+		values, err := idxReader.LabelValues(name)
+		if err != nil {
+			return nil, err
+		}
+		summary.Set(name, values)
+	}
+	return summary, nil
+}
+
 func parquetMetaFile(filePath string, size int64) (block.File, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
@@ -590,6 +652,9 @@ type profileRowIterator struct {
 	currentRow       profileRow
 	currentSeriesIdx uint32
 	chunks           []index.ChunkMeta
+
+	// skip, if set, excludes the profiles from the iteration.
+	skip func(schemav1.ProfileRow) bool
 }
 
 func newProfileRowIterator(s BlockReader) (*profileRowIterator, error) {
@@ -616,19 +681,35 @@ func (p *profileRowIterator) At() profileRow {
 }
 
 func (p *profileRowIterator) Next() bool {
-	if !p.profiles.Next() {
-		return false
+	var row schemav1.ProfileRow
+	for {
+		if !p.profiles.Next() {
+			return false
+		}
+		row = schemav1.ProfileRow(p.profiles.At())
+		if p.skip == nil || !p.skip(row) {
+			break
+		}
 	}
 	p.currentRow.blockReader = p.blockReader
-	p.currentRow.row = schemav1.ProfileRow(p.profiles.At())
+	p.currentRow.row = row
 	seriesIndex := p.currentRow.row.SeriesIndex()
 	p.currentRow.timeNanos = p.currentRow.row.TimeNanos()
 	// do we have a new series?
 	if seriesIndex == p.currentSeriesIdx {
 		return true
 	}
+	// Postings are advanced to the series: the series
+	// which profiles have all been skipped are omitted.
+	steps := seriesIndex + 1
+	if p.currentSeriesIdx != math.MaxUint32 {
+		steps = seriesIndex - p.currentSeriesIdx
+	}
 	p.currentSeriesIdx = seriesIndex
-	if !p.allPostings.Next() {
+	for ; steps > 0; steps-- {
+		if p.allPostings.Next() {
+			continue
+		}
 		if err := p.allPostings.Err(); err != nil {
 			p.err = err
 			return false
@@ -663,13 +744,14 @@ func (p *profileRowIterator) Close() error {
 	return err
 }
 
-func newMergeRowProfileIterator(src []BlockReader) (iter.Iterator[profileRow], error) {
+func newMergeRowProfileIterator(src []BlockReader, skip func(schemav1.ProfileRow) bool) (iter.Iterator[profileRow], error) {
 	its := make([]iter.Iterator[profileRow], len(src))
 	for i, s := range src {
 		it, err := newProfileRowIterator(s)
 		if err != nil {
 			return nil, err
 		}
+		it.skip = skip
 		its[i] = it
 	}
 	if len(its) == 1 {
@@ -701,11 +783,19 @@ func newMergeRowProfileIterator(src []BlockReader) (iter.Iterator[profileRow], e
 	}, nil
 }
 
+// dedupeProfileRowIterator removes the duplicate profiles: blocks
+// produced by replicated ingesters hold the same profiles, which
+// only differ in the block they come from.
 type dedupeProfileRowIterator struct {
 	iter.Iterator[profileRow]
 
 	prevFP        model.Fingerprint
 	prevTimeNanos int64
+	// IDs of the profiles of the series with the current
+	// timestamp: the order of profiles with the same timestamp
+	// is not defined, therefore duplicates may not be adjacent.
+	ids        [][16]byte
+	duplicates uint64
 }
 
 func (it *dedupeProfileRowIterator) Next() bool {
@@ -714,16 +804,32 @@ func (it *dedupeProfileRowIterator) Next() bool {
 			return false
 		}
 		currentProfile := it.Iterator.At()
-		if it.prevFP == currentProfile.fp && it.prevTimeNanos == currentProfile.timeNanos {
-			// skip duplicate profile
+		var id [16]byte
+		copy(id[:], currentProfile.row.ID())
+		if it.prevFP != currentProfile.fp || it.prevTimeNanos != currentProfile.timeNanos {
+			it.prevFP = currentProfile.fp
+			it.prevTimeNanos = currentProfile.timeNanos
+			it.ids = append(it.ids[:0], id)
+			return true
+		}
+		if it.seen(id) {
+			it.duplicates++
 			continue
 		}
-		it.prevFP = currentProfile.fp
-		it.prevTimeNanos = currentProfile.timeNanos
+		it.ids = append(it.ids, id)
 		return true
 	}
 }
 
+func (it *dedupeProfileRowIterator) seen(id [16]byte) bool {
+	for _, x := range it.ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
 type symbolsCompactor struct {
 	rewriters   map[BlockReader]*symdb.Rewriter
 	w           *symdb.SymDB
@@ -11,8 +11,11 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/google/uuid"
 	"github.com/oklog/ulid"
 	"github.com/parquet-go/parquet-go"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/samber/lo"
@@ -128,6 +131,10 @@ func TestCompactWithDownsampling(t *testing.T) {
 		require.NotNil(t, f)
 		assert.NotZero(t, f.SizeBytes)
 	}
+	require.Equal(t, []block.DownsampleTable{
+		{Resolution: model.Duration(5 * time.Minute), Aggregation: "sum"},
+		{Resolution: model.Duration(time.Hour), Aggregation: "sum"},
+	}, compacted.Downsample.Tables)
 
 	querier := blockQuerierFromMeta(t, dst, compacted)
 	matchAll := &ingesterv1.SelectProfilesRequest{
@@ -187,6 +194,7 @@ func TestCompactWithSplitting(t *testing.T) {
 		)
 	})
 	dst := t.TempDir()
+	duplicates := prometheus.NewCounter(prometheus.CounterOpts{})
 	compacted, err := CompactWithSplitting(ctx, CompactWithSplittingOpts{
 		Src:                []BlockReader{b1, b2, b2, b1},
 		Dst:                dst,
@@ -195,8 +203,11 @@ func TestCompactWithSplitting(t *testing.T) {
 		SplitBy:            SplitByFingerprint,
 		DownsamplerEnabled: true,
 		Logger:             log.NewNopLogger(),
+		DuplicateProfiles:  duplicates,
 	})
 	require.NoError(t, err)
+	// 120 source profiles, 40 of which are unique.
+	require.Equal(t, float64(80), promtestutil.ToFloat64(duplicates))
 
 	require.NoDirExists(t, filepath.Join(dst, symdb.DefaultDirName))
 
@@ -209,6 +220,12 @@ func TestCompactWithSplitting(t *testing.T) {
 
 	require.Equal(t, model.TimeFromUnix(1), compacted[1].MinTime)
 	require.Equal(t, model.TimeFromUnix(20), compacted[1].MaxTime)
+	for _, m := range compacted {
+		require.Equal(t, block.LabelSummary{
+			phlaremodel.LabelNameServiceName: {},
+			phlaremodel.LabelNameProfileType: {"process_cpu:cpu:nanoseconds:cpu:nanoseconds"},
+		}, m.LabelSummary)
+	}
 
 	// We first verify we have all series and timestamps across querying all blocks.
 	queriers := make(Queriers, len(compacted))
@@ -294,12 +311,15 @@ func profileSeriesGenerator(t *testing.T, from, through time.Time, interval time
 	t.Helper()
 	var builders []*testhelper.ProfileBuilder
 	for ts := from; ts.Before(through) || ts.Equal(through); ts = ts.Add(interval) {
-		builders = append(builders,
-			testhelper.NewProfileBuilder(ts.UnixNano()).
-				CPUProfile().
-				WithLabels(
-					lbls...,
-				).ForStacktraceString("foo", "bar", "baz").AddSamples(1))
+		p := testhelper.NewProfileBuilder(ts.UnixNano()).
+			CPUProfile().
+			WithLabels(
+				lbls...,
+			).ForStacktraceString("foo", "bar", "baz").AddSamples(1)
+		// Profiles generated for the same series and timestamp are
+		// replicas: they share the ID, as if they were ingested once.
+		p.UUID = uuid.NewSHA1(uuid.Nil, []byte(fmt.Sprint(ts.UnixNano(), lbls)))
+		builders = append(builders, p)
 	}
 	return builders
 }
@@ -373,6 +393,9 @@ func TestMergeRowProfileIterator(t *testing.T) {
 	type profile struct {
 		timeNanos int64
 		labels    phlaremodel.Labels
+		// id distinguishes profiles with the same series and timestamp:
+		// replicas of the same profile share the same ID.
+		id byte
 	}
 
 	a, b, c := phlaremodel.Labels{
@@ -384,9 +407,10 @@ func TestMergeRowProfileIterator(t *testing.T) {
 	}
 
 	for _, tc := range []struct {
-		name     string
-		in       [][]profile
-		expected []profile
+		name       string
+		in         [][]profile
+		expected   []profile
+		duplicates uint64
 	}{
 		{
 			name: "only duplicates",
@@ -407,6 +431,7 @@ func TestMergeRowProfileIterator(t *testing.T) {
 			expected: []profile{
 				{timeNanos: 1, labels: a}, {timeNanos: 2, labels: b}, {timeNanos: 3, labels: c},
 			},
+			duplicates: 9,
 		},
 		{
 			name: "missing some",
@@ -424,6 +449,25 @@ func TestMergeRowProfileIterator(t *testing.T) {
 			expected: []profile{
 				{timeNanos: 1, labels: a}, {timeNanos: 2, labels: b}, {timeNanos: 3, labels: c}, {timeNanos: 4, labels: c},
 			},
+			duplicates: 2,
+		},
+		{
+			name: "same timestamp different profiles",
+			in: [][]profile{
+				{
+					{timeNanos: 1, labels: a, id: 2}, {timeNanos: 1, labels: a, id: 1},
+				},
+				{
+					{timeNanos: 1, labels: a, id: 1}, {timeNanos: 1, labels: a, id: 3},
+				},
+				{
+					{timeNanos: 1, labels: a, id: 3}, {timeNanos: 1, labels: a, id: 2},
+				},
+			},
+			expected: []profile{
+				{timeNanos: 1, labels: a, id: 1}, {timeNanos: 1, labels: a, id: 2}, {timeNanos: 1, labels: a, id: 3},
+			},
+			duplicates: 3,
 		},
 		{
 			name: "no duplicates",
@@ -451,6 +495,7 @@ func TestMergeRowProfileIterator(t *testing.T) {
 					for _, p := range profiles {
 						prof := testhelper.NewProfileBuilder(p.timeNanos).
 							CPUProfile().ForStacktraceString("foo").AddSamples(1)
+						prof.UUID = uuid.UUID{p.id}
 						for _, l := range p.labels {
 							prof.WithLabels(l.Name, l.Value)
 						}
@@ -459,19 +504,31 @@ func TestMergeRowProfileIterator(t *testing.T) {
 					return builders
 				})
 			}
-			it, err := newMergeRowProfileIterator(blocks)
+			it, err := newMergeRowProfileIterator(blocks, nil)
 			require.NoError(t, err)
 			actual := []profile{}
 			for it.Next() {
 				actual = append(actual, profile{
 					timeNanos: it.At().timeNanos,
 					labels:    it.At().labels.WithoutPrivateLabels(),
+					id:        it.At().row.ID()[0],
 				})
 				require.Equal(t, model.Fingerprint(it.At().labels.Hash()), it.At().fp)
 			}
 			require.NoError(t, it.Err())
 			require.NoError(t, it.Close())
+			// The order of profiles with the same timestamp is not defined.
+			sort.Slice(actual, func(i, j int) bool {
+				if c := phlaremodel.CompareLabelPairs(actual[i].labels, actual[j].labels); c != 0 {
+					return c < 0
+				}
+				if actual[i].timeNanos != actual[j].timeNanos {
+					return actual[i].timeNanos < actual[j].timeNanos
+				}
+				return actual[i].id < actual[j].id
+			})
 			require.Equal(t, tc.expected, actual)
+			require.Equal(t, tc.duplicates, it.(*dedupeProfileRowIterator).duplicates)
 		})
 	}
 }
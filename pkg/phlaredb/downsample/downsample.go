@@ -1,10 +1,10 @@
 package downsample
 
 import (
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/dolthub/swiss"
 	"github.com/go-kit/log"
@@ -27,6 +27,9 @@ type interval struct {
 type aggregationType struct {
 	fn   func(a, b int64) int64
 	name string
+	// perProfile indicates that the aggregated values are
+	// divided by the number of profiles aggregated.
+	perProfile bool
 }
 
 type state struct {
@@ -41,30 +44,36 @@ type state struct {
 }
 
 type downsampleConfig struct {
+	table       block.DownsampleTable
 	interval    interval
 	aggregation aggregationType
 }
 
 var (
-	intervals = []interval{
-		{
-			durationSeconds: 5 * 60,
-			shortName:       "5m",
+	aggregations = map[string]aggregationType{
+		block.DownsampleAggregationSum: {
+			name: block.DownsampleAggregationSum,
+			fn: func(a, b int64) int64 {
+				return a + b
+			},
 		},
-		{
-			durationSeconds: 60 * 60,
-			shortName:       "1h",
+		block.DownsampleAggregationMax: {
+			name: block.DownsampleAggregationMax,
+			fn: func(a, b int64) int64 {
+				if a > b {
+					return a
+				}
+				return b
+			},
 		},
-	}
-	aggregations = []aggregationType{
-		{
-			name: "sum",
+		block.DownsampleAggregationAvgPerProfile: {
+			name: block.DownsampleAggregationAvgPerProfile,
 			fn: func(a, b int64) int64 {
 				return a + b
 			},
+			perProfile: true,
 		},
 	}
-	configs               = initConfigs()
 	inputSamplesHistogram = promauto.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "pyroscope_downsampler_input_profile_samples",
@@ -79,15 +88,17 @@ var (
 		}, []string{"interval"})
 )
 
-func initConfigs() []downsampleConfig {
-	configs := make([]downsampleConfig, 0)
-	for _, i := range intervals {
-		for _, a := range aggregations {
-			configs = append(configs, downsampleConfig{
-				interval:    i,
-				aggregation: a,
-			})
-		}
+func downsampleConfigs(c block.DownsampleConfig) []downsampleConfig {
+	configs := make([]downsampleConfig, 0, len(c.Resolutions)*len(c.Aggregations))
+	for _, t := range c.Tables() {
+		configs = append(configs, downsampleConfig{
+			table: t,
+			interval: interval{
+				durationSeconds: int64(time.Duration(t.Resolution) / time.Second),
+				shortName:       t.Resolution.String(),
+			},
+			aggregation: aggregations[t.Aggregation],
+		})
 	}
 	return configs
 }
@@ -109,8 +120,8 @@ func (p *profilesWriter) WriteRow(r parquet.Row) error {
 	return nil
 }
 
-func newProfilesWriter(path string, i interval, aggregation string) (*profilesWriter, error) {
-	profilePath := filepath.Join(path, fmt.Sprintf("profiles_%s_%s", i.shortName, aggregation)+block.ParquetSuffix)
+func newProfilesWriter(path string, t block.DownsampleTable) (*profilesWriter, error) {
+	profilePath := filepath.Join(path, t.FileName())
 	profileFile, err := os.OpenFile(profilePath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
 	if err != nil {
 		return nil, err
@@ -133,16 +144,21 @@ func newParquetProfileWriter(writer io.Writer, options ...parquet.WriterOption)
 
 type Downsampler struct {
 	path           string
+	configs        []downsampleConfig
 	profileWriters []*profilesWriter
 	states         []*state
 	logger         log.Logger
 }
 
-func NewDownsampler(path string, logger log.Logger) (*Downsampler, error) {
-	writers := make([]*profilesWriter, 0)
-	states := make([]*state, 0)
+func NewDownsampler(path string, config block.DownsampleConfig, logger log.Logger) (*Downsampler, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	configs := downsampleConfigs(config)
+	writers := make([]*profilesWriter, 0, len(configs))
+	states := make([]*state, 0, len(configs))
 	for _, c := range configs {
-		writer, err := newProfilesWriter(path, c.interval, c.aggregation.name)
+		writer, err := newProfilesWriter(path, c.table)
 		if err != nil {
 			return nil, err
 		}
@@ -152,6 +168,7 @@ func NewDownsampler(path string, logger log.Logger) (*Downsampler, error) {
 
 	return &Downsampler{
 		path:           path,
+		configs:        configs,
 		profileWriters: writers,
 		states:         states,
 		logger:         logger,
@@ -166,6 +183,13 @@ func (d *Downsampler) flush(s *state, w *profilesWriter, c downsampleConfig) err
 		"sourceProfileCount", s.profileCount,
 		"sampleCount", len(s.values))
 	outputSamplesHistogram.WithLabelValues(c.interval.shortName).Observe(float64(len(s.values)))
+	s.totalValue = 0
+	for i := range s.values {
+		if c.aggregation.perProfile {
+			s.values[i] /= s.profileCount
+		}
+		s.totalValue += s.values[i]
+	}
 	var (
 		col    = len(s.currentRow) - 1
 		newCol = func() int {
@@ -204,7 +228,7 @@ func (d *Downsampler) flush(s *state, w *profilesWriter, c downsampleConfig) err
 func (d *Downsampler) AddRow(row schemav1.ProfileRow, fp model.Fingerprint) error {
 	rowTimeSeconds := row.TimeNanos() / 1000 / 1000 / 1000
 	sourceSampleCount := 0
-	for i, c := range configs {
+	for i, c := range d.configs {
 		s := d.states[i]
 		aggregationTime := rowTimeSeconds / c.interval.durationSeconds * c.interval.durationSeconds
 		if len(d.states[i].currentRow) == 0 {
@@ -230,7 +254,6 @@ func (d *Downsampler) AddRow(row schemav1.ProfileRow, fp model.Fingerprint) erro
 					s.values = append(s.values, value)
 					s.stackTraceIdToIndex.Put(stacktraceId, len(s.values)-1)
 				}
-				s.totalValue = c.aggregation.fn(s.totalValue, value)
 			}
 			sourceSampleCount = len(values)
 		})
@@ -240,7 +263,7 @@ func (d *Downsampler) AddRow(row schemav1.ProfileRow, fp model.Fingerprint) erro
 }
 
 func (d *Downsampler) Close() error {
-	for i, c := range configs {
+	for i, c := range d.configs {
 		if len(d.states[i].currentRow) > 0 {
 			err := d.flush(d.states[i], d.profileWriters[i], c)
 			if err != nil {
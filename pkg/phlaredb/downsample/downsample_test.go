@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/parquet-go/parquet-go"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	phlareparquet "github.com/grafana/pyroscope/pkg/parquet"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
 	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
 	schemav1testhelper "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1/testhelper"
 	"github.com/grafana/pyroscope/pkg/pprof/testhelper"
@@ -18,7 +20,7 @@ import (
 
 func TestDownsampler_ProfileCounts(t *testing.T) {
 	outDir := t.TempDir()
-	d, err := NewDownsampler(outDir, log.NewNopLogger())
+	d, err := NewDownsampler(outDir, block.DefaultDownsampleConfig(), log.NewNopLogger())
 	require.NoError(t, err)
 
 	f, err := os.Open("../testdata/01HHYG6245NWHZWVP27V8WJRT7/profiles.parquet")
@@ -75,7 +77,7 @@ func TestDownsampler_Aggregation(t *testing.T) {
 	require.NoError(t, err)
 
 	outDir := t.TempDir()
-	d, err := NewDownsampler(outDir, log.NewNopLogger())
+	d, err := NewDownsampler(outDir, block.DefaultDownsampleConfig(), log.NewNopLogger())
 	require.NoError(t, err)
 
 	for _, row := range rows {
@@ -105,6 +107,65 @@ func TestDownsampler_Aggregation(t *testing.T) {
 	})
 }
 
+func TestDownsampler_Aggregations(t *testing.T) {
+	profiles := make([]schemav1.InMemoryProfile, 0)
+	builder := testhelper.NewProfileBuilder(1703853310000000000).CPUProfile() // 2023-12-29T12:35:10Z
+	builder.ForStacktraceString("a", "b", "c").AddSamples(30)
+	builder.ForStacktraceString("a", "b", "c", "d").AddSamples(20)
+	batch, _ := schemav1testhelper.NewProfileSchema(builder.Profile, "cpu")
+	profiles = append(profiles, batch...)
+
+	builder = testhelper.NewProfileBuilder(1703853559000000000).CPUProfile() // 2023-12-29T12:39:19Z
+	builder.ForStacktraceString("a", "b", "c").AddSamples(40)
+	builder.ForStacktraceString("a", "b", "c", "d").AddSamples(10)
+	builder.ForStacktraceString("a", "b", "c", "d", "e").AddSamples(20)
+	batch, _ = schemav1testhelper.NewProfileSchema(builder.Profile, "cpu")
+	profiles = append(profiles, batch...)
+
+	reader := schemav1.NewInMemoryProfilesRowReader(profiles)
+	rows, err := phlareparquet.ReadAllWithBufferSize(reader, 1024)
+	require.NoError(t, err)
+
+	outDir := t.TempDir()
+	d, err := NewDownsampler(outDir, block.DownsampleConfig{
+		Resolutions:  []time.Duration{5 * time.Minute},
+		Aggregations: []string{block.DownsampleAggregationSum, block.DownsampleAggregationMax, block.DownsampleAggregationAvgPerProfile},
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	for _, row := range rows {
+		err = d.AddRow(schemav1.ProfileRow(row), 1)
+		require.NoError(t, err)
+	}
+
+	err = d.Close()
+	require.NoError(t, err)
+
+	totalValueCol, ok := schemav1.DownsampledProfilesSchema.Lookup(schemav1.TotalValueColumnName)
+	require.True(t, ok)
+	for file, expected := range map[string][]int64{
+		"profiles_5m_sum.parquet":             {70, 30, 20},
+		"profiles_5m_max.parquet":             {40, 20, 20},
+		"profiles_5m_avg-per-profile.parquet": {35, 15, 10},
+	} {
+		downsampledRows := readDownsampledRows(t, filepath.Join(outDir, file), 1)
+		row := schemav1.DownsampledProfileRow(downsampledRows[0])
+		var total int64
+		row.ForValues(func(values []parquet.Value) {
+			require.Equal(t, len(expected), len(values), file)
+			for i, v := range expected {
+				require.Equal(t, v, values[i].Int64(), file)
+				total += v
+			}
+		})
+		for _, v := range row {
+			if v.Column() == totalValueCol.ColumnIndex {
+				require.Equal(t, total, v.Int64(), file)
+			}
+		}
+	}
+}
+
 func TestDownsampler_VaryingFingerprints(t *testing.T) {
 	profiles := make([]schemav1.InMemoryProfile, 0)
 	for i := 0; i < 5; i++ {
@@ -119,7 +180,7 @@ func TestDownsampler_VaryingFingerprints(t *testing.T) {
 	require.NoError(t, err)
 
 	outDir := t.TempDir()
-	d, err := NewDownsampler(outDir, log.NewNopLogger())
+	d, err := NewDownsampler(outDir, block.DefaultDownsampleConfig(), log.NewNopLogger())
 	require.NoError(t, err)
 
 	for i, row := range rows {
@@ -149,7 +210,7 @@ func BenchmarkDownsampler_AddRow(b *testing.B) {
 	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		outDir := b.TempDir()
-		d, err := NewDownsampler(outDir, log.NewNopLogger())
+		d, err := NewDownsampler(outDir, block.DefaultDownsampleConfig(), log.NewNopLogger())
 
 		require.NoError(b, err)
 		for _, row := range rows {
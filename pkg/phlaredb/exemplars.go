@@ -0,0 +1,231 @@
+package phlaredb
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/iter"
+	"github.com/grafana/pyroscope/pkg/pprof"
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+// ProfileExemplar is a selected profile along with its ID and total value.
+type ProfileExemplar struct {
+	Profile
+	ID    uuid.UUID
+	Total int64
+}
+
+// SelectExemplars returns the profiles with the highest total values
+// matching the request, at most limit per querier.
+func SelectExemplars(ctx context.Context, req *ingestv1.SelectExemplarsRequest, blockGetter BlockGetter) (*ingestv1.SelectExemplarsResponse, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectExemplars")
+	defer sp.Finish()
+
+	if req.Request == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing select request"))
+	}
+	sp.SetTag("start", model.Time(req.Request.Start).Time().String()).
+		SetTag("end", model.Time(req.Request.End).Time().String()).
+		SetTag("selector", req.Request.LabelSelector).
+		SetTag("limit", req.Limit)
+
+	var (
+		lock      sync.Mutex
+		exemplars []ProfileExemplar
+	)
+	err := forMatchingProfileExemplars(ctx, req.Request, blockGetter, int(req.Limit), func(_ Querier, e []ProfileExemplar) error {
+		lock.Lock()
+		exemplars = append(exemplars, e...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exemplars = topExemplars(exemplars, int(req.Limit))
+	res := &ingestv1.SelectExemplarsResponse{
+		Exemplars: make([]*typesv1.Exemplar, 0, len(exemplars)),
+	}
+	for _, e := range exemplars {
+		res.Exemplars = append(res.Exemplars, &typesv1.Exemplar{
+			Timestamp: int64(e.Timestamp()),
+			ProfileId: e.ID.String(),
+			Labels:    e.Labels(),
+			Value:     e.Total,
+		})
+	}
+	return res, nil
+}
+
+// SelectProfileByID returns the profile with the given ID in the pprof
+// format. The profile is nil, if no profile matching the request has the ID.
+func SelectProfileByID(ctx context.Context, req *ingestv1.SelectProfileByIDRequest, blockGetter BlockGetter) (*ingestv1.SelectProfileByIDResponse, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID")
+	defer sp.Finish()
+
+	if req.Request == nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing select request"))
+	}
+	id, err := uuid.Parse(req.ProfileId)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.Wrap(err, "invalid profile id"))
+	}
+	sp.SetTag("profile_id", req.ProfileId)
+
+	var (
+		lock   sync.Mutex
+		result *profilev1.Profile
+	)
+	err = forMatchingProfileExemplars(ctx, req.Request, blockGetter, 0, func(q Querier, exemplars []ProfileExemplar) error {
+		for _, e := range exemplars {
+			if e.ID != id {
+				continue
+			}
+			p, err := q.MergePprof(ctx, iter.NewSliceIterator([]Profile{e.Profile}), 0, nil)
+			if err != nil {
+				return err
+			}
+			pprof.SetProfileMetadata(p, req.Request.Type, e.Timestamp().UnixNano(), 0)
+			lock.Lock()
+			result = p
+			lock.Unlock()
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ingestv1.SelectProfileByIDResponse{Profile: result}, nil
+}
+
+// forMatchingProfileExemplars calls fn concurrently with the exemplars of
+// the profiles matching the request, for each querier. If limit is positive,
+// only the top limit exemplars of each querier are passed to fn.
+func forMatchingProfileExemplars(ctx context.Context, req *ingestv1.SelectProfilesRequest, blockGetter BlockGetter, limit int, fn func(Querier, []ProfileExemplar) error) error {
+	queriers, err := blockGetter(ctx, model.Time(req.Start), model.Time(req.End), req.Hints)
+	if err != nil {
+		return err
+	}
+	skipBlock := HintsToBlockSkipper(req.Hints)
+	g, ctx := errgroup.WithContext(ctx)
+	for _, querier := range queriers {
+		if skipBlock(querier.BlockID()) {
+			continue
+		}
+		querier := querier
+		g.Go(util.RecoverPanic(func() error {
+			it, err := querier.SelectMatchingProfiles(ctx, req)
+			if err != nil {
+				return err
+			}
+			profiles, err := iter.Slice(it)
+			if err != nil {
+				return err
+			}
+			if len(profiles) == 0 {
+				return nil
+			}
+			// Profiles must be read in the order of rows.
+			exemplars, err := querier.ProfileExemplars(ctx, iter.NewSliceIterator(querier.Sort(profiles)), limit)
+			if err != nil {
+				return err
+			}
+			return fn(querier, exemplars)
+		}))
+	}
+	return g.Wait()
+}
+
+// topExemplars sorts the exemplars by value in descending order and
+// truncates them to the limit, if it is positive.
+func topExemplars(exemplars []ProfileExemplar, limit int) []ProfileExemplar {
+	sort.Slice(exemplars, func(i, j int) bool {
+		return exemplarBefore(exemplars[i].Total, exemplars[i].Timestamp(), exemplars[j].Total, exemplars[j].Timestamp())
+	})
+	if limit > 0 && len(exemplars) > limit {
+		exemplars = exemplars[:limit]
+	}
+	return exemplars
+}
+
+// exemplarBefore reports whether the exemplar a ranks before the
+// exemplar b: exemplars with higher values come first, and the
+// earliest exemplar comes first among those with the same value.
+func exemplarBefore(aTotal int64, aTime model.Time, bTotal int64, bTime model.Time) bool {
+	if aTotal != bTotal {
+		return aTotal > bTotal
+	}
+	return aTime < bTime
+}
+
+// exemplarsHeap collects the top exemplars, at most limit, if it is
+// positive. The heap root is the lowest ranked exemplar collected.
+type exemplarsHeap struct {
+	limit     int
+	exemplars []ProfileExemplar
+}
+
+func newExemplarsHeap(limit int) *exemplarsHeap {
+	h := &exemplarsHeap{limit: limit}
+	if limit > 0 {
+		h.exemplars = make([]ProfileExemplar, 0, limit)
+	}
+	return h
+}
+
+// Admits reports whether an exemplar with the given value and timestamp
+// would be collected. It allows to skip building rejected exemplars.
+func (h *exemplarsHeap) Admits(total int64, t model.Time) bool {
+	if h.limit <= 0 || len(h.exemplars) < h.limit {
+		return true
+	}
+	return exemplarBefore(total, t, h.exemplars[0].Total, h.exemplars[0].Timestamp())
+}
+
+// Add collects the exemplar, replacing the lowest ranked one if the
+// limit is reached. The exemplar must be admitted.
+func (h *exemplarsHeap) Add(e ProfileExemplar) {
+	if h.limit <= 0 || len(h.exemplars) < h.limit {
+		heap.Push(h, e)
+		return
+	}
+	h.exemplars[0] = e
+	heap.Fix(h, 0)
+}
+
+// Exemplars returns the collected exemplars in no particular order.
+func (h *exemplarsHeap) Exemplars() []ProfileExemplar { return h.exemplars }
+
+func (h *exemplarsHeap) Len() int { return len(h.exemplars) }
+
+func (h *exemplarsHeap) Less(i, j int) bool {
+	return exemplarBefore(h.exemplars[j].Total, h.exemplars[j].Timestamp(), h.exemplars[i].Total, h.exemplars[i].Timestamp())
+}
+
+func (h *exemplarsHeap) Swap(i, j int) {
+	h.exemplars[i], h.exemplars[j] = h.exemplars[j], h.exemplars[i]
+}
+
+func (h *exemplarsHeap) Push(x interface{}) { h.exemplars = append(h.exemplars, x.(ProfileExemplar)) }
+
+func (h *exemplarsHeap) Pop() interface{} {
+	n := len(h.exemplars) - 1
+	x := h.exemplars[n]
+	h.exemplars = h.exemplars[:n]
+	return x
+}
@@ -0,0 +1,166 @@
+package phlaredb
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
+)
+
+// ingestExemplarProfiles ingests n profiles, the i-th profile total
+// value is (i+1) times the total of newProfileFoo.
+func ingestExemplarProfiles(t *testing.T, ctx context.Context, head *Head, n int, now time.Time) []uuid.UUID {
+	ids := make([]uuid.UUID, n)
+	for i := 0; i < n; i++ {
+		x := newProfileFoo()
+		x.TimeNanos = now.Add(time.Second * time.Duration(i)).UnixNano()
+		for _, s := range x.Sample {
+			s.Value[0] *= int64(i + 1)
+		}
+		ids[i] = uuid.New()
+		require.NoError(t, head.Ingest(ctx, x, ids[i], []*typesv1.LabelPair{
+			{Name: "job", Value: "foo"},
+			{Name: "x", Value: strconv.Itoa(i)},
+		}...))
+	}
+	return ids
+}
+
+func testExemplars(t *testing.T, ctx context.Context, queriers Queriers, ids []uuid.UUID, now time.Time) {
+	const total = 0o123 + 1234
+	typ, err := phlaremodel.ParseProfileTypeSelector(":type:unit:type:unit")
+	require.NoError(t, err)
+	selectProfiles := &ingestv1.SelectProfilesRequest{
+		LabelSelector: `{job="foo"}`,
+		Type:          typ,
+		Start:         now.UnixMilli(),
+		End:           now.Add(time.Hour).UnixMilli(),
+	}
+
+	res, err := SelectExemplars(ctx, &ingestv1.SelectExemplarsRequest{
+		Request: selectProfiles,
+		Limit:   3,
+	}, queriers.forTimeRange)
+	require.NoError(t, err)
+	require.Len(t, res.Exemplars, 3)
+	for i, e := range res.Exemplars {
+		x := len(ids) - 1 - i
+		assert.Equal(t, ids[x].String(), e.ProfileId)
+		assert.Equal(t, int64(total*(x+1)), e.Value)
+		assert.Equal(t, now.Add(time.Second*time.Duration(x)).UnixMilli(), e.Timestamp)
+		assert.Equal(t, strconv.Itoa(x), phlaremodel.Labels(e.Labels).Get("x"))
+	}
+
+	for _, x := range []int{0, len(ids) - 1} {
+		p, err := SelectProfileByID(ctx, &ingestv1.SelectProfileByIDRequest{
+			Request:   selectProfiles,
+			ProfileId: ids[x].String(),
+		}, queriers.forTimeRange)
+		require.NoError(t, err)
+		require.NotNil(t, p.Profile)
+		var sum int64
+		for _, s := range p.Profile.Sample {
+			sum += s.Value[0]
+		}
+		assert.Equal(t, int64(total*(x+1)), sum)
+		assert.Equal(t, now.Add(time.Second*time.Duration(x)).UnixNano(), p.Profile.TimeNanos)
+	}
+
+	p, err := SelectProfileByID(ctx, &ingestv1.SelectProfileByIDRequest{
+		Request:   selectProfiles,
+		ProfileId: uuid.New().String(),
+	}, queriers.forTimeRange)
+	require.NoError(t, err)
+	assert.Nil(t, p.Profile)
+
+	_, err = SelectProfileByID(ctx, &ingestv1.SelectProfileByIDRequest{
+		Request:   selectProfiles,
+		ProfileId: "not-a-uuid",
+	}, queriers.forTimeRange)
+	require.Error(t, err)
+}
+
+func TestSelectExemplars_Head(t *testing.T) {
+	ctx := testContext(t)
+	const n = 15
+	head, err := NewHead(ctx, Config{
+		DataPath: t.TempDir(),
+		Parquet: &ParquetConfig{
+			MaxBufferRowCount: n - 1,
+		},
+	}, NoLimit)
+	require.NoError(t, err)
+
+	c := make(chan struct{})
+	var closeOnce sync.Once
+	head.profiles.onFlush = func() {
+		closeOnce.Do(func() {
+			close(c)
+		})
+	}
+
+	now := time.Now().Truncate(time.Second)
+	ids := ingestExemplarProfiles(t, ctx, head, n, now)
+	<-c
+
+	queriers := head.Queriers()
+	require.Len(t, queriers, 2) // on-disk and in-memory parts.
+	testExemplars(t, ctx, queriers, ids, now)
+}
+
+func TestSelectExemplars_Block(t *testing.T) {
+	ctx := context.Background()
+	head := newTestHead(t)
+	now := time.Now().Truncate(time.Second)
+	ids := ingestExemplarProfiles(t, ctx, head.Head, 5, now)
+	require.NoError(t, head.Flush(ctx))
+	require.NoError(t, head.Move())
+
+	bucket, err := filesystem.NewBucket(filepath.Dir(head.localPath))
+	require.NoError(t, err)
+	q := NewBlockQuerier(ctx, bucket)
+	require.NoError(t, q.Sync(ctx))
+
+	testExemplars(t, ctx, q.Queriers(), ids, now)
+}
+
+func Test_exemplarsHeap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, limit := range []int{0, 1, 3, 10, 100} {
+		exemplars := make([]ProfileExemplar, 50)
+		for i := range exemplars {
+			// Few distinct values and timestamps to exercise ties.
+			exemplars[i] = ProfileExemplar{
+				Profile: BlockProfile{timestamp: model.Time(r.Intn(5))},
+				ID:      uuid.New(),
+				Total:   int64(r.Intn(5)),
+			}
+		}
+		h := newExemplarsHeap(limit)
+		for _, e := range exemplars {
+			if h.Admits(e.Total, e.Timestamp()) {
+				h.Add(e)
+			}
+		}
+		expected := topExemplars(append([]ProfileExemplar(nil), exemplars...), limit)
+		actual := topExemplars(h.Exemplars(), 0)
+		require.Len(t, actual, len(expected))
+		for i := range expected {
+			assert.Equal(t, expected[i].Total, actual[i].Total)
+			assert.Equal(t, expected[i].Timestamp(), actual[i].Timestamp())
+		}
+	}
+}
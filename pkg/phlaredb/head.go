@@ -70,13 +70,14 @@ type Head struct {
 	totalSamples  *atomic.Uint64
 	tables        []Table
 	delta         *deltaProfiles
+	wal           *headWAL // nil, if the WAL is disabled.
 
 	limiter   TenantLimiter
 	updatedAt *atomic.Time
 }
 
 const (
-	pathHead          = "head"
+	PathHead          = "head"
 	PathLocal         = "local"
 	defaultFolderMode = 0o755
 )
@@ -97,7 +98,7 @@ func NewHead(phlarectx context.Context, cfg Config, limiter TenantLimiter) (*Hea
 		limiter:       limiter,
 		updatedAt:     atomic.NewTime(time.Now()),
 	}
-	h.headPath = filepath.Join(cfg.DataPath, pathHead, h.meta.ULID.String())
+	h.headPath = filepath.Join(cfg.DataPath, PathHead, h.meta.ULID.String())
 	h.localPath = filepath.Join(cfg.DataPath, PathLocal, h.meta.ULID.String())
 
 	if cfg.Parquet != nil {
@@ -124,6 +125,13 @@ func NewHead(phlarectx context.Context, cfg Config, limiter TenantLimiter) (*Hea
 		}
 	}
 
+	if !cfg.DisableWAL {
+		h.wal, err = newHeadWAL(h.logger, h.metrics, filepath.Join(h.headPath, walDirName))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	h.symdb = symdb.NewSymDB(symdb.DefaultConfig().
 		WithDirectory(filepath.Join(h.headPath, symdb.DefaultDirName)).
 		WithParquetConfig(symdb.ParquetConfig{
@@ -146,6 +154,21 @@ func (h *Head) Size() uint64 {
 	return h.profiles.Size() + h.symdb.MemorySize()
 }
 
+// blockStats returns the statistics of the head: the profiles kept in
+// memory are accounted as the profile table of the original resolution.
+func (h *Head) blockStats() *typesv1.BlockStats {
+	numProfiles := uint64(h.profiles.index.totalProfiles.Load())
+	return &typesv1.BlockStats{
+		NumSeries:   uint64(h.profiles.index.totalSeries.Load()),
+		NumProfiles: numProfiles,
+		NumSamples:  h.totalSamples.Load(),
+		ProfileTables: []*typesv1.ProfileTableStats{{
+			NumRows:   numProfiles,
+			SizeBytes: h.profiles.Size(),
+		}},
+	}
+}
+
 func (h *Head) loop() {
 	symdbMetricsUpdateTicker := time.NewTicker(5 * time.Second)
 	var memStats symdb.MemoryStats
@@ -184,6 +207,7 @@ func (h *Head) Ingest(ctx context.Context, p *profilev1.Profile, id uuid.UUID, e
 		return nil
 	}
 
+	walLabels := externalLabels
 	delta := phlaremodel.Labels(externalLabels).Get(phlaremodel.LabelNameDelta) != "false"
 	externalLabels = phlaremodel.Labels(externalLabels).Delete(phlaremodel.LabelNameDelta)
 
@@ -195,6 +219,12 @@ func (h *Head) Ingest(ctx context.Context, p *profilev1.Profile, id uuid.UUID, e
 		}
 	}
 
+	if h.wal != nil {
+		if err := h.wal.append(p, id, walLabels); err != nil {
+			return err
+		}
+	}
+
 	// determine the stacktraces partition ID
 	partition := phlaremodel.StacktracePartitionFromProfile(lbls, p)
 
@@ -524,6 +554,11 @@ func (h *Head) flush(ctx context.Context) error {
 	// It must be guaranteed that no new inserts will happen
 	// after the call start.
 	h.inFlightProfiles.Wait()
+	if h.wal != nil {
+		if err := h.wal.Close(); err != nil {
+			return errors.Wrap(err, "closing wal")
+		}
+	}
 	if h.profiles.index.totalProfiles.Load() == 0 {
 		level.Info(h.logger).Log("msg", "head empty - no block written")
 		return os.RemoveAll(h.headPath)
@@ -584,6 +619,11 @@ func (h *Head) flush(ctx context.Context) error {
 		h.metrics.flushedFileSizeBytes.WithLabelValues("tsdb").Observe(float64(f.SizeBytes))
 	}
 	files = append(files, f)
+	summary, err := labelSummaryFromIndex(filepath.Join(h.headPath, block.IndexFilename))
+	if err != nil {
+		return errors.Wrap(err, "summarizing labels")
+	}
+	h.meta.LabelSummary = summary
 
 	h.metrics.flushedBlockSizeBytes.Observe(float64(blockSize))
 	sort.Slice(files, func(i, j int) bool {
@@ -617,6 +657,11 @@ func (h *Head) Move() error {
 		return err
 	}
 
+	// The WAL is not needed anymore: the block has been written.
+	if err := os.RemoveAll(filepath.Join(h.headPath, walDirName)); err != nil {
+		return err
+	}
+
 	// move block to the local directory
 	if err := os.MkdirAll(filepath.Dir(h.localPath), defaultFolderMode); err != nil {
 		return err
@@ -161,7 +161,7 @@ func (q *headOnDiskQuerier) SelectMergeBySpans(ctx context.Context, params *inge
 	if err != nil {
 		return nil, err
 	}
-	spans, err := phlaremodel.NewSpanSelector(params.SpanSelector)
+	spans, err := phlaremodel.NewSpanAndTraceSelector(params.SpanSelector, params.TraceSelector)
 	if err != nil {
 		return nil, err
 	}
@@ -281,6 +281,12 @@ func (q *headOnDiskQuerier) MergeByLabels(ctx context.Context, rows iter.Iterato
 	return mergeByLabelsWithStackTraceSelector(ctx, q.rowGroup(), rows, r, by...)
 }
 
+func (q *headOnDiskQuerier) ProfileExemplars(ctx context.Context, rows iter.Iterator[Profile], limit int) ([]ProfileExemplar, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "ProfileExemplars - HeadOnDisk")
+	defer sp.Finish()
+	return profileExemplars(ctx, q.rowGroup(), "TotalValue", rows, limit)
+}
+
 func (q *headOnDiskQuerier) SelectMergeByLabels(ctx context.Context, params *ingestv1.SelectProfilesRequest, sts *typesv1.StackTraceSelector, by ...string) ([]*typesv1.Series, error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectMergeByLabels - HeadOnDisk")
 	defer sp.Finish()
@@ -459,7 +465,7 @@ func (q *headInMemoryQuerier) SelectMergeBySpans(ctx context.Context, params *in
 	if err != nil {
 		return nil, err
 	}
-	spans, err := phlaremodel.NewSpanSelector(params.SpanSelector)
+	spans, err := phlaremodel.NewSpanAndTraceSelector(params.SpanSelector, params.TraceSelector)
 	if err != nil {
 		return nil, err
 	}
@@ -485,7 +491,7 @@ func (q *headInMemoryQuerier) SelectMergeBySpans(ctx context.Context, params *in
 			if p.Timestamp() > end {
 				break
 			}
-			if len(p.Samples.Spans) > 0 {
+			if len(p.Samples.Spans) > 0 || len(p.Samples.Traces) > 0 {
 				r.AddSamplesWithSpanSelector(p.StacktracePartition, p.Samples, spans)
 			}
 		}
@@ -633,6 +639,29 @@ func (q *headInMemoryQuerier) MergeByLabels(
 	return seriesBuilder.build(), nil
 }
 
+func (q *headInMemoryQuerier) ProfileExemplars(ctx context.Context, rows iter.Iterator[Profile], limit int) ([]ProfileExemplar, error) {
+	sp, _ := opentracing.StartSpanFromContext(ctx, "ProfileExemplars - HeadInMemory")
+	defer sp.Finish()
+	exemplars := newExemplarsHeap(limit)
+	for rows.Next() {
+		p, ok := rows.At().(ProfileWithLabels)
+		if !ok {
+			return nil, errors.New("expected ProfileWithLabels")
+		}
+		if total := p.Total(); exemplars.Admits(total, p.Timestamp()) {
+			exemplars.Add(ProfileExemplar{
+				Profile: p,
+				ID:      p.profile.ID,
+				Total:   total,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return exemplars.Exemplars(), nil
+}
+
 func (q *headInMemoryQuerier) SelectMergeByLabels(
 	ctx context.Context,
 	params *ingestv1.SelectProfilesRequest,
@@ -722,7 +751,7 @@ func (q *headInMemoryQuerier) MergeBySpans(ctx context.Context, rows iter.Iterat
 			return nil, errors.New("expected ProfileWithLabels")
 		}
 		samples := p.Samples()
-		if len(samples.Spans) > 0 {
+		if len(samples.Spans) > 0 || len(samples.Traces) > 0 {
 			r.AddSamplesWithSpanSelector(p.StacktracePartition(), samples, spanSelector)
 		}
 	}
@@ -413,6 +413,19 @@ func TestHeadFlush(t *testing.T) {
 				},
 			},
 			Version: 3,
+			LabelSummary: block.LabelSummary{
+				"__profile_type__": {
+					":CPU:nanoseconds:CPU:nanoseconds",
+					":alloc_objects:count:space:bytes",
+					":alloc_space:bytes:space:bytes",
+					":cpu:nanoseconds:cpu:nanoseconds",
+					":inuse_objects:count:space:bytes",
+					":inuse_space:bytes:space:bytes",
+					":sample:count:CPU:nanoseconds",
+					":samples:count:cpu:nanoseconds",
+				},
+				"service_name": {},
+			},
 		},
 	}
 
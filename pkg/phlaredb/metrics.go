@@ -42,6 +42,10 @@ type headMetrics struct {
 	flushedBlocksReasons        *prometheus.CounterVec
 	writtenProfileSegments      *prometheus.CounterVec
 	writtenProfileSegmentsBytes prometheus.Histogram
+
+	walRecordsWritten  *prometheus.CounterVec
+	walWrittenBytes    prometheus.Counter
+	walRecordsReplayed *prometheus.CounterVec
 }
 
 func newHeadMetrics(reg prometheus.Registerer) *headMetrics {
@@ -150,6 +154,18 @@ func newHeadMetrics(reg prometheus.Registerer) *headMetrics {
 			Name: "pyroscope_head_samples",
 			Help: "Number of samples in the head.",
 		}),
+		walRecordsWritten: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_head_wal_records_written_total",
+			Help: "Total number and status of records written to the head WAL.",
+		}, []string{"status"}),
+		walWrittenBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_head_wal_written_bytes_total",
+			Help: "Total number of bytes written to the head WAL.",
+		}),
+		walRecordsReplayed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_head_wal_records_replayed_total",
+			Help: "Total number and status of records replayed from the head WAL.",
+		}, []string{"status"}),
 	}
 
 	m.register(reg)
@@ -179,6 +195,9 @@ func (m *headMetrics) register(reg prometheus.Registerer) {
 	m.flushedBlocksReasons = util.RegisterOrGet(reg, m.flushedBlocksReasons)
 	m.writtenProfileSegments = util.RegisterOrGet(reg, m.writtenProfileSegments)
 	m.writtenProfileSegmentsBytes = util.RegisterOrGet(reg, m.writtenProfileSegmentsBytes)
+	m.walRecordsWritten = util.RegisterOrGet(reg, m.walRecordsWritten)
+	m.walWrittenBytes = util.RegisterOrGet(reg, m.walWrittenBytes)
+	m.walRecordsReplayed = util.RegisterOrGet(reg, m.walRecordsReplayed)
 }
 
 func contextWithHeadMetrics(ctx context.Context, m *headMetrics) context.Context {
@@ -21,6 +21,7 @@ import (
 	"github.com/oklog/ulid"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
 	"github.com/samber/lo"
 
 	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
@@ -30,6 +31,7 @@ import (
 	phlareobj "github.com/grafana/pyroscope/pkg/objstore"
 	phlarecontext "github.com/grafana/pyroscope/pkg/phlare/context"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
 	"github.com/grafana/pyroscope/pkg/util"
 )
 
@@ -54,6 +56,8 @@ type Config struct {
 	MinDiskAvailablePercentage float64       `yaml:"min_disk_available_percentage"`
 	EnforcementInterval        time.Duration `yaml:"enforcement_interval"`
 	DisableEnforcement         bool          `yaml:"disable_enforcement"`
+
+	DisableWAL bool `yaml:"disable_wal"`
 }
 
 type ParquetConfig struct {
@@ -70,6 +74,7 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 	f.Float64Var(&cfg.MinDiskAvailablePercentage, "pyroscopedb.retention-policy-min-disk-available-percentage", DefaultMinDiskAvailablePercentage, "Which percentage of free disk space to keep")
 	f.DurationVar(&cfg.EnforcementInterval, "pyroscopedb.retention-policy-enforcement-interval", DefaultRetentionPolicyEnforcementInterval, "How often to enforce disk retention")
 	f.BoolVar(&cfg.DisableEnforcement, "pyroscopedb.retention-policy-disable", false, "Disable retention policy enforcement")
+	f.BoolVar(&cfg.DisableWAL, "pyroscopedb.wal-disable", false, "Disable the write-ahead log of the head block. Without the WAL, profiles not yet flushed to a block are lost if the process terminates unexpectedly.")
 }
 
 type TenantLimiter interface {
@@ -105,6 +110,9 @@ type PhlareDB struct {
 	blockQuerier *BlockQuerier
 	limiter      TenantLimiter
 	evictCh      chan *blockEviction
+
+	// Tombstones of the delete requests, applied at query time.
+	tombstones *bucket.TombstonesLoader
 }
 
 func New(phlarectx context.Context, cfg Config, limiter TenantLimiter, fs phlareobj.Bucket) (*PhlareDB, error) {
@@ -126,6 +134,9 @@ func New(phlarectx context.Context, cfg Config, limiter TenantLimiter, fs phlare
 	// ensure head metrics are registered early so they are reused for the new head
 	phlarectx = contextWithHeadMetrics(phlarectx, f.metrics)
 	f.phlarectx = phlarectx
+	if err := f.recoverHeads(); err != nil {
+		return nil, fmt.Errorf("recover heads: %w", err)
+	}
 	f.wg.Add(1)
 	go f.loop()
 
@@ -139,6 +150,74 @@ func New(phlarectx context.Context, cfg Config, limiter TenantLimiter, fs phlare
 	return f, nil
 }
 
+// recoverHeads restores the heads left behind by the previous process.
+// Heads that have been flushed but not moved yet are moved to the local
+// blocks directory; the rest are rebuilt by replaying their WAL.
+func (f *PhlareDB) recoverHeads() error {
+	headsPath := filepath.Join(f.cfg.DataPath, PathHead)
+	entries, err := os.ReadDir(headsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if _, ok := block.IsBlockDir(e.Name()); !ok || !e.IsDir() {
+			continue
+		}
+		headPath := filepath.Join(headsPath, e.Name())
+		walPath := filepath.Join(headPath, walDirName)
+		if _, err = os.Stat(filepath.Join(headPath, block.MetaFilename)); err == nil {
+			if err = os.RemoveAll(walPath); err != nil {
+				return err
+			}
+			localPath := filepath.Join(f.LocalDataPath(), e.Name())
+			if err = fileutil.Rename(headPath, localPath); err != nil {
+				return err
+			}
+			level.Info(f.logger).Log("msg", "recovered flushed head", "block_path", localPath)
+			continue
+		}
+		if _, err = os.Stat(walPath); err != nil {
+			// Nothing to recover.
+			continue
+		}
+		if err = f.replayHeadWAL(walPath); err != nil {
+			// The WAL might be partially corrupted: we keep what has been
+			// replayed so far, and the rest is lost.
+			level.Error(f.logger).Log("msg", "failed to replay head wal", "wal_path", walPath, "err", err)
+		}
+		// At this point the replayed profiles are written to the WAL
+		// of the new heads, therefore the old one can be removed.
+		if err = os.RemoveAll(headPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *PhlareDB) replayHeadWAL(walPath string) error {
+	ctx := context.Background()
+	var replayed, failed int
+	defer func() {
+		level.Info(f.logger).Log("msg", "head wal replayed", "wal_path", walPath, "profiles", replayed, "failed", failed)
+	}()
+	return replayWAL(walPath, func(p *profilev1.Profile, id uuid.UUID, externalLabels []*typesv1.LabelPair) error {
+		// Ingestion errors (e.g. limits) are not fatal: the
+		// profile would have been rejected in the first place.
+		if err := f.Ingest(ctx, p, id, externalLabels...); err != nil {
+			level.Warn(f.logger).Log("msg", "failed to ingest profile from wal", "profile_id", id, "err", err)
+			f.metrics.walRecordsReplayed.WithLabelValues("failed").Inc()
+			failed++
+			return nil
+		}
+		f.metrics.walRecordsReplayed.WithLabelValues("success").Inc()
+		replayed++
+		return nil
+	})
+}
+
 func (f *PhlareDB) LocalDataPath() string {
 	return filepath.Join(f.cfg.DataPath, PathLocal)
 }
@@ -306,6 +385,18 @@ func (f *PhlareDB) queriers() Queriers {
 	return append(queriers, head...)
 }
 
+// blockGetter returns the queriers for the time range, with the
+// tombstones applied.
+func (f *PhlareDB) blockGetter() BlockGetter {
+	return WithTombstones(f.queriers().forTimeRange, f.tombstones)
+}
+
+// SetTombstonesLoader sets the loader of the tombstones to apply at query
+// time. It must be called before the database is queried.
+func (f *PhlareDB) SetTombstonesLoader(l *bucket.TombstonesLoader) {
+	f.tombstones = l
+}
+
 func (f *PhlareDB) headQueriers() Queriers {
 	res := make(Queriers, 0, len(f.heads)+len(f.flushing))
 	for _, h := range f.heads {
@@ -438,32 +529,70 @@ func (f *PhlareDB) Series(ctx context.Context, req *connect.Request[ingestv1.Ser
 	return f.queriers().Series(ctx, req)
 }
 
+// GetCardinality returns the label values and series of the head, or, if
+// the request has a time range, of all the blocks in the range.
+func (f *PhlareDB) GetCardinality(ctx context.Context, req *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "PhlareDB GetCardinality")
+	defer sp.Finish()
+
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	_, ok := phlaremodel.GetTimeRange(req.Msg)
+	if !ok {
+		return f.headQueriers().GetCardinality(ctx, req)
+	}
+	return f.queriers().GetCardinality(ctx, req)
+}
+
+func (f *PhlareDB) SelectExemplars(ctx context.Context, req *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error) {
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	res, err := SelectExemplars(ctx, req.Msg, f.blockGetter())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
+func (f *PhlareDB) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	f.headLock.RLock()
+	defer f.headLock.RUnlock()
+
+	res, err := SelectProfileByID(ctx, req.Msg, f.blockGetter())
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(res), nil
+}
+
 func (f *PhlareDB) MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesStacktracesRequest, ingestv1.MergeProfilesStacktracesResponse]) error {
 	f.headLock.RLock()
 	defer f.headLock.RUnlock()
 
-	return f.queriers().MergeProfilesStacktraces(ctx, stream)
+	return MergeProfilesStacktraces(ctx, stream, f.blockGetter())
 }
 
 func (f *PhlareDB) MergeProfilesLabels(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesLabelsRequest, ingestv1.MergeProfilesLabelsResponse]) error {
 	f.headLock.RLock()
 	defer f.headLock.RUnlock()
 
-	return f.queriers().MergeProfilesLabels(ctx, stream)
+	return MergeProfilesLabels(ctx, stream, f.blockGetter())
 }
 
 func (f *PhlareDB) MergeProfilesPprof(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesPprofRequest, ingestv1.MergeProfilesPprofResponse]) error {
 	f.headLock.RLock()
 	defer f.headLock.RUnlock()
 
-	return f.queriers().MergeProfilesPprof(ctx, stream)
+	return MergeProfilesPprof(ctx, stream, f.blockGetter())
 }
 
 func (f *PhlareDB) MergeSpanProfile(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeSpanProfileRequest, ingestv1.MergeSpanProfileResponse]) error {
 	f.headLock.RLock()
 	defer f.headLock.RUnlock()
 
-	return f.queriers().MergeSpanProfile(ctx, stream)
+	return MergeSpanProfile(ctx, stream, f.blockGetter())
 }
 
 type blockEviction struct {
@@ -495,21 +624,26 @@ func (f *PhlareDB) BlockMetadata(ctx context.Context, req *connect.Request[inges
 
 	var result ingestv1.BlockMetadataResponse
 
-	appendInRange := func(q TimeBounded, meta *block.Meta) {
+	appendInRange := func(q TimeBounded, meta *block.Meta) *typesv1.BlockInfo {
 		if !InRange(q, model.Time(req.Msg.Start), model.Time(req.Msg.End)) {
-			return
+			return nil
 		}
 		var info typesv1.BlockInfo
 		meta.WriteBlockInfo(&info)
 		result.Blocks = append(result.Blocks, &info)
+		return &info
 	}
 
 	f.headLock.RLock()
 	for _, h := range f.heads {
-		appendInRange(h, h.meta)
+		if info := appendInRange(h, h.meta); info != nil {
+			info.Stats = h.blockStats()
+		}
 	}
 	for _, h := range f.flushing {
-		appendInRange(h, h.meta)
+		if info := appendInRange(h, h.meta); info != nil {
+			info.Stats = h.blockStats()
+		}
 	}
 	f.headLock.RUnlock()
 
@@ -145,6 +145,18 @@ func (i *ingesterHandlerPhlareDB) GetProfileStats(context.Context, *connect.Requ
 	return nil, errors.New("not implemented")
 }
 
+func (i *ingesterHandlerPhlareDB) GetCardinality(context.Context, *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error) {
+	return nil, errors.New("not implemented")
+}
+
+func (i *ingesterHandlerPhlareDB) SelectExemplars(context.Context, *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error) {
+	return nil, errors.New("not implemented")
+}
+
+func (i *ingesterHandlerPhlareDB) SelectProfileByID(context.Context, *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	return nil, errors.New("not implemented")
+}
+
 func TestMergeProfilesStacktraces(t *testing.T) {
 	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
 
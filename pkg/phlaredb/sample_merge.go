@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/grafana/dskit/runutil"
 	"github.com/opentracing/opentracing-go"
 	"github.com/parquet-go/parquet-go"
@@ -87,6 +88,25 @@ func (b *singleBlockQuerier) MergeByLabels(ctx context.Context, rows iter.Iterat
 	return mergeByLabelsWithStackTraceSelector(ctx, b.profileSourceTable().file, rows, r, by...)
 }
 
+func (b *singleBlockQuerier) ProfileExemplars(ctx context.Context, rows iter.Iterator[Profile], limit int) ([]ProfileExemplar, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "ProfileExemplars - Block")
+	defer sp.Finish()
+	sp.SetTag("block ULID", b.meta.ULID.String())
+
+	if err := b.Open(ctx); err != nil {
+		return nil, err
+	}
+	b.queries.Add(1)
+	defer b.queries.Done()
+
+	ctx = query.AddMetricsToContext(ctx, b.metrics.query)
+	columnName := "TotalValue"
+	if b.meta.Version == 1 {
+		columnName = "Samples.list.element.Value"
+	}
+	return profileExemplars(ctx, b.profileSourceTable().file, columnName, rows, limit)
+}
+
 func (b *singleBlockQuerier) MergeBySpans(ctx context.Context, rows iter.Iterator[Profile], spanSelector phlaremodel.SpanSelector) (*phlaremodel.Tree, error) {
 	sp, _ := opentracing.StartSpanFromContext(ctx, "MergeBySpans - Block")
 	defer sp.Finish()
@@ -139,28 +159,41 @@ func mergeBySpans[T interface{ StacktracePartition() uint64 }](ctx context.Conte
 	if err = columns.Resolve(profileSource.Schema()); err != nil {
 		return err
 	}
-	if !columns.HasSpanID() {
+	// Trace IDs are only read if they are present and selected.
+	hasTraces := columns.HasTraceID() && spanSelector.SelectsTraces()
+	if !columns.HasSpanID() && !hasTraces {
 		return nil
 	}
-	profiles := query.NewRepeatedRowIterator(ctx, rows, profileSource.RowGroups(),
+	columnIndices := []int{
 		columns.StacktraceID.ColumnIndex,
 		columns.Value.ColumnIndex,
-		columns.SpanID.ColumnIndex,
-	)
+	}
+	spansIdx, tracesIdx := -1, -1
+	if columns.HasSpanID() {
+		spansIdx = len(columnIndices)
+		columnIndices = append(columnIndices, columns.SpanID.ColumnIndex)
+	}
+	if hasTraces {
+		tracesIdx = len(columnIndices)
+		columnIndices = append(columnIndices, columns.TraceID.ColumnIndex)
+	}
+	profiles := query.NewRepeatedRowIterator(ctx, rows, profileSource.RowGroups(), columnIndices...)
 	defer runutil.CloseWithErrCapture(&err, profiles, "failed to close profile stream")
 	for profiles.Next() {
 		p := profiles.At()
 		partition := p.Row.StacktracePartition()
 		stacktraces := p.Values[0]
 		values := p.Values[1]
-		spans := p.Values[2]
 		r.WithPartitionSamples(partition, func(samples map[uint32]int64) {
+			var spanID, traceID uint64
 			for i, sid := range stacktraces {
-				spanID := spans[i].Uint64()
-				if spanID == 0 {
-					continue
+				if spansIdx >= 0 {
+					spanID = p.Values[spansIdx][i].Uint64()
+				}
+				if tracesIdx >= 0 {
+					traceID = p.Values[tracesIdx][i].Uint64()
 				}
-				if _, ok := spanSelector[spanID]; ok {
+				if spanSelector.Match(spanID, traceID) {
 					samples[sid.Uint32()] += values[i].Int64()
 				}
 			}
@@ -260,6 +293,50 @@ func mergeByLabels[T Profile](
 	return seriesBuilder.build(), profiles.Err()
 }
 
+func profileExemplars[T Profile](
+	ctx context.Context,
+	profileSource Source,
+	totalColumnName string,
+	rows iter.Iterator[T],
+	limit int,
+) (_ []ProfileExemplar, err error) {
+	idColumn, err := v1.ResolveColumnByPath(profileSource.Schema(), []string{"ID"})
+	if err != nil {
+		return nil, err
+	}
+	totalColumn, err := v1.ResolveColumnByPath(profileSource.Schema(), strings.Split(totalColumnName, "."))
+	if err != nil {
+		return nil, err
+	}
+	profiles := query.NewRepeatedRowIterator(ctx, rows, profileSource.RowGroups(),
+		idColumn.ColumnIndex,
+		totalColumn.ColumnIndex,
+	)
+	defer runutil.CloseWithErrCapture(&err, profiles, "failed to close profile stream")
+
+	exemplars := newExemplarsHeap(limit)
+	for profiles.Next() {
+		p := profiles.At()
+		var total int64
+		for _, v := range p.Values[1] {
+			total += v.Int64()
+		}
+		if !exemplars.Admits(total, p.Row.Timestamp()) {
+			continue
+		}
+		id, err := uuid.FromBytes(p.Values[0][0].ByteArray())
+		if err != nil {
+			return nil, err
+		}
+		exemplars.Add(ProfileExemplar{
+			Profile: p.Row,
+			ID:      id,
+			Total:   total,
+		})
+	}
+	return exemplars.Exemplars(), profiles.Err()
+}
+
 func mergeByLabelsWithStackTraceSelector[T Profile](
 	ctx context.Context,
 	profileSource Source,
@@ -667,6 +667,78 @@ func TestHeadMergeSpans(t *testing.T) {
 	require.Equal(t, expected.String(), result.String())
 }
 
+func TestMergeSpansByTraces(t *testing.T) {
+	ctx := testContext(t)
+	db, err := New(ctx, Config{
+		DataPath:         contextDataDir(ctx),
+		MaxBlockDuration: time.Duration(100000) * time.Minute, // we will manually flush
+	}, NoLimit, ctx.localBucketClient)
+	require.NoError(t, err)
+
+	prof, err := pprof.FromProfile(pprofth.FooBarProfileWithTraces)
+	require.NoError(t, err)
+	prof.TimeNanos = 1000
+	require.NoError(t, db.Ingest(ctx, prof, uuid.New(), &typesv1.LabelPair{
+		Name:  model.MetricNameLabel,
+		Value: "process_cpu",
+	}))
+
+	req := &ingestv1.SelectProfilesRequest{
+		LabelSelector: `{}`,
+		Type: &typesv1.ProfileType{
+			Name:       "process_cpu",
+			SampleType: "cpu",
+			SampleUnit: "nanoseconds",
+			PeriodType: "cpu",
+			PeriodUnit: "nanoseconds",
+		},
+		Start: int64(model.TimeFromUnixNano(0)),
+		End:   int64(model.TimeFromUnixNano(int64(1 * time.Minute))),
+	}
+
+	mergeBySpans := func(t *testing.T, q Querier, spans, traces []string) string {
+		t.Helper()
+		profileIt, err := q.SelectMatchingProfiles(ctx, req)
+		require.NoError(t, err)
+		profiles, err := iter.Slice(profileIt)
+		require.NoError(t, err)
+		q.Sort(profiles)
+		spanSelector, err := phlaremodel.NewSpanAndTraceSelector(spans, traces)
+		require.NoError(t, err)
+		result, err := q.MergeBySpans(ctx, iter.NewSliceIterator(profiles), spanSelector)
+		require.NoError(t, err)
+		return result.String()
+	}
+
+	byTrace := new(phlaremodel.Tree)
+	byTrace.InsertStack(1, "bar", "foo")
+	byTrace.InsertStack(2, "foo")
+
+	bySpanAndTrace := new(phlaremodel.Tree)
+	bySpanAndTrace.InsertStack(2, "bar", "foo")
+	bySpanAndTrace.InsertStack(2, "foo")
+
+	test := func(t *testing.T, q Querier) {
+		require.Equal(t, byTrace.String(), mergeBySpans(t, q, nil, []string{"0123456789abcdef0123456789abcdef"}))
+		require.Equal(t, bySpanAndTrace.String(), mergeBySpans(t, q, []string{"badbadbadbadbadb"}, []string{"0123456789abcdef0123456789abcdef"}))
+		require.Equal(t, new(phlaremodel.Tree).String(), mergeBySpans(t, q, nil, []string{"0000000000000000000000000000000f"}))
+	}
+
+	t.Run("head", func(t *testing.T) {
+		test(t, db.headQueriers()[0])
+	})
+
+	require.NoError(t, db.Flush(context.Background(), true, ""))
+	b, err := filesystem.NewBucket(filepath.Join(contextDataDir(ctx), PathLocal))
+	require.NoError(t, err)
+	q := NewBlockQuerier(context.Background(), b)
+	require.NoError(t, q.Sync(context.Background()))
+
+	t.Run("block", func(t *testing.T) {
+		test(t, q.queriers[0])
+	})
+}
+
 func generateProfile(t *testing.T, ts int) *googlev1.Profile {
 	t.Helper()
 
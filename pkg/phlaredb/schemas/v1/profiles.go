@@ -37,6 +37,7 @@ var (
 		phlareparquet.NewGroupField("Value", parquet.Encoded(parquet.Int(64), &parquet.DeltaBinaryPacked)),
 		phlareparquet.NewGroupField("Labels", pprofLabels),
 		phlareparquet.NewGroupField("SpanID", parquet.Optional(parquet.Encoded(parquet.Uint(64), &parquet.RLEDictionary))),
+		phlareparquet.NewGroupField("TraceID", parquet.Optional(parquet.Encoded(parquet.Uint(64), &parquet.RLEDictionary))),
 	}
 	ProfilesSchema = parquet.NewSchema("Profile", phlareparquet.Group{
 		phlareparquet.NewGroupField("ID", parquet.UUID()),
@@ -67,8 +68,10 @@ var (
 	sampleStacktraceIDColumnPath = strings.Split("Samples.list.element.StacktraceID", ".")
 	SampleValueColumnPath        = strings.Split("Samples.list.element.Value", ".")
 	sampleSpanIDColumnPath       = strings.Split("Samples.list.element.SpanID", ".")
+	sampleTraceIDColumnPath      = strings.Split("Samples.list.element.TraceID", ".")
 
 	maxProfileRow               parquet.Row
+	idColIndex                  int
 	seriesIndexColIndex         int
 	stacktraceIDColIndex        int
 	valueColIndex               int
@@ -85,6 +88,11 @@ func init() {
 		SeriesIndex: math.MaxUint32,
 		TimeNanos:   math.MaxInt64,
 	}, maxProfileRow)
+	idCol, ok := ProfilesSchema.Lookup("ID")
+	if !ok {
+		panic(fmt.Errorf("ID column not found"))
+	}
+	idColIndex = idCol.ColumnIndex
 	seriesCol, ok := ProfilesSchema.Lookup(SeriesIndexColumnName)
 	if !ok {
 		panic(fmt.Errorf("SeriesIndex index column not found"))
@@ -122,6 +130,7 @@ type SampleColumns struct {
 	StacktraceID parquet.LeafColumn
 	Value        parquet.LeafColumn
 	SpanID       parquet.LeafColumn
+	TraceID      parquet.LeafColumn
 }
 
 func (c *SampleColumns) Resolve(schema *parquet.Schema) error {
@@ -134,6 +143,7 @@ func (c *SampleColumns) Resolve(schema *parquet.Schema) error {
 	}
 	// Optional.
 	c.SpanID, _ = ResolveColumnByPath(schema, sampleSpanIDColumnPath)
+	c.TraceID, _ = ResolveColumnByPath(schema, sampleTraceIDColumnPath)
 	return nil
 }
 
@@ -141,6 +151,10 @@ func (c *SampleColumns) HasSpanID() bool {
 	return c.SpanID.Node != nil
 }
 
+func (c *SampleColumns) HasTraceID() bool {
+	return c.TraceID.Node != nil
+}
+
 func ResolveColumnByPath(schema *parquet.Schema, path []string) (parquet.LeafColumn, error) {
 	if c, ok := schema.Lookup(path...); ok {
 		return c, nil
@@ -153,6 +167,7 @@ type Sample struct {
 	Value        int64              `parquet:",delta"`
 	Labels       []*profilev1.Label `parquet:",list"`
 	SpanID       uint64             `parquet:",optional"`
+	TraceID      uint64             `parquet:",optional"`
 }
 
 type Profile struct {
@@ -321,6 +336,9 @@ type Samples struct {
 	// Span associated with samples.
 	// Optional: Spans == nil, if not present.
 	Spans []uint64
+	// Lower 64 bits of the trace IDs associated with samples.
+	// Optional: Traces == nil, if not present.
+	Traces []uint64
 }
 
 func NewSamples(size int) Samples {
@@ -387,6 +405,9 @@ func trimZeroAndNegativeSamples(samples Samples) Samples {
 			if len(samples.Spans) > 0 {
 				samples.Spans[n] = samples.Spans[j]
 			}
+			if len(samples.Traces) > 0 {
+				samples.Traces[n] = samples.Traces[j]
+			}
 			n++
 		}
 	}
@@ -397,6 +418,9 @@ func trimZeroAndNegativeSamples(samples Samples) Samples {
 	if len(samples.Spans) > 0 {
 		s.Spans = samples.Spans[:n]
 	}
+	if len(samples.Traces) > 0 {
+		s.Traces = samples.Traces[:n]
+	}
 	return s
 }
 
@@ -405,6 +429,7 @@ func cloneSamples(samples Samples) Samples {
 		StacktraceIDs: copySlice(samples.StacktraceIDs),
 		Values:        copySlice(samples.Values),
 		Spans:         copySlice(samples.Spans),
+		Traces:        copySlice(samples.Traces),
 	}
 }
 
@@ -418,6 +443,9 @@ func (s Samples) Swap(i, j int) {
 	if len(s.Spans) > 0 {
 		s.Spans[i], s.Spans[j] = s.Spans[j], s.Spans[i]
 	}
+	if len(s.Traces) > 0 {
+		s.Traces[i], s.Traces[j] = s.Traces[j], s.Traces[i]
+	}
 }
 
 func (s Samples) Len() int {
@@ -436,6 +464,9 @@ func (s SamplesBySpanID) Swap(i, j int) {
 	if len(s.Spans) > 0 {
 		s.Spans[i], s.Spans[j] = s.Spans[j], s.Spans[i]
 	}
+	if len(s.Traces) > 0 {
+		s.Traces[i], s.Traces[j] = s.Traces[j], s.Traces[i]
+	}
 }
 
 func (s SamplesBySpanID) Len() int {
@@ -528,7 +559,7 @@ func deconstructMemoryProfile(imp InMemoryProfile, row parquet.Row) parquet.Row
 			col++
 			return col
 		}
-		totalCols = 8 + (7 * len(imp.Samples.StacktraceIDs)) + len(imp.Comments)
+		totalCols = 8 + (8 * len(imp.Samples.StacktraceIDs)) + len(imp.Comments)
 	)
 	if cap(row) < totalCols {
 		row = make(parquet.Row, 0, totalCols)
@@ -599,6 +630,28 @@ func deconstructMemoryProfile(imp InMemoryProfile, row parquet.Row) parquet.Row
 		}
 	}
 
+	newCol()
+	repetition = -1
+	if len(imp.Samples.Traces) == 0 {
+		// Fill the row with empty entries (one per value).
+		if len(imp.Samples.Values) == 0 {
+			row = append(row, parquet.Value{}.Level(0, 0, col))
+		}
+		for range imp.Samples.Values {
+			if repetition < 1 {
+				repetition++
+			}
+			row = append(row, parquet.Value{}.Level(repetition, 1, col))
+		}
+	} else {
+		for i := range imp.Samples.Traces {
+			if repetition < 1 {
+				repetition++
+			}
+			row = append(row, parquet.Int64Value(int64(imp.Samples.Traces[i])).Level(repetition, 2, col))
+		}
+	}
+
 	if imp.DropFrames == 0 {
 		row = append(row, parquet.Value{}.Level(0, 0, newCol()))
 	} else {
@@ -674,6 +727,12 @@ func lessProfileRows(r1, r2 parquet.Row) bool {
 
 type ProfileRow parquet.Row
 
+// ID returns the profile ID. The returned slice
+// refers to the row buffer and must not be retained.
+func (p ProfileRow) ID() []byte {
+	return p[idColIndex].ByteArray()
+}
+
 func (p ProfileRow) SeriesIndex() uint32 {
 	return p[seriesIndexColIndex].Uint32()
 }
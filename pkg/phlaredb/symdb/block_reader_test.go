@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
@@ -79,3 +80,34 @@ type mockStacktraceInserter struct{ mock.Mock }
 func (m *mockStacktraceInserter) InsertStacktrace(stacktraceID uint32, locations []int32) {
 	m.Called(stacktraceID, locations)
 }
+
+func Test_Reader_VerifyPartition(t *testing.T) {
+	for _, version := range []string{"v1", "v2"} {
+		version := version
+		t.Run(version, func(t *testing.T) {
+			b, err := filesystem.NewBucket("testdata/symbols/" + version)
+			require.NoError(t, err)
+			x, err := Open(context.Background(), b, testBlockMeta)
+			require.NoError(t, err)
+			require.NotEmpty(t, x.Partitions())
+			for _, p := range x.Partitions() {
+				require.NoError(t, x.VerifyPartition(context.Background(), p))
+			}
+			require.ErrorIs(t, x.VerifyPartition(context.Background(), 1<<40), ErrPartitionNotFound)
+		})
+	}
+}
+
+func Test_Reader_HasStacktrace(t *testing.T) {
+	b, err := filesystem.NewBucket("testdata/symbols/v1")
+	require.NoError(t, err)
+	x, err := Open(context.Background(), b, testBlockMeta)
+	require.NoError(t, err)
+
+	for _, id := range []uint32{2, 3, 11, 16, 18} {
+		assert.True(t, x.HasStacktrace(1, id), id)
+	}
+	assert.False(t, x.HasStacktrace(1, 0))
+	assert.False(t, x.HasStacktrace(1, 1<<30))
+	assert.False(t, x.HasStacktrace(1<<40, 2))
+}
@@ -34,6 +34,7 @@ func (p *PartitionWriter) WriteProfileSymbols(profile *profilev1.Profile) []sche
 	rewrites := &rewriter{}
 
 	spans := pprof.ProfileSpans(profile)
+	traces := pprof.ProfileTraces(profile)
 	pprof.ZeroLabelStrings(profile)
 
 	p.strings.ingest(profile.StringTable, rewrites)
@@ -85,7 +86,7 @@ func (p *PartitionWriter) WriteProfileSymbols(profile *profilev1.Profile) []sche
 	}
 
 	p.locations.ingest(locs, rewrites)
-	samplesPerType := p.convertSamples(rewrites, profile.Sample, spans)
+	samplesPerType := p.convertSamples(rewrites, profile.Sample, spans, traces)
 
 	profiles := make([]schemav1.InMemoryProfile, len(samplesPerType))
 	for idxType := range samplesPerType {
@@ -104,7 +105,7 @@ func (p *PartitionWriter) WriteProfileSymbols(profile *profilev1.Profile) []sche
 	return profiles
 }
 
-func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample, spans []uint64) []schemav1.Samples {
+func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample, spans, traces []uint64) []schemav1.Samples {
 	if len(in) == 0 {
 		return nil
 	}
@@ -124,6 +125,10 @@ func (p *PartitionWriter) convertSamples(r *rewriter, in []*profilev1.Sample, sp
 			s.Spans = make([]uint64, len(spans))
 			copy(s.Spans, spans)
 		}
+		if len(traces) > 0 {
+			s.Traces = make([]uint64, len(traces))
+			copy(s.Traces, traces)
+		}
 		samplesByType[i] = s
 	}
 
@@ -148,8 +148,15 @@ func (r *Resolver) AddSamplesFromParquetRow(partition uint64, stacktraceIDs, val
 
 func (r *Resolver) AddSamplesWithSpanSelector(partition uint64, s schemav1.Samples, spanSelector model.SpanSelector) {
 	r.WithPartitionSamples(partition, func(samples map[uint32]int64) {
+		var spanID, traceID uint64
 		for i, sid := range s.StacktraceIDs {
-			if _, ok := spanSelector[s.Spans[i]]; ok {
+			if len(s.Spans) > 0 {
+				spanID = s.Spans[i]
+			}
+			if len(s.Traces) > 0 {
+				traceID = s.Traces[i]
+			}
+			if spanSelector.Match(spanID, traceID) {
 				samples[sid] += int64(s.Values[i])
 			}
 		}
@@ -2,6 +2,7 @@ package symdb
 
 import (
 	"bufio"
+	"encoding/binary"
 	"io"
 	"unsafe"
 
@@ -330,9 +331,36 @@ func decodeU32Groups(dst []uint32, src []byte) (i, j, rm int) {
 		if rm = len(src[j:]); rm < n {
 			return i, j, rm
 		}
-		groupvarint.Decode4(dst[i:], src[j:])
+		decodeU32Group(dst[i:], src[j:])
 		i += 4
 		j += n
 	}
 	return i, j, 0
 }
+
+var u32GroupMasks = [4]uint32{0xff, 0xffff, 0xffffff, 0xffffffff}
+
+// decodeU32Group decodes a single group of four values from src,
+// which must hold the complete group.
+//
+// groupvarint.Decode4 is not used: its amd64 implementation loads
+// the shuffle mask with an aligned instruction, but the alignment
+// of the mask table is not guaranteed, and it reads 16 bytes past
+// the group tag regardless of the group size.
+func decodeU32Group(dst []uint32, src []byte) {
+	if len(src) < maxGroupSize {
+		// Every value is loaded as four bytes and masked.
+		var b [maxGroupSize]byte
+		copy(b[:], src)
+		src = b[:]
+	}
+	_ = dst[3]
+	tag := src[0]
+	o := 1
+	for k := 0; k < 4; k++ {
+		s := tag & 3
+		dst[k] = binary.LittleEndian.Uint32(src[o:o+4]) & u32GroupMasks[s]
+		o += int(s) + 1
+		tag >>= 2
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/dgryski/go-groupvarint"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -179,3 +180,59 @@ func Benchmark_stacktrace_tree_insert(b *testing.B) {
 		}
 	}
 }
+
+func Test_decodeU32Groups(t *testing.T) {
+	values := []uint32{0, 1, 0xff, 0x100, 0xffff, 0x10000, 0xffffff, 0x1000000, 0xffffffff}
+	r := rand.New(rand.NewSource(42))
+	for k := 0; k < 1000; k++ {
+		src := make([]uint32, 4*(1+r.Intn(8)))
+		for i := range src {
+			src[i] = values[r.Intn(len(values))]
+		}
+		var b []byte
+		g := make([]byte, maxGroupSize)
+		for i := 0; i < len(src); i += 4 {
+			groupvarint.Encode4(g, src[i:i+4])
+			b = append(b, g[:groupvarint.BytesUsed[g[0]]]...)
+		}
+		// The buffer ends with the last group.
+		b = b[:len(b):len(b)]
+
+		dst := make([]uint32, len(src))
+		i, j, rm := decodeU32Groups(dst, b)
+		require.Equal(t, len(dst), i)
+		require.Equal(t, len(b), j)
+		require.Zero(t, rm)
+		require.Equal(t, src, dst)
+
+		// Incomplete group.
+		n := len(b) - 1
+		i, j, rm = decodeU32Groups(dst, b[:n])
+		require.Equal(t, len(dst)-4, i)
+		require.Equal(t, n-j, rm)
+		require.Less(t, rm, groupvarint.BytesUsed[b[j]])
+	}
+}
+
+func Benchmark_stacktrace_tree_decode(b *testing.B) {
+	p, err := pprof.OpenFile("testdata/profile.pb.gz")
+	require.NoError(b, err)
+
+	x := newStacktraceTree(0)
+	for j := range p.Sample {
+		x.insert(p.Sample[j].LocationId)
+	}
+	var buf bytes.Buffer
+	_, err = x.WriteTo(&buf)
+	require.NoError(b, err)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		ppt := newParentPointerTree(x.len())
+		if _, err = ppt.ReadFrom(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
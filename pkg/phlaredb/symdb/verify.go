@@ -0,0 +1,90 @@
+package symdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// Partitions returns the identifiers of the partitions present in the block.
+func (r *Reader) Partitions() []uint64 {
+	ids := make([]uint64, len(r.index.PartitionHeaders))
+	for i, h := range r.index.PartitionHeaders {
+		ids[i] = h.Partition
+	}
+	return ids
+}
+
+// VerifyPartition fetches the partition and checks its integrity:
+// the checksums of the stack trace chunks, the stack trace tree
+// structure, and the references between the partition symbols.
+// Once a partition is verified, every stack trace ID that passes
+// the HasStacktrace check can be resolved safely.
+func (r *Reader) VerifyPartition(ctx context.Context, partition uint64) error {
+	p, err := r.partition(ctx, partition)
+	if err != nil {
+		return err
+	}
+	defer p.Release()
+	// Symbols are stored in the partition since v2,
+	// otherwise the references can't be verified here.
+	checkRefs := r.index.Header.Version > FormatV1
+	for i, c := range p.stacktraceChunks {
+		for j, n := range c.t.nodes {
+			if j == 0 {
+				continue
+			}
+			if n.p < 0 || int(n.p) >= j {
+				return fmt.Errorf("stack trace chunk %d: node %d: invalid parent %d", i, j, n.p)
+			}
+			if checkRefs && (n.r < 0 || int(n.r) >= len(p.locations.s)) {
+				return fmt.Errorf("stack trace chunk %d: node %d: location %d not found", i, j, n.r)
+			}
+		}
+	}
+	if !checkRefs {
+		return nil
+	}
+	for i, l := range p.locations.s {
+		if int(l.MappingId) >= len(p.mappings.s) {
+			return fmt.Errorf("location %d: mapping %d not found", i, l.MappingId)
+		}
+		for _, line := range l.Line {
+			if int(line.FunctionId) >= len(p.functions.s) {
+				return fmt.Errorf("location %d: function %d not found", i, line.FunctionId)
+			}
+		}
+	}
+	for i, f := range p.functions.s {
+		for _, s := range []uint32{f.Name, f.SystemName, f.Filename} {
+			if int(s) >= len(p.strings.s) {
+				return fmt.Errorf("function %d: string %d not found", i, s)
+			}
+		}
+	}
+	for i, m := range p.mappings.s {
+		for _, s := range []uint32{m.Filename, m.BuildId} {
+			if int(s) >= len(p.strings.s) {
+				return fmt.Errorf("mapping %d: string %d not found", i, s)
+			}
+		}
+	}
+	return nil
+}
+
+// HasStacktrace reports whether the stack trace ID refers to a node of
+// the partition stack trace tree. The check only relies on the partition
+// header, and does not fetch the partition data.
+func (r *Reader) HasStacktrace(partition uint64, stacktraceID uint32) bool {
+	p, ok := r.partitionsMap[partition]
+	if !ok || len(p.stacktraceChunks) == 0 {
+		return false
+	}
+	chunk, local := uint32(0), stacktraceID
+	if n := p.stacktraceChunks[0].header.StacktraceMaxNodes; n > 0 {
+		chunk, local = stacktraceID/n, stacktraceID%n
+	}
+	if local == 0 || int(chunk) >= len(p.stacktraceChunks) {
+		return false
+	}
+	return local < p.stacktraceChunks[chunk].header.StacktraceNodes
+}
@@ -0,0 +1,169 @@
+package phlaredb
+
+import (
+	"context"
+
+	"github.com/prometheus/common/model"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/iter"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+	"github.com/grafana/pyroscope/pkg/phlaredb/tsdb/index"
+)
+
+// WithTombstones returns a BlockGetter that hides the profiles deleted by
+// the tombstones of the loader.
+func WithTombstones(blockGetter BlockGetter, loader *bucket.TombstonesLoader) BlockGetter {
+	if loader == nil {
+		return blockGetter
+	}
+	return func(ctx context.Context, start, end model.Time, hints *ingestv1.Hints) (Queriers, error) {
+		queriers, err := blockGetter(ctx, start, end, hints)
+		if err != nil {
+			return nil, err
+		}
+		tombstones, err := loader.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return ApplyTombstones(queriers, tombstones), nil
+	}
+}
+
+// ApplyTombstones wraps the queriers overlapping the tombstones, so that
+// the profiles deleted are not selected.
+//
+// Note that only the profiles are hidden: the series and label values
+// remain visible until the compactor removes them from the blocks.
+func ApplyTombstones(queriers Queriers, tombstones bucket.Tombstones) Queriers {
+	if len(tombstones) == 0 {
+		return queriers
+	}
+	r := make(Queriers, len(queriers))
+	for i, q := range queriers {
+		r[i] = q
+		if t := tombstones.Overlapping(q.Bounds()); len(t) > 0 {
+			r[i] = &tombstonesQuerier{Querier: q, tombstones: t}
+		}
+	}
+	return r
+}
+
+// tombstonesQuerier filters out the deleted profiles. The Select* methods
+// are implemented by merging the profiles selected, as the optimized code
+// paths of the underlying querier are not aware of the tombstones.
+type tombstonesQuerier struct {
+	Querier
+	tombstones bucket.Tombstones
+}
+
+func (q *tombstonesQuerier) SelectMatchingProfiles(ctx context.Context, params *ingestv1.SelectProfilesRequest) (iter.Iterator[Profile], error) {
+	it, err := q.Querier.SelectMatchingProfiles(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &tombstonesIterator{Iterator: it, tombstones: q.tombstones}, nil
+}
+
+func (q *tombstonesQuerier) selectSorted(ctx context.Context, params *ingestv1.SelectProfilesRequest) (iter.Iterator[Profile], error) {
+	it, err := q.SelectMatchingProfiles(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := iter.Slice(it)
+	if err != nil {
+		return nil, err
+	}
+	return iter.NewSliceIterator(q.Sort(profiles)), nil
+}
+
+func (q *tombstonesQuerier) SelectMergeByStacktraces(ctx context.Context, params *ingestv1.SelectProfilesRequest) (*phlaremodel.Tree, error) {
+	rows, err := q.selectSorted(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return q.MergeByStacktraces(ctx, rows)
+}
+
+func (q *tombstonesQuerier) SelectMergeByLabels(ctx context.Context, params *ingestv1.SelectProfilesRequest, s *typesv1.StackTraceSelector, by ...string) ([]*typesv1.Series, error) {
+	rows, err := q.selectSorted(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return q.MergeByLabels(ctx, rows, s, by...)
+}
+
+func (q *tombstonesQuerier) SelectMergeBySpans(ctx context.Context, params *ingestv1.SelectSpanProfileRequest) (*phlaremodel.Tree, error) {
+	spans, err := phlaremodel.NewSpanAndTraceSelector(params.SpanSelector, params.TraceSelector)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.selectSorted(ctx, &ingestv1.SelectProfilesRequest{
+		LabelSelector: params.LabelSelector,
+		Type:          params.Type,
+		Start:         params.Start,
+		End:           params.End,
+		Hints:         params.Hints,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q.MergeBySpans(ctx, rows, spans)
+}
+
+func (q *tombstonesQuerier) SelectMergePprof(ctx context.Context, params *ingestv1.SelectProfilesRequest, maxNodes int64, s *typesv1.StackTraceSelector) (*profilev1.Profile, error) {
+	rows, err := q.selectSorted(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return q.MergePprof(ctx, rows, maxNodes, s)
+}
+
+// IndexMatchesTombstones reports whether the index has series matching
+// the tombstones with profiles within their time range. Only the series
+// time bounds are checked, therefore the result may be a false positive.
+func IndexMatchesTombstones(idx IndexReader, tombstones bucket.Tombstones) (bool, error) {
+	var (
+		lbls   phlaremodel.Labels
+		chunks []index.ChunkMeta
+	)
+	for _, t := range tombstones {
+		postings, err := PostingsForMatchers(idx, nil, t.Matchers()...)
+		if err != nil {
+			return false, err
+		}
+		for postings.Next() {
+			if _, err = idx.Series(postings.At(), &lbls, &chunks); err != nil {
+				return false, err
+			}
+			for _, c := range chunks {
+				// Chunk time bounds are in nanoseconds.
+				if t.Overlaps(model.TimeFromUnixNano(c.MinTime), model.TimeFromUnixNano(c.MaxTime)) {
+					return true, nil
+				}
+			}
+		}
+		if err = postings.Err(); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+type tombstonesIterator struct {
+	iter.Iterator[Profile]
+	tombstones bucket.Tombstones
+}
+
+func (it *tombstonesIterator) Next() bool {
+	for it.Iterator.Next() {
+		p := it.At()
+		if !it.tombstones.Deletes(p.Labels(), p.Timestamp()) {
+			return true
+		}
+	}
+	return false
+}
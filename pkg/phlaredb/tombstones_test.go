@@ -0,0 +1,113 @@
+package phlaredb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	ingesterv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/phlaredb/bucket"
+	"github.com/grafana/pyroscope/pkg/pprof/testhelper"
+)
+
+func newTombstonesTestBlock(t *testing.T) *singleBlockQuerier {
+	return newBlock(t, func() []*testhelper.ProfileBuilder {
+		var builders []*testhelper.ProfileBuilder
+		for _, job := range []string{"a", "b"} {
+			for i := 1; i <= 3; i++ {
+				builders = append(builders, testhelper.NewProfileBuilder(int64(time.Second)*int64(i)).
+					CPUProfile().
+					WithLabels("job", job).
+					ForStacktraceString("foo", "bar", "baz").AddSamples(1))
+			}
+		}
+		return builders
+	})
+}
+
+func newTestTombstones(t *testing.T, selector string, start, end int64) bucket.Tombstones {
+	ts, err := bucket.NewTombstone(selector, start, end, time.Now())
+	require.NoError(t, err)
+	return bucket.Tombstones{ts}
+}
+
+func selectSeriesByJob(t *testing.T, q Querier) []*typesv1.Series {
+	series, err := q.SelectMergeByLabels(context.Background(), &ingesterv1.SelectProfilesRequest{
+		LabelSelector: "{}",
+		Type:          mustParseProfileSelector(t, "process_cpu:cpu:nanoseconds:cpu:nanoseconds"),
+		Start:         0,
+		End:           40000,
+	}, nil, "job")
+	require.NoError(t, err)
+	return series
+}
+
+func TestApplyTombstones(t *testing.T) {
+	b := newTombstonesTestBlock(t)
+	tombstones := newTestTombstones(t, `{job="a"}`, 1500, 2500)
+
+	queriers := ApplyTombstones(Queriers{b}, tombstones)
+	require.Equal(t, []*typesv1.Series{
+		{Labels: phlaremodel.LabelsFromStrings("job", "a"), Points: []*typesv1.Point{{Value: 1, Timestamp: 1000}, {Value: 1, Timestamp: 3000}}},
+		{Labels: phlaremodel.LabelsFromStrings("job", "b"), Points: []*typesv1.Point{{Value: 1, Timestamp: 1000}, {Value: 1, Timestamp: 2000}, {Value: 1, Timestamp: 3000}}},
+	}, selectSeriesByJob(t, queriers[0]))
+
+	// Tombstones not overlapping the querier are not applied.
+	queriers = ApplyTombstones(Queriers{b}, newTestTombstones(t, `{job="a"}`, 10000, 20000))
+	require.Same(t, b, queriers[0])
+}
+
+func TestIndexMatchesTombstones(t *testing.T) {
+	b := newTombstonesTestBlock(t)
+	for _, tc := range []struct {
+		selector   string
+		start, end int64
+		expected   bool
+	}{
+		{selector: `{job="a"}`, start: 1500, end: 2500, expected: true},
+		{selector: `{job="c"}`, start: 1500, end: 2500, expected: false},
+		{selector: `{job="a"}`, start: 3001, end: 4000, expected: false},
+	} {
+		matches, err := IndexMatchesTombstones(b.Index(), newTestTombstones(t, tc.selector, tc.start, tc.end))
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, matches, tc.selector, tc.start, tc.end)
+	}
+}
+
+func TestCompactWithTombstones(t *testing.T) {
+	ctx := context.Background()
+	b := newTombstonesTestBlock(t)
+	dst := t.TempDir()
+	metas, err := CompactWithSplitting(ctx, CompactWithSplittingOpts{
+		Src:        []BlockReader{b},
+		Dst:        dst,
+		SplitCount: 1,
+		SplitBy:    SplitByFingerprint,
+		Tombstones: newTestTombstones(t, `{job="b"}`, 0, 2500),
+	})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.Equal(t, uint64(4), metas[0].Stats.NumProfiles)
+	// Rewriting a block keeps its compaction level.
+	require.Equal(t, b.Meta().Compaction.Level, metas[0].Compaction.Level)
+
+	require.Equal(t, []*typesv1.Series{
+		{Labels: phlaremodel.LabelsFromStrings("job", "a"), Points: []*typesv1.Point{{Value: 1, Timestamp: 1000}, {Value: 1, Timestamp: 2000}, {Value: 1, Timestamp: 3000}}},
+		{Labels: phlaremodel.LabelsFromStrings("job", "b"), Points: []*typesv1.Point{{Value: 1, Timestamp: 3000}}},
+	}, selectSeriesByJob(t, blockQuerierFromMeta(t, dst, metas[0])))
+
+	// All the profiles deleted.
+	metas, err = CompactWithSplitting(ctx, CompactWithSplittingOpts{
+		Src:        []BlockReader{b},
+		Dst:        t.TempDir(),
+		SplitCount: 1,
+		SplitBy:    SplitByFingerprint,
+		Tombstones: newTestTombstones(t, `{job=~"a|b"}`, 0, 5000),
+	})
+	require.NoError(t, err)
+	require.Empty(t, metas)
+}
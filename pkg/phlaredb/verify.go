@@ -0,0 +1,548 @@
+package phlaredb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/multierror"
+	"github.com/grafana/dskit/runutil"
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	thanosobjstore "github.com/thanos-io/objstore"
+
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	phlareobj "github.com/grafana/pyroscope/pkg/objstore"
+	parquetobj "github.com/grafana/pyroscope/pkg/objstore/parquet"
+	phlareparquet "github.com/grafana/pyroscope/pkg/parquet"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	schemav1 "github.com/grafana/pyroscope/pkg/phlaredb/schemas/v1"
+	"github.com/grafana/pyroscope/pkg/phlaredb/symdb"
+	"github.com/grafana/pyroscope/pkg/phlaredb/tsdb/index"
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+// BlockIssue is a problem found in a block.
+type BlockIssue struct {
+	// File is the block file the issue relates to, if any.
+	File string
+	Err  error
+	// Recoverable indicates whether the issue is fixed by RepairBlock.
+	Recoverable bool
+}
+
+func (i BlockIssue) Error() string {
+	if i.File == "" {
+		return i.Err.Error()
+	}
+	return i.File + ": " + i.Err.Error()
+}
+
+// BlockReport is the result of the block verification.
+type BlockReport struct {
+	Meta   *block.Meta
+	Issues []BlockIssue
+	// InvalidProfiles is the number of profiles referring
+	// to series or stack traces not present in the block.
+	InvalidProfiles uint64
+
+	// The meta.json matching the block files.
+	fixed       *block.Meta
+	rewriteMeta bool
+	// Downsampled tables to be removed.
+	dropTables []string
+	// Results of the profile references verification.
+	numSeries  uint64
+	partitions map[uint64]error
+}
+
+// OK reports whether no issues have been found.
+func (r *BlockReport) OK() bool { return len(r.Issues) == 0 }
+
+// Recoverable reports whether all the issues found can be fixed by RepairBlock.
+func (r *BlockReport) Recoverable() bool {
+	for _, i := range r.Issues {
+		if !i.Recoverable {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *BlockReport) recoverable(file string, err error) {
+	r.Issues = append(r.Issues, BlockIssue{File: file, Err: err, Recoverable: true})
+}
+
+func (r *BlockReport) unrecoverable(file string, err error) {
+	r.Issues = append(r.Issues, BlockIssue{File: file, Err: err})
+}
+
+// Profiles with invalid references are removed on repair.
+func (r *BlockReport) rewriteProfiles() bool {
+	if r.InvalidProfiles > 0 {
+		return true
+	}
+	for _, err := range r.partitions {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyBlock checks the integrity of the block: the list of files and
+// their sizes in meta.json, the parquet file footers, the consistency of
+// the TSDB index, the symbols partitions, and that every profile refers to
+// a series and stack traces present in the block. The bucket is expected
+// to contain the block directory.
+//
+// The problems found are listed in the report; an error is only returned
+// if the verification can't be carried out.
+func VerifyBlock(ctx context.Context, bkt phlareobj.Bucket, meta *block.Meta) (*BlockReport, error) {
+	r := &BlockReport{
+		Meta:       meta,
+		fixed:      meta.Clone(),
+		partitions: make(map[uint64]error),
+	}
+	b := phlareobj.NewPrefixedBucket(bkt, meta.ULID.String())
+	if err := r.verifyFiles(ctx, b); err != nil {
+		return nil, err
+	}
+	if !r.Recoverable() {
+		// The block can't be opened.
+		return r, nil
+	}
+	if err := r.verifyIndex(ctx, b); err != nil {
+		r.unrecoverable(block.IndexFilename, err)
+		return r, ctx.Err()
+	}
+	v := &profileVerifier{numSeries: r.numSeries, partitions: r.partitions}
+	if r.fixed.Version == block.MetaVersion3 {
+		s, err := symdb.Open(ctx, b, r.fixed)
+		if err != nil {
+			r.unrecoverable(symdb.DefaultDirName, err)
+			return r, ctx.Err()
+		}
+		defer runutil.CloseWithLogOnErr(util.Logger, s, "closing symbols reader")
+		for _, p := range s.Partitions() {
+			if r.partitions[p] = s.VerifyPartition(ctx, p); r.partitions[p] != nil {
+				r.recoverable(symdb.DefaultDirName, fmt.Errorf("partition %d: %w", p, r.partitions[p]))
+			}
+		}
+		v.symbols = s
+	}
+	if err := r.verifyProfiles(ctx, b, v); err != nil {
+		r.unrecoverable(profileTableName(), err)
+	}
+	if r.rewriteMeta || len(r.dropTables) > 0 {
+		r.fixed.Downsample.Tables = r.fixed.Downsample.Tables[:0]
+		for _, t := range r.Meta.Downsample.Tables {
+			if r.fixed.FileByRelPath(t.FileName()) != nil {
+				r.fixed.Downsample.Tables = append(r.fixed.Downsample.Tables, t)
+			}
+		}
+	}
+	return r, ctx.Err()
+}
+
+func profileTableName() string {
+	return new(schemav1.ProfilePersister).Name() + block.ParquetSuffix
+}
+
+func isDownsampledTable(name string) bool {
+	resolution, _, ok := block.ParseProfileTableName(name)
+	return ok && resolution > 0
+}
+
+// verifyFiles checks that the files listed in meta.json are present
+// in the bucket, and that their sizes match. Parquet footers are read,
+// and the number of rows and row groups are compared.
+func (r *BlockReport) verifyFiles(ctx context.Context, b phlareobj.Bucket) error {
+	present := make(map[string]struct{})
+	err := b.Iter(ctx, "", func(name string) error {
+		if !strings.HasSuffix(name, ".json") {
+			present[name] = struct{}{}
+		}
+		return nil
+	}, thanosobjstore.WithRecursiveIter)
+	if err != nil {
+		return err
+	}
+
+	files := make([]block.File, 0, len(r.Meta.Files))
+	for _, f := range r.Meta.Files {
+		if f.RelPath == block.MetaFilename {
+			continue
+		}
+		if _, ok := present[f.RelPath]; !ok {
+			if isDownsampledTable(f.RelPath) {
+				r.recoverable(f.RelPath, errors.New("downsampled table not found"))
+				r.rewriteMeta = true
+				continue
+			}
+			r.unrecoverable(f.RelPath, errors.New("file not found"))
+			files = append(files, f)
+			continue
+		}
+		delete(present, f.RelPath)
+		if r.verifyFile(ctx, b, &f) {
+			files = append(files, f)
+		}
+	}
+	// Files not listed in meta.json.
+	unlisted := make([]string, 0, len(present))
+	for name := range present {
+		unlisted = append(unlisted, name)
+	}
+	sort.Strings(unlisted)
+	for _, name := range unlisted {
+		f := block.File{RelPath: name}
+		r.recoverable(name, errors.New("file is not listed in meta.json"))
+		r.rewriteMeta = true
+		if r.verifyFile(ctx, b, &f) {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].RelPath < files[j].RelPath
+	})
+	r.fixed.Files = files
+
+	for _, name := range []string{block.IndexFilename, profileTableName()} {
+		if r.fixed.FileByRelPath(name) == nil {
+			r.unrecoverable(name, errors.New("file not found"))
+		}
+	}
+	return ctx.Err()
+}
+
+// verifyFile updates the file description to match the object, and reports
+// whether the file is to be kept in the block: unreadable downsampled tables
+// are removed on repair.
+func (r *BlockReport) verifyFile(ctx context.Context, b phlareobj.Bucket, f *block.File) bool {
+	attrs, err := b.Attributes(ctx, f.RelPath)
+	if err != nil {
+		r.unrecoverable(f.RelPath, err)
+		return true
+	}
+	size := uint64(attrs.Size)
+	// The size is optional.
+	if f.SizeBytes != 0 && f.SizeBytes != size {
+		r.recoverable(f.RelPath, fmt.Errorf("size %d does not match %d in meta.json", size, f.SizeBytes))
+		r.rewriteMeta = true
+	}
+	f.SizeBytes = size
+	if filepath.Ext(f.RelPath) != block.ParquetSuffix {
+		return true
+	}
+	info, err := verifyParquetFile(ctx, b, *f)
+	if err != nil {
+		if isDownsampledTable(f.RelPath) {
+			r.recoverable(f.RelPath, err)
+			r.dropTables = append(r.dropTables, f.RelPath)
+			return false
+		}
+		r.unrecoverable(f.RelPath, err)
+		return true
+	}
+	// The number of row groups is not compared: it is not
+	// reported accurately for the symbols tables.
+	if f.Parquet != nil && f.Parquet.NumRows != info.NumRows {
+		r.recoverable(f.RelPath, fmt.Errorf("%d rows do not match %d in meta.json", info.NumRows, f.Parquet.NumRows))
+		r.rewriteMeta = true
+	}
+	f.Parquet = &info
+	return true
+}
+
+// verifyParquetFile reads the parquet file footer. The downsampled tables
+// are read entirely: unlike the table of the original resolution, which is
+// read when the profiles are verified, they are not checked otherwise.
+func verifyParquetFile(ctx context.Context, b phlareobj.Bucket, f block.File) (block.ParquetFile, error) {
+	var pf parquetobj.File
+	if err := pf.Open(ctx, b, f, parquet.SkipBloomFilters(true)); err != nil {
+		return block.ParquetFile{}, err
+	}
+	defer runutil.CloseWithLogOnErr(util.Logger, &pf, "closing parquet file")
+	info := block.ParquetFile{
+		NumRows:      uint64(pf.NumRows()),
+		NumRowGroups: uint64(len(pf.RowGroups())),
+	}
+	if !isDownsampledTable(f.RelPath) {
+		return info, nil
+	}
+	reader := parquet.NewReader(pf.File, schemav1.DownsampledProfilesSchema)
+	defer runutil.CloseWithLogOnErr(util.Logger, reader, "closing parquet reader")
+	rows := phlareparquet.NewBufferedRowReaderIterator(reader, 32)
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return block.ParquetFile{}, err
+	}
+	return info, rows.Close()
+}
+
+// verifyIndex checks that the series are stored in the order
+// of the series index the profiles refer to.
+func (r *BlockReport) verifyIndex(ctx context.Context, b phlareobj.Bucket) error {
+	o, err := b.Get(ctx, block.IndexFilename)
+	if err != nil {
+		return err
+	}
+	buf, err := io.ReadAll(o)
+	if err = errors.Wrap(multierror.New(err, o.Close()).Err(), "reading tsdb index"); err != nil {
+		return err
+	}
+	idx, err := index.NewReader(index.RealByteSlice(buf))
+	if err != nil {
+		return errors.Wrap(err, "opening tsdb index")
+	}
+	defer runutil.CloseWithLogOnErr(util.Logger, idx, "closing tsdb index")
+	k, v := index.AllPostingsKey()
+	postings, err := idx.Postings(k, nil, v)
+	if err != nil {
+		return err
+	}
+	var (
+		numSeries uint64
+		chunks    = make([]index.ChunkMeta, 1)
+		lbls      phlaremodel.Labels
+	)
+	for postings.Next() {
+		if _, err = idx.Series(postings.At(), &lbls, &chunks); err != nil {
+			return errors.Wrapf(err, "series %d", numSeries)
+		}
+		if len(chunks) == 0 || uint64(chunks[0].SeriesIndex) != numSeries {
+			return fmt.Errorf("series %d: series index does not match the series order", numSeries)
+		}
+		numSeries++
+	}
+	if err = postings.Err(); err != nil {
+		return err
+	}
+	r.numSeries = numSeries
+	for i, f := range r.fixed.Files {
+		if f.RelPath != block.IndexFilename {
+			continue
+		}
+		if f.TSDB != nil && f.TSDB.NumSeries != numSeries {
+			r.recoverable(f.RelPath, fmt.Errorf("%d series do not match %d in meta.json", numSeries, f.TSDB.NumSeries))
+			r.rewriteMeta = true
+		}
+		r.fixed.Files[i].TSDB = &block.TSDBFile{NumSeries: numSeries}
+	}
+	if r.fixed.Stats.NumSeries != numSeries {
+		r.recoverable(block.MetaFilename, fmt.Errorf("%d series do not match %d in the block stats", numSeries, r.fixed.Stats.NumSeries))
+		r.rewriteMeta = true
+		r.fixed.Stats.NumSeries = numSeries
+	}
+	return nil
+}
+
+// verifyProfiles reads the profiles table and checks
+// the references of the profiles.
+func (r *BlockReport) verifyProfiles(ctx context.Context, b phlareobj.Bucket, v *profileVerifier) error {
+	f := r.fixed.FileByRelPath(profileTableName())
+	var pf parquetobj.File
+	if err := pf.Open(ctx, b, *f, parquet.SkipBloomFilters(true)); err != nil {
+		return err
+	}
+	defer runutil.CloseWithLogOnErr(util.Logger, &pf, "closing parquet file")
+	reader := parquet.NewReader(pf.File, schemav1.ProfilesSchema)
+	defer runutil.CloseWithLogOnErr(util.Logger, reader, "closing parquet reader")
+	rows := phlareparquet.NewBufferedRowReaderIterator(reader, 32)
+	defer runutil.CloseWithLogOnErr(util.Logger, rows, "closing rows iterator")
+
+	var (
+		profiles uint64
+		series   int64 = -1
+		invalid  error
+	)
+	for rows.Next() {
+		if profiles%1024 == 0 && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		row := schemav1.ProfileRow(rows.At())
+		s := int64(row.SeriesIndex())
+		if s < series {
+			return fmt.Errorf("profile %d: profiles are not ordered by series", profiles)
+		}
+		series = s
+		if err := v.verify(row); err != nil {
+			if invalid == nil {
+				invalid = fmt.Errorf("profile %d: %w", profiles, err)
+			}
+			r.InvalidProfiles++
+		}
+		profiles++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if invalid != nil {
+		r.recoverable(f.RelPath, fmt.Errorf("%d invalid profiles, first: %w", r.InvalidProfiles, invalid))
+	}
+	if r.fixed.Stats.NumProfiles != profiles {
+		r.recoverable(block.MetaFilename, fmt.Errorf("%d profiles do not match %d in the block stats", profiles, r.fixed.Stats.NumProfiles))
+		r.rewriteMeta = true
+		r.fixed.Stats.NumProfiles = profiles
+	}
+	return nil
+}
+
+type profileVerifier struct {
+	numSeries uint64
+	// Symbols are only verified in v3 blocks.
+	symbols    *symdb.Reader
+	partitions map[uint64]error
+}
+
+func (v *profileVerifier) verify(row schemav1.ProfileRow) (err error) {
+	if uint64(row.SeriesIndex()) >= v.numSeries {
+		return fmt.Errorf("series %d not found", row.SeriesIndex())
+	}
+	if v.symbols == nil {
+		return nil
+	}
+	partition := row.StacktracePartitionID()
+	if pErr, ok := v.partitions[partition]; !ok {
+		return fmt.Errorf("partition %d not found", partition)
+	} else if pErr != nil {
+		return fmt.Errorf("partition %d is invalid", partition)
+	}
+	row.ForStacktraceIDsValues(func(values []parquet.Value) {
+		for _, id := range values {
+			if !v.symbols.HasStacktrace(partition, id.Uint32()) {
+				err = fmt.Errorf("stack trace %d not found in partition %d", id.Uint32(), partition)
+				return
+			}
+		}
+	})
+	return err
+}
+
+var repairedBlocks = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "pyroscope_repaired_blocks_marked_for_deletion_total",
+	Help: "Total number of blocks marked for deletion after being repaired.",
+})
+
+// RepairBlock fixes the recoverable issues found in the block. The meta.json
+// file is rewritten to match the block files, and the unreadable downsampled
+// tables are removed. If the block has profiles referring to series or stack
+// traces not present in the block, a new block without them is uploaded to
+// the bucket and the original block is marked for deletion.
+//
+// The meta of the repaired block is returned.
+func RepairBlock(ctx context.Context, logger log.Logger, bkt phlareobj.Bucket, r *BlockReport) (*block.Meta, error) {
+	if r.OK() {
+		return r.Meta, nil
+	}
+	if !r.Recoverable() {
+		return nil, fmt.Errorf("block %s has unrecoverable issues", r.Meta.ULID)
+	}
+	b := phlareobj.NewPrefixedBucket(bkt, r.Meta.ULID.String())
+	for _, name := range r.dropTables {
+		if err := b.Delete(ctx, name); err != nil && !b.IsObjNotFoundErr(err) {
+			return nil, errors.Wrapf(err, "removing %s", name)
+		}
+	}
+	if r.rewriteMeta || len(r.dropTables) > 0 {
+		var buf bytes.Buffer
+		if _, err := r.fixed.WriteTo(&buf); err != nil {
+			return nil, err
+		}
+		if err := b.Upload(ctx, block.MetaFilename, &buf); err != nil {
+			return nil, errors.Wrapf(err, "uploading %s", block.MetaFilename)
+		}
+	}
+	if !r.rewriteProfiles() {
+		return r.fixed, nil
+	}
+
+	dir, err := os.MkdirTemp("", "pyroscope-repair-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	src := NewSingleBlockQuerierFromMeta(ctx, bkt, r.fixed)
+	if err = src.Open(ctx); err != nil {
+		return nil, errors.Wrap(err, "opening block")
+	}
+	defer runutil.CloseWithLogOnErr(logger, src, "closing block")
+	v := &profileVerifier{numSeries: r.numSeries, partitions: r.partitions}
+	if r.fixed.Version == block.MetaVersion3 {
+		if v.symbols, err = symdb.Open(ctx, b, r.fixed); err != nil {
+			return nil, err
+		}
+		defer runutil.CloseWithLogOnErr(logger, v.symbols, "closing symbols reader")
+	}
+	config := downsampleConfig(r.fixed)
+	metas, err := CompactWithSplitting(ctx, CompactWithSplittingOpts{
+		Src:                []BlockReader{src},
+		Dst:                dir,
+		SplitCount:         1,
+		SplitBy:            SplitByFingerprint,
+		DownsamplerEnabled: len(config.Resolutions) > 0,
+		DownsamplerConfig:  config,
+		Logger:             logger,
+		skipProfile: func(row schemav1.ProfileRow) bool {
+			return v.verify(row) != nil
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "rewriting block")
+	}
+	if len(metas) == 0 {
+		return nil, fmt.Errorf("block %s has no valid profiles", r.Meta.ULID)
+	}
+	repaired := metas[0]
+	bdir := filepath.Join(dir, repaired.ULID.String())
+	if err = ValidateLocalBlock(ctx, bdir); err != nil {
+		return nil, errors.Wrapf(err, "invalid repaired block %s", repaired.ULID)
+	}
+	if err = block.Upload(ctx, logger, bkt, bdir); err != nil {
+		return nil, errors.Wrapf(err, "uploading repaired block %s", repaired.ULID)
+	}
+	if err = block.MarkForDeletion(ctx, logger, bkt, r.Meta.ULID, "source of repaired block", false, repairedBlocks); err != nil {
+		return nil, err
+	}
+	return &repaired, nil
+}
+
+// downsampleConfig returns the downsampling config
+// matching the downsampled tables present in the block.
+func downsampleConfig(meta *block.Meta) block.DownsampleConfig {
+	var (
+		config       block.DownsampleConfig
+		resolutions  = make(map[time.Duration]struct{})
+		aggregations = make(map[string]struct{})
+	)
+	for _, f := range meta.Files {
+		resolution, aggregation, ok := block.ParseProfileTableName(f.RelPath)
+		if !ok || resolution == 0 {
+			continue
+		}
+		if _, ok = resolutions[resolution]; !ok {
+			resolutions[resolution] = struct{}{}
+			config.Resolutions = append(config.Resolutions, resolution)
+		}
+		if _, ok = aggregations[aggregation]; !ok {
+			aggregations[aggregation] = struct{}{}
+			config.Aggregations = append(config.Aggregations, aggregation)
+		}
+	}
+	sort.Slice(config.Resolutions, func(i, j int) bool {
+		return config.Resolutions[i] < config.Resolutions[j]
+	})
+	return config
+}
@@ -0,0 +1,126 @@
+package phlaredb_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/pyroscope/pkg/objstore/providers/filesystem"
+	"github.com/grafana/pyroscope/pkg/phlaredb"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block/testutil"
+	"github.com/grafana/pyroscope/pkg/pprof/testhelper"
+)
+
+func createVerifyTestBlock(t *testing.T, jobs ...string) (block.Meta, string) {
+	return testutil.CreateBlock(t, func() []*testhelper.ProfileBuilder {
+		builders := make([]*testhelper.ProfileBuilder, 0, len(jobs))
+		for i, job := range jobs {
+			builders = append(builders, testhelper.NewProfileBuilder(int64(i+1)).
+				CPUProfile().
+				WithLabels("job", job).
+				ForStacktraceString("foo", "bar", job).AddSamples(1))
+		}
+		return builders
+	})
+}
+
+func Test_VerifyBlock(t *testing.T) {
+	ctx := context.Background()
+	meta, dir := createVerifyTestBlock(t, "a", "b")
+	bkt, err := filesystem.NewBucket(dir)
+	require.NoError(t, err)
+
+	r, err := phlaredb.VerifyBlock(ctx, bkt, &meta)
+	require.NoError(t, err)
+	require.True(t, r.OK(), r.Issues)
+
+	t.Run("missing index is unrecoverable", func(t *testing.T) {
+		meta, dir := createVerifyTestBlock(t, "a", "b")
+		require.NoError(t, os.Remove(filepath.Join(dir, meta.ULID.String(), block.IndexFilename)))
+		bkt, err := filesystem.NewBucket(dir)
+		require.NoError(t, err)
+
+		r, err := phlaredb.VerifyBlock(ctx, bkt, &meta)
+		require.NoError(t, err)
+		require.False(t, r.OK())
+		require.False(t, r.Recoverable())
+		_, err = phlaredb.RepairBlock(ctx, log.NewNopLogger(), bkt, r)
+		require.Error(t, err)
+	})
+}
+
+func Test_RepairBlock_Meta(t *testing.T) {
+	ctx := context.Background()
+	meta, dir := createVerifyTestBlock(t, "a", "b")
+	bkt, err := filesystem.NewBucket(dir)
+	require.NoError(t, err)
+
+	corrupted := meta.Clone()
+	corrupted.Stats.NumProfiles = 10
+	for i := range corrupted.Files {
+		corrupted.Files[i].SizeBytes++
+	}
+	_, err = corrupted.WriteToFile(log.NewNopLogger(), filepath.Join(dir, meta.ULID.String()))
+	require.NoError(t, err)
+
+	r, err := phlaredb.VerifyBlock(ctx, bkt, corrupted)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.True(t, r.Recoverable(), r.Issues)
+	assert.Zero(t, r.InvalidProfiles)
+
+	repaired, err := phlaredb.RepairBlock(ctx, log.NewNopLogger(), bkt, r)
+	require.NoError(t, err)
+	assert.Equal(t, meta.ULID, repaired.ULID)
+
+	stored, err := block.ReadMetaFromDir(filepath.Join(dir, meta.ULID.String()))
+	require.NoError(t, err)
+	require.Len(t, stored.Files, len(meta.Files))
+	for i, f := range meta.Files {
+		assert.Equal(t, f.RelPath, stored.Files[i].RelPath)
+		assert.Equal(t, f.SizeBytes, stored.Files[i].SizeBytes)
+	}
+	assert.Equal(t, meta.Stats, stored.Stats)
+	r, err = phlaredb.VerifyBlock(ctx, bkt, stored)
+	require.NoError(t, err)
+	require.True(t, r.OK(), r.Issues)
+}
+
+func Test_RepairBlock_InvalidProfiles(t *testing.T) {
+	ctx := context.Background()
+	meta, dir := createVerifyTestBlock(t, "a", "b")
+	bkt, err := filesystem.NewBucket(dir)
+	require.NoError(t, err)
+
+	// The index of a block with a single series: the profiles
+	// of the second one refer to a series that does not exist.
+	other, otherDir := createVerifyTestBlock(t, "a")
+	index, err := os.ReadFile(filepath.Join(otherDir, other.ULID.String(), block.IndexFilename))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, meta.ULID.String(), block.IndexFilename), index, 0o644))
+
+	r, err := phlaredb.VerifyBlock(ctx, bkt, &meta)
+	require.NoError(t, err)
+	require.False(t, r.OK())
+	require.True(t, r.Recoverable(), r.Issues)
+	assert.Equal(t, uint64(1), r.InvalidProfiles)
+
+	repaired, err := phlaredb.RepairBlock(ctx, log.NewNopLogger(), bkt, r)
+	require.NoError(t, err)
+	require.NotEqual(t, meta.ULID, repaired.ULID)
+	assert.Equal(t, uint64(1), repaired.Stats.NumProfiles)
+	assert.Equal(t, uint64(1), repaired.Stats.NumSeries)
+	assert.FileExists(t, filepath.Join(dir, meta.ULID.String(), block.DeletionMarkFilename))
+
+	stored, err := block.ReadMetaFromDir(filepath.Join(dir, repaired.ULID.String()))
+	require.NoError(t, err)
+	r, err = phlaredb.VerifyBlock(ctx, bkt, stored)
+	require.NoError(t, err)
+	require.True(t, r.OK(), r.Issues)
+}
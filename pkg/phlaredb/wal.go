@@ -0,0 +1,131 @@
+package phlaredb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+
+	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+const walDirName = "wal"
+
+type walRecordType byte
+
+const (
+	walRecordProfile walRecordType = 1
+)
+
+// headWAL is the write-ahead log of a head block. Every profile ingested
+// into the head is appended to the WAL before it is written to the in-memory
+// tables. The log is replayed on start-up if the head has not been flushed.
+type headWAL struct {
+	wal     *wlog.WL
+	metrics *headMetrics
+}
+
+func newHeadWAL(logger log.Logger, metrics *headMetrics, dir string) (*headWAL, error) {
+	// The WAL metrics are not registered: there might be multiple
+	// heads at the same time, which would cause a conflict.
+	w, err := wlog.NewSize(logger, nil, dir, wlog.DefaultSegmentSize, wlog.CompressionSnappy)
+	if err != nil {
+		return nil, err
+	}
+	return &headWAL{wal: w, metrics: metrics}, nil
+}
+
+func (w *headWAL) append(p *profilev1.Profile, id uuid.UUID, externalLabels []*typesv1.LabelPair) error {
+	rec, err := encodeWALProfile(p, id, externalLabels)
+	if err != nil {
+		return err
+	}
+	if err = w.wal.Log(rec); err != nil {
+		w.metrics.walRecordsWritten.WithLabelValues("failed").Inc()
+		return errors.Wrap(err, "writing wal record")
+	}
+	w.metrics.walRecordsWritten.WithLabelValues("success").Inc()
+	w.metrics.walWrittenBytes.Add(float64(len(rec)))
+	return nil
+}
+
+func (w *headWAL) Close() error {
+	return w.wal.Close()
+}
+
+// replayWAL reads the WAL located in dir and calls fn for every profile
+// record found. Records that can not be decoded are skipped, which may only
+// happen if the WAL is corrupted; the replay is stopped at the first error
+// reading the WAL.
+func replayWAL(dir string, fn func(p *profilev1.Profile, id uuid.UUID, externalLabels []*typesv1.LabelPair) error) error {
+	sr, err := wlog.NewSegmentsReader(dir)
+	if err != nil {
+		return errors.Wrap(err, "opening wal segments")
+	}
+	defer sr.Close()
+	r := wlog.NewReader(sr)
+	for r.Next() {
+		p, id, externalLabels, err := decodeWALProfile(r.Record())
+		if err != nil {
+			return errors.Wrapf(err, "decoding wal record at segment %d offset %d", r.Segment(), r.Offset())
+		}
+		if err = fn(p, id, externalLabels); err != nil {
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// Profile record layout:
+//
+//	| type (1) | profile ID (16) | labels size (uvarint) | labels | profile |
+func encodeWALProfile(p *profilev1.Profile, id uuid.UUID, externalLabels []*typesv1.LabelPair) ([]byte, error) {
+	lbls := typesv1.Labels{Labels: externalLabels}
+	lblsSize := lbls.SizeVT()
+	var sizeBuf [binary.MaxVarintLen64]byte
+	sizeLen := binary.PutUvarint(sizeBuf[:], uint64(lblsSize))
+	buf := make([]byte, 1+len(id)+sizeLen+lblsSize+p.SizeVT())
+	buf[0] = byte(walRecordProfile)
+	n := 1
+	n += copy(buf[n:], id[:])
+	n += copy(buf[n:], sizeBuf[:sizeLen])
+	if _, err := lbls.MarshalToSizedBufferVT(buf[n : n+lblsSize]); err != nil {
+		return nil, errors.Wrap(err, "marshalling labels")
+	}
+	n += lblsSize
+	if _, err := p.MarshalToSizedBufferVT(buf[n:]); err != nil {
+		return nil, errors.Wrap(err, "marshalling profile")
+	}
+	return buf, nil
+}
+
+func decodeWALProfile(rec []byte) (*profilev1.Profile, uuid.UUID, []*typesv1.LabelPair, error) {
+	var id uuid.UUID
+	if len(rec) < 1+len(id) {
+		return nil, id, nil, fmt.Errorf("record is too short: %d bytes", len(rec))
+	}
+	if t := walRecordType(rec[0]); t != walRecordProfile {
+		return nil, id, nil, fmt.Errorf("unknown record type %d", t)
+	}
+	n := 1
+	n += copy(id[:], rec[n:])
+	lblsSize, m := binary.Uvarint(rec[n:])
+	if m <= 0 || uint64(len(rec)-n-m) < lblsSize {
+		return nil, id, nil, fmt.Errorf("invalid labels size")
+	}
+	n += m
+	var lbls typesv1.Labels
+	if err := lbls.UnmarshalVT(rec[n : n+int(lblsSize)]); err != nil {
+		return nil, id, nil, errors.Wrap(err, "unmarshalling labels")
+	}
+	n += int(lblsSize)
+	p := new(profilev1.Profile)
+	if err := p.UnmarshalVT(rec[n:]); err != nil {
+		return nil, id, nil, errors.Wrap(err, "unmarshalling profile")
+	}
+	return p, id, lbls.Labels, nil
+}
@@ -0,0 +1,127 @@
+package phlaredb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_WALRecordEncoding(t *testing.T) {
+	p := newProfileFoo()
+	id := uuid.New()
+	lbls := []*typesv1.LabelPair{{Name: "foo", Value: "bar"}, {Name: "__delta__", Value: "false"}}
+
+	rec, err := encodeWALProfile(p, id, lbls)
+	require.NoError(t, err)
+	actualProfile, actualID, actualLabels, err := decodeWALProfile(rec)
+	require.NoError(t, err)
+	assert.Equal(t, id, actualID)
+	assert.True(t, p.EqualVT(actualProfile))
+	assert.Equal(t, lbls, actualLabels)
+
+	_, _, _, err = decodeWALProfile(rec[:10])
+	require.Error(t, err)
+}
+
+// crash stops the database without flushing heads.
+func crash(t *testing.T, db *PhlareDB) {
+	close(db.stopCh)
+	db.wg.Wait()
+	for _, h := range db.heads {
+		require.NoError(t, h.wal.Close())
+		close(h.stopCh)
+		h.wg.Wait()
+	}
+	close(db.evictCh)
+	require.NoError(t, db.blockQuerier.Close())
+}
+
+func Test_HeadWALReplay(t *testing.T) {
+	var (
+		ctx   = testContext(t)
+		cfg   = Config{DataPath: contextDataDir(ctx), MaxBlockDuration: 30 * time.Minute}
+		end   = time.Unix(0, int64(time.Hour))
+		start = end.Add(-time.Hour)
+		step  = 15 * time.Second
+	)
+
+	db, err := New(ctx, cfg, NoLimit, ctx.localBucketClient)
+	require.NoError(t, err)
+	ingestProfiles(t, db, cpuProfileGenerator, start.UnixNano(), end.UnixNano(), step,
+		&typesv1.LabelPair{Name: "namespace", Value: "my-namespace"},
+		&typesv1.LabelPair{Name: "pod", Value: "my-pod"},
+	)
+	require.Len(t, db.heads, 3)
+	expectedSeries, err := db.Series(ctx, connect.NewRequest(&ingestv1.SeriesRequest{}))
+	require.NoError(t, err)
+	expectedProfiles := totalHeadProfiles(db)
+	crash(t, db)
+
+	db, err = New(ctx, cfg, NoLimit, ctx.localBucketClient)
+	require.NoError(t, err)
+	require.Len(t, db.heads, 3)
+	series, err := db.Series(ctx, connect.NewRequest(&ingestv1.SeriesRequest{}))
+	require.NoError(t, err)
+	assert.Equal(t, expectedSeries.Msg.LabelsSet, series.Msg.LabelsSet)
+	assert.Equal(t, expectedProfiles, totalHeadProfiles(db))
+
+	// Only the heads of the replayed WAL are left.
+	entries, err := os.ReadDir(filepath.Join(cfg.DataPath, PathHead))
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// Once the heads are written, the WAL is removed.
+	require.NoError(t, db.Flush(ctx, true, ""))
+	metas, err := db.BlockMetas(ctx)
+	require.NoError(t, err)
+	require.Len(t, metas, 3)
+	for _, m := range metas {
+		_, err = os.Stat(filepath.Join(db.LocalDataPath(), m.ULID.String(), walDirName))
+		assert.True(t, os.IsNotExist(err))
+	}
+	require.NoError(t, db.Close())
+}
+
+func Test_RecoverFlushedHead(t *testing.T) {
+	var (
+		ctx   = testContext(t)
+		cfg   = Config{DataPath: contextDataDir(ctx), MaxBlockDuration: time.Hour}
+		start = time.Unix(0, 0)
+		end   = start.Add(time.Minute)
+		step  = 15 * time.Second
+	)
+
+	db, err := New(ctx, cfg, NoLimit, ctx.localBucketClient)
+	require.NoError(t, err)
+	ingestProfiles(t, db, cpuProfileGenerator, start.UnixNano(), end.UnixNano(), step)
+	expectedProfiles := totalHeadProfiles(db)
+	// Close flushes the heads, but does not move them.
+	require.NoError(t, db.Close())
+
+	db, err = New(ctx, cfg, NoLimit, ctx.localBucketClient)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	assert.Empty(t, db.heads)
+	metas, err := db.BlockMetas(ctx)
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	assert.Equal(t, uint64(expectedProfiles), metas[0].Stats.NumProfiles)
+}
+
+func totalHeadProfiles(db *PhlareDB) (n int64) {
+	for _, h := range db.heads {
+		n += h.profiles.index.totalProfiles.Load()
+	}
+	return n
+}
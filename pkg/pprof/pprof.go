@@ -24,6 +24,7 @@ import (
 
 	profilev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 	"github.com/grafana/pyroscope/pkg/slices"
 	"github.com/grafana/pyroscope/pkg/util"
 )
@@ -975,6 +976,7 @@ var uint32SlicePool zeropool.Pool[[]uint32]
 const (
 	ProfileIDLabelName = "profile_id" // For compatibility with the existing clients.
 	SpanIDLabelName    = "span_id"    // Will be supported in the future.
+	TraceIDLabelName   = "trace_id"
 )
 
 func LabelID(p *profilev1.Profile, name string) int64 {
@@ -1014,6 +1016,29 @@ func spanIDFromLabels(tmp []byte, labelIdx int64, stringTable []string, labels [
 	return 0
 }
 
+// ProfileTraces returns the lower 64 bits of the trace ID of each sample,
+// or nil, if the profile has no trace_id labels.
+func ProfileTraces(p *profilev1.Profile) []uint64 {
+	i := LabelID(p, TraceIDLabelName)
+	if i <= 0 {
+		return nil
+	}
+	tmp := make([]byte, 16)
+	t := make([]uint64, len(p.Sample))
+	for j, sample := range p.Sample {
+		for _, x := range sample.Label {
+			if x.Key != i {
+				continue
+			}
+			if id, ok := phlaremodel.DecodeTraceID(tmp, p.StringTable[x.Str]); ok {
+				t[j] = id
+				break
+			}
+		}
+	}
+	return t
+}
+
 func decodeSpanID(tmp []byte, s string) bool {
 	if len(s) != 16 {
 		return false
@@ -1129,3 +1129,18 @@ func Benchmark_GetProfileLanguage(b *testing.B) {
 		})
 	}
 }
+
+func Test_ProfileTraces(t *testing.T) {
+	p := &profilev1.Profile{
+		StringTable: []string{"", "trace_id", "0000000000000000fedcba9876543210", "fedcba9876543210", "foo", "bar"},
+		Sample: []*profilev1.Sample{
+			{Label: []*profilev1.Label{{Key: 1, Str: 2}}},
+			{Label: []*profilev1.Label{{Key: 4, Str: 5}, {Key: 1, Str: 3}}},
+			{Label: []*profilev1.Label{{Key: 4, Str: 5}}},
+			{Label: []*profilev1.Label{{Key: 1, Str: 4}}},
+		},
+	}
+	const id = uint64(0x1032547698badcfe)
+	assert.Equal(t, []uint64{id, id, 0, 0}, ProfileTraces(p))
+	assert.Nil(t, ProfileTraces(&profilev1.Profile{StringTable: []string{"", "foo"}}))
+}
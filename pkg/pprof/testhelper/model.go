@@ -132,4 +132,50 @@ var (
 		},
 		Period: 10000000,
 	}
+
+	FooBarProfileWithTraces = &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		DefaultSampleType: "cpu",
+		Sample: []*profile.Sample{
+			{
+				Value:    []int64{1},
+				Location: []*profile.Location{FooLocation, BarLocation},
+				Label: map[string][]string{
+					"span_id":  {"badbadbadbadbada"},
+					"trace_id": {"0123456789abcdef0123456789abcdef"},
+				},
+			},
+			{
+				Value:    []int64{1},
+				Location: []*profile.Location{FooLocation, BarLocation},
+				Label: map[string][]string{
+					"span_id":  {"badbadbadbadbadb"},
+					"trace_id": {"fedcba9876543210fedcba9876543210"},
+				},
+			},
+			{
+				Value:    []int64{2},
+				Location: []*profile.Location{FooLocation},
+				Label: map[string][]string{
+					"trace_id": {"0123456789abcdef0123456789abcdef"},
+				},
+			},
+			{
+				Value:    []int64{3},
+				Location: []*profile.Location{BarLocation},
+			},
+		},
+		TimeNanos:     1,
+		DurationNanos: int64(15 * time.Nanosecond),
+		Mapping:       []*profile.Mapping{FooMapping},
+		Location:      []*profile.Location{FooLocation, BarLocation},
+		Function:      []*profile.Function{FooFunction, BarFunction},
+		PeriodType: &profile.ValueType{
+			Type: "cpu",
+			Unit: "nanoseconds",
+		},
+		Period: 10000000,
+	}
 )
@@ -0,0 +1,168 @@
+package querier
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log/level"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/util/spanlogger"
+)
+
+const defaultCardinalityLimit = 10
+
+// GetCardinality reports the label names with the most distinct values and
+// the services and profile types with the most series. Ingesters and store
+// gateways read the series from the TSDB index and only return the top ones,
+// as sketches of the values and series. The responses are then merged, so
+// that every label value and series is only counted once.
+func (q *Querier) GetCardinality(ctx context.Context, req *connect.Request[querierv1.GetCardinalityRequest]) (*connect.Response[querierv1.GetCardinalityResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "GetCardinality")
+	defer sp.Finish()
+
+	_, hasTimeRange := phlaremodel.GetTimeRange(req.Msg)
+	sp.LogFields(
+		otlog.Bool("legacy_request", !hasTimeRange),
+		otlog.String("matchers", strings.Join(req.Msg.Matchers, ",")),
+		otlog.Int64("start", req.Msg.Start),
+		otlog.Int64("end", req.Msg.End),
+	)
+
+	limit := req.Msg.GetLimit()
+	if limit <= 0 {
+		limit = defaultCardinalityLimit
+	}
+
+	if q.storeGatewayQuerier == nil || !hasTimeRange {
+		responses, err := q.cardinalityFromIngesters(ctx, &ingestv1.GetCardinalityRequest{
+			Matchers: req.Msg.Matchers,
+			Start:    req.Msg.Start,
+			End:      req.Msg.End,
+			Limit:    limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return connect.NewResponse(mergeCardinality(responses, limit)), nil
+	}
+
+	storeQueries := splitQueryToStores(model.Time(req.Msg.Start), model.Time(req.Msg.End), model.Now(), q.cfg.QueryStoreAfter, nil)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	var responses []ResponseFromReplica[*ingestv1.GetCardinalityResponse]
+	var lock sync.Mutex
+	group, ctx := errgroup.WithContext(ctx)
+
+	if storeQueries.ingester.shouldQuery {
+		group.Go(func() error {
+			ir, err := q.cardinalityFromIngesters(ctx, storeQueries.ingester.GetCardinalityRequest(req.Msg, limit))
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			responses = append(responses, ir...)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	if storeQueries.storeGateway.shouldQuery {
+		group.Go(func() error {
+			ir, err := q.cardinalityFromStoreGateway(ctx, storeQueries.storeGateway.GetCardinalityRequest(req.Msg, limit))
+			if err != nil {
+				return err
+			}
+
+			lock.Lock()
+			responses = append(responses, ir...)
+			lock.Unlock()
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(mergeCardinality(responses, limit)), nil
+}
+
+// mergeCardinality merges the responses of the replicas. The label names
+// and series groups outside of the top of a replica are not accounted
+// for, therefore the counts of the merged top ones may be underestimated.
+func mergeCardinality(responses []ResponseFromReplica[*ingestv1.GetCardinalityResponse], limit int64) *querierv1.GetCardinalityResponse {
+	type seriesKey struct{ serviceName, profileType string }
+	values := make(map[string]*ingestv1.CardinalitySketch)
+	series := make(map[seriesKey]*ingestv1.CardinalitySketch)
+	var all *ingestv1.CardinalitySketch
+	for _, r := range responses {
+		if r.response == nil {
+			continue
+		}
+		for _, lv := range r.response.LabelValues {
+			values[lv.Name] = phlaremodel.MergeCardinalitySketches(values[lv.Name], lv.Values)
+		}
+		for _, s := range r.response.Series {
+			k := seriesKey{serviceName: s.ServiceName, profileType: s.ProfileType}
+			series[k] = phlaremodel.MergeCardinalitySketches(series[k], s.Fingerprints)
+		}
+		all = phlaremodel.MergeCardinalitySketches(all, r.response.AllSeries)
+	}
+
+	res := &querierv1.GetCardinalityResponse{
+		SeriesCount: phlaremodel.CardinalityEstimate(all),
+		LabelNames:  make([]*querierv1.LabelNameCardinality, 0, len(values)),
+		Series:      make([]*querierv1.SeriesCardinality, 0, len(series)),
+	}
+	for name, v := range values {
+		res.LabelNames = append(res.LabelNames, &querierv1.LabelNameCardinality{
+			Name:       name,
+			ValueCount: phlaremodel.CardinalityEstimate(v),
+		})
+	}
+	sort.Slice(res.LabelNames, func(i, j int) bool {
+		if res.LabelNames[i].ValueCount != res.LabelNames[j].ValueCount {
+			return res.LabelNames[i].ValueCount > res.LabelNames[j].ValueCount
+		}
+		return res.LabelNames[i].Name < res.LabelNames[j].Name
+	})
+	for k, f := range series {
+		res.Series = append(res.Series, &querierv1.SeriesCardinality{
+			ServiceName: k.serviceName,
+			ProfileType: k.profileType,
+			SeriesCount: phlaremodel.CardinalityEstimate(f),
+		})
+	}
+	sort.Slice(res.Series, func(i, j int) bool {
+		a, b := res.Series[i], res.Series[j]
+		if a.SeriesCount != b.SeriesCount {
+			return a.SeriesCount > b.SeriesCount
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.ProfileType < b.ProfileType
+	})
+	if int64(len(res.LabelNames)) > limit {
+		res.LabelNames = res.LabelNames[:limit]
+	}
+	if int64(len(res.Series)) > limit {
+		res.Series = res.Series[:limit]
+	}
+	return res
+}
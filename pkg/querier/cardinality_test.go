@@ -0,0 +1,59 @@
+package querier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+func Test_mergeCardinality(t *testing.T) {
+	sketch := phlaremodel.NewCardinalitySketch
+	// Replicas report overlapping label values and series.
+	responses := []ResponseFromReplica[*ingestv1.GetCardinalityResponse]{
+		{addr: "1", response: &ingestv1.GetCardinalityResponse{
+			LabelValues: []*ingestv1.LabelNameValues{
+				{Name: "pod", Values: sketch([]uint64{1, 2})},
+				{Name: "service_name", Values: sketch([]uint64{10})},
+			},
+			Series: []*ingestv1.SeriesFingerprints{
+				{ServiceName: "a", ProfileType: "cpu", Fingerprints: sketch([]uint64{1, 2})},
+			},
+			AllSeries: sketch([]uint64{1, 2}),
+		}},
+		{addr: "2", response: &ingestv1.GetCardinalityResponse{
+			LabelValues: []*ingestv1.LabelNameValues{
+				{Name: "pod", Values: sketch([]uint64{2, 3})},
+				{Name: "service_name", Values: sketch([]uint64{10, 11})},
+				{Name: "region", Values: sketch([]uint64{20})},
+			},
+			Series: []*ingestv1.SeriesFingerprints{
+				{ServiceName: "a", ProfileType: "cpu", Fingerprints: sketch([]uint64{2, 3})},
+				{ServiceName: "b", ProfileType: "memory", Fingerprints: sketch([]uint64{4})},
+			},
+			AllSeries: sketch([]uint64{2, 3, 4}),
+		}},
+		{addr: "3"},
+	}
+
+	assert.Equal(t, &querierv1.GetCardinalityResponse{
+		SeriesCount: 4,
+		LabelNames: []*querierv1.LabelNameCardinality{
+			{Name: "pod", ValueCount: 3},
+			{Name: "service_name", ValueCount: 2},
+			{Name: "region", ValueCount: 1},
+		},
+		Series: []*querierv1.SeriesCardinality{
+			{ServiceName: "a", ProfileType: "cpu", SeriesCount: 3},
+			{ServiceName: "b", ProfileType: "memory", SeriesCount: 1},
+		},
+	}, mergeCardinality(responses, 10))
+
+	limited := mergeCardinality(responses, 1)
+	assert.Equal(t, uint64(4), limited.SeriesCount)
+	assert.Equal(t, []*querierv1.LabelNameCardinality{{Name: "pod", ValueCount: 3}}, limited.LabelNames)
+	assert.Equal(t, []*querierv1.SeriesCardinality{{ServiceName: "a", ProfileType: "cpu", SeriesCount: 3}}, limited.Series)
+}
@@ -0,0 +1,195 @@
+package querier
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/go-kit/log/level"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
+
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+	"github.com/grafana/pyroscope/pkg/util/spanlogger"
+)
+
+const defaultExemplarsLimit = 10
+
+// SelectExemplars returns the individual profiles with the highest total
+// values, which can be fetched with SelectProfileByID.
+func (q *Querier) SelectExemplars(ctx context.Context, req *connect.Request[querierv1.SelectExemplarsRequest]) (*connect.Response[querierv1.SelectExemplarsResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectExemplars")
+	sp.SetTag("start", model.Time(req.Msg.Start).Time().String()).
+		SetTag("end", model.Time(req.Msg.End).Time().String()).
+		SetTag("selector", req.Msg.LabelSelector).
+		SetTag("profile_type", req.Msg.ProfileTypeID)
+	defer sp.Finish()
+
+	profileType, err := phlaremodel.ParseProfileTypeSelector(req.Msg.ProfileTypeID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	limit := req.Msg.GetLimit()
+	if limit <= 0 {
+		limit = defaultExemplarsLimit
+	}
+
+	var (
+		lock      sync.Mutex
+		responses []ResponseFromReplica[*ingestv1.SelectExemplarsResponse]
+	)
+	err = q.forStoreQueries(ctx, req.Msg.Start, req.Msg.End,
+		func(ctx context.Context, sq storeQuery) error {
+			r, err := q.exemplarsFromIngesters(ctx, sq.SelectExemplarsRequest(req.Msg, profileType, limit))
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			responses = append(responses, r...)
+			lock.Unlock()
+			return nil
+		},
+		func(ctx context.Context, sq storeQuery) error {
+			r, err := q.exemplarsFromStoreGateway(ctx, sq.SelectExemplarsRequest(req.Msg, profileType, limit))
+			if err != nil {
+				return err
+			}
+			lock.Lock()
+			responses = append(responses, r...)
+			lock.Unlock()
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&querierv1.SelectExemplarsResponse{
+		Exemplars: mergeExemplars(responses, int(limit)),
+	}), nil
+}
+
+// SelectProfileByID returns the profile with the given ID in the pprof format.
+func (q *Querier) SelectProfileByID(ctx context.Context, req *connect.Request[querierv1.SelectProfileByIDRequest]) (*connect.Response[googlev1.Profile], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID")
+	sp.SetTag("start", model.Time(req.Msg.Start).Time().String()).
+		SetTag("end", model.Time(req.Msg.End).Time().String()).
+		SetTag("selector", req.Msg.LabelSelector).
+		SetTag("profile_type", req.Msg.ProfileTypeID).
+		SetTag("profile_id", req.Msg.ProfileId)
+	defer sp.Finish()
+
+	profileType, err := phlaremodel.ParseProfileTypeSelector(req.Msg.ProfileTypeID)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	msg := req.Msg
+	if msg.LabelSelector == "" {
+		msg = msg.CloneVT()
+		msg.LabelSelector = "{}"
+	}
+
+	var (
+		lock    sync.Mutex
+		profile *googlev1.Profile
+	)
+	setProfile := func(responses []ResponseFromReplica[*ingestv1.SelectProfileByIDResponse]) {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, r := range responses {
+			if profile == nil && r.response.GetProfile() != nil {
+				profile = r.response.Profile
+			}
+		}
+	}
+	err = q.forStoreQueries(ctx, msg.Start, msg.End,
+		func(ctx context.Context, sq storeQuery) error {
+			r, err := q.profileByIDFromIngesters(ctx, sq.SelectProfileByIDRequest(msg, profileType))
+			if err != nil {
+				return err
+			}
+			setProfile(r)
+			return nil
+		},
+		func(ctx context.Context, sq storeQuery) error {
+			r, err := q.profileByIDFromStoreGateway(ctx, sq.SelectProfileByIDRequest(msg, profileType))
+			if err != nil {
+				return err
+			}
+			setProfile(r)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, connect.NewError(connect.CodeNotFound, errors.Errorf("profile %q not found", msg.ProfileId))
+	}
+	return connect.NewResponse(profile), nil
+}
+
+// forStoreQueries splits the time range between ingesters and store
+// gateways and calls the corresponding function for each of them,
+// concurrently.
+func (q *Querier) forStoreQueries(
+	ctx context.Context,
+	start, end int64,
+	ingesters func(context.Context, storeQuery) error,
+	storeGateways func(context.Context, storeQuery) error,
+) error {
+	if q.storeGatewayQuerier == nil {
+		return ingesters(ctx, storeQuery{start: model.Time(start), end: model.Time(end), shouldQuery: true})
+	}
+
+	storeQueries := splitQueryToStores(model.Time(start), model.Time(end), model.Now(), q.cfg.QueryStoreAfter, nil)
+	if !storeQueries.ingester.shouldQuery && !storeQueries.storeGateway.shouldQuery {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("start and end time are outside of the ingester and store gateway retention"))
+	}
+	storeQueries.Log(level.Debug(spanlogger.FromContext(ctx, q.logger)))
+
+	g, ctx := errgroup.WithContext(ctx)
+	if storeQueries.ingester.shouldQuery {
+		g.Go(func() error { return ingesters(ctx, storeQueries.ingester) })
+	}
+	if storeQueries.storeGateway.shouldQuery {
+		g.Go(func() error { return storeGateways(ctx, storeQueries.storeGateway) })
+	}
+	return g.Wait()
+}
+
+// mergeExemplars deduplicates the exemplars returned by replicas and
+// returns the ones with the highest values.
+func mergeExemplars(responses []ResponseFromReplica[*ingestv1.SelectExemplarsResponse], limit int) []*typesv1.Exemplar {
+	seen := make(map[string]struct{})
+	exemplars := make([]*typesv1.Exemplar, 0, limit)
+	for _, r := range responses {
+		for _, e := range r.response.GetExemplars() {
+			if _, ok := seen[e.ProfileId]; ok {
+				continue
+			}
+			seen[e.ProfileId] = struct{}{}
+			exemplars = append(exemplars, e)
+		}
+	}
+	sort.Slice(exemplars, func(i, j int) bool {
+		if exemplars[i].Value != exemplars[j].Value {
+			return exemplars[i].Value > exemplars[j].Value
+		}
+		if exemplars[i].Timestamp != exemplars[j].Timestamp {
+			return exemplars[i].Timestamp < exemplars[j].Timestamp
+		}
+		return exemplars[i].ProfileId < exemplars[j].ProfileId
+	})
+	if len(exemplars) > limit {
+		exemplars = exemplars[:limit]
+	}
+	return exemplars
+}
@@ -0,0 +1,42 @@
+package querier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_mergeExemplars(t *testing.T) {
+	// Replicas return the same profiles.
+	responses := []ResponseFromReplica[*ingestv1.SelectExemplarsResponse]{
+		{addr: "1", response: &ingestv1.SelectExemplarsResponse{
+			Exemplars: []*typesv1.Exemplar{
+				{ProfileId: "a", Timestamp: 1, Value: 10},
+				{ProfileId: "b", Timestamp: 2, Value: 5},
+			},
+		}},
+		{addr: "2", response: &ingestv1.SelectExemplarsResponse{
+			Exemplars: []*typesv1.Exemplar{
+				{ProfileId: "a", Timestamp: 1, Value: 10},
+				{ProfileId: "c", Timestamp: 3, Value: 20},
+				{ProfileId: "d", Timestamp: 0, Value: 5},
+			},
+		}},
+		{addr: "3"},
+	}
+
+	assert.Equal(t, []*typesv1.Exemplar{
+		{ProfileId: "c", Timestamp: 3, Value: 20},
+		{ProfileId: "a", Timestamp: 1, Value: 10},
+		{ProfileId: "d", Timestamp: 0, Value: 5},
+		{ProfileId: "b", Timestamp: 2, Value: 5},
+	}, mergeExemplars(responses, 10))
+
+	assert.Equal(t, []*typesv1.Exemplar{
+		{ProfileId: "c", Timestamp: 3, Value: 20},
+		{ProfileId: "a", Timestamp: 1, Value: 10},
+	}, mergeExemplars(responses, 2))
+}
@@ -177,6 +177,32 @@ func (q *QueryHandlers) Render(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if format == "collapsed" || format == "speedscope" {
+		if *selectParams.MaxNodes == 0 {
+			// The exported profile is not meant to be rendered:
+			// it is only truncated if max nodes are requested.
+			noTruncation := int64(-1)
+			selectParams.MaxNodes = &noTruncation
+		}
+		resp, err := q.client.SelectMergeStacktraces(req.Context(), connect.NewRequest(selectParams))
+		if err != nil {
+			httputil.Error(w, err)
+			return
+		}
+		m := phlaremodel.NewFlameGraphMerger()
+		m.MergeFlameGraph(resp.Msg.Flamegraph)
+		if format == "collapsed" {
+			w.Header().Add("Content-Type", "text/plain")
+			m.Tree().WriteCollapsed(w)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		if err = m.Tree().WriteSpeedscope(w, profileType); err != nil {
+			httputil.Error(w, err)
+		}
+		return
+	}
+
 	var resFlame *connect.Response[querierv1.SelectMergeStacktracesResponse]
 	g, ctx := errgroup.WithContext(req.Context())
 	selectParamsClone := selectParams.CloneVT()
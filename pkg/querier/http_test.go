@@ -1,16 +1,24 @@
 package querier
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
 
+	"connectrpc.com/connect"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/querier/v1/querierv1connect"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
 )
 
 func Test_ParseQuery(t *testing.T) {
@@ -40,3 +48,50 @@ func Test_ParseQuery(t *testing.T) {
 
 	require.Equal(t, `{foo="bar",bar=~"buzz"}`, queryRequest.LabelSelector)
 }
+
+type renderQuerierClient struct {
+	querierv1connect.QuerierServiceClient
+	tree *phlaremodel.Tree
+	req  *querierv1.SelectMergeStacktracesRequest
+}
+
+func (c *renderQuerierClient) SelectMergeStacktraces(_ context.Context, req *connect.Request[querierv1.SelectMergeStacktracesRequest]) (*connect.Response[querierv1.SelectMergeStacktracesResponse], error) {
+	c.req = req.Msg
+	return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{
+		Flamegraph: phlaremodel.NewFlameGraph(c.tree, req.Msg.GetMaxNodes()),
+	}), nil
+}
+
+func Test_Render_Export(t *testing.T) {
+	tree := new(phlaremodel.Tree)
+	tree.InsertStack(1, "c", "b", "a")
+	tree.InsertStack(2, "d", "b", "a")
+	var collapsed bytes.Buffer
+	tree.WriteCollapsed(&collapsed)
+
+	for _, tc := range []struct {
+		query       string
+		contentType string
+		maxNodes    int64
+	}{
+		{query: "format=collapsed", contentType: "text/plain", maxNodes: -1},
+		{query: "format=collapsed&max-nodes=5", contentType: "text/plain", maxNodes: 5},
+		{query: "format=speedscope", contentType: "application/json", maxNodes: -1},
+		{query: "format=speedscope&maxNodes=5", contentType: "application/json", maxNodes: 5},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			client := &renderQuerierClient{tree: tree}
+			req := httptest.NewRequest(http.MethodGet, "/pyroscope/render?query=process_cpu:cpu:nanoseconds:cpu:nanoseconds{}&from=now-1h&"+tc.query, nil)
+			w := httptest.NewRecorder()
+			NewHTTPHandlers(client).Render(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+			require.Equal(t, tc.maxNodes, client.req.GetMaxNodes())
+			if tc.contentType == "text/plain" {
+				require.Equal(t, collapsed.String(), w.Body.String())
+			} else {
+				require.True(t, json.Valid(w.Body.Bytes()))
+			}
+		})
+	}
+}
@@ -33,6 +33,9 @@ type IngesterQueryClient interface {
 	MergeSpanProfile(ctx context.Context) clientpool.BidiClientMergeSpanProfile
 	BlockMetadata(ctx context.Context, req *connect.Request[ingestv1.BlockMetadataRequest]) (*connect.Response[ingestv1.BlockMetadataResponse], error)
 	GetProfileStats(ctx context.Context, req *connect.Request[typesv1.GetProfileStatsRequest]) (*connect.Response[typesv1.GetProfileStatsResponse], error)
+	GetCardinality(ctx context.Context, req *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error)
+	SelectExemplars(ctx context.Context, req *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error)
+	SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error)
 }
 
 // IngesterQuerier helps with querying the ingesters.
@@ -271,6 +274,23 @@ func (q *Querier) labelNamesFromIngesters(ctx context.Context, req *typesv1.Labe
 	return responses, nil
 }
 
+func (q *Querier) cardinalityFromIngesters(ctx context.Context, req *ingestv1.GetCardinalityRequest) ([]ResponseFromReplica[*ingestv1.GetCardinalityResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "GetCardinality Ingesters")
+	defer sp.Finish()
+
+	responses, err := forAllIngesters(ctx, q.ingesterQuerier, func(childCtx context.Context, ic IngesterQueryClient) (*ingestv1.GetCardinalityResponse, error) {
+		res, err := ic.GetCardinality(childCtx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return responses, nil
+}
+
 func (q *Querier) seriesFromIngesters(ctx context.Context, req *ingesterv1.SeriesRequest) ([]ResponseFromReplica[[]*typesv1.Labels], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "Series Ingesters")
 	defer sp.Finish()
@@ -337,6 +357,7 @@ func (q *Querier) selectSpanProfileFromIngesters(ctx context.Context, req *queri
 					End:           req.End,
 					Type:          profileType,
 					SpanSelector:  req.SpanSelector,
+					TraceSelector: req.TraceSelector,
 					Hints:         &ingestv1.Hints{Block: hints},
 				},
 				MaxNodes: req.MaxNodes,
@@ -370,3 +391,37 @@ func (q *Querier) blockSelectFromIngesters(ctx context.Context, req *ingestv1.Bl
 	}
 	return responses, nil
 }
+
+func (q *Querier) exemplarsFromIngesters(ctx context.Context, req *ingestv1.SelectExemplarsRequest) ([]ResponseFromReplica[*ingestv1.SelectExemplarsResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectExemplars Ingesters")
+	defer sp.Finish()
+
+	responses, err := forAllIngesters(ctx, q.ingesterQuerier, func(childCtx context.Context, ic IngesterQueryClient) (*ingestv1.SelectExemplarsResponse, error) {
+		res, err := ic.SelectExemplars(childCtx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return responses, nil
+}
+
+func (q *Querier) profileByIDFromIngesters(ctx context.Context, req *ingestv1.SelectProfileByIDRequest) ([]ResponseFromReplica[*ingestv1.SelectProfileByIDResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID Ingesters")
+	defer sp.Finish()
+
+	responses, err := forAllIngesters(ctx, q.ingesterQuerier, func(childCtx context.Context, ic IngesterQueryClient) (*ingestv1.SelectProfileByIDResponse, error) {
+		res, err := ic.SelectProfileByID(childCtx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return responses, nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/samber/lo"
 	"golang.org/x/sync/errgroup"
@@ -338,7 +339,9 @@ func (q *Querier) LabelNames(ctx context.Context, req *connect.Request[typesv1.L
 	}), nil
 }
 
-func (q *Querier) blockSelect(ctx context.Context, start, end model.Time) (blockPlan, error) {
+// blockSelect plans the blocks to query. Blocks that can't contain the
+// series selected by the matchers, if any, are excluded from the plan.
+func (q *Querier) blockSelect(ctx context.Context, start, end model.Time, matchers ...*labels.Matcher) (blockPlan, error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "blockSelect")
 	defer sp.Finish()
 
@@ -347,6 +350,40 @@ func (q *Querier) blockSelect(ctx context.Context, start, end model.Time) (block
 		otlog.String("end", end.Time().String()),
 	)
 
+	results, err := q.blockMetadata(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return results.blockPlan(ctx, matchers...), nil
+}
+
+// blockSelectMatchers returns the matchers of the series selected by the
+// query. Invalid selectors are rejected when the query is executed, and
+// no blocks are pruned in this case.
+func blockSelectMatchers(profileTypeID, labelSelector string) []*labels.Matcher {
+	matchers, err := parser.ParseMetricSelector(labelSelector)
+	if err != nil {
+		return nil
+	}
+	profileType, err := phlaremodel.ParseProfileTypeSelector(profileTypeID)
+	if err != nil {
+		return nil
+	}
+	return append(matchers, phlaremodel.SelectorFromProfileType(profileType))
+}
+
+// plannedBlockSelect returns the plan of the dry-run request the query has
+// been estimated with by the query-frontend, if any, or plans the query.
+func (q *Querier) plannedBlockSelect(ctx context.Context, plan *querierv1.QueryPlan, start, end model.Time, matchers ...*labels.Matcher) (blockPlan, error) {
+	if plan != nil {
+		return blockPlanFromProto(plan), nil
+	}
+	return q.blockSelect(ctx, start, end, matchers...)
+}
+
+// blockMetadata collects the metadata of the blocks overlapping the time
+// range from store gateways and ingesters.
+func (q *Querier) blockMetadata(ctx context.Context, start, end model.Time) (*replicasPerBlockID, error) {
 	ingesterReq := &ingestv1.BlockMetadataRequest{
 		Start: int64(start),
 		End:   int64(end),
@@ -372,7 +409,7 @@ func (q *Querier) blockSelect(ctx context.Context, start, end model.Time) (block
 		results.add(res, ingesterInstance)
 	}
 
-	return results.blockPlan(ctx), nil
+	return results, nil
 }
 
 func (q *Querier) Series(ctx context.Context, req *connect.Request[querierv1.SeriesRequest]) (*connect.Response[querierv1.SeriesResponse], error) {
@@ -515,6 +552,71 @@ func (q *Querier) Diff(ctx context.Context, req *connect.Request[querierv1.DiffR
 	}), nil
 }
 
+func (q *Querier) DiffByLabel(ctx context.Context, req *connect.Request[querierv1.DiffByLabelRequest]) (*connect.Response[querierv1.DiffByLabelResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "DiffByLabel")
+	defer func() {
+		sp.LogFields(
+			otlog.String("start", model.Time(req.Msg.Start).Time().String()),
+			otlog.String("end", model.Time(req.Msg.End).Time().String()),
+			otlog.String("selector", req.Msg.LabelSelector),
+			otlog.String("profile_id", req.Msg.ProfileTypeID),
+			otlog.String("label_name", req.Msg.LabelName),
+		)
+		sp.Finish()
+	}()
+
+	leftSelector, rightSelector, err := phlaremodel.DiffByLabelSelectors(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	maxNodes := req.Msg.GetMaxNodes()
+	if maxNodes == 0 {
+		maxNodes = maxNodesDefault
+	}
+	// The trees are not truncated before the diff: the functions below
+	// the cut would otherwise be merged into "other" on each side. The
+	// max nodes only apply to the flame graph diff.
+	noTruncation := int64(-1)
+
+	var leftTree, rightTree *phlaremodel.Tree
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		leftTree, err = q.selectTree(gCtx, &querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID: req.Msg.ProfileTypeID,
+			LabelSelector: leftSelector,
+			Start:         req.Msg.Start,
+			End:           req.Msg.End,
+			MaxNodes:      &noTruncation,
+		})
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		rightTree, err = q.selectTree(gCtx, &querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID: req.Msg.ProfileTypeID,
+			LabelSelector: rightSelector,
+			Start:         req.Msg.Start,
+			End:           req.Msg.End,
+			MaxNodes:      &noTruncation,
+		})
+		return err
+	})
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	fd, err := phlaremodel.NewFlamegraphDiff(leftTree, rightTree, maxNodes)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connect.NewResponse(&querierv1.DiffByLabelResponse{
+		Flamegraph: fd,
+		Functions:  phlaremodel.NewFunctionsDiff(leftTree, rightTree, req.Msg.GetMaxFunctions()),
+	}), nil
+}
+
 func (q *Querier) GetProfileStats(ctx context.Context, req *connect.Request[typesv1.GetProfileStatsRequest]) (*connect.Response[typesv1.GetProfileStatsResponse], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "GetProfileStats")
 	defer sp.Finish()
@@ -584,6 +686,14 @@ func (q *Querier) SelectMergeStacktraces(ctx context.Context, req *connect.Reque
 		sp.Finish()
 	}()
 
+	if req.Msg.DryRun {
+		estimate, plan, err := q.estimateQuery(ctx, model.Time(req.Msg.Start), model.Time(req.Msg.End), true, blockSelectMatchers(req.Msg.ProfileTypeID, req.Msg.LabelSelector)...)
+		if err != nil {
+			return nil, err
+		}
+		return connect.NewResponse(&querierv1.SelectMergeStacktracesResponse{Estimate: estimate, Plan: plan}), nil
+	}
+
 	if req.Msg.MaxNodes == nil || *req.Msg.MaxNodes == 0 {
 		mn := maxNodesDefault
 		req.Msg.MaxNodes = &mn
@@ -626,6 +736,49 @@ func (q *Querier) SelectMergeSpanProfile(ctx context.Context, req *connect.Reque
 	}), nil
 }
 
+func (q *Querier) SelectFunctions(ctx context.Context, req *connect.Request[querierv1.SelectFunctionsRequest]) (*connect.Response[querierv1.SelectFunctionsResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectFunctions")
+	level.Info(spanlogger.FromContext(ctx, q.logger)).Log(
+		"start", model.Time(req.Msg.Start).Time().String(),
+		"end", model.Time(req.Msg.End).Time().String(),
+		"selector", req.Msg.LabelSelector,
+		"profile_id", req.Msg.ProfileTypeID,
+		"with_locations", req.Msg.WithLocations,
+	)
+	defer func() {
+		sp.Finish()
+	}()
+
+	// The tree and the profile are not truncated: functions
+	// hidden by truncation would be missing from the table.
+	m := phlaremodel.NewFunctionsMerger()
+	if req.Msg.WithLocations {
+		p, err := q.selectProfile(ctx, &querierv1.SelectMergeProfileRequest{
+			ProfileTypeID: req.Msg.ProfileTypeID,
+			LabelSelector: req.Msg.LabelSelector,
+			Start:         req.Msg.Start,
+			End:           req.Msg.End,
+		})
+		if err != nil {
+			return nil, err
+		}
+		m.MergeProfile(p)
+	} else {
+		t, err := q.selectTree(ctx, &querierv1.SelectMergeStacktracesRequest{
+			ProfileTypeID: req.Msg.ProfileTypeID,
+			LabelSelector: req.Msg.LabelSelector,
+			Start:         req.Msg.Start,
+			End:           req.Msg.End,
+		})
+		if err != nil {
+			return nil, err
+		}
+		m.MergeTree(t)
+	}
+
+	return connect.NewResponse(m.SelectFunctionsResponse(req.Msg.OrderBy, req.Msg.GetMaxFunctions())), nil
+}
+
 func isEndpointNotExistingErr(err error) bool {
 	if err == nil {
 		return false
@@ -641,7 +794,7 @@ func isEndpointNotExistingErr(err error) bool {
 
 func (q *Querier) selectTree(ctx context.Context, req *querierv1.SelectMergeStacktracesRequest) (*phlaremodel.Tree, error) {
 	// determine the block hints
-	plan, err := q.blockSelect(ctx, model.Time(req.Start), model.Time(req.End))
+	plan, err := q.plannedBlockSelect(ctx, req.Plan, model.Time(req.Start), model.Time(req.End), blockSelectMatchers(req.ProfileTypeID, req.LabelSelector)...)
 	if isEndpointNotExistingErr(err) {
 		level.Warn(spanlogger.FromContext(ctx, q.logger)).Log(
 			"msg", "block select not supported on at least one component, fallback to use full dataset",
@@ -732,6 +885,7 @@ func (sq storeQuery) MergeSpanProfileRequest(req *querierv1.SelectMergeSpanProfi
 		ProfileTypeID: req.ProfileTypeID,
 		LabelSelector: req.LabelSelector,
 		SpanSelector:  req.SpanSelector,
+		TraceSelector: req.TraceSelector,
 		MaxNodes:      req.MaxNodes,
 	}
 }
@@ -756,6 +910,39 @@ func (sq storeQuery) SeriesRequest(req *querierv1.SeriesRequest) *ingestv1.Serie
 	}
 }
 
+func (sq storeQuery) GetCardinalityRequest(req *querierv1.GetCardinalityRequest, limit int64) *ingestv1.GetCardinalityRequest {
+	return &ingestv1.GetCardinalityRequest{
+		Matchers: req.Matchers,
+		Start:    int64(sq.start),
+		End:      int64(sq.end),
+		Limit:    limit,
+	}
+}
+
+func (sq storeQuery) SelectExemplarsRequest(req *querierv1.SelectExemplarsRequest, profileType *typesv1.ProfileType, limit int64) *ingestv1.SelectExemplarsRequest {
+	return &ingestv1.SelectExemplarsRequest{
+		Request: &ingestv1.SelectProfilesRequest{
+			Type:          profileType,
+			LabelSelector: req.LabelSelector,
+			Start:         int64(sq.start),
+			End:           int64(sq.end),
+		},
+		Limit: limit,
+	}
+}
+
+func (sq storeQuery) SelectProfileByIDRequest(req *querierv1.SelectProfileByIDRequest, profileType *typesv1.ProfileType) *ingestv1.SelectProfileByIDRequest {
+	return &ingestv1.SelectProfileByIDRequest{
+		Request: &ingestv1.SelectProfilesRequest{
+			Type:          profileType,
+			LabelSelector: req.LabelSelector,
+			Start:         int64(sq.start),
+			End:           int64(sq.end),
+		},
+		ProfileId: req.ProfileId,
+	}
+}
+
 func (sq storeQuery) LabelNamesRequest(req *typesv1.LabelNamesRequest) *typesv1.LabelNamesRequest {
 	return &typesv1.LabelNamesRequest{
 		Matchers: req.Matchers,
@@ -831,6 +1018,18 @@ func (q *Querier) SelectMergeProfile(ctx context.Context, req *connect.Request[q
 		SetTag("profile_type", req.Msg.ProfileTypeID)
 	defer sp.Finish()
 
+	if req.Msg.DryRun {
+		estimate, _, err := q.estimateQuery(ctx, model.Time(req.Msg.Start), model.Time(req.Msg.End), true, blockSelectMatchers(req.Msg.ProfileTypeID, req.Msg.LabelSelector)...)
+		if err != nil {
+			return nil, err
+		}
+		resp := connect.NewResponse(&googlev1.Profile{})
+		if err = phlaremodel.SetQueryEstimateHeader(resp, estimate); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
 	profile, err := q.selectProfile(ctx, req.Msg)
 	if err != nil {
 		return nil, err
@@ -842,7 +1041,7 @@ func (q *Querier) SelectMergeProfile(ctx context.Context, req *connect.Request[q
 
 func (q *Querier) selectProfile(ctx context.Context, req *querierv1.SelectMergeProfileRequest) (*googlev1.Profile, error) {
 	// determine the block hints
-	plan, err := q.blockSelect(ctx, model.Time(req.Start), model.Time(req.End))
+	plan, err := q.plannedBlockSelect(ctx, req.Plan, model.Time(req.Start), model.Time(req.End), blockSelectMatchers(req.ProfileTypeID, req.LabelSelector)...)
 	if isEndpointNotExistingErr(err) {
 		level.Warn(spanlogger.FromContext(ctx, q.logger)).Log(
 			"msg", "block select not supported on at least one component, fallback to use full dataset",
@@ -927,12 +1126,20 @@ func (q *Querier) SelectSeries(ctx context.Context, req *connect.Request[querier
 		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("step must be non-zero"))
 	}
 
+	if req.Msg.DryRun {
+		estimate, plan, err := q.estimateQuery(ctx, model.Time(req.Msg.Start), model.Time(req.Msg.End), false, blockSelectMatchers(req.Msg.ProfileTypeID, req.Msg.LabelSelector)...)
+		if err != nil {
+			return nil, err
+		}
+		return connect.NewResponse(&querierv1.SelectSeriesResponse{Estimate: estimate, Plan: plan}), nil
+	}
+
 	stepMs := time.Duration(req.Msg.Step * float64(time.Second)).Milliseconds()
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// determine the block hints
-	plan, err := q.blockSelect(ctx, model.Time(req.Msg.Start), model.Time(req.Msg.End))
+	plan, err := q.plannedBlockSelect(ctx, req.Msg.Plan, model.Time(req.Msg.Start), model.Time(req.Msg.End), blockSelectMatchers(req.Msg.ProfileTypeID, req.Msg.LabelSelector)...)
 	if isEndpointNotExistingErr(err) {
 		level.Warn(spanlogger.FromContext(ctx, q.logger)).Log(
 			"msg", "block select not supported on at least one component, fallback to use full dataset",
@@ -1110,7 +1317,7 @@ func uniqueSortedStrings(responses []ResponseFromReplica[[]string]) []string {
 
 func (q *Querier) selectSpanProfile(ctx context.Context, req *querierv1.SelectMergeSpanProfileRequest) (*phlaremodel.Tree, error) {
 	// determine the block hints
-	plan, err := q.blockSelect(ctx, model.Time(req.Start), model.Time(req.End))
+	plan, err := q.blockSelect(ctx, model.Time(req.Start), model.Time(req.End), blockSelectMatchers(req.ProfileTypeID, req.LabelSelector)...)
 	if isEndpointNotExistingErr(err) {
 		level.Warn(spanlogger.FromContext(ctx, q.logger)).Log(
 			"msg", "block select not supported on at least one component, fallback to use full dataset",
@@ -0,0 +1,126 @@
+package querier
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+// estimateQuery estimates the cost of the query from the statistics of the
+// blocks the query would read. Downsampled profile tables are only used by
+// the queries merging profiles: time series are built from the original ones.
+// Blocks that can't contain the series selected by the matchers are ignored.
+// The plan of the query is returned along with the estimate, so that the
+// query can be executed without planning it again.
+func (q *Querier) estimateQuery(ctx context.Context, start, end model.Time, downsampled bool, matchers ...*labels.Matcher) (*querierv1.QueryEstimate, *querierv1.QueryPlan, error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "estimateQuery")
+	defer sp.Finish()
+
+	results, err := q.blockMetadata(ctx, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	var blocks []*typesv1.BlockInfo
+	plan := blockPlan(results.blockPlan(ctx, matchers...))
+	if plan != nil {
+		for _, hints := range plan {
+			for _, id := range hints.Ulids {
+				if b, ok := results.meta[id]; ok {
+					blocks = append(blocks, b)
+				}
+			}
+		}
+	} else {
+		// The query reads all the blocks if planning fails.
+		for _, b := range results.meta {
+			blocks = append(blocks, b)
+		}
+	}
+	return estimateBlocks(blocks, start, end, downsampled), plan.toProto(), nil
+}
+
+// estimateBlocks sums the rows and bytes to scan in the profile tables of
+// the blocks. The profiles are assumed to be distributed evenly over the
+// block time range; blocks without statistics are only counted.
+func estimateBlocks(blocks []*typesv1.BlockInfo, start, end model.Time, downsampled bool) *querierv1.QueryEstimate {
+	var (
+		estimate    querierv1.QueryEstimate
+		resolutions = make(map[time.Duration]struct{})
+	)
+	for _, b := range blocks {
+		minT, maxT := model.Time(b.MinTime), model.Time(b.MaxTime)
+		if minT < start {
+			minT = start
+		}
+		if maxT > end {
+			maxT = end
+		}
+		if maxT < minT {
+			continue
+		}
+		estimate.Blocks++
+
+		var (
+			original *typesv1.ProfileTableStats
+			tables   = make(map[time.Duration]*typesv1.ProfileTableStats)
+		)
+		for _, t := range b.GetStats().GetProfileTables() {
+			switch {
+			case t.Resolution == 0:
+				original = t
+			// Profiles are merged with the sum aggregation, therefore only
+			// the downsampled tables of the aggregation can be used.
+			case downsampled && t.Aggregation == block.DownsampleAggregationSum:
+				tables[time.Duration(t.Resolution)*time.Millisecond] = t
+			}
+		}
+
+		blockRange := float64(b.MaxTime - b.MinTime + 1)
+		scan := func(t *typesv1.ProfileTableStats, start, end time.Time) {
+			if t == nil {
+				return
+			}
+			ratio := float64(end.Sub(start).Milliseconds()+1) / blockRange
+			if ratio > 1 {
+				ratio = 1
+			}
+			estimate.Rows += uint64(math.Round(float64(t.NumRows) * ratio))
+			estimate.Bytes += uint64(math.Round(float64(t.SizeBytes) * ratio))
+		}
+		if len(tables) == 0 {
+			scan(original, minT.Time(), maxT.Time())
+			continue
+		}
+		available := make([]time.Duration, 0, len(tables))
+		for r := range tables {
+			available = append(available, r)
+		}
+		util.SplitTimeRangeByResolution(minT.Time(), maxT.Time(), available, func(tr util.TimeRange) {
+			if t, ok := tables[tr.Resolution]; ok {
+				resolutions[tr.Resolution] = struct{}{}
+				scan(t, tr.Start, tr.End)
+				return
+			}
+			scan(original, tr.Start, tr.End)
+		})
+	}
+
+	estimate.DownsampledResolutions = make([]int64, 0, len(resolutions))
+	for r := range resolutions {
+		estimate.DownsampledResolutions = append(estimate.DownsampledResolutions, r.Milliseconds())
+	}
+	sort.Slice(estimate.DownsampledResolutions, func(i, j int) bool {
+		return estimate.DownsampledResolutions[i] < estimate.DownsampledResolutions[j]
+	})
+	return &estimate
+}
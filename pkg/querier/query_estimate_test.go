@@ -0,0 +1,98 @@
+package querier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+func Test_estimateBlocks(t *testing.T) {
+	hour := time.Hour.Milliseconds()
+	blocks := []*typesv1.BlockInfo{
+		{
+			Ulid:    "downsampled",
+			MinTime: 0,
+			MaxTime: hour - 1,
+			Stats: &typesv1.BlockStats{
+				ProfileTables: []*typesv1.ProfileTableStats{
+					{NumRows: 3600, SizeBytes: 3600000},
+					{Resolution: (5 * time.Minute).Milliseconds(), Aggregation: "sum", NumRows: 120, SizeBytes: 120000},
+				},
+			},
+		},
+		{
+			// Blocks of components not reporting statistics.
+			Ulid:    "no-stats",
+			MinTime: 0,
+			MaxTime: hour - 1,
+		},
+		{
+			Ulid:    "out-of-range",
+			MinTime: 2 * hour,
+			MaxTime: 3*hour - 1,
+			Stats: &typesv1.BlockStats{
+				ProfileTables: []*typesv1.ProfileTableStats{{NumRows: 3600, SizeBytes: 3600000}},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		start, end  int64
+		downsampled bool
+		expected    *querierv1.QueryEstimate
+	}{
+		{
+			name:  "original resolution",
+			start: 0, end: hour/2 - 1,
+			expected: &querierv1.QueryEstimate{
+				Blocks:                 2,
+				Rows:                   1800,
+				Bytes:                  1800000,
+				DownsampledResolutions: []int64{},
+			},
+		},
+		{
+			name:  "downsampled tables ignored",
+			start: 0, end: hour - 1,
+			expected: &querierv1.QueryEstimate{
+				Blocks:                 2,
+				Rows:                   3600,
+				Bytes:                  3600000,
+				DownsampledResolutions: []int64{},
+			},
+		},
+		{
+			name:  "downsampled",
+			start: 0, end: hour - 1,
+			downsampled: true,
+			expected: &querierv1.QueryEstimate{
+				Blocks:                 2,
+				Rows:                   120,
+				Bytes:                  120000,
+				DownsampledResolutions: []int64{(5 * time.Minute).Milliseconds()},
+			},
+		},
+		{
+			name:  "partially downsampled",
+			start: time.Minute.Milliseconds(), end: hour - 1,
+			downsampled: true,
+			expected: &querierv1.QueryEstimate{
+				Blocks:                 2,
+				Rows:                   240 + 110,
+				Bytes:                  240000 + 110000,
+				DownsampledResolutions: []int64{(5 * time.Minute).Milliseconds()},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := estimateBlocks(blocks, model.Time(tc.start), model.Time(tc.end), tc.downsampled)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -12,11 +12,14 @@ import (
 	"github.com/grafana/dskit/ring"
 	"github.com/opentracing/opentracing-go"
 	otlog "github.com/opentracing/opentracing-go/log"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/samber/lo"
 	"golang.org/x/sync/errgroup"
 
 	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
 	"github.com/grafana/pyroscope/pkg/phlaredb/sharding"
 	"github.com/grafana/pyroscope/pkg/util"
 	"github.com/grafana/pyroscope/pkg/util/spanlogger"
@@ -320,6 +323,26 @@ func (r *replicasPerBlockID) pruneSupersededBlocks(sharded bool) error {
 	return nil
 }
 
+// pruneByLabelSummary removes the blocks that can't contain series
+// selected by the matchers. Blocks without label summary are kept.
+func (r *replicasPerBlockID) pruneByLabelSummary(matchers []*labels.Matcher) int {
+	if len(matchers) == 0 {
+		return 0
+	}
+	var pruned int
+	for blockID := range r.m {
+		meta, ok := r.meta[blockID]
+		if !ok {
+			continue
+		}
+		if s := block.LabelSummaryFromBlockInfo(meta); s != nil && !s.Matches(matchers...) {
+			r.removeBlock(blockID)
+			pruned++
+		}
+	}
+	return pruned
+}
+
 type blockPlan map[string]*ingestv1.BlockHints
 
 func (p blockPlan) String() string {
@@ -327,7 +350,36 @@ func (p blockPlan) String() string {
 	return string(data)
 }
 
-func (r *replicasPerBlockID) blockPlan(ctx context.Context) map[string]*ingestv1.BlockHints {
+// toProto returns the plan as passed back by the query-frontend.
+// A nil plan, meaning all the blocks are queried, is returned as nil.
+func (p blockPlan) toProto() *querierv1.QueryPlan {
+	if p == nil {
+		return nil
+	}
+	r := &querierv1.QueryPlan{Replicas: make([]*querierv1.QueryPlanReplica, 0, len(p))}
+	for addr, hints := range p {
+		r.Replicas = append(r.Replicas, &querierv1.QueryPlanReplica{
+			Addr:          addr,
+			Ulids:         hints.Ulids,
+			Deduplication: hints.Deduplication,
+		})
+	}
+	sort.Slice(r.Replicas, func(i, j int) bool { return r.Replicas[i].Addr < r.Replicas[j].Addr })
+	return r
+}
+
+func blockPlanFromProto(p *querierv1.QueryPlan) blockPlan {
+	r := make(blockPlan, len(p.Replicas))
+	for _, x := range p.Replicas {
+		r[x.Addr] = &ingestv1.BlockHints{Ulids: x.Ulids, Deduplication: x.Deduplication}
+	}
+	return r
+}
+
+// blockPlan selects the blocks to query and the replicas to query them from.
+// If matchers are provided, blocks that can't contain the selected series
+// are excluded from the plan.
+func (r *replicasPerBlockID) blockPlan(ctx context.Context, matchers ...*labels.Matcher) map[string]*ingestv1.BlockHints {
 	sp, _ := opentracing.StartSpanFromContext(ctx, "blockPlan")
 	defer sp.Finish()
 
@@ -357,6 +409,11 @@ func (r *replicasPerBlockID) blockPlan(ctx context.Context) map[string]*ingestv1
 		return nil
 	}
 
+	// Blocks are pruned by labels only after the compaction based pruning:
+	// otherwise, a sharded compaction group missing the shards that do not
+	// contain the series would be considered incomplete.
+	prunedByLabels := r.pruneByLabelSummary(matchers)
+
 	// now we go through all blocks and choose the replicas that we want to query
 	for blockID, replicas := range r.m {
 		// skip if we have no replicas, then block is already contained i an higher compaction level one
@@ -441,6 +498,7 @@ func (r *replicasPerBlockID) blockPlan(ctx context.Context) map[string]*ingestv1
 		otlog.Int32("smallest_compaction_level", smallestCompactionLevel),
 		otlog.Int("planned_blocks_ingesters", plannedIngesterBlocks),
 		otlog.Int("planned_blocks_store_gateways", plannedStoreGatwayBlocks),
+		otlog.Int("pruned_blocks_by_labels", prunedByLabels),
 	)
 
 	level.Debug(spanlogger.FromContext(ctx, r.logger)).Log(
@@ -449,6 +507,7 @@ func (r *replicasPerBlockID) blockPlan(ctx context.Context) map[string]*ingestv1
 		"smallest_compaction_level", smallestCompactionLevel,
 		"planned_blocks_ingesters", plannedIngesterBlocks,
 		"planned_blocks_store_gateways", plannedStoreGatwayBlocks,
+		"pruned_blocks_by_labels", prunedByLabels,
 		"plan", blockPlan(plan),
 	)
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/require"
 
 	ingestv1 "github.com/grafana/pyroscope/api/gen/proto/go/ingester/v1"
@@ -67,6 +68,14 @@ func (b *blockInfo) withCompactorShard(shard, shardsCount uint64) *blockInfo {
 	)
 }
 
+func (b *blockInfo) withLabelSummary(k string, v ...string) *blockInfo {
+	b.i.LabelSummary = append(b.i.LabelSummary, &typesv1.LabelSummary{
+		Name:   k,
+		Values: v,
+	})
+	return b
+}
+
 func (b *blockInfo) info() *typesv1.BlockInfo {
 	return &b.i
 }
@@ -98,6 +107,7 @@ func Test_replicasPerBlockID_blockPlan(t *testing.T) {
 	for _, tc := range []struct {
 		name       string
 		inputs     func(r *replicasPerBlockID)
+		matchers   []*labels.Matcher
 		validators []validatorFunc
 	}{
 		{
@@ -272,15 +282,95 @@ func Test_replicasPerBlockID_blockPlan(t *testing.T) {
 				validatePlanBlocksOnReplica("ingester-0", "b"),
 			},
 		},
+		{
+			name: "ignore blocks which can't contain the selected series",
+			inputs: func(r *replicasPerBlockID) {
+				r.add([]ResponseFromReplica[[]*typesv1.BlockInfo]{
+					{
+						addr: "ingester-0",
+						response: []*typesv1.BlockInfo{
+							newBlockInfo("a").info(),
+						},
+					},
+				}, ingesterInstance)
+				r.add([]ResponseFromReplica[[]*typesv1.BlockInfo]{
+					{
+						addr: "store-gateway-0",
+						response: []*typesv1.BlockInfo{
+							newBlockInfo("b").withLabelSummary("service_name", "svc-1", "svc-2").info(),
+							newBlockInfo("c").withLabelSummary("service_name", "svc-3").info(),
+							newBlockInfo("d").withLabelSummary("service_name", "svc-1").
+								withLabelSummary("__profile_type__", "memory").info(),
+						},
+					},
+				}, storeGatewayInstance)
+			},
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "service_name", "svc-1"),
+				labels.MustNewMatcher(labels.MatchEqual, "__profile_type__", "cpu"),
+			},
+			validators: []validatorFunc{
+				validatePlanBlockIDs("a", "b"),
+				validatePlanBlocksOnReplica("store-gateway-0", "b"),
+				validatePlanBlocksOnReplica("ingester-0", "a"),
+			},
+		},
+		{
+			name: "prune sharded blocks once the shards are known to be complete",
+			inputs: func(r *replicasPerBlockID) {
+				r.add([]ResponseFromReplica[[]*typesv1.BlockInfo]{
+					{
+						addr: "store-gateway-0",
+						response: []*typesv1.BlockInfo{
+							newBlockInfo("a").withLabelSummary("service_name", "svc-1", "svc-2").info(),
+							newBlockInfo("a-1").
+								withCompactionLevel(3).
+								withCompactionSources("a").
+								withCompactorShard(0, 2).
+								withLabelSummary("service_name", "svc-1").
+								info(),
+							newBlockInfo("a-2").
+								withCompactionLevel(3).
+								withCompactionSources("a").
+								withCompactorShard(1, 2).
+								withLabelSummary("service_name", "svc-2").
+								info(),
+						},
+					},
+				}, storeGatewayInstance)
+			},
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "service_name", "svc-1"),
+			},
+			validators: []validatorFunc{
+				validatePlanBlockIDs("a-1"),
+			},
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			r := newReplicasPerBlockID(log.NewNopLogger())
 			tc.inputs(r)
 
-			plan := r.blockPlan(context.TODO())
+			plan := r.blockPlan(context.TODO(), tc.matchers...)
 			for _, v := range tc.validators {
 				v(t, plan)
 			}
 		})
 	}
 }
+
+func Test_blockPlan_proto(t *testing.T) {
+	require.Nil(t, blockPlan(nil).toProto())
+
+	plan := blockPlan{
+		"store-gateway-b": &ingestv1.BlockHints{Ulids: []string{"block-c"}},
+		"store-gateway-a": &ingestv1.BlockHints{Ulids: []string{"block-a", "block-b"}, Deduplication: true},
+	}
+	p := plan.toProto()
+	require.Len(t, p.Replicas, 2)
+	require.Equal(t, "store-gateway-a", p.Replicas[0].Addr)
+	require.Equal(t, plan, blockPlanFromProto(p))
+
+	// An empty plan means no blocks are to be queried, not all of them.
+	require.Equal(t, blockPlan{}, blockPlanFromProto(blockPlan{}.toProto()))
+}
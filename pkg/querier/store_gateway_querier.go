@@ -39,6 +39,9 @@ type StoreGatewayQueryClient interface {
 	LabelNames(context.Context, *connect.Request[typesv1.LabelNamesRequest]) (*connect.Response[typesv1.LabelNamesResponse], error)
 	Series(context.Context, *connect.Request[ingestv1.SeriesRequest]) (*connect.Response[ingestv1.SeriesResponse], error)
 	BlockMetadata(ctx context.Context, req *connect.Request[ingestv1.BlockMetadataRequest]) (*connect.Response[ingestv1.BlockMetadataResponse], error)
+	GetCardinality(ctx context.Context, req *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error)
+	SelectExemplars(ctx context.Context, req *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error)
+	SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error)
 }
 
 type StoreGatewayLimits interface {
@@ -380,6 +383,28 @@ func (q *Querier) labelNamesFromStoreGateway(ctx context.Context, req *typesv1.L
 	return responses, nil
 }
 
+func (q *Querier) cardinalityFromStoreGateway(ctx context.Context, req *ingestv1.GetCardinalityRequest) ([]ResponseFromReplica[*ingestv1.GetCardinalityResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "GetCardinality StoreGateway")
+	defer sp.Finish()
+
+	tenantID, err := tenant.ExtractTenantIDFromContext(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	responses, err := forAllStoreGateways(ctx, tenantID, q.storeGatewayQuerier, func(ctx context.Context, ic StoreGatewayQueryClient) (*ingestv1.GetCardinalityResponse, error) {
+		res, err := ic.GetCardinality(ctx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return responses, nil
+}
+
 func (q *Querier) seriesFromStoreGateway(ctx context.Context, req *ingestv1.SeriesRequest) ([]ResponseFromReplica[[]*typesv1.Labels], error) {
 	sp, ctx := opentracing.StartSpanFromContext(ctx, "Series StoreGateway")
 	defer sp.Finish()
@@ -449,6 +474,7 @@ func (q *Querier) selectSpanProfileFromStoreGateway(ctx context.Context, req *qu
 					End:           req.End,
 					Type:          profileType,
 					SpanSelector:  req.SpanSelector,
+					TraceSelector: req.TraceSelector,
 					Hints:         &ingestv1.Hints{Block: hints},
 				},
 				MaxNodes: req.MaxNodes,
@@ -484,3 +510,47 @@ func (q *Querier) blockSelectFromStoreGateway(ctx context.Context, req *ingestv1
 	}
 	return responses, nil
 }
+
+func (q *Querier) exemplarsFromStoreGateway(ctx context.Context, req *ingestv1.SelectExemplarsRequest) ([]ResponseFromReplica[*ingestv1.SelectExemplarsResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectExemplars StoreGateway")
+	defer sp.Finish()
+
+	tenantID, err := tenant.ExtractTenantIDFromContext(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	responses, err := forAllStoreGateways(ctx, tenantID, q.storeGatewayQuerier, func(childCtx context.Context, ic StoreGatewayQueryClient) (*ingestv1.SelectExemplarsResponse, error) {
+		res, err := ic.SelectExemplars(childCtx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return responses, nil
+}
+
+func (q *Querier) profileByIDFromStoreGateway(ctx context.Context, req *ingestv1.SelectProfileByIDRequest) ([]ResponseFromReplica[*ingestv1.SelectProfileByIDResponse], error) {
+	sp, ctx := opentracing.StartSpanFromContext(ctx, "SelectProfileByID StoreGateway")
+	defer sp.Finish()
+
+	tenantID, err := tenant.ExtractTenantIDFromContext(ctx)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	responses, err := forAllStoreGateways(ctx, tenantID, q.storeGatewayQuerier, func(childCtx context.Context, ic StoreGatewayQueryClient) (*ingestv1.SelectProfileByIDResponse, error) {
+		res, err := ic.SelectProfileByID(childCtx, connect.NewRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		return res.Msg, nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return responses, nil
+}
@@ -0,0 +1,274 @@
+package ruler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/prometheus/prometheus/model/labels"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+// SeriesQuerier is the part of the querier API the rules are evaluated with.
+type SeriesQuerier interface {
+	SelectSeries(context.Context, *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error)
+}
+
+type AlertState int
+
+const (
+	StateInactive AlertState = iota
+	StatePending
+	StateFiring
+)
+
+func (s AlertState) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateFiring:
+		return "firing"
+	default:
+		return "inactive"
+	}
+}
+
+// Alert is an active (pending or firing) alert of a rule.
+type Alert struct {
+	Labels      labels.Labels
+	Annotations labels.Labels
+	State       AlertState
+	ActiveAt    time.Time
+	Value       float64
+}
+
+// AlertingRule holds the state of the alerts created by the rule.
+type AlertingRule struct {
+	rule Rule
+
+	mu             sync.Mutex
+	active         map[uint64]*Alert
+	lastError      error
+	lastEvaluation time.Time
+	evaluationTime time.Duration
+}
+
+func NewAlertingRule(rule Rule) *AlertingRule {
+	return &AlertingRule{
+		rule:   rule,
+		active: make(map[uint64]*Alert),
+	}
+}
+
+func (r *AlertingRule) Rule() Rule { return r.rule }
+
+// Eval evaluates the rule at ts and updates the state of its alerts.
+// Alerts of series that no longer match the condition are resolved
+// and removed.
+func (r *AlertingRule) Eval(ctx context.Context, q SeriesQuerier, ts time.Time) error {
+	start := time.Now()
+	values, err := r.rule.selectValues(ctx, q, ts)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastEvaluation = ts
+	r.evaluationTime = time.Since(start)
+	r.lastError = err
+	if err != nil {
+		return err
+	}
+
+	matches := operators[r.rule.Op]
+	resolved := make(map[uint64]struct{}, len(r.active))
+	for h := range r.active {
+		resolved[h] = struct{}{}
+	}
+	for _, v := range values {
+		if !matches(v.value, r.rule.Threshold) {
+			continue
+		}
+		lbls := r.alertLabels(v.labels)
+		h := lbls.Hash()
+		delete(resolved, h)
+		a, ok := r.active[h]
+		if !ok {
+			a = &Alert{
+				Labels:   lbls,
+				State:    StatePending,
+				ActiveAt: ts,
+			}
+			r.active[h] = a
+		}
+		a.Value = v.value
+		a.Annotations = r.expandAnnotations(lbls, v.value)
+		if a.State == StatePending && ts.Sub(a.ActiveAt) >= time.Duration(r.rule.For) {
+			a.State = StateFiring
+		}
+	}
+	for h := range resolved {
+		delete(r.active, h)
+	}
+	return nil
+}
+
+func (r *AlertingRule) alertLabels(series labels.Labels) labels.Labels {
+	b := labels.NewBuilder(series)
+	for name, value := range r.rule.Labels {
+		b.Set(name, value)
+	}
+	b.Set(labels.AlertName, r.rule.Alert)
+	return b.Labels()
+}
+
+// expandAnnotations expands annotation templates. Similarly to Prometheus,
+// $labels and $value variables refer to the alert labels and value.
+func (r *AlertingRule) expandAnnotations(lbls labels.Labels, value float64) labels.Labels {
+	if len(r.rule.Annotations) == 0 {
+		return labels.EmptyLabels()
+	}
+	data := struct {
+		Labels map[string]string
+		Value  float64
+	}{
+		Labels: lbls.Map(),
+		Value:  value,
+	}
+	const preamble = "{{$labels := .Labels}}{{$value := .Value}}"
+	b := labels.NewScratchBuilder(len(r.rule.Annotations))
+	var buf bytes.Buffer
+	for name, text := range r.rule.Annotations {
+		buf.Reset()
+		t, err := template.New(name).Option("missingkey=zero").Parse(preamble + text)
+		if err == nil {
+			err = t.Execute(&buf, data)
+		}
+		if err != nil {
+			b.Add(name, fmt.Sprintf("<error expanding template: %v>", err))
+			continue
+		}
+		b.Add(name, buf.String())
+	}
+	b.Sort()
+	return b.Labels()
+}
+
+// State returns the state of the rule: the highest state of its alerts.
+func (r *AlertingRule) State() AlertState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := StateInactive
+	for _, a := range r.active {
+		if a.State > s {
+			s = a.State
+		}
+	}
+	return s
+}
+
+// ActiveAlerts returns a copy of the active alerts, ordered by labels.
+func (r *AlertingRule) ActiveAlerts() []Alert {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	alerts := make([]Alert, 0, len(r.active))
+	for _, a := range r.active {
+		alerts = append(alerts, *a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return labels.Compare(alerts[i].Labels, alerts[j].Labels) < 0
+	})
+	return alerts
+}
+
+// LastEvaluation returns the time of the last evaluation,
+// its duration, and the error, if the evaluation failed.
+func (r *AlertingRule) LastEvaluation() (time.Time, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastEvaluation, r.evaluationTime, r.lastError
+}
+
+type seriesValue struct {
+	labels labels.Labels
+	value  float64
+}
+
+func (r *Rule) selectValues(ctx context.Context, q SeriesQuerier, ts time.Time) ([]seriesValue, error) {
+	aggregation := typesv1.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_SUM
+	req := &querierv1.SelectSeriesRequest{
+		ProfileTypeID: r.ProfileType,
+		LabelSelector: r.Selector,
+		Start:         ts.Add(-time.Duration(r.Range)).UnixMilli(),
+		End:           ts.UnixMilli(),
+		GroupBy:       r.GroupBy,
+		// A single point per series is expected, but the range
+		// may span two steps depending on the alignment.
+		Step:        time.Duration(r.Range).Seconds(),
+		Aggregation: &aggregation,
+	}
+	if len(r.CallSite) > 0 {
+		callSite := make([]*typesv1.Location, len(r.CallSite))
+		for i, name := range r.CallSite {
+			callSite[i] = &typesv1.Location{Name: name}
+		}
+		req.StackTraceSelector = &typesv1.StackTraceSelector{CallSite: callSite}
+	}
+	selected, err := selectSeries(ctx, q, req)
+	if err != nil {
+		return nil, err
+	}
+	if r.Value != ValueShare {
+		return selected, nil
+	}
+
+	totalReq := req.CloneVT()
+	totalReq.StackTraceSelector = nil
+	total, err := selectSeries(ctx, q, totalReq)
+	if err != nil {
+		return nil, err
+	}
+	selectedByLabels := make(map[uint64]float64, len(selected))
+	for _, s := range selected {
+		selectedByLabels[s.labels.Hash()] = s.value
+	}
+	values := make([]seriesValue, 0, len(total))
+	for _, s := range total {
+		if s.value == 0 {
+			continue
+		}
+		// Series that have no stack traces matching
+		// the call site have zero share.
+		values = append(values, seriesValue{
+			labels: s.labels,
+			value:  selectedByLabels[s.labels.Hash()] / s.value,
+		})
+	}
+	return values, nil
+}
+
+func selectSeries(ctx context.Context, q SeriesQuerier, req *querierv1.SelectSeriesRequest) ([]seriesValue, error) {
+	resp, err := q.SelectSeries(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	values := make([]seriesValue, 0, len(resp.Msg.Series))
+	for _, s := range resp.Msg.Series {
+		b := labels.NewScratchBuilder(len(s.Labels))
+		for _, l := range s.Labels {
+			b.Add(l.Name, l.Value)
+		}
+		b.Sort()
+		v := seriesValue{labels: b.Labels()}
+		for _, p := range s.Points {
+			v.value += p.Value
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,114 @@
+package ruler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+)
+
+// mockQuerier returns series of total values and values of
+// stack traces selected with a stack trace selector, by pod.
+type mockQuerier struct {
+	total    map[string]float64
+	selected map[string]float64
+	requests []*querierv1.SelectSeriesRequest
+}
+
+func (m *mockQuerier) SelectSeries(_ context.Context, req *connect.Request[querierv1.SelectSeriesRequest]) (*connect.Response[querierv1.SelectSeriesResponse], error) {
+	m.requests = append(m.requests, req.Msg)
+	values := m.total
+	if req.Msg.StackTraceSelector != nil {
+		values = m.selected
+	}
+	var resp querierv1.SelectSeriesResponse
+	for pod, v := range values {
+		resp.Series = append(resp.Series, &typesv1.Series{
+			Labels: []*typesv1.LabelPair{{Name: "pod", Value: pod}},
+			// The value is split into two points.
+			Points: []*typesv1.Point{{Value: v / 2}, {Value: v / 2}},
+		})
+	}
+	return connect.NewResponse(&resp), nil
+}
+
+func Test_AlertingRule_Eval(t *testing.T) {
+	q := &mockQuerier{
+		total:    map[string]float64{"a": 100, "b": 100},
+		selected: map[string]float64{"a": 30, "b": 10},
+	}
+	r := NewAlertingRule(Rule{
+		Alert:       "MallocHighShare",
+		ProfileType: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		Selector:    `{service_name="my-service"}`,
+		CallSite:    []string{"main", "runtime.mallocgc"},
+		GroupBy:     []string{"pod"},
+		Value:       ValueShare,
+		Range:       model.Duration(10 * time.Minute),
+		Op:          ">",
+		Threshold:   0.2,
+		For:         model.Duration(2 * time.Minute),
+		Labels:      map[string]string{"severity": "warning"},
+		Annotations: map[string]string{"summary": "{{ $labels.pod }}: {{ $value }}"},
+	})
+	ctx := context.Background()
+	ts := time.Unix(1000, 0)
+
+	require.NoError(t, r.Eval(ctx, q, ts))
+	require.Len(t, q.requests, 2)
+	assert.Equal(t, ts.Add(-10*time.Minute).UnixMilli(), q.requests[0].Start)
+	assert.Equal(t, ts.UnixMilli(), q.requests[0].End)
+	assert.Equal(t, float64(600), q.requests[0].Step)
+	assert.Equal(t, "runtime.mallocgc", q.requests[0].StackTraceSelector.CallSite[1].Name)
+
+	alerts := r.ActiveAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, labels.FromStrings("alertname", "MallocHighShare", "pod", "a", "severity", "warning"), alerts[0].Labels)
+	assert.Equal(t, labels.FromStrings("summary", "a: 0.3"), alerts[0].Annotations)
+	assert.Equal(t, StatePending, alerts[0].State)
+	assert.Equal(t, 0.3, alerts[0].Value)
+	assert.Equal(t, StatePending, r.State())
+
+	// The condition holds for the duration specified.
+	require.NoError(t, r.Eval(ctx, q, ts.Add(2*time.Minute)))
+	alerts = r.ActiveAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	assert.Equal(t, ts, alerts[0].ActiveAt)
+
+	// The alert is resolved.
+	q.selected["a"] = 10
+	require.NoError(t, r.Eval(ctx, q, ts.Add(3*time.Minute)))
+	assert.Empty(t, r.ActiveAlerts())
+	assert.Equal(t, StateInactive, r.State())
+}
+
+func Test_AlertingRule_Eval_Total(t *testing.T) {
+	q := &mockQuerier{total: map[string]float64{"a": 100, "b": 10}}
+	r := NewAlertingRule(Rule{
+		Alert:       "LowCPU",
+		ProfileType: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		Selector:    `{}`,
+		GroupBy:     []string{"pod"},
+		Value:       ValueTotal,
+		Range:       model.Duration(time.Minute),
+		Op:          "<",
+		Threshold:   50,
+	})
+	require.NoError(t, r.Eval(context.Background(), q, time.Now()))
+	require.Len(t, q.requests, 1)
+	assert.Nil(t, q.requests[0].StackTraceSelector)
+	alerts := r.ActiveAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "b", alerts[0].Labels.Get("pod"))
+	// No "for" duration: the alert fires immediately.
+	assert.Equal(t, StateFiring, alerts[0].State)
+}
@@ -0,0 +1,246 @@
+package ruler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/tenant"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/model/labels"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+// The types below follow the Prometheus HTTP API, so that the endpoints
+// can be consumed by the existing tooling (e.g., Grafana).
+
+type response struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+type alertsData struct {
+	Alerts []*alertJSON `json:"alerts"`
+}
+
+type alertJSON struct {
+	Labels      labels.Labels `json:"labels"`
+	Annotations labels.Labels `json:"annotations"`
+	State       string        `json:"state"`
+	ActiveAt    *time.Time    `json:"activeAt,omitempty"`
+	Value       string        `json:"value"`
+}
+
+type rulesData struct {
+	Groups []*ruleGroupJSON `json:"groups"`
+}
+
+type ruleGroupJSON struct {
+	Name           string        `json:"name"`
+	File           string        `json:"file"`
+	Rules          []interface{} `json:"rules"`
+	Interval       float64       `json:"interval"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	EvaluationTime float64       `json:"evaluationTime"`
+}
+
+type alertingRuleJSON struct {
+	State          string        `json:"state"`
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Duration       float64       `json:"duration"`
+	Labels         labels.Labels `json:"labels"`
+	Annotations    labels.Labels `json:"annotations"`
+	Alerts         []*alertJSON  `json:"alerts"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError,omitempty"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	Type           string        `json:"type"`
+}
+
+type recordingRuleJSON struct {
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Labels         labels.Labels `json:"labels,omitempty"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError,omitempty"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	Type           string        `json:"type"`
+}
+
+func alertsToJSON(alerts []Alert) []*alertJSON {
+	res := make([]*alertJSON, 0, len(alerts))
+	for i := range alerts {
+		a := &alerts[i]
+		res = append(res, &alertJSON{
+			Labels:      a.Labels,
+			Annotations: a.Annotations,
+			State:       a.State.String(),
+			ActiveAt:    &a.ActiveAt,
+			Value:       strconv.FormatFloat(a.Value, 'e', -1, 64),
+		})
+	}
+	return res
+}
+
+func ruleToJSON(r RuleEvaluator) interface{} {
+	rule := r.Rule()
+	lastEvaluation, evaluationTime, err := r.LastEvaluation()
+	health, lastError := "ok", ""
+	switch {
+	case err != nil:
+		health, lastError = "err", err.Error()
+	case lastEvaluation.IsZero():
+		health = "unknown"
+	}
+	switch r := r.(type) {
+	case *AlertingRule:
+		return &alertingRuleJSON{
+			State:          r.State().String(),
+			Name:           rule.Alert,
+			Query:          rule.Query(),
+			Duration:       time.Duration(rule.For).Seconds(),
+			Labels:         labels.FromMap(rule.Labels),
+			Annotations:    labels.FromMap(rule.Annotations),
+			Alerts:         alertsToJSON(r.ActiveAlerts()),
+			Health:         health,
+			LastError:      lastError,
+			LastEvaluation: lastEvaluation,
+			EvaluationTime: evaluationTime.Seconds(),
+			Type:           "alerting",
+		}
+	default:
+		return &recordingRuleJSON{
+			Name:           rule.Record,
+			Query:          rule.Query(),
+			Labels:         labels.FromMap(rule.Labels),
+			Health:         health,
+			LastError:      lastError,
+			LastEvaluation: lastEvaluation,
+			EvaluationTime: evaluationTime.Seconds(),
+			Type:           "recording",
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, errorType string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response{
+		Status:    "error",
+		ErrorType: errorType,
+		Error:     err.Error(),
+	})
+}
+
+// AlertsHandler lists the active alerts of the tenant.
+func (r *Ruler) AlertsHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID, err := tenant.TenantID(req.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", err)
+		return
+	}
+	alerts := make([]*alertJSON, 0)
+	for _, g := range r.Groups(tenantID) {
+		for _, rule := range g.Rules {
+			if ar, ok := rule.(*AlertingRule); ok {
+				alerts = append(alerts, alertsToJSON(ar.ActiveAlerts())...)
+			}
+		}
+	}
+	util.WriteJSONResponse(w, response{
+		Status: "success",
+		Data:   alertsData{Alerts: alerts},
+	})
+}
+
+// RulesHandler lists the rule groups of the tenant along with the
+// rules state and their active alerts.
+func (r *Ruler) RulesHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID, err := tenant.TenantID(req.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unauthorized", err)
+		return
+	}
+	groups := make([]*ruleGroupJSON, 0)
+	for _, g := range r.Groups(tenantID) {
+		lastEvaluation, evaluationTime := g.LastEvaluation()
+		gj := &ruleGroupJSON{
+			Name:           g.Name,
+			File:           g.Namespace,
+			Rules:          make([]interface{}, 0, len(g.Rules)),
+			Interval:       r.cfg.EvaluationInterval.Seconds(),
+			LastEvaluation: lastEvaluation,
+			EvaluationTime: evaluationTime.Seconds(),
+		}
+		for _, rule := range g.Rules {
+			gj.Rules = append(gj.Rules, ruleToJSON(rule))
+		}
+		groups = append(groups, gj)
+	}
+	util.WriteJSONResponse(w, response{
+		Status: "success",
+		Data:   rulesData{Groups: groups},
+	})
+}
+
+// MetricsHandler exposes the samples of the tenant recording
+// rules in the Prometheus text exposition format.
+func (r *Ruler) MetricsHandler(w http.ResponseWriter, req *http.Request) {
+	tenantID, err := tenant.TenantID(req.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	families := make(map[string]*dto.MetricFamily)
+	var names []string
+	for _, g := range r.Groups(tenantID) {
+		for _, rule := range g.Rules {
+			rr, ok := rule.(*RecordingRule)
+			if !ok {
+				continue
+			}
+			rec := rr.Rule()
+			name := rec.Record
+			mf, ok := families[name]
+			if !ok {
+				mf = &dto.MetricFamily{
+					Name: proto.String(name),
+					Help: proto.String("Recording rule " + rec.Query()),
+					Type: dto.MetricType_GAUGE.Enum(),
+				}
+				families[name] = mf
+				names = append(names, name)
+			}
+			for _, s := range rr.Samples() {
+				m := &dto.Metric{Gauge: &dto.Gauge{Value: proto.Float64(s.Value)}}
+				s.Labels.Range(func(l labels.Label) {
+					if l.Name != labels.MetricName {
+						m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(l.Name), Value: proto.String(l.Value)})
+					}
+				})
+				mf.Metric = append(mf.Metric, m)
+			}
+		}
+	}
+	sort.Strings(names)
+	format := expfmt.Negotiate(req.Header)
+	w.Header().Set("Content-Type", string(format))
+	enc := expfmt.NewEncoder(w, format)
+	for _, name := range names {
+		if err = enc.Encode(families[name]); err != nil {
+			level.Warn(r.logger).Log("msg", "failed to encode recording rules metrics", "err", err)
+			return
+		}
+	}
+}
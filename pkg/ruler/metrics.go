@@ -0,0 +1,40 @@
+package ruler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type metrics struct {
+	evaluations        *prometheus.CounterVec
+	evaluationFailures *prometheus.CounterVec
+	alerts             *prometheus.GaugeVec
+
+	remoteWriteSamples  *prometheus.CounterVec
+	remoteWriteFailures *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	return &metrics{
+		evaluations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_rule_evaluations_total",
+			Help: "Total number of rule evaluations.",
+		}, []string{"tenant"}),
+		evaluationFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_rule_evaluation_failures_total",
+			Help: "Total number of failed rule evaluations.",
+		}, []string{"tenant"}),
+		alerts: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pyroscope_ruler_alerts",
+			Help: "Number of active alerts by state.",
+		}, []string{"tenant", "state"}),
+		remoteWriteSamples: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_remote_write_samples_total",
+			Help: "Total number of recording rules samples pushed to the remote-write endpoint.",
+		}, []string{"tenant"}),
+		remoteWriteFailures: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_ruler_remote_write_failures_total",
+			Help: "Total number of failed remote-write requests.",
+		}, []string{"tenant"}),
+	}
+}
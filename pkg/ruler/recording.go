@@ -0,0 +1,89 @@
+package ruler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Sample is a value of a series produced by a recording rule.
+type Sample struct {
+	Labels    labels.Labels
+	Value     float64
+	Timestamp time.Time
+}
+
+// RecordingRule holds the samples produced by the
+// last successful evaluation of the rule.
+type RecordingRule struct {
+	rule Rule
+
+	mu             sync.Mutex
+	samples        []Sample
+	lastError      error
+	lastEvaluation time.Time
+	evaluationTime time.Duration
+}
+
+func NewRecordingRule(rule Rule) *RecordingRule {
+	return &RecordingRule{rule: rule}
+}
+
+func (r *RecordingRule) Rule() Rule { return r.rule }
+
+// Eval evaluates the rule at ts and replaces the samples. If the
+// evaluation fails, the samples of the previous evaluation are dropped,
+// so that stale values are not exported.
+func (r *RecordingRule) Eval(ctx context.Context, q SeriesQuerier, ts time.Time) error {
+	start := time.Now()
+	values, err := r.rule.selectValues(ctx, q, ts)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastEvaluation = ts
+	r.evaluationTime = time.Since(start)
+	r.lastError = err
+	r.samples = nil
+	if err != nil {
+		return err
+	}
+	r.samples = make([]Sample, 0, len(values))
+	for _, v := range values {
+		r.samples = append(r.samples, Sample{
+			Labels:    r.sampleLabels(v.labels),
+			Value:     v.value,
+			Timestamp: ts,
+		})
+	}
+	sort.Slice(r.samples, func(i, j int) bool {
+		return labels.Compare(r.samples[i].Labels, r.samples[j].Labels) < 0
+	})
+	return nil
+}
+
+func (r *RecordingRule) sampleLabels(series labels.Labels) labels.Labels {
+	b := labels.NewBuilder(series)
+	for name, value := range r.rule.Labels {
+		b.Set(name, value)
+	}
+	b.Set(labels.MetricName, r.rule.Record)
+	return b.Labels()
+}
+
+// Samples returns the samples of the last evaluation, ordered by labels.
+func (r *RecordingRule) Samples() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Sample(nil), r.samples...)
+}
+
+// LastEvaluation returns the time of the last evaluation,
+// its duration, and the error, if the evaluation failed.
+func (r *RecordingRule) LastEvaluation() (time.Time, time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastEvaluation, r.evaluationTime, r.lastError
+}
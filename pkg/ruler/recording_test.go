@@ -0,0 +1,115 @@
+package ruler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRecordingRules = `
+groups:
+  - name: json
+    rules:
+      - record: pod:json_cpu_nanoseconds:sum
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        call_site: [main.main, encoding/json.Marshal]
+        group_by: [pod]
+        range: 1m
+        labels:
+          team: a
+`
+
+func Test_RecordingRule_Eval(t *testing.T) {
+	q := &mockQuerier{
+		total:    map[string]float64{"a": 100, "b": 100},
+		selected: map[string]float64{"a": 30, "b": 10},
+	}
+	r := NewRecordingRule(Rule{
+		Record:      "pod:json_cpu_nanoseconds:sum",
+		ProfileType: "process_cpu:cpu:nanoseconds:cpu:nanoseconds",
+		Selector:    "{}",
+		CallSite:    []string{"main.main", "encoding/json.Marshal"},
+		GroupBy:     []string{"pod"},
+		Value:       ValueTotal,
+		Range:       model.Duration(time.Minute),
+		Labels:      map[string]string{"team": "a"},
+	})
+	ts := time.Unix(1000, 0)
+	require.NoError(t, r.Eval(context.Background(), q, ts))
+	require.Len(t, q.requests, 1)
+	assert.Equal(t, "encoding/json.Marshal", q.requests[0].StackTraceSelector.CallSite[1].Name)
+	assert.Equal(t, []string{"pod"}, q.requests[0].GroupBy)
+
+	assert.Equal(t, []Sample{
+		{Labels: labels.FromStrings("__name__", "pod:json_cpu_nanoseconds:sum", "pod", "a", "team", "a"), Value: 30, Timestamp: ts},
+		{Labels: labels.FromStrings("__name__", "pod:json_cpu_nanoseconds:sum", "pod", "b", "team", "a"), Value: 10, Timestamp: ts},
+	}, r.Samples())
+}
+
+func Test_Ruler_RecordingRules(t *testing.T) {
+	var (
+		received *prompb.WriteRequest
+		orgID    string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		orgID = req.Header.Get("X-Scope-OrgID")
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, body)
+		require.NoError(t, err)
+		received = new(prompb.WriteRequest)
+		require.NoError(t, received.Unmarshal(data))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tenant"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tenant", "rules.yaml"), []byte(testRecordingRules), 0o644))
+
+	q := &mockQuerier{selected: map[string]float64{"a": 30}}
+	cfg := Config{
+		RulePath:              dir,
+		EvaluationInterval:    time.Minute,
+		EvaluationConcurrency: 1,
+		RemoteWrite:           RemoteWriteConfig{URL: server.URL},
+	}
+	r, err := New(cfg, q, log.NewNopLogger(), prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ts := time.Unix(1000, 0)
+	r.syncRules()
+	r.evaluate(context.Background(), ts)
+
+	assert.Equal(t, "tenant", orgID)
+	require.NotNil(t, received)
+	require.Len(t, received.Timeseries, 1)
+	assert.Equal(t, []prompb.Label{
+		{Name: "__name__", Value: "pod:json_cpu_nanoseconds:sum"},
+		{Name: "pod", Value: "a"},
+		{Name: "team", Value: "a"},
+	}, received.Timeseries[0].Labels)
+	assert.Equal(t, []prompb.Sample{{Value: 30, Timestamp: ts.UnixMilli()}}, received.Timeseries[0].Samples)
+
+	req := httptest.NewRequest(http.MethodGet, "/ruler/metrics", nil)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "tenant"))
+	w := httptest.NewRecorder()
+	r.MetricsHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "# TYPE pod:json_cpu_nanoseconds:sum gauge\n")
+	assert.Contains(t, w.Body.String(), `pod:json_cpu_nanoseconds:sum{pod="a",team="a"} 30`)
+}
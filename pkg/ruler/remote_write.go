@@ -0,0 +1,105 @@
+package ruler
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/grafana/pyroscope/pkg/util"
+)
+
+type RemoteWriteConfig struct {
+	URL               string         `yaml:"url"`
+	Timeout           time.Duration  `yaml:"timeout" category:"advanced"`
+	BasicAuthUsername string         `yaml:"basic_auth_username"`
+	BasicAuthPassword flagext.Secret `yaml:"basic_auth_password"`
+}
+
+func (cfg *RemoteWriteConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.URL, "ruler.remote-write.url", "", "URL of the Prometheus remote-write endpoint the recording rules samples are pushed to. If empty, the samples are only exposed at the /ruler/metrics endpoint.")
+	f.DurationVar(&cfg.Timeout, "ruler.remote-write.timeout", 30*time.Second, "Timeout of a remote-write request.")
+	f.StringVar(&cfg.BasicAuthUsername, "ruler.remote-write.basic-auth-username", "", "Username for the remote-write endpoint basic authentication.")
+	f.Var(&cfg.BasicAuthPassword, "ruler.remote-write.basic-auth-password", "Password for the remote-write endpoint basic authentication.")
+}
+
+// remoteWriter pushes samples to a Prometheus remote-write endpoint.
+// Samples of a tenant are sent with the X-Scope-OrgID header, so that
+// they end up in the same tenant of a multi-tenant metrics backend.
+type remoteWriter struct {
+	cfg    RemoteWriteConfig
+	client *http.Client
+}
+
+func newRemoteWriter(cfg RemoteWriteConfig) *remoteWriter {
+	return &remoteWriter{
+		cfg: cfg,
+		// The instrumented client uses HTTP/2 without TLS, which
+		// is not what a remote-write endpoint is expected to speak.
+		client: &http.Client{
+			Transport: util.WrapWithInstrumentedHTTPTransport(http.DefaultTransport),
+		},
+	}
+}
+
+func (w *remoteWriter) push(ctx context.Context, tenantID string, samples []Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	data, err := buildWriteRequest(samples).Marshal()
+	if err != nil {
+		return err
+	}
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(snappy.Encode(nil, data)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("X-Scope-OrgID", tenantID)
+	if w.cfg.BasicAuthUsername != "" {
+		req.SetBasicAuth(w.cfg.BasicAuthUsername, w.cfg.BasicAuthPassword.String())
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("remote write failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func buildWriteRequest(samples []Sample) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{
+		Timeseries: make([]prompb.TimeSeries, 0, len(samples)),
+	}
+	for _, s := range samples {
+		ts := prompb.TimeSeries{
+			Labels:  make([]prompb.Label, 0, s.Labels.Len()),
+			Samples: []prompb.Sample{{Value: s.Value, Timestamp: s.Timestamp.UnixMilli()}},
+		}
+		s.Labels.Range(func(l labels.Label) {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: l.Name, Value: l.Value})
+		})
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+	return req
+}
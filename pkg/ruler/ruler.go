@@ -0,0 +1,280 @@
+package ruler
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/concurrency"
+	"github.com/grafana/dskit/services"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/pyroscope/pkg/tenant"
+)
+
+type Config struct {
+	RulePath              string        `yaml:"rule_path"`
+	EvaluationInterval    time.Duration `yaml:"evaluation_interval"`
+	EvaluationConcurrency int           `yaml:"evaluation_concurrency" category:"advanced"`
+	QueryAddress          string        `yaml:"query_address"`
+
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+}
+
+func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&cfg.RulePath, "ruler.rule-path", "", "Directory containing the rule files, laid out as <rule-path>/<tenant>/<namespace>. Each file contains rule groups evaluated for the tenant. If empty, the ruler is disabled.")
+	f.DurationVar(&cfg.EvaluationInterval, "ruler.evaluation-interval", time.Minute, "How frequently the rules are evaluated. The rule files are reloaded at the same interval.")
+	f.IntVar(&cfg.EvaluationConcurrency, "ruler.evaluation-concurrency", 4, "Max number of rule groups evaluated concurrently.")
+	f.StringVar(&cfg.QueryAddress, "ruler.query-address", "", "HTTP address of the query-frontend or querier the rules are evaluated with. If empty, the local server is used.")
+	cfg.RemoteWrite.RegisterFlags(f)
+}
+
+// RuleEvaluator is a rule along with its evaluation state:
+// either an *AlertingRule or a *RecordingRule.
+type RuleEvaluator interface {
+	Rule() Rule
+	Eval(ctx context.Context, q SeriesQuerier, ts time.Time) error
+	LastEvaluation() (time.Time, time.Duration, error)
+}
+
+func newRuleEvaluator(rule Rule) RuleEvaluator {
+	if rule.Record != "" {
+		return NewRecordingRule(rule)
+	}
+	return NewAlertingRule(rule)
+}
+
+// Group is a group of rules of a tenant. Rules
+// of a group are evaluated sequentially.
+type Group struct {
+	Namespace string
+	Name      string
+	Rules     []RuleEvaluator
+
+	mu             sync.Mutex
+	lastEvaluation time.Time
+	evaluationTime time.Duration
+}
+
+func (g *Group) eval(ctx context.Context, q SeriesQuerier, ts time.Time) (evaluated, failed int) {
+	start := time.Now()
+	for _, r := range g.Rules {
+		if ctx.Err() != nil {
+			break
+		}
+		evaluated++
+		if err := r.Eval(ctx, q, ts); err != nil {
+			failed++
+		}
+	}
+	g.mu.Lock()
+	g.lastEvaluation = ts
+	g.evaluationTime = time.Since(start)
+	g.mu.Unlock()
+	return evaluated, failed
+}
+
+// LastEvaluation returns the time and duration of the last evaluation.
+func (g *Group) LastEvaluation() (time.Time, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastEvaluation, g.evaluationTime
+}
+
+// Samples returns the samples of the group recording rules.
+func (g *Group) Samples() []Sample {
+	var samples []Sample
+	for _, r := range g.Rules {
+		if rr, ok := r.(*RecordingRule); ok {
+			samples = append(samples, rr.Samples()...)
+		}
+	}
+	return samples
+}
+
+// Ruler periodically evaluates rules of all tenants, keeps track
+// of the alerts they create, and exports samples of recording rules.
+type Ruler struct {
+	services.Service
+
+	cfg         Config
+	logger      log.Logger
+	querier     SeriesQuerier
+	metrics     *metrics
+	remoteWrite *remoteWriter
+
+	mu     sync.RWMutex
+	groups map[string][]*Group // By tenant.
+}
+
+func New(cfg Config, querier SeriesQuerier, logger log.Logger, reg prometheus.Registerer) (*Ruler, error) {
+	r := &Ruler{
+		cfg:     cfg,
+		logger:  logger,
+		querier: querier,
+		metrics: newMetrics(reg),
+		groups:  make(map[string][]*Group),
+	}
+	if cfg.RemoteWrite.URL != "" {
+		r.remoteWrite = newRemoteWriter(cfg.RemoteWrite)
+	}
+	r.Service = services.NewBasicService(r.starting, r.running, nil)
+	return r, nil
+}
+
+func (r *Ruler) starting(context.Context) error {
+	r.syncRules()
+	return nil
+}
+
+func (r *Ruler) running(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.EvaluationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.syncRules()
+			r.evaluate(ctx, time.Now())
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// syncRules reloads the rule files. The state of rules that have
+// not changed since the last sync is preserved. If a file can not be
+// loaded, rule groups previously loaded from the file are kept.
+func (r *Ruler) syncRules() {
+	files, err := LoadRules(r.cfg.RulePath)
+	if err != nil {
+		level.Warn(r.logger).Log("msg", "failed to load rules", "err", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	groups := make(map[string][]*Group, len(files))
+	for tenantID, tenantFiles := range files {
+		current := make(map[[2]string]*Group)
+		for _, g := range r.groups[tenantID] {
+			current[[2]string{g.Namespace, g.Name}] = g
+		}
+		for _, f := range tenantFiles {
+			if f.Err != nil {
+				level.Warn(r.logger).Log("msg", "failed to load rules", "tenant", tenantID, "err", f.Err)
+				for _, g := range r.groups[tenantID] {
+					if g.Namespace == f.Namespace {
+						groups[tenantID] = append(groups[tenantID], g)
+					}
+				}
+				continue
+			}
+			for _, rg := range f.Groups {
+				g := &Group{
+					Namespace: f.Namespace,
+					Name:      rg.Name,
+					Rules:     make([]RuleEvaluator, len(rg.Rules)),
+				}
+				prev := current[[2]string{f.Namespace, rg.Name}]
+				for i, rule := range rg.Rules {
+					if prev != nil && i < len(prev.Rules) && reflect.DeepEqual(prev.Rules[i].Rule(), rule) {
+						g.Rules[i] = prev.Rules[i]
+						continue
+					}
+					g.Rules[i] = newRuleEvaluator(rule)
+				}
+				groups[tenantID] = append(groups[tenantID], g)
+			}
+		}
+	}
+	r.groups = groups
+}
+
+func (r *Ruler) evaluate(ctx context.Context, ts time.Time) {
+	type job struct {
+		tenantID string
+		group    *Group
+	}
+	var jobs []job
+	r.mu.RLock()
+	for tenantID, groups := range r.groups {
+		for _, g := range groups {
+			jobs = append(jobs, job{tenantID: tenantID, group: g})
+		}
+	}
+	r.mu.RUnlock()
+
+	_ = concurrency.ForEachJob(ctx, len(jobs), r.cfg.EvaluationConcurrency, func(ctx context.Context, idx int) error {
+		j := jobs[idx]
+		sp, ctx := opentracing.StartSpanFromContext(tenant.InjectTenantID(ctx, j.tenantID), "Ruler.EvaluateGroup")
+		defer sp.Finish()
+		sp.SetTag("tenant", j.tenantID)
+		sp.SetTag("namespace", j.group.Namespace)
+		sp.SetTag("group", j.group.Name)
+		evaluated, failed := j.group.eval(ctx, r.querier, ts)
+		r.metrics.evaluations.WithLabelValues(j.tenantID).Add(float64(evaluated))
+		r.metrics.evaluationFailures.WithLabelValues(j.tenantID).Add(float64(failed))
+		if failed > 0 {
+			level.Warn(r.logger).Log("msg", "failed to evaluate rules", "tenant", j.tenantID,
+				"namespace", j.group.Namespace, "group", j.group.Name, "failed", failed)
+		}
+		if r.remoteWrite != nil {
+			r.pushSamples(ctx, j.tenantID, j.group)
+		}
+		return nil
+	})
+	r.updateAlertsMetric()
+}
+
+func (r *Ruler) pushSamples(ctx context.Context, tenantID string, g *Group) {
+	samples := g.Samples()
+	if len(samples) == 0 {
+		return
+	}
+	if err := r.remoteWrite.push(ctx, tenantID, samples); err != nil {
+		r.metrics.remoteWriteFailures.WithLabelValues(tenantID).Inc()
+		level.Warn(r.logger).Log("msg", "failed to push recording rules samples", "tenant", tenantID,
+			"namespace", g.Namespace, "group", g.Name, "err", err)
+		return
+	}
+	r.metrics.remoteWriteSamples.WithLabelValues(tenantID).Add(float64(len(samples)))
+}
+
+func (r *Ruler) updateAlertsMetric() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.metrics.alerts.Reset()
+	for tenantID, groups := range r.groups {
+		var pending, firing int
+		for _, g := range groups {
+			for _, rule := range g.Rules {
+				ar, ok := rule.(*AlertingRule)
+				if !ok {
+					continue
+				}
+				for _, a := range ar.ActiveAlerts() {
+					switch a.State {
+					case StatePending:
+						pending++
+					case StateFiring:
+						firing++
+					}
+				}
+			}
+		}
+		r.metrics.alerts.WithLabelValues(tenantID, StatePending.String()).Set(float64(pending))
+		r.metrics.alerts.WithLabelValues(tenantID, StateFiring.String()).Set(float64(firing))
+	}
+}
+
+// Groups returns the rule groups of the tenant.
+func (r *Ruler) Groups(tenantID string) []*Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.groups[tenantID]
+}
@@ -0,0 +1,82 @@
+package ruler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/user"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Ruler(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tenant"), 0o755))
+	path := filepath.Join(dir, "tenant", "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testRules), 0o644))
+
+	q := &mockQuerier{
+		total:    map[string]float64{"a": 100},
+		selected: map[string]float64{"a": 30},
+	}
+	r, err := New(Config{RulePath: dir, EvaluationInterval: time.Minute, EvaluationConcurrency: 1}, q, log.NewNopLogger(), prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ts := time.Now()
+	r.syncRules()
+	r.evaluate(context.Background(), ts)
+	r.evaluate(context.Background(), ts.Add(5*time.Minute))
+
+	// The alert state is preserved if the rules do not change.
+	r.syncRules()
+	groups := r.Groups("tenant")
+	require.Len(t, groups, 1)
+	alerts := groups[0].Rules[0].(*AlertingRule).ActiveAlerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, StateFiring, alerts[0].State)
+
+	req := httptest.NewRequest(http.MethodGet, "/prometheus/api/v1/alerts", nil)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "tenant"))
+	w := httptest.NewRecorder()
+	r.AlertsHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Status string
+		Data   struct {
+			Alerts []struct {
+				Labels map[string]string
+				State  string
+				Value  string
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "success", resp.Status)
+	require.Len(t, resp.Data.Alerts, 1)
+	assert.Equal(t, "firing", resp.Data.Alerts[0].State)
+	assert.Equal(t, "MallocHighShare", resp.Data.Alerts[0].Labels["alertname"])
+	assert.Equal(t, "3e-01", resp.Data.Alerts[0].Value)
+
+	// Rules of a file that can not be parsed are kept.
+	require.NoError(t, os.WriteFile(path, []byte(testRules+"        unknown_field: 1m\n"), 0o644))
+	r.syncRules()
+	groups = r.Groups("tenant")
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0].Rules[0].(*AlertingRule).ActiveAlerts(), 1)
+
+	// Once the rule is changed, the state is reset.
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(testRules, "0.2", "0.5", 1)), 0o644))
+	r.syncRules()
+	groups = r.Groups("tenant")
+	require.Len(t, groups, 1)
+	assert.Empty(t, groups[0].Rules[0].(*AlertingRule).ActiveAlerts())
+}
@@ -0,0 +1,242 @@
+package ruler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	phlaremodel "github.com/grafana/pyroscope/pkg/model"
+)
+
+// Value types a rule can be evaluated against.
+const (
+	// ValueTotal is the sum of the selected profiles values.
+	ValueTotal = "total"
+	// ValueShare is the share of the stack traces matching the call
+	// site in the selected profiles total, in the range [0, 1].
+	ValueShare = "share"
+)
+
+var operators = map[string]func(v, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+}
+
+// RuleGroups is the content of a rule file.
+type RuleGroups struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is either an alerting or a recording rule. The rule selects
+// profiles of the given type over the range preceding the evaluation
+// time, and aggregates their values into a series per set of group_by
+// labels.
+//
+// An alerting rule compares the series values with the threshold. An
+// alert is created for every series matching the condition; the alert
+// is firing once the condition has been true for the duration specified.
+//
+// A recording rule exports the series values as metrics
+// named after the rule, so they outlive the profiles.
+//
+// For example, the rule below fires if runtime.mallocgc called from
+// main.run accounts for more than 20% of CPU time of the service:
+//
+//	alert: MallocHighShare
+//	profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+//	selector: '{service_name="my-service"}'
+//	call_site: [main.main, main.run, runtime.mallocgc]
+//	value: share
+//	range: 10m
+//	op: '>'
+//	threshold: 0.2
+type Rule struct {
+	Alert       string            `yaml:"alert,omitempty"`
+	Record      string            `yaml:"record,omitempty"`
+	ProfileType string            `yaml:"profile_type"`
+	Selector    string            `yaml:"selector"`
+	CallSite    []string          `yaml:"call_site,omitempty"`
+	GroupBy     []string          `yaml:"group_by,omitempty"`
+	Value       string            `yaml:"value,omitempty"`
+	Range       model.Duration    `yaml:"range"`
+	Op          string            `yaml:"op,omitempty"`
+	Threshold   float64           `yaml:"threshold"`
+	For         model.Duration    `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+func (r *Rule) setDefaults() {
+	if r.Value == "" {
+		r.Value = ValueTotal
+	}
+	if r.Alert != "" && r.Op == "" {
+		r.Op = ">"
+	}
+	if r.Selector == "" {
+		r.Selector = "{}"
+	}
+}
+
+// Name returns the alert name of an alerting rule,
+// or the metric name of a recording rule.
+func (r *Rule) Name() string {
+	if r.Record != "" {
+		return r.Record
+	}
+	return r.Alert
+}
+
+func (r *Rule) validate() error {
+	switch {
+	case r.Alert == "" && r.Record == "":
+		return fmt.Errorf("either alert or record name is required")
+	case r.Alert != "" && r.Record != "":
+		return fmt.Errorf("alert and record names are mutually exclusive")
+	case !model.IsValidMetricName(model.LabelValue(r.Name())):
+		return fmt.Errorf("invalid rule name %q", r.Name())
+	}
+	if r.Record != "" {
+		if r.Op != "" || r.Threshold != 0 || r.For != 0 || len(r.Annotations) > 0 {
+			return fmt.Errorf("op, threshold, for, and annotations are not supported by recording rules")
+		}
+	} else if _, ok := operators[r.Op]; !ok {
+		return fmt.Errorf("unknown operator %q", r.Op)
+	}
+	if _, err := phlaremodel.ParseProfileTypeSelector(r.ProfileType); err != nil {
+		return err
+	}
+	if r.Range <= 0 {
+		return fmt.Errorf("range must be positive")
+	}
+	switch r.Value {
+	case ValueTotal:
+	case ValueShare:
+		if len(r.CallSite) == 0 {
+			return fmt.Errorf("call site is required for %q value", ValueShare)
+		}
+	default:
+		return fmt.Errorf("unknown value %q", r.Value)
+	}
+	for name := range r.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
+
+// Query returns a human-readable representation of the rule expression.
+func (r *Rule) Query() string {
+	var b strings.Builder
+	b.WriteString(r.Value)
+	b.WriteString("(")
+	b.WriteString(r.ProfileType)
+	b.WriteString(r.Selector)
+	if len(r.CallSite) > 0 {
+		b.WriteString(", call_site=")
+		b.WriteString(strings.Join(r.CallSite, ";"))
+	}
+	b.WriteString(")")
+	if len(r.GroupBy) > 0 {
+		b.WriteString(" by (")
+		b.WriteString(strings.Join(r.GroupBy, ", "))
+		b.WriteString(")")
+	}
+	fmt.Fprintf(&b, "[%s]", r.Range)
+	if r.Alert != "" {
+		fmt.Fprintf(&b, " %s %v", r.Op, r.Threshold)
+	}
+	return b.String()
+}
+
+// ParseRuleGroups parses and validates rule groups.
+func ParseRuleGroups(content []byte) (*RuleGroups, error) {
+	var groups RuleGroups
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&groups); err != nil && err != io.EOF {
+		return nil, err
+	}
+	names := make(map[string]struct{}, len(groups.Groups))
+	for i := range groups.Groups {
+		g := &groups.Groups[i]
+		if g.Name == "" {
+			return nil, fmt.Errorf("group name is required")
+		}
+		if _, ok := names[g.Name]; ok {
+			return nil, fmt.Errorf("duplicate group name %q", g.Name)
+		}
+		names[g.Name] = struct{}{}
+		for j := range g.Rules {
+			g.Rules[j].setDefaults()
+			if err := g.Rules[j].validate(); err != nil {
+				return nil, errors.Wrapf(err, "group %q, rule %d", g.Name, j)
+			}
+		}
+	}
+	return &groups, nil
+}
+
+// RuleFile is a rule file of a tenant. The file name is
+// used as the namespace of the rule groups it contains.
+type RuleFile struct {
+	Namespace string
+	Groups    []RuleGroup
+	// Err is set if the file can not be read or parsed.
+	Err error
+}
+
+// LoadRules loads rule files of all tenants from the directory. The
+// expected layout is <dir>/<tenant>/<namespace>, where each file
+// contains rule groups.
+func LoadRules(dir string) (map[string][]RuleFile, error) {
+	tenants, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	rules := make(map[string][]RuleFile)
+	for _, t := range tenants {
+		if !t.IsDir() {
+			continue
+		}
+		tenantDir := filepath.Join(dir, t.Name())
+		files, err := os.ReadDir(tenantDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			rf := RuleFile{Namespace: f.Name()}
+			path := filepath.Join(tenantDir, f.Name())
+			content, err := os.ReadFile(path)
+			if err == nil {
+				var groups *RuleGroups
+				if groups, err = ParseRuleGroups(content); err == nil {
+					rf.Groups = groups.Groups
+				}
+			}
+			if err != nil {
+				rf.Err = errors.Wrapf(err, "loading rule file %s", path)
+			}
+			rules[t.Name()] = append(rules[t.Name()], rf)
+		}
+	}
+	return rules, nil
+}
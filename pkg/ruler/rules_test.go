@@ -0,0 +1,103 @@
+package ruler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+groups:
+  - name: cpu
+    rules:
+      - alert: MallocHighShare
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        selector: '{service_name="my-service"}'
+        call_site: [main.main, runtime.mallocgc]
+        value: share
+        range: 10m
+        threshold: 0.2
+        for: 5m
+        labels:
+          severity: warning
+        annotations:
+          summary: 'mallocgc share is {{ $value }}'
+`
+
+func Test_ParseRuleGroups(t *testing.T) {
+	groups, err := ParseRuleGroups([]byte(testRules))
+	require.NoError(t, err)
+	require.Len(t, groups.Groups, 1)
+	require.Len(t, groups.Groups[0].Rules, 1)
+	r := groups.Groups[0].Rules[0]
+	assert.Equal(t, "MallocHighShare", r.Alert)
+	assert.Equal(t, ValueShare, r.Value)
+	assert.Equal(t, ">", r.Op)
+	assert.Equal(t, model.Duration(10*time.Minute), r.Range)
+	assert.Equal(t, model.Duration(5*time.Minute), r.For)
+	assert.Equal(t, `share(process_cpu:cpu:nanoseconds:cpu:nanoseconds{service_name="my-service"}, call_site=main.main;runtime.mallocgc)[10m] > 0.2`, r.Query())
+
+	groups, err = ParseRuleGroups([]byte(`
+groups:
+  - name: json
+    rules:
+      - record: service:json_cpu_nanoseconds:sum
+        profile_type: process_cpu:cpu:nanoseconds:cpu:nanoseconds
+        call_site: [encoding/json.Marshal]
+        group_by: [service_name]
+        range: 1m
+`))
+	require.NoError(t, err)
+	r = groups.Groups[0].Rules[0]
+	assert.Equal(t, "service:json_cpu_nanoseconds:sum", r.Name())
+	assert.Empty(t, r.Op)
+	assert.Equal(t, `total(process_cpu:cpu:nanoseconds:cpu:nanoseconds{}, call_site=encoding/json.Marshal) by (service_name)[1m]`, r.Query())
+
+	groups, err = ParseRuleGroups(nil)
+	require.NoError(t, err)
+	assert.Empty(t, groups.Groups)
+}
+
+func Test_ParseRuleGroups_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown field":     "groups: [{name: a, rules: [{alert: A, foo: bar}]}]",
+		"no group name":     "groups: [{rules: []}]",
+		"duplicate group":   "groups: [{name: a}, {name: a}]",
+		"no alert name":     "groups: [{name: a, rules: [{profile_type: 'a:b:c:d:e', range: 1m}]}]",
+		"invalid type":      "groups: [{name: a, rules: [{alert: A, profile_type: cpu, range: 1m}]}]",
+		"no range":          "groups: [{name: a, rules: [{alert: A, profile_type: 'a:b:c:d:e'}]}]",
+		"unknown operator":  "groups: [{name: a, rules: [{alert: A, profile_type: 'a:b:c:d:e', range: 1m, op: '=='}]}]",
+		"share no callsite": "groups: [{name: a, rules: [{alert: A, profile_type: 'a:b:c:d:e', range: 1m, value: share}]}]",
+		"alert and record":  "groups: [{name: a, rules: [{alert: A, record: a, profile_type: 'a:b:c:d:e', range: 1m}]}]",
+		"invalid record":    "groups: [{name: a, rules: [{record: 'a-b', profile_type: 'a:b:c:d:e', range: 1m}]}]",
+		"record threshold":  "groups: [{name: a, rules: [{record: a, profile_type: 'a:b:c:d:e', range: 1m, threshold: 1}]}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRuleGroups([]byte(content))
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_LoadRules(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tenant-a"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tenant-b"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tenant-a", "rules.yaml"), []byte(testRules), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tenant-b", "invalid.yaml"), []byte("groups: [{}]"), 0o644))
+
+	rules, err := LoadRules(dir)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	require.Len(t, rules["tenant-a"], 1)
+	assert.Equal(t, "rules.yaml", rules["tenant-a"][0].Namespace)
+	assert.NoError(t, rules["tenant-a"][0].Err)
+	assert.Len(t, rules["tenant-a"][0].Groups, 1)
+	require.Len(t, rules["tenant-b"], 1)
+	assert.Error(t, rules["tenant-b"][0].Err)
+}
@@ -19,6 +19,7 @@ import (
 	phlareobj "github.com/grafana/pyroscope/pkg/objstore"
 	"github.com/grafana/pyroscope/pkg/phlaredb"
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
+	phlarebucket "github.com/grafana/pyroscope/pkg/phlaredb/bucket"
 )
 
 // TODO move this to a config.
@@ -30,8 +31,9 @@ type BucketStoreStats struct {
 }
 
 type BucketStore struct {
-	bucket  phlareobj.Bucket
-	fetcher block.MetadataFetcher
+	bucket     phlareobj.Bucket
+	fetcher    block.MetadataFetcher
+	tombstones *phlarebucket.TombstonesLoader
 
 	tenantID, syncDir string
 
@@ -60,6 +62,8 @@ func NewBucketStore(bucket phlareobj.Bucket, fetcher block.MetadataFetcher, tena
 		)),
 	}
 
+	s.tombstones = phlarebucket.NewTombstonesLoader(s.bucket, phlarebucket.TombstonesRefreshInterval)
+
 	if err := os.MkdirAll(syncDir, 0o750); err != nil {
 		return nil, errors.Wrap(err, "create dir")
 	}
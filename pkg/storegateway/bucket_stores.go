@@ -31,6 +31,8 @@ type BucketStoreConfig struct {
 	IgnoreBlocksWithin       time.Duration `yaml:"ignore_blocks_within" category:"advanced"`
 	MetaSyncConcurrency      int           `yaml:"meta_sync_concurrency" category:"advanced"`
 	IgnoreDeletionMarksDelay time.Duration `yaml:"ignore_deletion_mark_delay" category:"advanced"`
+
+	Cache phlareobj.CachingBucketConfig `yaml:"cache"`
 }
 
 // RegisterFlags registers the BucketStore flags
@@ -56,6 +58,7 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger)
 	// f.DurationVar(&cfg.DeprecatedConsistencyDelay, consistencyDelayFlag, 0, "Minimum age of a block before it's being read. Set it to safe value (e.g 30m) if your object storage is eventually consistent. GCS and S3 are (roughly) strongly consistent.")
 	f.DurationVar(&cfg.IgnoreDeletionMarksDelay, "blocks-storage.bucket-store.ignore-deletion-marks-delay", 30*time.Minute, "Duration after which the blocks marked for deletion will be filtered out while fetching blocks. "+
 		"The idea of ignore-deletion-marks-delay is to ignore blocks that are marked for deletion with some delay. This ensures store can still serve blocks that are meant to be deleted but do not have a replacement yet.")
+	cfg.Cache.RegisterFlagsWithPrefix("blocks-storage.bucket-store.cache.", f)
 	// f.IntVar(&cfg.PostingOffsetsInMemSampling, "blocks-storage.bucket-store.posting-offsets-in-mem-sampling", DefaultPostingOffsetInMemorySampling, "Controls what is the ratio of postings offsets that the store will hold in memory.")
 	// f.BoolVar(&cfg.IndexHeaderLazyLoadingEnabled, "blocks-storage.bucket-store.index-header-lazy-loading-enabled", true, "If enabled, store-gateway will lazy load an index-header only once required by a query.")
 	// f.DurationVar(&cfg.IndexHeaderLazyLoadingIdleTimeout, "blocks-storage.bucket-store.index-header-lazy-loading-idle-timeout", 60*time.Minute, "If index-header lazy loading is enabled and this setting is > 0, the store-gateway will offload unused index-headers after 'idle timeout' inactivity.")
@@ -67,6 +70,9 @@ func (cfg *BucketStoreConfig) RegisterFlags(f *flag.FlagSet, logger log.Logger)
 
 // Validate the config.
 func (cfg *BucketStoreConfig) Validate(logger log.Logger) error {
+	if err := cfg.Cache.Validate(); err != nil {
+		return errors.Wrap(err, "cache configuration")
+	}
 	// if cfg.StreamingBatchSize <= 0 {
 	// 	return errInvalidStreamingBatchSize
 	// }
@@ -121,6 +127,10 @@ type BucketStores struct {
 }
 
 func NewBucketStores(cfg BucketStoreConfig, shardingStrategy ShardingStrategy, storageBucket phlareobj.Bucket, limits Limits, logger log.Logger, reg prometheus.Registerer) (*BucketStores, error) {
+	storageBucket, err := phlareobj.NewCachingBucket(storageBucket, cfg.Cache, logger, reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "create caching bucket")
+	}
 	bs := &BucketStores{
 		storageBucket: storageBucket,
 		logger:        logger,
@@ -113,6 +113,57 @@ func (s *StoreGateway) Series(ctx context.Context, req *connect.Request[ingestv1
 	return connect.NewResponse(res), nil
 }
 
+func (s *StoreGateway) GetCardinality(ctx context.Context, req *connect.Request[ingestv1.GetCardinalityRequest]) (*connect.Response[ingestv1.GetCardinalityResponse], error) {
+	res := new(ingestv1.GetCardinalityResponse)
+	_, err := s.forBucketStore(ctx, func(bs *BucketStore) error {
+		var err error
+		res, err = phlaredb.GetCardinality(ctx, req.Msg, bs.openBlocksForReading)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (s *StoreGateway) SelectExemplars(ctx context.Context, req *connect.Request[ingestv1.SelectExemplarsRequest]) (*connect.Response[ingestv1.SelectExemplarsResponse], error) {
+	res := new(ingestv1.SelectExemplarsResponse)
+	_, err := s.forBucketStore(ctx, func(bs *BucketStore) error {
+		var err error
+		res, err = phlaredb.SelectExemplars(ctx, req.Msg, bs.openBlocksForReading)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (s *StoreGateway) SelectProfileByID(ctx context.Context, req *connect.Request[ingestv1.SelectProfileByIDRequest]) (*connect.Response[ingestv1.SelectProfileByIDResponse], error) {
+	res := new(ingestv1.SelectProfileByIDResponse)
+	_, err := s.forBucketStore(ctx, func(bs *BucketStore) error {
+		var err error
+		res, err = phlaredb.SelectProfileByID(ctx, req.Msg, bs.openBlocksForReading)
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+
+	return connect.NewResponse(res), nil
+}
+
 func (s *StoreGateway) MergeSpanProfile(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeSpanProfileRequest, ingestv1.MergeSpanProfileResponse]) error {
 	found, err := s.forBucketStore(ctx, func(bs *BucketStore) error {
 		return bs.MergeSpanProfile(ctx, stream)
@@ -179,7 +230,11 @@ func (s *BucketStore) openBlocksForReading(ctx context.Context, minT, maxT model
 	if err := querier.Open(ctx); err != nil {
 		return nil, err
 	}
-	return querier, nil
+	tombstones, err := s.tombstones.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return phlaredb.ApplyTombstones(querier, tombstones), nil
 }
 
 func (store *BucketStore) MergeProfilesStacktraces(ctx context.Context, stream *connect.BidiStream[ingestv1.MergeProfilesStacktracesRequest, ingestv1.MergeProfilesStacktracesResponse]) error {
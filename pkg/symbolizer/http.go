@@ -0,0 +1,112 @@
+package symbolizer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+
+	elf2 "github.com/grafana/pyroscope/ebpf/symtab/elf"
+
+	"github.com/grafana/pyroscope/pkg/tenant"
+)
+
+// ServeHTTP handles the debug file uploads: PUT /debuginfo/{build_id}
+// stores the ELF file in the request body, and HEAD reports whether the
+// debug file of the build ID exists. The file must have the build ID
+// and the symbols; typically, it is either the unstripped binary, or the
+// debug file produced with objcopy --only-keep-debug.
+func (s *Symbolizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := tenant.ExtractTenantIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	buildID := strings.ToLower(mux.Vars(r)["build_id"])
+	if !buildIDRe.MatchString(buildID) {
+		http.Error(w, "invalid build ID: a hex-encoded GNU build ID is expected", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodHead:
+		ok, err := s.tenantBucket(tenantID).Exists(r.Context(), buildID)
+		switch {
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case http.MethodPut:
+		s.upload(w, r, tenantID, buildID)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Symbolizer) upload(w http.ResponseWriter, r *http.Request, tenantID, buildID string) {
+	tmp, err := os.CreateTemp(s.cfg.DataDir, "upload-*.tmp")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	body := http.MaxBytesReader(w, r.Body, s.cfg.MaxDebugFileSize)
+	if _, err = io.Copy(tmp, body); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("debug file exceeds the size limit of %d bytes", s.cfg.MaxDebugFileSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = validateDebugFile(tmp.Name(), buildID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = s.tenantBucket(tenantID).Upload(r.Context(), buildID, tmp); err != nil {
+		level.Error(s.logger).Log("msg", "failed to upload debug file", "tenant", tenantID, "build_id", buildID, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The file may have been looked up before it was uploaded.
+	key := tenantID + "/" + buildID
+	s.mu.Lock()
+	s.files.Remove(key)
+	s.missing.Remove(key)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func validateDebugFile(path, buildID string) error {
+	me, err := elf2.NewMMapedElfFile(path)
+	if err != nil {
+		return fmt.Errorf("invalid ELF file: %w", err)
+	}
+	id, err := me.GNUBuildID()
+	me.Close()
+	if err != nil {
+		return err
+	}
+	if id.ID != buildID {
+		return fmt.Errorf("build ID mismatch: the file has build ID %s", id.ID)
+	}
+	f, err := openDebugFile(path)
+	if err != nil {
+		return err
+	}
+	f.table.Cleanup()
+	return nil
+}
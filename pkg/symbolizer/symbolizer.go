@@ -0,0 +1,453 @@
+// Package symbolizer resolves function names of the native code in the
+// profiles that only carry addresses: for example, the profiles of
+// stripped binaries. The debug files are uploaded to the object storage
+// by the users, and are looked up by the build ID of the mapping.
+package symbolizer
+
+import (
+	"context"
+	"debug/elf"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	elf2 "github.com/grafana/pyroscope/ebpf/symtab/elf"
+
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	"github.com/grafana/pyroscope/pkg/objstore"
+)
+
+type Config struct {
+	Enabled          bool   `yaml:"enabled" category:"experimental"`
+	DataDir          string `yaml:"data_dir" category:"experimental"`
+	MaxCachedFiles   int    `yaml:"max_cached_files" category:"experimental"`
+	MaxDebugFileSize int64  `yaml:"max_debug_file_size_bytes" category:"experimental"`
+
+	DownloadTimeout time.Duration `yaml:"download_timeout" category:"experimental"`
+}
+
+func (cfg *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
+	f.BoolVar(&cfg.Enabled, prefix+"enabled", false, "Symbolize the native code locations of the pushed profiles, using the debug files uploaded to the object storage.")
+	f.StringVar(&cfg.DataDir, prefix+"data-dir", "./data/symbolizer", "Directory where the debug files are downloaded to. The directory is not required to be persisted between restarts.")
+	f.IntVar(&cfg.MaxCachedFiles, prefix+"max-cached-files", 64, "Maximum number of the debug files kept open in the data directory.")
+	f.Int64Var(&cfg.MaxDebugFileSize, prefix+"max-debug-file-size-bytes", 1<<30, "Maximum size of an uploaded debug file, in bytes.")
+	f.DurationVar(&cfg.DownloadTimeout, prefix+"download-timeout", 5*time.Minute, "Timeout of a debug file download. The debug files are downloaded in the background: the profiles are symbolized once the file is loaded.")
+}
+
+func (cfg *Config) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.DataDir == "" {
+		return errors.New("symbolizer data directory is required")
+	}
+	if cfg.MaxCachedFiles <= 0 {
+		return errors.New("symbolizer max cached files must be positive")
+	}
+	if cfg.DownloadTimeout <= 0 {
+		return errors.New("symbolizer download timeout must be positive")
+	}
+	return nil
+}
+
+const (
+	// missingDebugFileTTL specifies how long the absence of
+	// a debug file is cached for, before it is looked up again.
+	missingDebugFileTTL  = time.Minute
+	maxMissingDebugFiles = 4096
+
+	maxConcurrentDownloads = 4
+)
+
+var buildIDRe = regexp.MustCompile(`^[0-9a-f]{16,}$`)
+
+// Symbolizer fills in the functions of the locations that belong to the
+// mappings with a build ID and without functions. The debug files are
+// stored per tenant; once downloaded, a file is kept in the local data
+// directory until it is evicted.
+//
+// The debug files are downloaded in the background, so that the ingestion
+// is not blocked: only the profiles received after the file is loaded are
+// symbolized.
+type Symbolizer struct {
+	cfg    Config
+	logger log.Logger
+	bucket objstore.Bucket
+
+	mu        sync.Mutex
+	files     *simplelru.LRU[string, *debugFile]
+	missing   *simplelru.LRU[string, time.Time]
+	loading   map[string]struct{}
+	downloads chan struct{}
+
+	lookups        *prometheus.CounterVec
+	downloadsTotal *prometheus.CounterVec
+	locations      *prometheus.CounterVec
+}
+
+func New(cfg Config, bucket objstore.Bucket, logger log.Logger, reg prometheus.Registerer) (*Symbolizer, error) {
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, err
+	}
+	// Files downloaded by the previous run are not reused.
+	if err := removeContents(cfg.DataDir); err != nil {
+		return nil, err
+	}
+	s := &Symbolizer{
+		cfg:       cfg,
+		logger:    logger,
+		bucket:    bucket,
+		loading:   make(map[string]struct{}),
+		downloads: make(chan struct{}, maxConcurrentDownloads),
+		lookups: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_symbolizer_debug_file_lookups_total",
+			Help: "Total number of debug file lookups by result.",
+		}, []string{"result"}),
+		downloadsTotal: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_symbolizer_debug_file_downloads_total",
+			Help: "Total number of debug file downloads by result.",
+		}, []string{"result"}),
+		locations: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Name: "pyroscope_symbolizer_locations_total",
+			Help: "Total number of locations the symbolizer attempted to resolve by result.",
+		}, []string{"result"}),
+	}
+	s.files, _ = simplelru.NewLRU[string, *debugFile](cfg.MaxCachedFiles, func(_ string, f *debugFile) {
+		f.close()
+	})
+	s.missing, _ = simplelru.NewLRU[string, time.Time](maxMissingDebugFiles, nil)
+	return s, nil
+}
+
+type symbolTable interface {
+	Resolve(addr uint64) string
+	Cleanup()
+}
+
+type debugFile struct {
+	mu    sync.Mutex
+	path  string
+	typ   elf.Type
+	progs []elf.ProgHeader
+	table symbolTable
+	// goTable resolves the files and lines of the Go functions;
+	// nil if the file has no Go symbol table.
+	goTable *elf2.GoTable
+	closed  bool
+}
+
+func (f *debugFile) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.table.Cleanup()
+	_ = os.Remove(f.path)
+}
+
+// Symbolize resolves the locations in place. A mapping is marked as
+// having functions, if all of its locations have been resolved. The
+// mappings which debug files have not been loaded yet are skipped.
+func (s *Symbolizer) Symbolize(tenantID string, p *googlev1.Profile) {
+	var st *symbolTableBuilder
+	for _, m := range p.Mapping {
+		if m.HasFunctions || m.BuildId <= 0 || m.BuildId >= int64(len(p.StringTable)) {
+			continue
+		}
+		buildID := strings.ToLower(p.StringTable[m.BuildId])
+		if !buildIDRe.MatchString(buildID) {
+			continue
+		}
+		f := s.debugFile(tenantID, buildID)
+		if f == nil {
+			continue
+		}
+		if st == nil {
+			st = newSymbolTableBuilder(p)
+		}
+		s.symbolizeMapping(st, m, f)
+	}
+}
+
+func (s *Symbolizer) symbolizeMapping(st *symbolTableBuilder, m *googlev1.Mapping, f *debugFile) {
+	var resolved, unresolved int
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	for _, loc := range st.p.Location {
+		if loc.MappingId != m.Id || len(loc.Line) > 0 {
+			continue
+		}
+		addr := f.address(m, loc.Address)
+		name := f.table.Resolve(addr)
+		if name == "" {
+			unresolved++
+			continue
+		}
+		var (
+			file            string
+			line, startLine int
+		)
+		if f.goTable != nil {
+			file, line, startLine = f.goTable.ResolveFileLine(addr)
+		}
+		loc.Line = append(loc.Line, &googlev1.Line{
+			FunctionId: st.function(name, file, int64(startLine)),
+			Line:       int64(line),
+		})
+		resolved++
+	}
+	f.mu.Unlock()
+	m.HasFunctions = resolved > 0 && unresolved == 0
+	s.locations.WithLabelValues("resolved").Add(float64(resolved))
+	s.locations.WithLabelValues("unresolved").Add(float64(unresolved))
+}
+
+// address translates the runtime address to the virtual address
+// in the ELF file.
+func (f *debugFile) address(m *googlev1.Mapping, addr uint64) uint64 {
+	if f.typ == elf.ET_EXEC || m.MemoryLimit == 0 {
+		return addr
+	}
+	offset := addr - m.MemoryStart + m.FileOffset
+	for _, prog := range f.progs {
+		if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 && prog.Filesz > 0 &&
+			offset >= prog.Off && offset < prog.Off+prog.Filesz {
+			return offset - prog.Off + prog.Vaddr
+		}
+	}
+	// Segments of a separate debug file do not hold any data and
+	// their offsets are not preserved: assume that the virtual
+	// address equals the file offset, which is usually the case
+	// for the shared objects and PIE executables.
+	return offset
+}
+
+type symbolTableBuilder struct {
+	p         *googlev1.Profile
+	strings   map[string]int64
+	functions map[functionKey]uint64
+	maxID     uint64
+}
+
+type functionKey struct {
+	name      int64
+	filename  int64
+	startLine int64
+}
+
+func newSymbolTableBuilder(p *googlev1.Profile) *symbolTableBuilder {
+	b := &symbolTableBuilder{
+		p:         p,
+		strings:   make(map[string]int64, len(p.StringTable)),
+		functions: make(map[functionKey]uint64, len(p.Function)),
+	}
+	for i, s := range p.StringTable {
+		b.strings[s] = int64(i)
+	}
+	for _, fn := range p.Function {
+		b.functions[functionKey{name: fn.Name, filename: fn.Filename, startLine: fn.StartLine}] = fn.Id
+		if fn.Id > b.maxID {
+			b.maxID = fn.Id
+		}
+	}
+	return b
+}
+
+func (b *symbolTableBuilder) string(s string) int64 {
+	n, ok := b.strings[s]
+	if !ok {
+		n = int64(len(b.p.StringTable))
+		b.p.StringTable = append(b.p.StringTable, s)
+		b.strings[s] = n
+	}
+	return n
+}
+
+func (b *symbolTableBuilder) function(name, filename string, startLine int64) uint64 {
+	k := functionKey{name: b.string(name), startLine: startLine}
+	if filename != "" {
+		k.filename = b.string(filename)
+	}
+	if id, ok := b.functions[k]; ok {
+		return id
+	}
+	b.maxID++
+	id := b.maxID
+	b.p.Function = append(b.p.Function, &googlev1.Function{
+		Id:         id,
+		Name:       k.name,
+		SystemName: k.name,
+		Filename:   k.filename,
+		StartLine:  k.startLine,
+	})
+	b.functions[k] = id
+	return id
+}
+
+// debugFile returns the debug file of the build ID, or nil if the file
+// has not been loaded. The file is loaded in the background, unless it
+// is known to be missing.
+func (s *Symbolizer) debugFile(tenantID, buildID string) *debugFile {
+	key := tenantID + "/" + buildID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.files.Get(key); ok {
+		s.lookups.WithLabelValues("cached").Inc()
+		return f
+	}
+	if expiresAt, ok := s.missing.Get(key); ok {
+		if time.Now().Before(expiresAt) {
+			s.lookups.WithLabelValues("not_found").Inc()
+			return nil
+		}
+		s.missing.Remove(key)
+	}
+	s.lookups.WithLabelValues("pending").Inc()
+	if _, ok := s.loading[key]; !ok {
+		s.loading[key] = struct{}{}
+		go s.load(key, tenantID, buildID)
+	}
+	return nil
+}
+
+// load downloads the debug file. The download is not bound to the
+// request the file has been looked up for. Files that fail to load
+// are looked up again after the same period as the missing ones.
+func (s *Symbolizer) load(key, tenantID, buildID string) {
+	s.downloads <- struct{}{}
+	defer func() { <-s.downloads }()
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.DownloadTimeout)
+	defer cancel()
+	f, err := s.download(ctx, tenantID, buildID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.loading, key)
+	switch {
+	case err != nil:
+		level.Warn(s.logger).Log("msg", "failed to load debug file", "tenant", tenantID, "build_id", buildID, "err", err)
+		s.downloadsTotal.WithLabelValues("error").Inc()
+		s.missing.Add(key, time.Now().Add(missingDebugFileTTL))
+	case f == nil:
+		s.downloadsTotal.WithLabelValues("not_found").Inc()
+		s.missing.Add(key, time.Now().Add(missingDebugFileTTL))
+	default:
+		s.downloadsTotal.WithLabelValues("loaded").Inc()
+		s.files.Add(key, f)
+	}
+}
+
+func (s *Symbolizer) download(ctx context.Context, tenantID, buildID string) (*debugFile, error) {
+	rc, err := s.tenantBucket(tenantID).Get(ctx, buildID)
+	if err != nil {
+		if s.bucket.IsObjNotFoundErr(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rc.Close()
+	dir := filepath.Join(s.cfg.DataDir, tenantID)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, buildID)
+	if err = writeFile(path, rc, s.cfg.MaxDebugFileSize); err != nil {
+		return nil, err
+	}
+	f, err := openDebugFile(path)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	level.Debug(s.logger).Log("msg", "debug file loaded", "tenant", tenantID, "build_id", buildID)
+	return f, nil
+}
+
+func (s *Symbolizer) tenantBucket(tenantID string) objstore.Bucket {
+	return objstore.NewPrefixedBucket(s.bucket, tenantID+"/debuginfo")
+}
+
+// openDebugFile opens the ELF file and creates the symbol table.
+// The Go symbol table is preferred, if present; the ELF symbols
+// are used as a fallback for the non-Go code.
+func openDebugFile(path string) (*debugFile, error) {
+	me, err := elf2.NewMMapedElfFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &debugFile{
+		path:  path,
+		typ:   me.Type,
+		progs: me.Progs,
+	}
+	goTable, goErr := me.NewGoTable()
+	var opts elf2.SymbolsOptions
+	if goErr == nil && goTable.Index.Entry.Length() > 0 {
+		opts.FilterFrom = goTable.Index.Entry.Get(0)
+		opts.FilterTo = goTable.Index.End
+	}
+	symTable, symErr := me.NewSymbolTable(&opts)
+	if goErr == nil {
+		f.goTable = goTable
+	}
+	switch {
+	case goErr == nil && symErr == nil:
+		f.table = &elf2.GoTableWithFallback{GoTable: goTable, SymTable: symTable}
+	case goErr == nil:
+		f.table = goTable
+	case symErr == nil:
+		f.table = symTable
+	default:
+		me.Close()
+		return nil, fmt.Errorf("no symbols found: %w", symErr)
+	}
+	return f, nil
+}
+
+func writeFile(path string, r io.Reader, maxSize int64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(tmp, io.LimitReader(r, maxSize+1))
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("debug file exceeds the size limit of %d bytes", maxSize)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func removeContents(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err = os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package symbolizer
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/thanos-io/objstore"
+
+	googlev1 "github.com/grafana/pyroscope/api/gen/proto/go/google/v1"
+	phlareobj "github.com/grafana/pyroscope/pkg/objstore"
+	"github.com/grafana/pyroscope/pkg/tenant"
+)
+
+const (
+	testdata    = "../../ebpf/symtab/elf/testdata/elfs/"
+	testBuildID = "1fcfa068c5fdb9f31e6d9f3f89019beacb70182d"
+)
+
+func newTestSymbolizer(t *testing.T) *Symbolizer {
+	t.Helper()
+	s, err := New(Config{
+		Enabled:          true,
+		DataDir:          t.TempDir(),
+		MaxCachedFiles:   2,
+		MaxDebugFileSize: 1 << 20,
+		DownloadTimeout:  time.Minute,
+	}, phlareobj.NewBucket(objstore.NewInMemBucket()), log.NewNopLogger(), prometheus.NewRegistry())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.files.Purge()
+	})
+	return s
+}
+
+func request(t *testing.T, s *Symbolizer, method, tenantID, buildID, file string) int {
+	t.Helper()
+	var body []byte
+	if file != "" {
+		var err error
+		body, err = os.ReadFile(testdata + file)
+		require.NoError(t, err)
+	}
+	r := httptest.NewRequest(method, "/debuginfo/"+buildID, bytes.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"build_id": buildID})
+	r = r.WithContext(tenant.InjectTenantID(r.Context(), tenantID))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w.Code
+}
+
+func Test_Upload(t *testing.T) {
+	s := newTestSymbolizer(t)
+	require.Equal(t, http.StatusNotFound, request(t, s, http.MethodHead, "tenant", testBuildID, ""))
+	require.Equal(t, http.StatusBadRequest, request(t, s, http.MethodPut, "tenant", "not-a-build-id", "elf.debug"))
+	require.Equal(t, http.StatusBadRequest, request(t, s, http.MethodPut, "tenant", testBuildID, "libexample.so"))
+	require.Equal(t, http.StatusBadRequest, request(t, s, http.MethodPut, "tenant", testBuildID, "elf.stripped"))
+	require.Equal(t, http.StatusOK, request(t, s, http.MethodPut, "tenant", testBuildID, "elf.debug"))
+	require.Equal(t, http.StatusOK, request(t, s, http.MethodHead, "tenant", testBuildID, ""))
+	require.Equal(t, http.StatusNotFound, request(t, s, http.MethodHead, "other", testBuildID, ""))
+}
+
+func testProfile() *googlev1.Profile {
+	return &googlev1.Profile{
+		StringTable: []string{"", testBuildID, "0000000000000000000000000000000000000000", "existing"},
+		Mapping: []*googlev1.Mapping{
+			{Id: 1, MemoryStart: 0x56483a0ef000, MemoryLimit: 0x56483a0f0000, FileOffset: 0x1000, BuildId: 1},
+			{Id: 2, MemoryStart: 0x7fa9f720f000, MemoryLimit: 0x7fa9f7210000, FileOffset: 0x1000, BuildId: 2},
+		},
+		Location: []*googlev1.Location{
+			{Id: 1, MappingId: 1, Address: 0x56483a0ef149},
+			{Id: 2, MappingId: 1, Address: 0x56483a0ef160},
+			{Id: 3, MappingId: 2, Address: 0x7fa9f720f139},
+		},
+		Function: []*googlev1.Function{{Id: 1, Name: 3}},
+	}
+}
+
+// symbolize symbolizes the profile once the debug files of its mappings
+// have been loaded, or are known to be missing.
+func symbolize(t *testing.T, s *Symbolizer, tenantID string, p *googlev1.Profile) {
+	t.Helper()
+	s.Symbolize(tenantID, p.CloneVT())
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.loading) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	s.Symbolize(tenantID, p)
+}
+
+func Test_Symbolize(t *testing.T) {
+	s := newTestSymbolizer(t)
+	require.Equal(t, http.StatusOK, request(t, s, http.MethodPut, "tenant", testBuildID, "elf.debug"))
+
+	// The profiles are not symbolized until the debug file is loaded.
+	p := testProfile()
+	s.Symbolize("tenant", p)
+	require.False(t, p.Mapping[0].HasFunctions)
+	require.Empty(t, p.Location[0].Line)
+
+	p = testProfile()
+	symbolize(t, s, "tenant", p)
+	require.True(t, p.Mapping[0].HasFunctions)
+	require.False(t, p.Mapping[1].HasFunctions)
+	names := make([]string, 0, len(p.Location))
+	for _, loc := range p.Location {
+		for _, line := range loc.Line {
+			names = append(names, p.StringTable[p.Function[line.FunctionId-1].Name])
+		}
+	}
+	require.Equal(t, []string{"iter", "main"}, names)
+	require.Empty(t, p.Location[2].Line)
+
+	// The debug files are only available to the tenant that uploaded them.
+	p = testProfile()
+	symbolize(t, s, "other", p)
+	require.False(t, p.Mapping[0].HasFunctions)
+	require.Empty(t, p.Location[0].Line)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(s.downloadsTotal.WithLabelValues("loaded")))
+	require.Equal(t, float64(3), testutil.ToFloat64(s.downloadsTotal.WithLabelValues("not_found")))
+	require.Equal(t, float64(0), testutil.ToFloat64(s.downloadsTotal.WithLabelValues("error")))
+}
+
+func Test_Symbolize_GoFileLine(t *testing.T) {
+	s := newTestSymbolizer(t)
+	s.cfg.MaxDebugFileSize = 4 << 20
+	// The test binary has no GNU build ID, and is stored bypassing the upload validation.
+	const buildID = "0123456789abcdef"
+	b, err := os.ReadFile(testdata + "go20")
+	require.NoError(t, err)
+	require.NoError(t, s.tenantBucket("tenant").Upload(context.Background(), buildID, bytes.NewReader(b)))
+
+	p := &googlev1.Profile{
+		StringTable: []string{"", buildID},
+		Mapping:     []*googlev1.Mapping{{Id: 1, MemoryStart: 0x400000, MemoryLimit: 0x482000, BuildId: 1}},
+		Location:    []*googlev1.Location{{Id: 1, MappingId: 1, Address: 0x4817a0 + 20}},
+	}
+	symbolize(t, s, "tenant", p)
+	require.True(t, p.Mapping[0].HasFunctions)
+	require.Len(t, p.Location[0].Line, 1)
+	line := p.Location[0].Line[0]
+	require.Equal(t, int64(6), line.Line)
+	fn := p.Function[line.FunctionId-1]
+	require.Equal(t, "main.main", p.StringTable[fn.Name])
+	require.Equal(t, "/go/hello.go", p.StringTable[fn.Filename])
+	require.Equal(t, int64(5), fn.StartLine)
+}
+
+func Test_Symbolize_DownloadSizeLimit(t *testing.T) {
+	s := newTestSymbolizer(t)
+	require.Equal(t, http.StatusOK, request(t, s, http.MethodPut, "tenant", testBuildID, "elf.debug"))
+	s.cfg.MaxDebugFileSize = 1 << 10
+
+	p := testProfile()
+	symbolize(t, s, "tenant", p)
+	require.False(t, p.Mapping[0].HasFunctions)
+	require.Equal(t, float64(1), testutil.ToFloat64(s.downloadsTotal.WithLabelValues("error")))
+	entries, err := os.ReadDir(filepath.Join(s.cfg.DataDir, "tenant"))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func Test_Symbolize_Eviction(t *testing.T) {
+	s := newTestSymbolizer(t)
+	for _, tenantID := range []string{"tenant", "a", "b"} {
+		require.Equal(t, http.StatusOK, request(t, s, http.MethodPut, tenantID, testBuildID, "elf.debug"))
+	}
+	symbolize(t, s, "tenant", testProfile())
+	f := s.debugFile("tenant", testBuildID)
+	require.NotNil(t, f)
+	require.FileExists(t, f.path)
+
+	symbolize(t, s, "a", testProfile())
+	symbolize(t, s, "b", testProfile())
+	require.NoFileExists(t, f.path)
+	require.True(t, f.closed)
+}
@@ -288,7 +288,7 @@ func (b *RequestBuilder) IngestJFRRequestBody(jfr []byte, labels []byte) *http.R
 }
 
 func (b *RequestBuilder) Render(metric string) *flamebearer.FlamebearerProfile {
-	queryURL := b.url + "/pyroscope/render?query=" + createRenderQuery(metric, b.AppName) + "&from=946656000&until=now&format=collapsed"
+	queryURL := b.url + "/pyroscope/render?query=" + createRenderQuery(metric, b.AppName) + "&from=946656000&until=now"
 	fmt.Println(queryURL)
 	queryRes, err := http.Get(queryURL)
 	require.NoError(b.t, err)
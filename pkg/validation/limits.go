@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/grafana/dskit/flagext"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
 	"gopkg.in/yaml.v3"
 
 	"github.com/grafana/pyroscope/pkg/phlaredb/block"
@@ -67,16 +70,20 @@ type Limits struct {
 	StoreGatewayTenantShardSize int `yaml:"store_gateway_tenant_shard_size" json:"store_gateway_tenant_shard_size"`
 
 	// Query frontend.
-	QuerySplitDuration model.Duration `yaml:"split_queries_by_interval" json:"split_queries_by_interval"`
+	QuerySplitDuration     model.Duration `yaml:"split_queries_by_interval" json:"split_queries_by_interval"`
+	MaxQueryEstimatedBytes int            `yaml:"max_query_estimated_bytes" json:"max_query_estimated_bytes"`
 
 	// Compactor.
-	CompactorBlocksRetentionPeriod     model.Duration `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
-	CompactorSplitAndMergeShards       int            `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
-	CompactorSplitAndMergeStageSize    int            `yaml:"compactor_split_and_merge_stage_size" json:"compactor_split_and_merge_stage_size"`
-	CompactorSplitGroups               int            `yaml:"compactor_split_groups" json:"compactor_split_groups"`
-	CompactorTenantShardSize           int            `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
-	CompactorPartialBlockDeletionDelay model.Duration `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
-	CompactorDownsamplerEnabled        bool           `yaml:"compactor_downsampler_enabled" json:"compactor_downsampler_enabled"`
+	CompactorBlocksRetentionPeriod     model.Duration         `yaml:"compactor_blocks_retention_period" json:"compactor_blocks_retention_period"`
+	CompactorSplitAndMergeShards       int                    `yaml:"compactor_split_and_merge_shards" json:"compactor_split_and_merge_shards"`
+	CompactorSplitAndMergeStageSize    int                    `yaml:"compactor_split_and_merge_stage_size" json:"compactor_split_and_merge_stage_size"`
+	CompactorSplitGroups               int                    `yaml:"compactor_split_groups" json:"compactor_split_groups"`
+	CompactorTenantShardSize           int                    `yaml:"compactor_tenant_shard_size" json:"compactor_tenant_shard_size"`
+	CompactorPartialBlockDeletionDelay model.Duration         `yaml:"compactor_partial_block_deletion_delay" json:"compactor_partial_block_deletion_delay"`
+	CompactorDownsamplerEnabled        bool                   `yaml:"compactor_downsampler_enabled" json:"compactor_downsampler_enabled"`
+	CompactorDownsamplerResolutions    flagext.StringSliceCSV `yaml:"compactor_downsampler_resolutions" json:"compactor_downsampler_resolutions"`
+	CompactorDownsamplerAggregations   flagext.StringSliceCSV `yaml:"compactor_downsampler_aggregations" json:"compactor_downsampler_aggregations"`
+	CompactorRetentionStreams          []RetentionStream      `yaml:"compactor_retention_streams" json:"compactor_retention_streams" doc:"nocli|description=Retention periods of the series matching the label selectors. The compactor removes the profiles of a series from the blocks that are entirely older than the retention period. When a series matches several selectors, the shortest period applies. The periods exceeding the blocks retention period have no effect."`
 
 	// This config doesn't have a CLI flag registered here because they're registered in
 	// their own original config struct.
@@ -89,6 +96,12 @@ type Limits struct {
 	RejectNewerThan model.Duration `yaml:"reject_newer_than" json:"reject_newer_than"`
 }
 
+// RetentionStream is the retention period of the series matching the selector.
+type RetentionStream struct {
+	Selector string         `yaml:"selector" json:"selector" doc:"description=Label selector of the series, for example {env=\"dev\"}."`
+	Period   model.Duration `yaml:"period" json:"period" doc:"description=Retention period of the series."`
+}
+
 // LimitError are errors that do not comply with the limits specified.
 type LimitError string
 
@@ -124,6 +137,8 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 
 	f.IntVar(&l.MaxQueryParallelism, "querier.max-query-parallelism", 0, "Maximum number of queries that will be scheduled in parallel by the frontend.")
 
+	f.IntVar(&l.MaxQueryEstimatedBytes, "querier.max-query-estimated-bytes", 0, "Maximum number of bytes a query is estimated to scan in the profile tables of the blocks. This limit is enforced in the query frontend. 0 to disable.")
+
 	f.IntVar(&l.MaxProfileSizeBytes, "validation.max-profile-size-bytes", 4*1024*1024, "Maximum size of a profile in bytes. This is based off the uncompressed size. 0 to disable.")
 	f.IntVar(&l.MaxProfileStacktraceSamples, "validation.max-profile-stacktrace-samples", 16000, "Maximum number of samples in a profile. 0 to disable.")
 	f.IntVar(&l.MaxProfileStacktraceSampleLabels, "validation.max-profile-stacktrace-sample-labels", 100, "Maximum number of labels in a profile sample. 0 to disable.")
@@ -144,6 +159,10 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	_ = l.CompactorPartialBlockDeletionDelay.Set("1d")
 	f.Var(&l.CompactorPartialBlockDeletionDelay, "compactor.partial-block-deletion-delay", fmt.Sprintf("If a partial block (unfinished block without %s file) hasn't been modified for this time, it will be marked for deletion. The minimum accepted value is %s: a lower value will be ignored and the feature disabled. 0 to disable.", block.MetaFilename, MinCompactorPartialBlockDeletionDelay.String()))
 	f.BoolVar(&l.CompactorDownsamplerEnabled, "compactor.compactor-downsampler-enabled", true, "If enabled, the compactor will downsample profiles in blocks at compaction level 3 and above. The original profiles are also kept.")
+	l.CompactorDownsamplerResolutions = []string{"5m", "1h"}
+	f.Var(&l.CompactorDownsamplerResolutions, "compactor.compactor-downsampler-resolutions", "Comma separated list of the resolutions of the downsampled profiles produced by the compactor.")
+	l.CompactorDownsamplerAggregations = []string{block.DownsampleAggregationSum}
+	f.Var(&l.CompactorDownsamplerAggregations, "compactor.compactor-downsampler-aggregations", fmt.Sprintf("Comma separated list of the aggregations of the downsampled profiles produced by the compactor, for each of the resolutions. Supported values: %s, %s, %s. Only the profiles downsampled with %s are used by queries.", block.DownsampleAggregationSum, block.DownsampleAggregationMax, block.DownsampleAggregationAvgPerProfile, block.DownsampleAggregationSum))
 
 	_ = l.RejectNewerThan.Set("10m")
 	f.Var(&l.RejectNewerThan, "validation.reject-newer-than", "This limits how far into the future profiling data can be ingested. This limit is enforced in the distributor. 0 to disable, defaults to 10m.")
@@ -174,9 +193,54 @@ func (l *Limits) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // Validate validates that this limits config is valid.
 func (l *Limits) Validate() error {
+	if _, err := l.compactorDownsamplerConfig(); err != nil {
+		return err
+	}
+	for _, r := range l.CompactorRetentionStreams {
+		if r.Period <= 0 {
+			return errors.Errorf("invalid retention period %v of selector %q: must be positive", r.Period, r.Selector)
+		}
+		matchers, err := parser.ParseMetricSelector(r.Selector)
+		if err != nil {
+			return errors.Wrapf(err, "invalid retention stream selector %q", r.Selector)
+		}
+		var nonEmpty bool
+		for _, m := range matchers {
+			if !m.Matches("") {
+				nonEmpty = true
+				break
+			}
+		}
+		if !nonEmpty {
+			return errors.Errorf("invalid retention stream selector %q: at least one matcher must not match empty values, use the blocks retention period to set the retention of all the series", r.Selector)
+		}
+	}
 	return nil
 }
 
+// compactorDownsamplerConfig returns the downsampling config of the limits.
+// The defaults apply to the options that are not specified.
+func (l *Limits) compactorDownsamplerConfig() (block.DownsampleConfig, error) {
+	config := block.DefaultDownsampleConfig()
+	if len(l.CompactorDownsamplerAggregations) > 0 {
+		config.Aggregations = l.CompactorDownsamplerAggregations
+	}
+	if len(l.CompactorDownsamplerResolutions) > 0 {
+		config.Resolutions = make([]time.Duration, 0, len(l.CompactorDownsamplerResolutions))
+		for _, v := range l.CompactorDownsamplerResolutions {
+			r, err := model.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return block.DownsampleConfig{}, errors.Wrapf(err, "invalid downsampling resolution %q", v)
+			}
+			config.Resolutions = append(config.Resolutions, time.Duration(r))
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return block.DownsampleConfig{}, err
+	}
+	return config, nil
+}
+
 // When we load YAML from disk, we want the various per-customer limits
 // to default to any values specified on the command line, not default
 // command line values.  This global contains those values.  I (Tom) cannot
@@ -337,6 +401,12 @@ func (o *Overrides) QuerySplitDuration(tenantID string) time.Duration {
 	return time.Duration(o.getOverridesForTenant(tenantID).QuerySplitDuration)
 }
 
+// MaxQueryEstimatedBytes returns the limit of the number of bytes a query
+// is estimated to scan.
+func (o *Overrides) MaxQueryEstimatedBytes(tenantID string) int {
+	return o.getOverridesForTenant(tenantID).MaxQueryEstimatedBytes
+}
+
 // CompactorTenantShardSize returns number of compactors that this user can use. 0 = all compactors.
 func (o *Overrides) CompactorTenantShardSize(userID string) int {
 	return o.getOverridesForTenant(userID).CompactorTenantShardSize
@@ -381,6 +451,23 @@ func (o *Overrides) CompactorDownsamplerEnabled(userId string) bool {
 	return o.getOverridesForTenant(userId).CompactorDownsamplerEnabled
 }
 
+// CompactorDownsamplerConfig returns the downsampled tables the compactor
+// produces for a given user.
+func (o *Overrides) CompactorDownsamplerConfig(userId string) block.DownsampleConfig {
+	config, err := o.getOverridesForTenant(userId).compactorDownsamplerConfig()
+	if err != nil {
+		// The limits are validated when loaded.
+		return block.DefaultDownsampleConfig()
+	}
+	return config
+}
+
+// CompactorRetentionStreams returns the retention periods of the series
+// matching the label selectors for a given user.
+func (o *Overrides) CompactorRetentionStreams(userID string) []RetentionStream {
+	return o.getOverridesForTenant(userID).CompactorRetentionStreams
+}
+
 // S3SSEType returns the per-tenant S3 SSE type.
 func (o *Overrides) S3SSEType(user string) string {
 	return o.getOverridesForTenant(user).S3SSEType
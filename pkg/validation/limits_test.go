@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/pyroscope/pkg/phlaredb/block"
 )
 
 func TestLimitsTagsYamlMatchJson(t *testing.T) {
@@ -60,6 +64,7 @@ max_concurrent_tail_requests: 160
 max_entries_limit_per_query: 170
 max_cache_freshness_per_query: 180s
 split_queries_by_interval: 190s
+max_query_estimated_bytes: 195
 ruler_evaluation_delay_duration: 200s
 ruler_max_rules_per_rule_group: 210
 ruler_max_rule_groups_per_tenant: 220
@@ -75,6 +80,11 @@ shard_streams:
 blocked_queries:
   - pattern: ".*foo.*"
     regex: true
+compactor_downsampler_resolutions: 5m,1h
+compactor_downsampler_aggregations: sum,max
+compactor_retention_streams:
+  - selector: '{env="dev"}'
+    period: 7d
 `
 	inputJSON := `
  {
@@ -105,6 +115,7 @@ blocked_queries:
   "max_entries_limit_per_query": 170,
   "max_cache_freshness_per_query": "180s",
   "split_queries_by_interval": "190s",
+  "max_query_estimated_bytes": 195,
   "ruler_evaluation_delay_duration": "200s",
   "ruler_max_rules_per_rule_group": 210,
   "ruler_max_rule_groups_per_tenant":220,
@@ -124,6 +135,14 @@ blocked_queries:
 		"pattern": ".*foo.*",
 		"regex": true
 	}
+  ],
+  "compactor_downsampler_resolutions": ["5m", "1h"],
+  "compactor_downsampler_aggregations": ["sum", "max"],
+  "compactor_retention_streams": [
+	{
+		"selector": "{env=\"dev\"}",
+		"period": "7d"
+	}
   ]
  }
 `
@@ -139,6 +158,114 @@ blocked_queries:
 	assert.Equal(t, limitsYAML, limitsJSON)
 }
 
+func TestLimits_ValidateRetentionStreams(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			yaml: `
+compactor_retention_streams:
+  - selector: '{env="dev"}'
+    period: 7d
+  - selector: '{env="prod", service_name=~"foo.*"}'
+    period: 90d
+`,
+		},
+		{
+			name: "invalid selector",
+			yaml: `
+compactor_retention_streams:
+  - selector: '{env="dev"'
+    period: 7d
+`,
+			wantErr: true,
+		},
+		{
+			name: "selector matching all series",
+			yaml: `
+compactor_retention_streams:
+  - selector: '{env=~".*"}'
+    period: 7d
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing period",
+			yaml: `
+compactor_retention_streams:
+  - selector: '{env="dev"}'
+`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var l Limits
+			require.NoError(t, yaml.Unmarshal([]byte(tc.yaml), &l))
+			err := l.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, l.CompactorRetentionStreams, 2)
+			assert.Equal(t, model.Duration(90*24*time.Hour), l.CompactorRetentionStreams[1].Period)
+		})
+	}
+}
+
+func TestLimits_ValidateDownsampler(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		yaml     string
+		expected block.DownsampleConfig
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			yaml:     `compactor_downsampler_enabled: true`,
+			expected: block.DefaultDownsampleConfig(),
+		},
+		{
+			name: "valid",
+			yaml: `
+compactor_downsampler_resolutions: 1m,15m,1d
+compactor_downsampler_aggregations: max,avg-per-profile
+`,
+			expected: block.DownsampleConfig{
+				Resolutions:  []time.Duration{time.Minute, 15 * time.Minute, 24 * time.Hour},
+				Aggregations: []string{block.DownsampleAggregationMax, block.DownsampleAggregationAvgPerProfile},
+			},
+		},
+		{
+			name:    "invalid resolution",
+			yaml:    `compactor_downsampler_resolutions: 5m,foo`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown aggregation",
+			yaml:    `compactor_downsampler_aggregations: sum,min`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var l Limits
+			require.NoError(t, yaml.Unmarshal([]byte(tc.yaml), &l))
+			err := l.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			config, err := l.compactorDownsamplerConfig()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, config)
+		})
+	}
+}
+
 func TestOverwriteMarshalingStringMapJSON(t *testing.T) {
 	m := NewOverwriteMarshalingStringMap(map[string]string{"foo": "bar"})
 
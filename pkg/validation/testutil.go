@@ -9,6 +9,9 @@ type MockLimits struct {
 	MaxQueryParallelismValue    int
 	MaxQueryLengthValue         time.Duration
 	MaxQueryLookbackValue       time.Duration
+	MaxQueryEstimatedBytesValue int
+	BlocksRetentionPeriodValue  time.Duration
+	RetentionStreamsValue       []RetentionStream
 	MaxLabelNameLengthValue     int
 	MaxLabelValueLengthValue    int
 	MaxLabelNamesPerSeriesValue int
@@ -33,6 +36,14 @@ func (m MockLimits) QuerySplitDuration(string) time.Duration        { return m.Q
 func (m MockLimits) MaxQueryParallelism(string) int                 { return m.MaxQueryParallelismValue }
 func (m MockLimits) MaxQueryLength(tenantID string) time.Duration   { return m.MaxQueryLengthValue }
 func (m MockLimits) MaxQueryLookback(tenantID string) time.Duration { return m.MaxQueryLookbackValue }
+func (m MockLimits) MaxQueryEstimatedBytes(tenantID string) int     { return m.MaxQueryEstimatedBytesValue }
+
+func (m MockLimits) CompactorBlocksRetentionPeriod(tenantID string) time.Duration {
+	return m.BlocksRetentionPeriodValue
+}
+func (m MockLimits) CompactorRetentionStreams(tenantID string) []RetentionStream {
+	return m.RetentionStreamsValue
+}
 
 func (m MockLimits) MaxFlameGraphNodesDefault(string) int { return m.MaxFlameGraphNodesDefaultValue }
 func (m MockLimits) MaxFlameGraphNodesMax(string) int     { return m.MaxFlameGraphNodesMaxValue }
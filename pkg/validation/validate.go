@@ -63,6 +63,7 @@ const (
 	LabelValueTooLongErrorMsg           = "profile with labels '%s' has label value too long: '%s'"
 	DuplicateLabelNamesErrorMsg         = "profile with labels '%s' has duplicate label name: '%s'"
 	QueryTooLongErrorMsg                = "the query time range exceeds the limit (max_query_length, actual: %s, limit: %s)"
+	QueryTooExpensiveErrorMsg           = "the query is estimated to scan more bytes than the limit (max_query_estimated_bytes, estimated: %d, limit: %d), reduce the time range of the query"
 	ProfileTooBigErrorMsg               = "the profile with labels '%s' exceeds the size limit (max_profile_size_byte, actual: %d, limit: %d)"
 	ProfileTooManySamplesErrorMsg       = "the profile with labels '%s' exceeds the samples count limit (max_profile_stacktrace_samples, actual: %d, limit: %d)"
 	ProfileTooManySampleLabelsErrorMsg  = "the profile with labels '%s' exceeds the sample labels limit (max_profile_stacktrace_sample_labels, actual: %d, limit: %d)"
@@ -345,3 +346,20 @@ func ValidateMaxNodes(l FlameGraphLimits, tenantIDs []string, n int64) (int64, e
 	}
 	return n, nil
 }
+
+type QueryEstimateLimits interface {
+	MaxQueryEstimatedBytes(tenantID string) int
+}
+
+// MaxQueryEstimatedBytes returns the smallest limit of the tenants, or 0
+// if the limit is disabled.
+func MaxQueryEstimatedBytes(l QueryEstimateLimits, tenantIDs []string) uint64 {
+	return uint64(validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, l.MaxQueryEstimatedBytes))
+}
+
+func ValidateQueryEstimate(l QueryEstimateLimits, tenantIDs []string, estimatedBytes uint64) error {
+	if maxBytes := MaxQueryEstimatedBytes(l, tenantIDs); maxBytes > 0 && estimatedBytes > maxBytes {
+		return NewErrorf(QueryLimit, QueryTooExpensiveErrorMsg, estimatedBytes, maxBytes)
+	}
+	return nil
+}
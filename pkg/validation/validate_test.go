@@ -400,3 +400,39 @@ func TestValidateFlamegraphMaxNodes(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateQueryEstimate(t *testing.T) {
+	type testCase struct {
+		name   string
+		bytes  uint64
+		limits QueryEstimateLimits
+		err    error
+	}
+
+	testCases := []testCase{
+		{
+			name:   "limit disabled",
+			bytes:  100,
+			limits: MockLimits{},
+		},
+		{
+			name:   "within limit",
+			bytes:  100,
+			limits: MockLimits{MaxQueryEstimatedBytesValue: 100},
+		},
+		{
+			name:   "limit exceeded",
+			bytes:  101,
+			limits: MockLimits{MaxQueryEstimatedBytesValue: 100},
+			err: &Error{Reason: "query_limit", msg: "the query is estimated to scan more bytes than the limit " +
+				"(max_query_estimated_bytes, estimated: 101, limit: 100), reduce the time range of the query"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, ValidateQueryEstimate(tc.limits, []string{"tenant"}, tc.bytes))
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/grafana/pyroscope/pkg/objstore/providers/swift"
 	"github.com/grafana/pyroscope/pkg/querier"
 	"github.com/grafana/pyroscope/pkg/querier/worker"
+	"github.com/grafana/pyroscope/pkg/ruler"
 	"github.com/grafana/pyroscope/pkg/scheduler"
 	"github.com/grafana/pyroscope/pkg/storegateway"
 	"github.com/grafana/pyroscope/pkg/validation"
@@ -73,6 +74,11 @@ var RootBlocks = []RootBlock{
 		StructType: reflect.TypeOf(compactor.Config{}),
 		Desc:       "The compactor block configures the compactor.",
 	},
+	{
+		Name:       "ruler",
+		StructType: reflect.TypeOf(ruler.Config{}),
+		Desc:       "The ruler block configures the ruler.",
+	},
 	{
 		Name:       "grpc_client",
 		StructType: reflect.TypeOf(grpcclient.Config{}),